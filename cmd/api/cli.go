@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"clean-arch-go/internal/domain/service"
+	"clean-arch-go/internal/infrastructure/search"
+	"clean-arch-go/internal/pkg/container"
+)
+
+const cliUsage = `Usage: api <command> [flags]
+
+Commands:
+  translations export-tmx [-lang code] [-o file]
+  translations import-tmx [-dry-run] [-overwrite] <file>
+  translations export-xliff -lang code [-o file]
+  translations import-xliff [-dry-run] [-overwrite] <file>
+  search rebuild-index
+  lending sweep
+  trash purge
+
+Without a command the HTTP and gRPC servers are started.
+`
+
+// runCLI runs a maintenance command and returns the process exit code
+func runCLI(c *container.Container, args []string) int {
+	if len(args) < 2 {
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+
+	var err error
+	ctx := context.Background()
+	switch args[0] + " " + args[1] {
+	case "translations export-tmx", "translations export-xliff":
+		err = runTranslationExport(ctx, c.TranslationMemorySvc, args[1], args[2:])
+	case "translations import-tmx", "translations import-xliff":
+		err = runTranslationImport(ctx, c.TranslationMemorySvc, args[1], args[2:])
+	case "search rebuild-index":
+		err = runRebuildIndex(ctx, c)
+	case "lending sweep":
+		err = c.LendingSvc.Sweep(ctx)
+	case "trash purge":
+		err = c.BookTrashSvc.PurgeExpired(ctx)
+	default:
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", args[1], err)
+		return 1
+	}
+	return 0
+}
+
+func runTranslationExport(ctx context.Context, svc service.TranslationMemoryService, command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	lang := flags.String("lang", "", "target language")
+	output := flags.String("o", "", "output file (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if command == "export-tmx" {
+		return svc.ExportTMX(ctx, w, *lang)
+	}
+	if *lang == "" {
+		return fmt.Errorf("-lang is required")
+	}
+	return svc.ExportXLIFF(ctx, w, *lang)
+}
+
+func runTranslationImport(ctx context.Context, svc service.TranslationMemoryService, command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	var opts service.ImportOptions
+	flags.BoolVar(&opts.DryRun, "dry-run", false, "only report the changes")
+	flags.BoolVar(&opts.Overwrite, "overwrite", false, "apply conflicting entries")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected exactly one file")
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var report *service.ImportReport
+	if command == "import-tmx" {
+		report, err = svc.ImportTMX(ctx, file, opts)
+	} else {
+		report, err = svc.ImportXLIFF(ctx, file, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// runRebuildIndex rebuilds the embedded search index from the database. A
+// running server keeps its own copy of the index until it's restarted.
+func runRebuildIndex(ctx context.Context, c *container.Container) error {
+	index := c.SearchIndex
+	if index == nil {
+		index = search.NewIndexBackend(c.BookRepo, c.ShelfRepo, c.Config.Search.IndexPath)
+	}
+
+	count, err := index.Rebuild(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Indexed %d books into %s\n", count, c.Config.Search.IndexPath)
+	return nil
+}
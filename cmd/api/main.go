@@ -7,6 +7,7 @@ import (
 	http "net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 
 	"clean-arch-go/docs" // docs is generated by Swag CLI
 	"clean-arch-go/internal/domain/service"
+	"clean-arch-go/internal/infrastructure/repository/cached"
 	"clean-arch-go/internal/pkg/config"
 	"clean-arch-go/internal/pkg/container"
 	"clean-arch-go/internal/pkg/i18n"
@@ -48,12 +50,29 @@ func main() {
 		log.Fatalf("Failed to initialize container: %v", err)
 	}
 
+	// Run a maintenance command instead of the servers
+	if len(os.Args) > 1 {
+		code := runCLI(container, os.Args[1:])
+		container.Close()
+		os.Exit(code)
+	}
+
 	// Initialize Gin router
 	router := setupRouter(
 		container.AuthSvc,
 		container.BookSvc,
 		container.TranslationSvc,
+		container.GlossarySvc,
+		container.TranslationMemorySvc,
+		container.BookCoverSvc,
+		container.ShelfSvc,
+		container.ReadingSvc,
+		container.LendingSvc,
+		container.NotificationSvc,
+		container.BookTransferSvc,
+		container.BookTrashSvc,
 		container.RedisClient,
+		container.Caches,
 		container.Config,
 	)
 
@@ -87,6 +106,16 @@ func main() {
 		}
 	}()
 
+	// Expire the holds not picked up in time and report the overdue loans
+	sweepCtx, stopSweep := context.WithCancel(context.Background())
+	go runPeriodically(sweepCtx, "Lending sweep", time.Duration(cfg.Lending.SweepMinute)*time.Minute, container.LendingSvc.Sweep)
+	// Purge the books deleted for longer than the trash retention period
+	go runPeriodically(sweepCtx, "Trash purge", time.Duration(cfg.Trash.PurgeMinute)*time.Minute, container.BookTrashSvc.PurgeExpired)
+
+	// Run the book import and export jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	go container.BookTransferSvc.Run(jobsCtx)
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -101,15 +130,37 @@ func main() {
 	}
 
 	grpcServer.Stop()
+	stopSweep()
+	stopJobs()
 
-	// Close Redis client
-	if err := container.RedisClient.Close(); err != nil {
-		log.Printf("Redis client shutdown error: %v", err)
+	// Close the container resources: search index, Redis and database
+	if err := container.Close(); err != nil {
+		log.Printf("Container shutdown error: %v", err)
 	}
 
 	log.Println("Server exiting")
 }
 
+// runPeriodically runs a maintenance task every interval until ctx is done,
+// never when interval isn't positive
+func runPeriodically(ctx context.Context, name string, interval time.Duration, task func(context.Context) error) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := task(ctx); err != nil {
+				log.Printf("%s failed: %v", name, err)
+			}
+		}
+	}
+}
+
 // @title           Clean Architecture Go API
 // @version         1.0
 // @description     This is a sample server for Clean Architecture in Go.
@@ -146,6 +197,26 @@ func healthCheck(c *gin.Context) {
 	})
 }
 
+// cacheStats returns the lookup statistics of the repository caches
+// @Summary Show the cache statistics
+// @Description Get the hits, negative hits (lookups of missing entities answered by the cache), misses and in-memory hits of each repository cache since the server started
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]cached.Stats "Statistics by cache"
+// @Failure 401 {object} handler.ErrorResponse "Unauthorized"
+// @Failure 403 {object} handler.ErrorResponse "Not an admin"
+// @Router /api/admin/cache/stats [get]
+func cacheStats(caches map[string]cached.StatsReporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats := make(map[string]cached.Stats, len(caches))
+		for name, cache := range caches {
+			stats[name] = cache.Stats()
+		}
+		c.JSON(http.StatusOK, stats)
+	}
+}
+
 // @contact.name   API Support
 // @contact.url    http://www.swagger.io/support
 // @contact.email  support@swagger.io
@@ -162,7 +233,17 @@ func setupRouter(
 	authSvc service.AuthService,
 	bookSvc service.BookService,
 	translationSvc service.TranslationService,
+	glossarySvc service.GlossaryService,
+	translationMemorySvc service.TranslationMemoryService,
+	bookCoverSvc service.BookCoverService,
+	shelfSvc service.ShelfService,
+	readingSvc service.ReadingService,
+	lendingSvc service.LendingService,
+	notificationSvc service.NotificationService,
+	bookTransferSvc service.BookTransferService,
+	bookTrashSvc service.BookTrashService,
 	redisClient *redis.RedisClient,
+	caches map[string]cached.StatsReporter,
 	cfg *config.Config,
 ) *gin.Engine {
 	// Initialize i18n
@@ -186,6 +267,11 @@ func setupRouter(
 	// Health check endpoint
 	router.GET("/health", healthCheck)
 
+	// Serve the media files of the local storage
+	if cfg.Storage.Backend == "local" && strings.HasPrefix(cfg.Storage.PublicURL, "/") {
+		router.Static(cfg.Storage.PublicURL, cfg.Storage.LocalPath)
+	}
+
 	// Swagger documentation
 	docs.SwaggerInfo.BasePath = "/api"
 	swaggerURL := ginSwagger.URL("/swagger/doc.json")
@@ -200,24 +286,75 @@ func setupRouter(
 		authSvc,
 		bookSvc,
 		translationSvc,
+		glossarySvc,
+		translationMemorySvc,
+		bookCoverSvc,
+		shelfSvc,
+		readingSvc,
+		lendingSvc,
+		notificationSvc,
+		bookTransferSvc,
+		bookTrashSvc,
 		redisClient,
 		httpconfig.NewHTTPConfig(cfg),
 	)
+	authMiddleware := middleware.NewAuthMiddleware(authSvc)
 
 	// Public routes with rate limiting
 	public := router.Group("/api")
 	public.Use(rateLimiter)
 	// Register auth routes
 	h.AuthHandler.RegisterAuthRoutes(public)
+	// Register the share links of unlisted and public books
+	h.RegisterSharedBookRoutes(public)
 
-	// Register translation routes
-	h.RegisterTranslationRoutes(public)
+	// Register translation routes, applying the caller's glossaries when authenticated
+	translations := router.Group("/api")
+	translations.Use(rateLimiter, authMiddleware.AuthOptional())
+	h.RegisterTranslationRoutes(translations)
 
 	// Protected routes (require authentication)
 	protected := router.Group("/api")
-	protected.Use(rateLimiter, middleware.NewAuthMiddleware(authSvc).AuthRequired())
+	protected.Use(rateLimiter, authMiddleware.AuthRequired())
 	// Register book routes
 	h.RegisterBookRoutes(protected)
+	h.RegisterBookTranslationRoutes(protected)
+	h.RegisterBookRevisionRoutes(protected)
+	h.RegisterBookCoverRoutes(protected)
+	h.RegisterSharingRoutes(protected)
+	h.RegisterBookTransferRoutes(protected)
+	h.RegisterTrashRoutes(protected)
+
+	// Register tag and shelf routes
+	h.RegisterTagRoutes(protected)
+	h.RegisterShelfRoutes(protected)
+
+	// Register reading progress routes
+	h.RegisterReadingRoutes(protected)
+
+	// Register review routes
+	h.RegisterReviewRoutes(protected)
+
+	// Register lending library and notification routes
+	h.RegisterLendingRoutes(protected)
+	h.RegisterNotificationRoutes(protected)
+
+	// Register glossary routes
+	h.RegisterGlossaryRoutes(protected)
+
+	// Admin routes (require an authenticated admin)
+	admin := router.Group("/api/admin")
+	admin.Use(rateLimiter, authMiddleware.AuthRequired(), authMiddleware.AdminRequired(cfg.App.AdminEmails))
+	// Register translation memory routes
+	h.RegisterTranslationMemoryRoutes(admin)
+	// Register review moderation routes
+	h.RegisterReviewModerationRoutes(admin)
+	// Register loan routes
+	h.RegisterLoanAdminRoutes(admin)
+	// Register the tenant routes
+	h.RegisterTenantAdminRoutes(admin)
+	// Register the cache statistics route
+	admin.GET("/cache/stats", cacheStats(caches))
 
 	return router
 }
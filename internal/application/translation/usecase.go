@@ -10,7 +10,8 @@ import (
 //go:generate mockery --name=TranslationUsecase --output=../mocks/translation
 
 type TranslationUsecase interface {
-	Translate(ctx context.Context, text string, targetLang string) (string, error)
+	Translate(ctx context.Context, text string, sourceLang string, targetLang string) (string, error)
+	ConfiguredLanguages(ctx context.Context) ([]string, error)
 }
 
 type translationUsecase struct {
@@ -23,7 +24,11 @@ func NewTranslationUsecase(repo translation.Repository) TranslationUsecase {
 	}
 }
 
-func (u *translationUsecase) Translate(ctx context.Context, text string, targetLang string) (string, error) {
+func (u *translationUsecase) Translate(ctx context.Context, text string, sourceLang string, targetLang string) (string, error) {
 	// Business logic here
-	return u.repo.Translate(ctx, text, targetLang)
+	return u.repo.Translate(ctx, text, sourceLang, targetLang)
+}
+
+func (u *translationUsecase) ConfiguredLanguages(ctx context.Context) ([]string, error) {
+	return u.repo.ConfiguredLanguages(ctx)
 }
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"clean-arch-go/internal/domain/book"
 	"clean-arch-go/internal/domain/entities"
 	"clean-arch-go/internal/domain/service"
 	"clean-arch-go/internal/pkg/i18n"
@@ -46,8 +47,8 @@ func (h *BookHandler) GetBook(c *gin.Context) {
 		return
 	}
 
-	// Check if the user owns the book
-	if err := h.bookService.CheckBookOwnership(c.Request.Context(), bookID, userID.(string)); err != nil {
+	// Check if the user can see the book
+	if _, err := h.bookService.AuthorizeBook(c.Request.Context(), bookID, userID.(string), book.AccessView); err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": i18n.GetLocalizer().MustTranslate(language.English, "error.forbidden", nil)})
 		return
 	}
@@ -107,8 +108,8 @@ func (h *BookHandler) UpdateBook(c *gin.Context) {
 		return
 	}
 
-	// Check if the user owns the book
-	if err := h.bookService.CheckBookOwnership(c.Request.Context(), bookID, userID.(string)); err != nil {
+	// Check if the user can edit the book
+	if _, err := h.bookService.AuthorizeBook(c.Request.Context(), bookID, userID.(string), book.AccessEdit); err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": i18n.GetLocalizer().MustTranslate(language.English, "error.forbidden", nil)})
 		return
 	}
@@ -137,7 +138,7 @@ func (h *BookHandler) UpdateBook(c *gin.Context) {
 		book.Author = req.Author
 	}
 
-	if err := h.bookService.UpdateBook(c.Request.Context(), bookID, book); err != nil {
+	if err := h.bookService.UpdateBook(c.Request.Context(), bookID, userID.(string), book); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.GetLocalizer().MustTranslate(language.English, "error.internal_server_error", nil)})
 		return
 	}
@@ -160,12 +161,12 @@ func (h *BookHandler) DeleteBook(c *gin.Context) {
 	}
 
 	// Check if the user owns the book
-	if err := h.bookService.CheckBookOwnership(c.Request.Context(), bookID, userID.(string)); err != nil {
+	if _, err := h.bookService.AuthorizeBook(c.Request.Context(), bookID, userID.(string), book.AccessOwner); err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": i18n.GetLocalizer().MustTranslate(language.English, "error.forbidden", nil)})
 		return
 	}
 
-	if err := h.bookService.DeleteBook(c.Request.Context(), bookID); err != nil {
+	if err := h.bookService.DeleteBook(c.Request.Context(), bookID, 0); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.GetLocalizer().MustTranslate(language.English, "error.internal_server_error", nil)})
 		return
 	}
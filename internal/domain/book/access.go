@@ -0,0 +1,66 @@
+package book
+
+// Access is what a user may do with a book, each level allowing what the
+// lower ones do
+type Access int
+
+const (
+	AccessNone Access = iota
+	// AccessView lets the user read the book, review it and track their reading
+	AccessView
+	// AccessEdit lets the user change the book, its cover and translations
+	AccessEdit
+	// AccessOwner lets the user delete and share the book and lend its copies
+	AccessOwner
+)
+
+// Visibilities of a book beyond its owner and grants
+const (
+	// VisibilityPrivate books are only seen by their owner and grantees
+	VisibilityPrivate = "private"
+	// VisibilityUnlisted books are also seen by whoever has their share link
+	VisibilityUnlisted = "unlisted"
+	// VisibilityPublic books are seen by every user and listed to them
+	VisibilityPublic = "public"
+)
+
+// Roles granted to users on a book
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+)
+
+// Access scopes of Filter, narrowing the books a viewer can see
+const (
+	ScopeOwned  = "owned"
+	ScopeShared = "shared"
+	ScopePublic = "public"
+)
+
+// ValidVisibility tells whether visibility is a known visibility
+func ValidVisibility(visibility string) bool {
+	switch visibility {
+	case VisibilityPrivate, VisibilityUnlisted, VisibilityPublic:
+		return true
+	}
+	return false
+}
+
+// ValidRole tells whether role is a known grant role
+func ValidRole(role string) bool {
+	return role == RoleViewer || role == RoleEditor
+}
+
+// AccessOf returns the access of a user to a book owned by ownerID with a
+// visibility, role being what the user was granted on it, empty if nothing
+func AccessOf(userID, ownerID, visibility, role string) Access {
+	switch {
+	case userID != "" && userID == ownerID:
+		return AccessOwner
+	case role == RoleEditor:
+		return AccessEdit
+	case role == RoleViewer, visibility == VisibilityPublic:
+		return AccessView
+	}
+	return AccessNone
+}
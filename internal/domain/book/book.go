@@ -1,6 +1,10 @@
 package book
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"strings"
+)
 
 // Book represents a book in the system
 type Book struct {
@@ -21,7 +25,85 @@ type Repository interface {
 
 // Filter represents the filter criteria for querying books
 type Filter struct {
+	// Query is matched against the title, description and author
+	Query  string `json:"q,omitempty"`
 	Title  string `json:"title,omitempty"`
 	Author string `json:"author,omitempty"`
-	// Add other filter fields as needed
+	// YearFrom and YearTo bound the publication year, ignored when zero
+	YearFrom int `json:"year_from,omitempty"`
+	YearTo   int `json:"year_to,omitempty"`
+	// ISBN matches an ISBN-10 or ISBN-13 exactly
+	ISBN      string `json:"isbn,omitempty"`
+	Publisher string `json:"publisher,omitempty"`
+	// Lang matches the language the books are written in
+	Lang string `json:"lang,omitempty"`
+	// Tags only keeps books having all of them
+	Tags []string `json:"tags,omitempty"`
+	// ShelfID only keeps the books on this shelf, ignored when zero
+	ShelfID uint   `json:"shelf,omitempty"`
+	OwnerID string `json:"owner,omitempty"`
+	// ViewerID restricts the results to the books this user may see: their
+	// own, the ones shared with them and the public ones
+	ViewerID string `json:"-"`
+	// Access narrows the books of ViewerID to the owned, shared or public
+	// ones, all of them when empty
+	Access string `json:"access,omitempty"`
+	// SharedBookIDs are the books shared with ViewerID, resolved for the
+	// backends that can't look the grants up themselves
+	SharedBookIDs []string `json:"-"`
+	// BookIDs restricts the results to these books when not nil. Backends
+	// that can't look shelves up themselves get ShelfID resolved into it.
+	BookIDs []string    `json:"-"`
+	Sort    []SortField `json:"sort,omitempty"`
+	Page    int         `json:"page,omitempty"`
+	Limit   int         `json:"limit,omitempty"`
+}
+
+// Sortable fields
+const (
+	SortRelevance = "relevance"
+	SortTitle     = "title"
+	SortAuthor    = "author"
+	SortYear      = "year"
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+)
+
+var sortFields = map[string]bool{
+	SortRelevance: true,
+	SortTitle:     true,
+	SortAuthor:    true,
+	SortYear:      true,
+	SortCreatedAt: true,
+	SortUpdatedAt: true,
+}
+
+// SortField is a field to sort by, in descending order when Desc is set
+type SortField struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
+}
+
+// ParseSort parses a comma-separated list of fields, each prefixed with "-"
+// for descending order, e.g. "-year,title"
+func ParseSort(value string) ([]SortField, error) {
+	var fields []SortField
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if !sortFields[field.Field] {
+			return nil, fmt.Errorf("unknown sort field %q", field.Field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// FacetCount is the number of matching books sharing a value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
 }
@@ -0,0 +1,79 @@
+package book
+
+import "testing"
+
+func TestNormalizeISBN(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"9780134190440", "9780134190440"},
+		{"978-0-13-419044-0", "9780134190440"},
+		{"978 0 13 419044 0", "9780134190440"},
+		{"9791069183032", "9791069183032"},
+		{"0306406152", "9780306406157"},
+		{"0-306-40615-2", "9780306406157"},
+		{"0134190440", "9780134190440"},
+		{"080442957X", "9780804429573"},
+		{"0-8044-2957-x", "9780804429573"},
+		{"097522980X", "9780975229804"},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeISBN(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeISBN(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeISBNInvalid(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"978013419044",   // 12 digits
+		"97801341904400", // 14 digits
+		"9780134190441",  // wrong ISBN-13 check digit
+		"978013419044X",  // X in an ISBN-13
+		"97801341904A0",  // letter in an ISBN-13
+		"0306406153",     // wrong ISBN-10 check digit
+		"0804429570",     // X check digit written as 0
+		"X804429573",     // X before the check digit
+		"03064O6152",     // letter O for zero
+		"978_0134190440", // unknown separator
+	} {
+		if got, err := NormalizeISBN(value); err != ErrInvalidISBN {
+			t.Errorf("NormalizeISBN(%q) = %q, %v, want ErrInvalidISBN", value, got, err)
+		}
+	}
+}
+
+func TestISBN10(t *testing.T) {
+	tests := []struct {
+		isbn13 string
+		want   string
+	}{
+		{"9780306406157", "0306406152"},
+		{"9780804429573", "080442957X"},
+		{"9780134190440", "0134190440"},
+		// 979 ISBNs and invalid values have no ISBN-10
+		{"9791069183032", ""},
+		{"978030640615", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ISBN10(tt.isbn13); got != tt.want {
+			t.Errorf("ISBN10(%q) = %q, want %q", tt.isbn13, got, tt.want)
+		}
+	}
+}
+
+func TestISBN10RoundTrip(t *testing.T) {
+	for _, isbn10 := range []string{"0306406152", "080442957X", "097522980X", "0134190440"} {
+		isbn13, err := NormalizeISBN(isbn10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ISBN10(isbn13); got != isbn10 {
+			t.Errorf("ISBN10(NormalizeISBN(%q)) = %q", isbn10, got)
+		}
+	}
+}
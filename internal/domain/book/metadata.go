@@ -0,0 +1,31 @@
+package book
+
+import (
+	"clean-arch-go/internal/errors"
+	"context"
+)
+
+// Metadata is the bibliographic record of an edition, as published by a
+// metadata provider
+type Metadata struct {
+	ISBN          string   `json:"isbn"`
+	Title         string   `json:"title"`
+	Authors       []string `json:"authors"`
+	Publisher     string   `json:"publisher,omitempty"`
+	PublishedYear int      `json:"published_year,omitempty"`
+	PageCount     int      `json:"page_count,omitempty"`
+	CoverURL      string   `json:"cover_url,omitempty"`
+}
+
+// MetadataProvider looks up the metadata of books by ISBN
+type MetadataProvider interface {
+	// LookupISBN returns the metadata of an ISBN-13, ErrMetadataNotFound
+	// when the provider doesn't know it
+	LookupISBN(ctx context.Context, isbn string) (*Metadata, error)
+}
+
+// ErrMetadataNotFound is returned when no metadata is known for an ISBN
+var ErrMetadataNotFound = errors.NewAppError("NOT_FOUND", "No book found for this ISBN", nil)
+
+// ErrMetadataUnavailable is returned when the metadata provider can't be reached
+var ErrMetadataUnavailable = errors.NewAppError("METADATA_UNAVAILABLE", "The book metadata provider is unavailable", nil)
@@ -3,22 +3,89 @@ package entities
 import (
 	"time"
 
+	"clean-arch-go/internal/domain/book"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type Book struct {
-	ID          string    `json:"id" gorm:"primaryKey"`
-	Title       string    `json:"title" gorm:"size:255;not null"`
-	Description string    `json:"description" gorm:"type:text"`
-	Author      string    `json:"author" gorm:"size:100;not null"`
-	UserID      string    `json:"user_id" gorm:"not null"`
-	User        User      `json:"-" gorm:"foreignKey:UserID"`
-	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          string `json:"id" gorm:"primaryKey;size:36"`
+	Title       string `json:"title" gorm:"size:255;not null;index:idx_books_fulltext,class:FULLTEXT"`
+	Description string `json:"description" gorm:"type:text;index:idx_books_fulltext,class:FULLTEXT"`
+	Author      string `json:"author" gorm:"size:100;not null;index;index:idx_books_fulltext,class:FULLTEXT"`
+	// ISBN is the ISBN-13 of the book, nil when unknown
+	ISBN          *string `json:"isbn" gorm:"size:13;uniqueIndex:idx_books_user_isbn,priority:2"`
+	PublishedYear int     `json:"published_year" gorm:"index"`
+	Publisher     string  `json:"publisher" gorm:"size:255;index"`
+	PageCount     int     `json:"page_count"`
+	Edition       string  `json:"edition" gorm:"size:50"`
+	// CoverKey is the storage key of the original cover image, empty when
+	// the book has no cover
+	CoverKey string `json:"cover_key" gorm:"size:255"`
+	// RatingAverage and RatingCount aggregate the visible reviews of the book
+	RatingAverage float64 `json:"rating_average" gorm:"not null;default:0"`
+	RatingCount   int     `json:"rating_count" gorm:"not null;default:0"`
+	// Visibility is private, unlisted or public, see the book package
+	Visibility string `json:"visibility" gorm:"size:10;not null;default:private;index"`
+	// ShareToken is the secret of the share link of unlisted and public
+	// books, nil for private ones
+	ShareToken *string `json:"share_token,omitempty" gorm:"size:64;uniqueIndex"`
+	// Version counts the changes to the book, its tags, translations,
+	// rating and sharing included. Updates only apply to the version they
+	// were read at.
+	Version int `json:"version" gorm:"not null;default:1"`
+	// Lang is the language of Title and Description as written by the owner
+	Lang      string         `json:"lang" gorm:"size:10;index"`
+	UserID    string         `json:"user_id" gorm:"size:36;index;not null;uniqueIndex:idx_books_user_isbn,priority:1"`
+	Tags      []Tag          `json:"tags,omitempty" gorm:"many2many:book_tags"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// ContentLang is the language Title and Description are currently in,
+	// which differs from Lang once the book has been localized for a reader
+	ContentLang string `json:"-" gorm:"-"`
 }
 
 func (Book) TableName() string {
 	return "books"
 }
+
+// BeforeCreate assigns a UUID to new books, which are private unless said
+// otherwise, and starts their version count
+func (b *Book) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == "" {
+		b.ID = uuid.NewString()
+	}
+	if b.Version == 0 {
+		b.Version = 1
+	}
+	if b.Visibility == "" {
+		b.Visibility = book.VisibilityPrivate
+	}
+	return nil
+}
+
+// Book translation sources
+const (
+	BookTranslationHuman   = "human"
+	BookTranslationMachine = "machine"
+)
+
+// BookTranslation is the title and description of a book in another language
+type BookTranslation struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	BookID      string `json:"book_id" gorm:"size:36;not null;uniqueIndex:idx_book_translation"`
+	Lang        string `json:"lang" gorm:"size:10;not null;uniqueIndex:idx_book_translation"`
+	Title       string `json:"title" gorm:"size:255;not null"`
+	Description string `json:"description" gorm:"type:text"`
+	// Source tells whether the translation was edited by a human or machine-generated
+	Source    string    `json:"source" gorm:"size:10;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (BookTranslation) TableName() string {
+	return "book_translations"
+}
@@ -0,0 +1,20 @@
+package entities
+
+import (
+	"time"
+)
+
+// BookGrant gives a user other than the owner a role on a book
+type BookGrant struct {
+	BookID string `json:"book_id" gorm:"primaryKey;size:36"`
+	UserID string `json:"user_id" gorm:"primaryKey;size:36;index"`
+	// Role is viewer or editor, see the book package
+	Role      string    `json:"role" gorm:"size:10;not null"`
+	GrantedBy string    `json:"granted_by" gorm:"size:36;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (BookGrant) TableName() string {
+	return "book_grants"
+}
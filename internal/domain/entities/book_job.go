@@ -0,0 +1,63 @@
+package entities
+
+import (
+	"time"
+)
+
+// Book job kinds
+const (
+	BookJobImport = "import"
+	BookJobExport = "export"
+)
+
+// Book job statuses
+const (
+	BookJobPending   = "pending"
+	BookJobRunning   = "running"
+	BookJobSucceeded = "succeeded"
+	BookJobFailed    = "failed"
+)
+
+// BookJob is the import of a file of books into the library of a user, or
+// the export of their books to a file, run in the background
+type BookJob struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	UserID string `json:"user_id" gorm:"size:36;not null;index"`
+	Kind   string `json:"kind" gorm:"size:10;not null"`
+	// Format is the format of the file, csv or json
+	Format string `json:"format" gorm:"size:10;not null"`
+	Status string `json:"status" gorm:"size:20;not null;index"`
+	// DryRun imports validate the rows without creating any book
+	DryRun bool `json:"dry_run" gorm:"not null;default:false"`
+	// Processed counts the rows read or the books written so far
+	Processed int `json:"processed" gorm:"not null;default:0"`
+	// Created, Skipped and Failed count the imported rows creating a book,
+	// or that would for dry runs, skipped as their ISBN is already in the
+	// library, and rejected
+	Created int `json:"created" gorm:"not null;default:0"`
+	Skipped int `json:"skipped" gorm:"not null;default:0"`
+	Failed  int `json:"failed" gorm:"not null;default:0"`
+	// RowErrors holds the first rejected rows of an import
+	RowErrors []BookJobRowError `json:"row_errors,omitempty" gorm:"type:text;serializer:json"`
+	// Error tells why a failed job stopped
+	Error string `json:"error,omitempty" gorm:"size:500"`
+	// Worker is the server instance running the job, which renews
+	// HeartbeatAt while it does
+	Worker      string     `json:"-" gorm:"size:32"`
+	HeartbeatAt *time.Time `json:"-"`
+	StartedAt   *time.Time `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// BookJobRowError is a row an import rejected
+type BookJobRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (BookJob) TableName() string {
+	return "book_jobs"
+}
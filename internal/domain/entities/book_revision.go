@@ -0,0 +1,55 @@
+package entities
+
+import (
+	"time"
+)
+
+// Book revision actions
+const (
+	BookRevisionCreate = "create"
+	BookRevisionUpdate = "update"
+	BookRevisionRevert = "revert"
+)
+
+// BookRevision records a change to the details of a book: who made it,
+// when, the changed fields and the book as it was afterwards
+type BookRevision struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	BookID string `json:"book_id" gorm:"size:36;not null;uniqueIndex:idx_book_revision_number,priority:1"`
+	// Number counts the revisions of a book from 1
+	Number int `json:"number" gorm:"not null;uniqueIndex:idx_book_revision_number,priority:2"`
+	// UserID is the user who made the change
+	UserID string `json:"user_id" gorm:"size:36;not null;index"`
+	// Action is create, update or revert
+	Action string `json:"action" gorm:"size:10;not null"`
+	// RevertedTo is the number of the revision a revert went back to
+	RevertedTo int               `json:"reverted_to,omitempty" gorm:"not null;default:0"`
+	Changes    []BookFieldChange `json:"changes" gorm:"type:text;serializer:json"`
+	Snapshot   BookSnapshot      `json:"snapshot" gorm:"type:text;serializer:json"`
+	CreatedAt  time.Time         `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// BookFieldChange is the value of a book field before and after a change
+type BookFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// BookSnapshot holds the details of a book tracked by its revisions
+type BookSnapshot struct {
+	Title         string   `json:"title"`
+	Author        string   `json:"author"`
+	Description   string   `json:"description"`
+	ISBN          *string  `json:"isbn"`
+	PublishedYear int      `json:"published_year"`
+	Publisher     string   `json:"publisher"`
+	PageCount     int      `json:"page_count"`
+	Edition       string   `json:"edition"`
+	Lang          string   `json:"lang"`
+	Tags          []string `json:"tags"`
+}
+
+func (BookRevision) TableName() string {
+	return "book_revisions"
+}
@@ -0,0 +1,50 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Glossary is a named set of terms a user wants translated in a fixed way.
+// A glossary with a TenantID is shared with the users of the tenant, who
+// get its terms applied too, while only its owner can change it.
+type Glossary struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	UserID    string         `json:"user_id" gorm:"size:36;index;not null"`
+	TenantID  string         `json:"tenant_id,omitempty" gorm:"size:36;index"`
+	Name      string         `json:"name" gorm:"size:100;not null"`
+	Terms     []GlossaryTerm `json:"terms,omitempty" gorm:"foreignKey:GlossaryID"`
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (Glossary) TableName() string {
+	return "glossaries"
+}
+
+// GlossaryTerm is a single glossary entry. A protected term is kept as-is in
+// the translated text, otherwise it's replaced with Translation.
+type GlossaryTerm struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	GlossaryID    uint           `json:"glossary_id" gorm:"index;not null"`
+	Term          string         `json:"term" gorm:"size:255;not null"`
+	TargetLang    string         `json:"target_lang" gorm:"size:10;index"`
+	Translation   string         `json:"translation" gorm:"size:255"`
+	Protected     bool           `json:"protected"`
+	CaseSensitive bool           `json:"case_sensitive"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (GlossaryTerm) TableName() string {
+	return "glossary_terms"
+}
+
+// AppliesTo reports whether the term is used when translating into targetLang.
+// Terms without a target language apply to every language.
+func (t *GlossaryTerm) AppliesTo(targetLang string) bool {
+	return t.TargetLang == "" || t.TargetLang == targetLang
+}
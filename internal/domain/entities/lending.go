@@ -0,0 +1,81 @@
+package entities
+
+import (
+	"time"
+)
+
+// Copy statuses
+const (
+	CopyAvailable = "available"
+	CopyOnLoan    = "on_loan"
+	// CopyOnHold copies wait for the user of a ready hold to pick them up
+	CopyOnHold = "on_hold"
+)
+
+// Hold statuses
+const (
+	HoldWaiting   = "waiting"
+	HoldReady     = "ready"
+	HoldFulfilled = "fulfilled"
+	HoldCancelled = "cancelled"
+	HoldExpired   = "expired"
+)
+
+// BookCopy is a physical copy of a book that can be borrowed
+type BookCopy struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	BookID string `json:"book_id" gorm:"size:36;not null;index:idx_copy_book_status,priority:1"`
+	// Label tells the copy apart, like a barcode or a shelf mark
+	Label     string    `json:"label" gorm:"size:100"`
+	Status    string    `json:"status" gorm:"size:20;not null;index:idx_copy_book_status,priority:2"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (BookCopy) TableName() string {
+	return "book_copies"
+}
+
+// Loan is the borrowing of a copy by a user, active until it's returned
+type Loan struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	CopyID       uint       `json:"copy_id" gorm:"not null;index"`
+	BookID       string     `json:"book_id" gorm:"size:36;not null;index"`
+	UserID       string     `json:"user_id" gorm:"size:36;not null;index"`
+	CheckedOutAt time.Time  `json:"checked_out_at" gorm:"not null"`
+	DueAt        time.Time  `json:"due_at" gorm:"not null;index"`
+	ReturnedAt   *time.Time `json:"returned_at"`
+	Renewals     int        `json:"renewals" gorm:"not null;default:0"`
+	// OverdueNotifiedAt is when the borrower was told the loan is overdue
+	OverdueNotifiedAt *time.Time `json:"-"`
+	// Overdue is set on the loans not returned by their due date
+	Overdue   bool      `json:"overdue" gorm:"-"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (Loan) TableName() string {
+	return "loans"
+}
+
+// Hold is the place of a user in the queue of a book without available
+// copies. The first waiting hold gets the next returned copy and is ready
+// until it expires.
+type Hold struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	BookID string `json:"book_id" gorm:"size:36;not null;index:idx_hold_book_status,priority:1"`
+	UserID string `json:"user_id" gorm:"size:36;not null;index"`
+	Status string `json:"status" gorm:"size:20;not null;index:idx_hold_book_status,priority:2"`
+	// CopyID is the copy kept for the user once the hold is ready
+	CopyID    *uint      `json:"copy_id,omitempty"`
+	ReadyAt   *time.Time `json:"ready_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Position is the place of a waiting hold in the queue, from 1
+	Position  int64     `json:"position,omitempty" gorm:"->;-:migration"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (Hold) TableName() string {
+	return "holds"
+}
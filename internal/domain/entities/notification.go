@@ -0,0 +1,30 @@
+package entities
+
+import (
+	"time"
+)
+
+// Notification kinds
+const (
+	NotificationHoldReady   = "hold_ready"
+	NotificationHoldExpired = "hold_expired"
+	// NotificationHoldCancelled tells of a hold ended by the purge of its book
+	NotificationHoldCancelled = "hold_cancelled"
+	NotificationLoanOverdue   = "loan_overdue"
+)
+
+// Notification is a message for a user about something that happened
+// without them, like a held book coming back
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    string     `json:"user_id" gorm:"size:36;not null;index:idx_notification_user_read,priority:1"`
+	Kind      string     `json:"kind" gorm:"size:30;not null"`
+	BookID    string     `json:"book_id,omitempty" gorm:"size:36"`
+	Message   string     `json:"message" gorm:"size:500;not null"`
+	ReadAt    *time.Time `json:"read_at" gorm:"index:idx_notification_user_read,priority:2"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
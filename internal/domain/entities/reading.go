@@ -0,0 +1,49 @@
+package entities
+
+import (
+	"time"
+)
+
+// Reading statuses
+const (
+	ReadingWantToRead = "want_to_read"
+	ReadingReading    = "reading"
+	ReadingFinished   = "finished"
+	ReadingAbandoned  = "abandoned"
+)
+
+// ReadingProgress is where a user is in a book, one per user and book
+type ReadingProgress struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	UserID string `json:"user_id" gorm:"size:36;not null;uniqueIndex:idx_reading_user_book;index:idx_reading_user_finished,priority:1"`
+	BookID string `json:"book_id" gorm:"size:36;not null;uniqueIndex:idx_reading_user_book;index"`
+	Status string `json:"status" gorm:"size:20;not null"`
+	// CurrentPage is the last page read, 0 when unknown
+	CurrentPage int `json:"current_page"`
+	// Percent is the share of the book read, between 0 and 100
+	Percent    float64    `json:"percent"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at" gorm:"index:idx_reading_user_finished,priority:2"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (ReadingProgress) TableName() string {
+	return "reading_progress"
+}
+
+// ReadingSession is a stretch of reading of a book by a user
+type ReadingSession struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    string     `json:"user_id" gorm:"size:36;not null;index:idx_reading_session_user_book"`
+	BookID    string     `json:"book_id" gorm:"size:36;not null;index:idx_reading_session_user_book"`
+	StartPage int        `json:"start_page"`
+	EndPage   int        `json:"end_page"`
+	StartedAt time.Time  `json:"started_at" gorm:"not null"`
+	EndedAt   *time.Time `json:"ended_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (ReadingSession) TableName() string {
+	return "reading_sessions"
+}
@@ -0,0 +1,29 @@
+package entities
+
+import (
+	"time"
+)
+
+// Review is the rating and opinion of a user on a book, one per user and book
+type Review struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	BookID string `json:"book_id" gorm:"size:36;not null;uniqueIndex:idx_review_book_user;index:idx_review_book_created,priority:1"`
+	UserID string `json:"user_id" gorm:"size:36;not null;uniqueIndex:idx_review_book_user;index"`
+	// Rating goes from 1 to 5 stars
+	Rating int    `json:"rating" gorm:"not null"`
+	Title  string `json:"title" gorm:"size:200"`
+	Body   string `json:"body" gorm:"type:text"`
+	// Hidden reviews are left out of listings and ratings by a moderator
+	Hidden bool `json:"hidden" gorm:"not null;default:false"`
+	// Flagged reviews await a closer look from a moderator
+	Flagged        bool       `json:"flagged" gorm:"not null;default:false;index"`
+	ModerationNote string     `json:"moderation_note,omitempty" gorm:"size:500"`
+	ModeratedBy    string     `json:"moderated_by,omitempty" gorm:"size:36"`
+	ModeratedAt    *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime;index:idx_review_book_created,priority:2"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (Review) TableName() string {
+	return "reviews"
+}
@@ -0,0 +1,35 @@
+package entities
+
+import (
+	"time"
+)
+
+// Shelf is a named collection of a user's books, kept in the user's order
+type Shelf struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	UserID      string `json:"user_id" gorm:"size:36;not null;uniqueIndex:idx_shelf_user_name"`
+	Name        string `json:"name" gorm:"size:100;not null;uniqueIndex:idx_shelf_user_name"`
+	Description string `json:"description" gorm:"size:500"`
+	// Position orders the shelves of a user, lowest first
+	Position int `json:"position" gorm:"not null;default:0"`
+	// BookCount is the number of books on the shelf, only loaded by listings
+	BookCount int64     `json:"book_count" gorm:"->;-:migration"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (Shelf) TableName() string {
+	return "shelves"
+}
+
+// ShelfBook places a book on a shelf at a position, the first being 0
+type ShelfBook struct {
+	ShelfID  uint      `json:"shelf_id" gorm:"primaryKey"`
+	BookID   string    `json:"book_id" gorm:"primaryKey;size:36;index"`
+	Position int       `json:"position" gorm:"not null"`
+	AddedAt  time.Time `json:"added_at" gorm:"autoCreateTime"`
+}
+
+func (ShelfBook) TableName() string {
+	return "shelf_books"
+}
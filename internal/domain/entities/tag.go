@@ -0,0 +1,19 @@
+package entities
+
+import (
+	"time"
+)
+
+// Tag is a label a user puts on books, unique per user
+type Tag struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	UserID string `json:"-" gorm:"size:36;not null;uniqueIndex:idx_tag_user_name"`
+	Name   string `json:"name" gorm:"size:50;not null;uniqueIndex:idx_tag_user_name"`
+	// BookCount is the number of books with the tag, only loaded by listings
+	BookCount int64     `json:"book_count,omitempty" gorm:"->;-:migration"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
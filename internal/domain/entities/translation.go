@@ -9,7 +9,24 @@ import (
 type Translation struct {
 	gorm.Model
 	SourceText     string    `gorm:"not null"`
+	SourceLang     string    `gorm:"size:10"`
 	TargetLang     string    `gorm:"not null"`
 	TranslatedText string    `gorm:"not null"`
 	LastAccessed   time.Time `gorm:"index"`
 }
+
+// LocaleMessage is a UI message text that overrides the embedded i18n locale
+// files. Text is the "other" plural form, Forms holds the other ones.
+type LocaleMessage struct {
+	ID        uint              `json:"id" gorm:"primaryKey"`
+	Lang      string            `json:"lang" gorm:"size:10;not null;uniqueIndex:idx_locale_message"`
+	MessageID string            `json:"message_id" gorm:"size:191;not null;uniqueIndex:idx_locale_message"`
+	Text      string            `json:"text" gorm:"type:text;not null"`
+	Forms     map[string]string `json:"forms,omitempty" gorm:"type:text;serializer:json"`
+	CreatedAt time.Time         `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (LocaleMessage) TableName() string {
+	return "locale_messages"
+}
@@ -14,6 +14,7 @@ type BaseRepository[T any] interface {
 	FindAll(ctx context.Context, page, limit int) ([]*T, error)
 	FindOne(ctx context.Context, query interface{}) (*T, error)
 	FindMany(ctx context.Context, query interface{}, page, limit int) ([]*T, error)
+	FindPage(ctx context.Context, query interface{}, cursor CursorQuery) (*CursorPage[T], error)
 	Count(ctx context.Context, query interface{}) (int64, error)
 }
 
@@ -88,9 +89,15 @@ func (r *baseRepository[T]) FindMany(ctx context.Context, query interface{}, pag
 	return entities, nil
 }
 
+// FindPage finds a page of the entities matching query, newest first, with
+// cursors instead of offsets so that concurrent inserts don't shift the pages
+func (r *baseRepository[T]) FindPage(ctx context.Context, query interface{}, cursor CursorQuery) (*CursorPage[T], error) {
+	return r.findPage(ctx, r.db, query, cursor)
+}
+
 func (r *baseRepository[T]) Count(ctx context.Context, query interface{}) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Where(query).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(new(T)).Where(query).Count(&count).Error
 	if err != nil {
 		return 0, fmt.Errorf("failed to count entities: %w", err)
 	}
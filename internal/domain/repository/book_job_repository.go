@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/errors"
+
+	"clean-arch-go/internal/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type BookJobRepository interface {
+	Create(ctx context.Context, job *entities.BookJob) error
+	FindByID(ctx context.Context, id uint) (*entities.BookJob, error)
+	ListByUserID(ctx context.Context, userID string, page, limit int) ([]*entities.BookJob, int64, error)
+	ListPendingIDs(ctx context.Context) ([]uint, error)
+	Claim(ctx context.Context, id uint, worker string, at time.Time) (bool, error)
+	Heartbeat(ctx context.Context, worker string, at time.Time) error
+	SaveProgress(ctx context.Context, job *entities.BookJob) error
+	FailExpired(ctx context.Context, message string, before, at time.Time) (int64, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type bookJobRepository struct {
+	db *gorm.DB
+}
+
+func NewBookJobRepository(db *database.Database) BookJobRepository {
+	return &bookJobRepository{db: db.DB}
+}
+
+func (r *bookJobRepository) Create(ctx context.Context, job *entities.BookJob) error {
+	if err := r.db.WithContext(ctx).Create(job).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// FindByID returns a job, nil if there's none
+func (r *bookJobRepository) FindByID(ctx context.Context, id uint) (*entities.BookJob, error) {
+	var job entities.BookJob
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&job).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &job, nil
+}
+
+// ListByUserID returns a page of the jobs of a user, newest first, with
+// their number
+func (r *bookJobRepository) ListByUserID(ctx context.Context, userID string, page, limit int) ([]*entities.BookJob, int64, error) {
+	matching := func() *gorm.DB {
+		return r.db.WithContext(ctx).Model(&entities.BookJob{}).Where("user_id = ?", userID)
+	}
+
+	var total int64
+	if err := matching().Count(&total).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+
+	var jobs []*entities.BookJob
+	if err := matching().
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&jobs).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+	return jobs, total, nil
+}
+
+// ListPendingIDs returns the IDs of the jobs waiting to run, oldest first
+func (r *bookJobRepository) ListPendingIDs(ctx context.Context) ([]uint, error) {
+	var ids []uint
+	if err := r.db.WithContext(ctx).
+		Model(&entities.BookJob{}).
+		Where("status = ?", entities.BookJobPending).
+		Order("id").
+		Pluck("id", &ids).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return ids, nil
+}
+
+// Claim starts a pending job on worker, telling whether it was still
+// pending so that each job runs once
+func (r *bookJobRepository) Claim(ctx context.Context, id uint, worker string, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&entities.BookJob{}).
+		Where("id = ? AND status = ?", id, entities.BookJobPending).
+		Updates(map[string]interface{}{
+			"status":       entities.BookJobRunning,
+			"worker":       worker,
+			"heartbeat_at": at,
+			"started_at":   at,
+		})
+	if result.Error != nil {
+		return false, errors.NewInternalServerError(result.Error.Error())
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Heartbeat renews the lease of the jobs running on worker
+func (r *bookJobRepository) Heartbeat(ctx context.Context, worker string, at time.Time) error {
+	if err := r.db.WithContext(ctx).
+		Model(&entities.BookJob{}).
+		Where("status = ? AND worker = ?", entities.BookJobRunning, worker).
+		UpdateColumn("heartbeat_at", at).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// SaveProgress saves the status, counters and errors of a job
+func (r *bookJobRepository) SaveProgress(ctx context.Context, job *entities.BookJob) error {
+	if err := r.db.WithContext(ctx).
+		Model(job).
+		Select("status", "processed", "created", "skipped", "failed", "row_errors", "error", "finished_at", "updated_at").
+		Updates(job).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// FailExpired fails the running jobs whose lease wasn't renewed since
+// before, as when their server stopped in the middle of them, and returns
+// their number
+func (r *bookJobRepository) FailExpired(ctx context.Context, message string, before, at time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&entities.BookJob{}).
+		Where("status = ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)", entities.BookJobRunning, before).
+		Updates(map[string]interface{}{"status": entities.BookJobFailed, "error": message, "finished_at": at})
+	if result.Error != nil {
+		return 0, errors.NewInternalServerError(result.Error.Error())
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *bookJobRepository) Delete(ctx context.Context, id uint) error {
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.BookJob{}).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
@@ -1,20 +1,67 @@
 package repository
 
 import (
+	"clean-arch-go/internal/domain/book"
 	"clean-arch-go/internal/domain/entities"
 	"clean-arch-go/internal/errors"
 	"context"
+	"database/sql"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"clean-arch-go/internal/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BookRepository interface {
 	BaseRepository[entities.Book]
 	ListByUserID(ctx context.Context, userID string, page, limit int) ([]*entities.Book, error)
+	ListTranslations(ctx context.Context, bookIDs ...string) ([]*entities.BookTranslation, error)
+	FindTranslation(ctx context.Context, bookID, lang string) (*entities.BookTranslation, error)
+	SaveTranslation(ctx context.Context, translation *entities.BookTranslation) error
+	DeleteTranslation(ctx context.Context, bookID, lang string) error
+	Search(ctx context.Context, filter *book.Filter) (*BookSearchResult, error)
+	SearchFacets(ctx context.Context, filter *book.Filter) (map[string][]book.FacetCount, error)
+	CreateDetails(ctx context.Context, book *entities.Book, tags []string, revise BookReviser) error
+	UpdateDetails(ctx context.Context, before, book *entities.Book, tags []string, revise BookReviser) error
+	ListTags(ctx context.Context, userID string) ([]*entities.Tag, error)
+	FindTag(ctx context.Context, userID string, id uint) (*entities.Tag, error)
+	FindTagByName(ctx context.Context, userID, name string) (*entities.Tag, error)
+	RenameTag(ctx context.Context, tag *entities.Tag, revise BookReviser) ([]string, error)
+	DeleteTag(ctx context.Context, tag *entities.Tag, revise BookReviser) ([]string, error)
+	RefreshRating(ctx context.Context, bookID string) error
+	FindByIDs(ctx context.Context, ids []string) ([]*entities.Book, error)
+	ListAfter(ctx context.Context, afterID string, limit int) ([]*entities.Book, error)
+	LastChangedAt(ctx context.Context) (time.Time, error)
+	FindByISBN(ctx context.Context, userID, isbn string) (*entities.Book, error)
+	ListPage(ctx context.Context, filter *book.Filter, cursor CursorQuery) (*CursorPage[entities.Book], error)
+	CountMatching(ctx context.Context, filter *book.Filter) (int64, error)
+	FindByShareToken(ctx context.Context, token string) (*entities.Book, error)
+	SetSharing(ctx context.Context, bookID, visibility string, shareToken *string) error
+	FindGrant(ctx context.Context, bookID, userID string) (*entities.BookGrant, error)
+	ListGrants(ctx context.Context, bookID string) ([]*entities.BookGrant, error)
+	SaveGrant(ctx context.Context, grant *entities.BookGrant) error
+	DeleteGrant(ctx context.Context, bookID, userID string) error
+	ListSharedBookIDs(ctx context.Context, userID string) ([]string, error)
+	ListDeleted(ctx context.Context, userID string, page, limit int) ([]*entities.Book, int64, error)
+	ListDeletedBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Book, error)
+	FindDeleted(ctx context.Context, id string) (*entities.Book, error)
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, id string) ([]*entities.Hold, error)
+	DeleteVersion(ctx context.Context, id string, version int) error
 }
 
+// BookReviser returns the revision recording the change of a book from
+// before, nil for a new book, to after, nil when there's nothing to record
+type BookReviser func(before, after *entities.Book) *entities.BookRevision
+
 type bookRepository struct {
 	*baseRepository[entities.Book]
+	// fulltextDisabled is set once MySQL reports the FULLTEXT index missing
+	fulltextDisabled atomic.Bool
 }
 
 func NewBookRepository(db *database.Database) BookRepository {
@@ -23,10 +70,296 @@ func NewBookRepository(db *database.Database) BookRepository {
 	}
 }
 
+func (r *bookRepository) FindByID(ctx context.Context, id string) (*entities.Book, error) {
+	var b entities.Book
+	if err := r.db.WithContext(ctx).Preload("Tags").Where("id = ?", id).First(&b).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &b, nil
+}
+
+// FindByIDs returns the existing books among ids, in no particular order
+func (r *bookRepository) FindByIDs(ctx context.Context, ids []string) ([]*entities.Book, error) {
+	var books []*entities.Book
+	if len(ids) == 0 {
+		return books, nil
+	}
+	if err := r.db.WithContext(ctx).Preload("Tags").Where("id IN ?", ids).Find(&books).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return books, nil
+}
+
+// FindByISBN returns the user's book with an ISBN-13, deleted or not, nil if there's none
+func (r *bookRepository) FindByISBN(ctx context.Context, userID, isbn string) (*entities.Book, error) {
+	var b entities.Book
+	if err := r.db.WithContext(ctx).Unscoped().Where("user_id = ? AND isbn = ?", userID, isbn).First(&b).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &b, nil
+}
+
+// ListAfter returns up to limit books ordered by ID, starting after afterID,
+// to walk through all books in batches
+func (r *bookRepository) ListAfter(ctx context.Context, afterID string, limit int) ([]*entities.Book, error) {
+	var books []*entities.Book
+	if err := r.db.WithContext(ctx).
+		Preload("Tags").
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&books).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return books, nil
+}
+
+// LastChangedAt returns when a book was last updated or deleted, the zero
+// time when there are no books
+func (r *bookRepository) LastChangedAt(ctx context.Context) (time.Time, error) {
+	var updatedAt, deletedAt sql.NullTime
+	if err := r.db.WithContext(ctx).
+		Unscoped().
+		Model(&entities.Book{}).
+		Select("MAX(updated_at), MAX(deleted_at)").
+		Row().
+		Scan(&updatedAt, &deletedAt); err != nil {
+		return time.Time{}, errors.NewInternalServerError(err.Error())
+	}
+	if deletedAt.Time.After(updatedAt.Time) {
+		return deletedAt.Time, nil
+	}
+	return updatedAt.Time, nil
+}
+
+// Update saves the book columns and moves it to the next version, its tags
+// are changed with UpdateDetails, its rating with RefreshRating and its
+// visibility with SetSharing. The book is only saved if it's still at the version it
+// was read at, a PRECONDITION_FAILED error is returned otherwise.
+func (r *bookRepository) Update(ctx context.Context, book *entities.Book) error {
+	return transactionError(updateBook(r.db.WithContext(ctx), book))
+}
+
+func updateBook(tx *gorm.DB, book *entities.Book) error {
+	version := book.Version
+	book.Version = version + 1
+	result := tx.Model(book).
+		Where("version = ?", version).
+		Select("*").
+		Omit(clause.Associations, "id", "created_at", "deleted_at", "rating_average", "rating_count", "visibility", "share_token").
+		Updates(book)
+	if result.Error != nil {
+		book.Version = version
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		book.Version = version
+		return errors.NewAppError("PRECONDITION_FAILED", "The book was changed in the meantime", nil)
+	}
+	return nil
+}
+
+// CreateDetails creates a book with tags, recording the revision revise
+// returns for it in the same transaction
+func (r *bookRepository) CreateDetails(ctx context.Context, book *entities.Book, tags []string, revise BookReviser) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(book).Error; err != nil {
+			return err
+		}
+		return saveDetails(tx, nil, book, tags, revise)
+	})
+	return transactionError(err)
+}
+
+// UpdateDetails saves book like Update and replaces its tags, recording the
+// revision revise returns for the change from before in the same
+// transaction, so that a book never changes without its revision
+func (r *bookRepository) UpdateDetails(ctx context.Context, before, book *entities.Book, tags []string, revise BookReviser) error {
+	version := book.Version
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := updateBook(tx, book); err != nil {
+			return err
+		}
+		return saveDetails(tx, before, book, tags, revise)
+	})
+	if err != nil {
+		book.Version = version
+	}
+	return transactionError(err)
+}
+
+// saveDetails sets the tags of a saved book and records its revision
+func saveDetails(tx *gorm.DB, before, book *entities.Book, tags []string, revise BookReviser) error {
+	var err error
+	if book.Tags, err = setTags(tx, book.ID, book.UserID, tags); err != nil {
+		return err
+	}
+	if revision := revise(before, book); revision != nil {
+		return createRevision(tx, revision)
+	}
+	return nil
+}
+
+func (r *bookRepository) Delete(ctx context.Context, id string) error {
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Delete(&entities.Book{}).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// DeleteVersion deletes a book if it's still at version, or at any version
+// when it's 0. A PRECONDITION_FAILED error is returned when the book was
+// changed in the meantime.
+func (r *bookRepository) DeleteVersion(ctx context.Context, id string, version int) error {
+	query := r.db.WithContext(ctx).Where("id = ?", id)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+	result := query.Delete(&entities.Book{})
+	if result.Error != nil {
+		return errors.NewInternalServerError(result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		if version == 0 {
+			return errors.NewAppError("NOT_FOUND", "Book not found", nil)
+		}
+		return errors.NewAppError("PRECONDITION_FAILED", "The book was changed in the meantime", nil)
+	}
+	return nil
+}
+
+// ListDeleted returns a page of the deleted books of a user, last deleted
+// first, with their number
+func (r *bookRepository) ListDeleted(ctx context.Context, userID string, page, limit int) ([]*entities.Book, int64, error) {
+	matching := func() *gorm.DB {
+		return r.db.WithContext(ctx).Unscoped().Model(&entities.Book{}).Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+	}
+
+	var total int64
+	if err := matching().Count(&total).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+
+	var books []*entities.Book
+	if err := matching().
+		Preload("Tags").
+		Order("deleted_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&books).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+	return books, total, nil
+}
+
+// ListDeletedBefore returns up to limit books deleted before a time, first
+// deleted first, leaving out the books on loan, which can't be purged
+func (r *bookRepository) ListDeletedBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Book, error) {
+	var books []*entities.Book
+	if err := r.db.WithContext(ctx).
+		Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Where("NOT EXISTS (?)", r.db.Table("loans").Select("1").Where("loans.book_id = books.id AND loans.returned_at IS NULL")).
+		Order("deleted_at, id").
+		Limit(limit).
+		Find(&books).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return books, nil
+}
+
+// FindDeleted returns a deleted book, nil if there's none
+func (r *bookRepository) FindDeleted(ctx context.Context, id string) (*entities.Book, error) {
+	var b entities.Book
+	if err := r.db.WithContext(ctx).
+		Unscoped().
+		Preload("Tags").
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		First(&b).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &b, nil
+}
+
+// Restore undeletes a deleted book
+func (r *bookRepository) Restore(ctx context.Context, id string) error {
+	if err := r.db.WithContext(ctx).
+		Unscoped().
+		Model(&entities.Book{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// Purge deletes a book for good with the rows depending on it: its tags,
+// translations, revisions, grants, shelf entries, reading progress,
+// reviews, copies, loans and holds. A book with a copy on loan can't be
+// purged, and the holds that were still active are returned.
+func (r *bookRepository) Purge(ctx context.Context, id string) ([]*entities.Hold, error) {
+	var holds []*entities.Hold
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the copies keeps them from being lent meanwhile
+		var copies []*entities.BookCopy
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("book_id = ?", id).Find(&copies).Error; err != nil {
+			return err
+		}
+		var lent int64
+		if err := tx.Model(&entities.Loan{}).Where("book_id = ? AND returned_at IS NULL", id).Count(&lent).Error; err != nil {
+			return err
+		}
+		if lent > 0 {
+			return errors.NewAppError("CONFLICT", "A copy of this book is on loan, it can be purged once returned", nil)
+		}
+		if err := tx.Where("book_id = ? AND status IN ?", id, []string{entities.HoldWaiting, entities.HoldReady}).
+			Order("id").
+			Find(&holds).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM book_tags WHERE book_id = ?", id).Error; err != nil {
+			return err
+		}
+		for _, dependent := range []interface{}{
+			&entities.BookTranslation{},
+			&entities.BookRevision{},
+			&entities.BookGrant{},
+			&entities.ShelfBook{},
+			&entities.ReadingProgress{},
+			&entities.ReadingSession{},
+			&entities.Review{},
+			&entities.Hold{},
+			&entities.Loan{},
+			&entities.BookCopy{},
+		} {
+			if err := tx.Where("book_id = ?", id).Delete(dependent).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("id = ?", id).Delete(&entities.Book{}).Error
+	})
+	if err != nil {
+		return nil, transactionError(err)
+	}
+	return holds, nil
+}
+
 func (r *bookRepository) ListByUserID(ctx context.Context, userID string, page, limit int) ([]*entities.Book, error) {
 	var books []*entities.Book
 	if err := r.db.WithContext(ctx).
+		Preload("Tags").
 		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
 		Offset((page - 1) * limit).
 		Limit(limit).
 		Find(&books).Error; err != nil {
@@ -35,6 +368,379 @@ func (r *bookRepository) ListByUserID(ctx context.Context, userID string, page,
 	return books, nil
 }
 
+// FindPage finds a page of the books matching query with their tags
+func (r *bookRepository) FindPage(ctx context.Context, query interface{}, cursor CursorQuery) (*CursorPage[entities.Book], error) {
+	page, err := r.findPage(ctx, r.db.Preload("Tags"), query, cursor)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return page, nil
+}
+
+// ListPage finds a page of the books matching the criteria of filter, its
+// query excepted, newest first
+func (r *bookRepository) ListPage(ctx context.Context, filter *book.Filter, cursor CursorQuery) (*CursorPage[entities.Book], error) {
+	page, err := r.findPage(ctx, r.searchScope(ctx, filter, false).Preload("Tags"), nil, cursor)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return page, nil
+}
+
+// CountMatching counts the books matching the criteria of filter, its query excepted
+func (r *bookRepository) CountMatching(ctx context.Context, filter *book.Filter) (int64, error) {
+	var count int64
+	if err := r.searchScope(ctx, filter, false).Count(&count).Error; err != nil {
+		return 0, errors.NewInternalServerError(err.Error())
+	}
+	return count, nil
+}
+
 func (r *bookRepository) Count(ctx context.Context, query interface{}) (int64, error) {
 	return r.baseRepository.Count(ctx, query)
 }
+
+// ListTranslations returns the translations of the given books
+func (r *bookRepository) ListTranslations(ctx context.Context, bookIDs ...string) ([]*entities.BookTranslation, error) {
+	var translations []*entities.BookTranslation
+	if len(bookIDs) == 0 {
+		return translations, nil
+	}
+	if err := r.db.WithContext(ctx).
+		Where("book_id IN ?", bookIDs).
+		Order("lang").
+		Find(&translations).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return translations, nil
+}
+
+// FindTranslation returns the translation of a book into lang, nil if there's none
+func (r *bookRepository) FindTranslation(ctx context.Context, bookID, lang string) (*entities.BookTranslation, error) {
+	var translation entities.BookTranslation
+	if err := r.db.WithContext(ctx).
+		Where("book_id = ? AND lang = ?", bookID, lang).
+		First(&translation).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &translation, nil
+}
+
+// SaveTranslation creates the translation or replaces the existing one in
+// the same language, moving the book to its next version
+func (r *bookRepository) SaveTranslation(ctx context.Context, translation *entities.BookTranslation) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "book_id"}, {Name: "lang"}},
+			DoUpdates: clause.AssignmentColumns([]string{"title", "description", "source", "updated_at"}),
+		}).Create(translation).Error; err != nil {
+			return err
+		}
+		return nextVersion(tx, translation.BookID)
+	})
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// DeleteTranslation deletes the translation of a book into a language,
+// moving the book to its next version
+func (r *bookRepository) DeleteTranslation(ctx context.Context, bookID, lang string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("book_id = ? AND lang = ?", bookID, lang).Delete(&entities.BookTranslation{}).Error; err != nil {
+			return err
+		}
+		return nextVersion(tx, bookID)
+	})
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// nextVersion moves books to their next version after a change to the
+// rows they're made of, marking them updated
+func nextVersion(tx *gorm.DB, bookIDs ...string) error {
+	if len(bookIDs) == 0 {
+		return nil
+	}
+	return tx.Model(&entities.Book{}).
+		Unscoped().
+		Where("id IN ?", bookIDs).
+		UpdateColumns(map[string]interface{}{
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// setTags replaces the tags of a book, creating the user's missing tags
+func setTags(tx *gorm.DB, bookID, userID string, names []string) ([]entities.Tag, error) {
+	tags := make([]entities.Tag, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+
+		tag := entities.Tag{UserID: userID, Name: name}
+		if err := tx.Where("user_id = ? AND name = ?", userID, name).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err := tx.Model(&entities.Book{ID: bookID}).Association("Tags").Replace(tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// ListTags returns the tags of a user by name, with the number of books having them
+func (r *bookRepository) ListTags(ctx context.Context, userID string) ([]*entities.Tag, error) {
+	var tags []*entities.Tag
+	if err := r.db.WithContext(ctx).
+		Select("tags.*, (?) AS book_count", r.db.Table("book_tags").
+			Select("COUNT(*)").
+			Joins("JOIN books ON books.id = book_tags.book_id AND books.deleted_at IS NULL").
+			Where("book_tags.tag_id = tags.id")).
+		Where("tags.user_id = ?", userID).
+		Order("tags.name").
+		Find(&tags).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return tags, nil
+}
+
+// FindTag returns a tag of the user, nil if there's none
+func (r *bookRepository) FindTag(ctx context.Context, userID string, id uint) (*entities.Tag, error) {
+	return r.findTag(ctx, "user_id = ? AND id = ?", userID, id)
+}
+
+// FindTagByName returns the tag of the user with a name, nil if there's none
+func (r *bookRepository) FindTagByName(ctx context.Context, userID, name string) (*entities.Tag, error) {
+	return r.findTag(ctx, "user_id = ? AND name = ?", userID, name)
+}
+
+func (r *bookRepository) findTag(ctx context.Context, query string, args ...interface{}) (*entities.Tag, error) {
+	var tag entities.Tag
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&tag).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &tag, nil
+}
+
+// RenameTag saves the name of a tag and returns the IDs of its books, moved
+// to their next version with the revisions revise returns
+func (r *bookRepository) RenameTag(ctx context.Context, tag *entities.Tag, revise BookReviser) ([]string, error) {
+	var bookIDs []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		books, err := taggedBooks(tx, tag.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(tag).Update("name", tag.Name).Error; err != nil {
+			return err
+		}
+		bookIDs, err = retagBooks(tx, books, revise, func(t entities.Tag) []entities.Tag {
+			if t.ID == tag.ID {
+				t.Name = tag.Name
+			}
+			return []entities.Tag{t}
+		})
+		return err
+	})
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return bookIDs, nil
+}
+
+// DeleteTag removes a tag from its books and deletes it, returning the IDs of
+// the books it was removed from, moved to their next version with the
+// revisions revise returns
+func (r *bookRepository) DeleteTag(ctx context.Context, tag *entities.Tag, revise BookReviser) ([]string, error) {
+	var bookIDs []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		books, err := taggedBooks(tx, tag.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM book_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		bookIDs, err = retagBooks(tx, books, revise, func(t entities.Tag) []entities.Tag {
+			if t.ID == tag.ID {
+				return nil
+			}
+			return []entities.Tag{t}
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	})
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return bookIDs, nil
+}
+
+// taggedBooks returns the books with a tag and their tags, deleted ones
+// included
+func taggedBooks(tx *gorm.DB, tagID uint) ([]*entities.Book, error) {
+	ids, err := taggedBookIDs(tx, tagID)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	var books []*entities.Book
+	err = tx.Unscoped().Preload("Tags").Where("id IN ?", ids).Find(&books).Error
+	return books, err
+}
+
+// retagBooks moves books whose tags were changed to their next version and
+// records their revisions, retag giving the tags each tag became. It
+// returns the IDs of the books.
+func retagBooks(tx *gorm.DB, books []*entities.Book, revise BookReviser, retag func(entities.Tag) []entities.Tag) ([]string, error) {
+	ids := make([]string, len(books))
+	for i, b := range books {
+		ids[i] = b.ID
+	}
+	if err := nextVersion(tx, ids...); err != nil {
+		return nil, err
+	}
+	for _, before := range books {
+		after := *before
+		after.Version++
+		after.Tags = nil
+		for _, tag := range before.Tags {
+			after.Tags = append(after.Tags, retag(tag)...)
+		}
+		if revision := revise(before, &after); revision != nil {
+			if err := createRevision(tx, revision); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ids, nil
+}
+
+// taggedBookIDs returns the IDs of the books with a tag, deleted ones
+// included, locking them so that the tag isn't added to other books until
+// the transaction ends
+func taggedBookIDs(tx *gorm.DB, tagID uint) ([]string, error) {
+	var ids []string
+	err := tx.Table("book_tags").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tag_id = ?", tagID).
+		Pluck("book_id", &ids).Error
+	return ids, err
+}
+
+// RefreshRating recomputes the average rating and review count of a book
+// from its visible reviews, moving it to its next version
+func (r *bookRepository) RefreshRating(ctx context.Context, bookID string) error {
+	visible := r.db.Table("reviews").Where("reviews.book_id = books.id AND reviews.hidden = ?", false)
+	if err := r.db.WithContext(ctx).
+		Model(&entities.Book{}).
+		Where("id = ?", bookID).
+		UpdateColumns(map[string]interface{}{
+			"rating_average": gorm.Expr("COALESCE((?), 0)", visible.Session(&gorm.Session{}).Select("ROUND(AVG(reviews.rating), 2)")),
+			"rating_count":   gorm.Expr("(?)", visible.Session(&gorm.Session{}).Select("COUNT(*)")),
+			"version":        gorm.Expr("version + 1"),
+		}).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// FindByShareToken returns the book with a share link, nil if there's none
+func (r *bookRepository) FindByShareToken(ctx context.Context, token string) (*entities.Book, error) {
+	var b entities.Book
+	if err := r.db.WithContext(ctx).Preload("Tags").Where("share_token = ?", token).First(&b).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &b, nil
+}
+
+// SetSharing saves the visibility and share link token of a book
+func (r *bookRepository) SetSharing(ctx context.Context, bookID, visibility string, shareToken *string) error {
+	if err := r.db.WithContext(ctx).
+		Model(&entities.Book{}).
+		Where("id = ?", bookID).
+		Updates(map[string]interface{}{
+			"visibility":  visibility,
+			"share_token": shareToken,
+			"version":     gorm.Expr("version + 1"),
+		}).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// FindGrant returns the grant of a user on a book, nil if there's none
+func (r *bookRepository) FindGrant(ctx context.Context, bookID, userID string) (*entities.BookGrant, error) {
+	var grant entities.BookGrant
+	if err := r.db.WithContext(ctx).
+		Where("book_id = ? AND user_id = ?", bookID, userID).
+		First(&grant).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &grant, nil
+}
+
+// ListGrants returns the grants on a book, oldest first
+func (r *bookRepository) ListGrants(ctx context.Context, bookID string) ([]*entities.BookGrant, error) {
+	var grants []*entities.BookGrant
+	if err := r.db.WithContext(ctx).
+		Where("book_id = ?", bookID).
+		Order("created_at, user_id").
+		Find(&grants).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return grants, nil
+}
+
+// SaveGrant creates the grant or changes the role of the existing one
+func (r *bookRepository) SaveGrant(ctx context.Context, grant *entities.BookGrant) error {
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "book_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "granted_by", "updated_at"}),
+	}).Create(grant).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+func (r *bookRepository) DeleteGrant(ctx context.Context, bookID, userID string) error {
+	if err := r.db.WithContext(ctx).
+		Where("book_id = ? AND user_id = ?", bookID, userID).
+		Delete(&entities.BookGrant{}).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// ListSharedBookIDs returns the IDs of the books a user was granted a role on
+func (r *bookRepository) ListSharedBookIDs(ctx context.Context, userID string) ([]string, error) {
+	ids := []string{}
+	if err := r.db.WithContext(ctx).
+		Model(&entities.BookGrant{}).
+		Where("user_id = ?", userID).
+		Pluck("book_id", &ids).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return ids, nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"clean-arch-go/internal/domain/entities"
+)
+
+func TestPurgeRefusesBooksOnLoan(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewBookRepository(db.database())
+
+	db.expect("SELECT * FROM book_copies WHERE book_id = ? FOR UPDATE").withArgs("book-1").returns(copyColumns, copyRow(1, entities.CopyOnLoan))
+	db.expect("SELECT count(*) FROM loans WHERE book_id = ? AND returned_at IS NULL").returns([]string{"count(*)"}, []driver.Value{int64(1)})
+	if _, err := r.Purge(context.Background(), "book-1"); appErrorCode(err) != "CONFLICT" {
+		t.Errorf("purge = %v, want CONFLICT", err)
+	}
+	if deleted := db.statements("DELETE"); len(deleted) != 0 {
+		t.Errorf("purge of a book on loan deleted %v", deleted)
+	}
+}
+
+func TestPurgeReturnsTheActiveHolds(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewBookRepository(db.database())
+
+	db.expect("SELECT * FROM book_copies WHERE book_id = ? FOR UPDATE").returns(copyColumns, copyRow(1, entities.CopyOnHold))
+	db.expect("SELECT count(*) FROM loans").returns([]string{"count(*)"}, []driver.Value{int64(0)})
+	db.expect("SELECT * FROM holds WHERE book_id = ? AND status IN (?,?) ORDER BY id").
+		withArgs("book-1", entities.HoldWaiting, entities.HoldReady).
+		returns(holdColumns,
+			holdRow(5, "user-2", entities.HoldReady, int64(1), readyUntil),
+			holdRow(6, "user-3", entities.HoldWaiting, nil, nil))
+	db.expect("DELETE FROM book_tags WHERE book_id = ?")
+	for _, table := range []string{"book_translations", "book_revisions", "book_grants", "shelf_books", "reading_progress", "reading_sessions", "reviews", "holds", "loans", "book_copies"} {
+		db.expect("DELETE FROM " + table + " WHERE book_id = ?").withArgs("book-1")
+	}
+	db.expect("DELETE FROM books WHERE id = ?").withArgs("book-1").affects(1)
+
+	holds, err := r.Purge(context.Background(), "book-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holds) != 2 || holds[0].UserID != "user-2" || holds[1].UserID != "user-3" {
+		t.Errorf("holds = %+v, want the ready and the waiting one", holds)
+	}
+	if commits := db.statements("COMMIT"); len(commits) != 1 {
+		t.Errorf("purge wasn't committed")
+	}
+}
+
+func TestRestoreOnlyUndeletesDeletedBooks(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewBookRepository(db.database())
+
+	db.expect("UPDATE books SET deleted_at=?", "WHERE id = ? AND deleted_at IS NOT NULL").withArgs(nil, anyArg, "book-1").affects(1)
+	if err := r.Restore(context.Background(), "book-1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListDeletedBeforeLeavesTheBooksOnLoanOut(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewBookRepository(db.database())
+
+	db.expect("SELECT * FROM books WHERE (deleted_at IS NOT NULL AND deleted_at < ?) AND NOT EXISTS (SELECT 1 FROM loans WHERE loans.book_id = books.id AND loans.returned_at IS NULL)",
+		"ORDER BY deleted_at, id LIMIT ?").
+		withArgs(lendingNow, 100).
+		returns([]string{"id", "user_id", "deleted_at"}, []driver.Value{"book-1", "user-1", lendingNow.Add(-time.Hour)})
+
+	books, err := r.ListDeletedBefore(context.Background(), lendingNow, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(books) != 1 || books[0].ID != "book-1" {
+		t.Errorf("books = %+v, want book-1", books)
+	}
+}
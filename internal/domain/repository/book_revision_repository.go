@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/errors"
+
+	"clean-arch-go/internal/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BookRevisionRepository interface {
+	Create(ctx context.Context, revision *entities.BookRevision) error
+	ListByBookID(ctx context.Context, bookID string, page, limit int) ([]*entities.BookRevision, int64, error)
+	FindByNumber(ctx context.Context, bookID string, number int) (*entities.BookRevision, error)
+}
+
+type bookRevisionRepository struct {
+	db *gorm.DB
+}
+
+func NewBookRevisionRepository(db *database.Database) BookRevisionRepository {
+	return &bookRevisionRepository{db: db.DB}
+}
+
+// Create saves a revision as the next one of its book, setting its number
+func (r *bookRevisionRepository) Create(ctx context.Context, revision *entities.BookRevision) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createRevision(tx, revision)
+	})
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+func createRevision(tx *gorm.DB, revision *entities.BookRevision) error {
+	// Locking the last revision keeps concurrent changes from taking the
+	// same number
+	var last int
+	if err := tx.Model(&entities.BookRevision{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("book_id = ?", revision.BookID).
+		Select("COALESCE(MAX(number), 0)").
+		Scan(&last).Error; err != nil {
+		return err
+	}
+	revision.Number = last + 1
+	return tx.Create(revision).Error
+}
+
+// ListByBookID returns a page of the revisions of a book, latest first,
+// with their number
+func (r *bookRevisionRepository) ListByBookID(ctx context.Context, bookID string, page, limit int) ([]*entities.BookRevision, int64, error) {
+	matching := func() *gorm.DB {
+		return r.db.WithContext(ctx).Model(&entities.BookRevision{}).Where("book_id = ?", bookID)
+	}
+
+	var total int64
+	if err := matching().Count(&total).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+
+	var revisions []*entities.BookRevision
+	if err := matching().
+		Order("number DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&revisions).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+	return revisions, total, nil
+}
+
+// FindByNumber returns a revision of a book, nil if there's none
+func (r *bookRevisionRepository) FindByNumber(ctx context.Context, bookID string, number int) (*entities.BookRevision, error) {
+	var revision entities.BookRevision
+	if err := r.db.WithContext(ctx).
+		Where("book_id = ? AND number = ?", bookID, number).
+		First(&revision).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &revision, nil
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+	"unicode/utf8"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/errors"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// fulltextColumns must match the columns of the idx_books_fulltext index
+	fulltextColumns = "books.title, books.description, books.author"
+	// fulltextMinLength is InnoDB's default innodb_ft_min_token_size, shorter
+	// words are not indexed
+	fulltextMinLength = 3
+	// errNoFulltextIndex is returned by MySQL when the FULLTEXT index is missing
+	errNoFulltextIndex = 1191
+	facetLimit         = 20
+)
+
+// Facet names
+const (
+	FacetAuthor = "author"
+	FacetYear   = "year"
+	FacetTag    = "tag"
+)
+
+// BookSearchResult is a page of books matching a search
+type BookSearchResult struct {
+	Books []*entities.Book
+	// Total is the number of matching books across all pages
+	Total int64
+	// Facets counts all matching books by facet name and value, when requested
+	Facets map[string][]book.FacetCount
+	// Highlights holds, by book ID, the matched fragments of each field when
+	// the search backend supports highlighting
+	Highlights map[string]map[string][]string
+}
+
+var sortColumns = map[string]string{
+	book.SortTitle:     "books.title",
+	book.SortAuthor:    "books.author",
+	book.SortYear:      "books.published_year",
+	book.SortCreatedAt: "books.created_at",
+	book.SortUpdatedAt: "books.updated_at",
+}
+
+// Search returns the page of books matching filter. The query uses the
+// FULLTEXT index on MySQL and falls back to LIKE elsewhere, for words
+// shorter than the index minimum and when the index is missing.
+func (r *bookRepository) Search(ctx context.Context, filter *book.Filter) (*BookSearchResult, error) {
+	result := &BookSearchResult{}
+	err := r.withFulltext(filter, func(fulltext bool) error {
+		if err := r.searchScope(ctx, filter, fulltext).Count(&result.Total).Error; err != nil {
+			return err
+		}
+		return r.searchScope(ctx, filter, fulltext).
+			Preload("Tags").
+			Order(r.searchOrder(filter, fulltext)).
+			Offset((filter.Page - 1) * filter.Limit).
+			Limit(filter.Limit).
+			Find(&result.Books).Error
+	})
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return result, nil
+}
+
+// SearchFacets counts the books matching filter by author, publication year and tag
+func (r *bookRepository) SearchFacets(ctx context.Context, filter *book.Filter) (map[string][]book.FacetCount, error) {
+	facets := make(map[string][]book.FacetCount)
+	err := r.withFulltext(filter, func(fulltext bool) error {
+		var authors, years, tags []book.FacetCount
+		if err := r.searchScope(ctx, filter, fulltext).
+			Select("books.author AS value, COUNT(*) AS count").
+			Group("books.author").
+			Order("count DESC, value").
+			Limit(facetLimit).
+			Scan(&authors).Error; err != nil {
+			return err
+		}
+		if err := r.searchScope(ctx, filter, fulltext).
+			Select("books.published_year AS value, COUNT(*) AS count").
+			Where("books.published_year > 0").
+			Group("books.published_year").
+			Order("books.published_year DESC").
+			Limit(facetLimit).
+			Scan(&years).Error; err != nil {
+			return err
+		}
+		if err := r.searchScope(ctx, filter, fulltext).
+			Joins("JOIN book_tags ON book_tags.book_id = books.id").
+			Joins("JOIN tags ON tags.id = book_tags.tag_id").
+			Select("tags.name AS value, COUNT(*) AS count").
+			Group("tags.name").
+			Order("count DESC, value").
+			Limit(facetLimit).
+			Scan(&tags).Error; err != nil {
+			return err
+		}
+		facets[FacetAuthor] = authors
+		facets[FacetYear] = years
+		facets[FacetTag] = tags
+		return nil
+	})
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return facets, nil
+}
+
+// withFulltext runs search with the FULLTEXT index when it can be used,
+// retrying with LIKE and disabling FULLTEXT searches if the index is missing
+func (r *bookRepository) withFulltext(filter *book.Filter, search func(fulltext bool) error) error {
+	if !r.canUseFulltext(filter.Query) {
+		return search(false)
+	}
+
+	err := search(true)
+	var mysqlErr *mysql.MySQLError
+	if stderrors.As(err, &mysqlErr) && mysqlErr.Number == errNoFulltextIndex {
+		r.fulltextDisabled.Store(true)
+		return search(false)
+	}
+	return err
+}
+
+func (r *bookRepository) canUseFulltext(query string) bool {
+	if query == "" || r.fulltextDisabled.Load() || r.db.Dialector.Name() != "mysql" {
+		return false
+	}
+	for _, word := range strings.Fields(query) {
+		if utf8.RuneCountInString(word) < fulltextMinLength {
+			return false
+		}
+	}
+	return true
+}
+
+// searchScope returns the books matching all the criteria of filter
+func (r *bookRepository) searchScope(ctx context.Context, filter *book.Filter, fulltext bool) *gorm.DB {
+	db := r.db.WithContext(ctx).Model(&entities.Book{})
+
+	if filter.ViewerID != "" {
+		shared := r.db.Table("book_grants").Select("1").
+			Where("book_grants.book_id = books.id AND book_grants.user_id = ?", filter.ViewerID)
+		switch filter.Access {
+		case book.ScopeOwned:
+			db = db.Where("books.user_id = ?", filter.ViewerID)
+		case book.ScopeShared:
+			db = db.Where("books.user_id <> ? AND EXISTS (?)", filter.ViewerID, shared)
+		case book.ScopePublic:
+			db = db.Where("books.user_id <> ? AND books.visibility = ?", filter.ViewerID, book.VisibilityPublic)
+		default:
+			db = db.Where("(books.user_id = ? OR books.visibility = ? OR EXISTS (?))", filter.ViewerID, book.VisibilityPublic, shared)
+		}
+	}
+	if filter.OwnerID != "" {
+		db = db.Where("books.user_id = ?", filter.OwnerID)
+	}
+	if filter.Title != "" {
+		db = db.Where("books.title LIKE ?", containsPattern(filter.Title))
+	}
+	if filter.Author != "" {
+		db = db.Where("books.author LIKE ?", containsPattern(filter.Author))
+	}
+	if filter.ISBN != "" {
+		db = db.Where("books.isbn = ?", filter.ISBN)
+	}
+	if filter.Publisher != "" {
+		db = db.Where("books.publisher LIKE ?", containsPattern(filter.Publisher))
+	}
+	if filter.Lang != "" {
+		db = db.Where("books.lang = ?", filter.Lang)
+	}
+	if filter.YearFrom > 0 {
+		db = db.Where("books.published_year >= ?", filter.YearFrom)
+	}
+	if filter.YearTo > 0 {
+		db = db.Where("books.published_year <= ?", filter.YearTo)
+	}
+	if len(filter.Tags) > 0 {
+		tagged := r.db.Table("book_tags").
+			Select("book_tags.book_id").
+			Joins("JOIN tags ON tags.id = book_tags.tag_id").
+			Where("tags.name IN ?", filter.Tags).
+			Group("book_tags.book_id").
+			Having("COUNT(DISTINCT tags.name) = ?", len(filter.Tags))
+		db = db.Where("books.id IN (?)", tagged)
+	}
+	if filter.ShelfID != 0 {
+		shelved := r.db.Table("shelf_books").Select("book_id").Where("shelf_id = ?", filter.ShelfID)
+		db = db.Where("books.id IN (?)", shelved)
+	}
+	if filter.BookIDs != nil {
+		db = db.Where("books.id IN ?", filter.BookIDs)
+	}
+
+	switch {
+	case filter.Query == "":
+	case fulltext:
+		db = db.Where("MATCH("+fulltextColumns+") AGAINST (? IN NATURAL LANGUAGE MODE)", filter.Query)
+	default:
+		for _, word := range strings.Fields(filter.Query) {
+			pattern := containsPattern(word)
+			db = db.Where("(books.title LIKE ? OR books.description LIKE ? OR books.author LIKE ?)", pattern, pattern, pattern)
+		}
+	}
+	return db
+}
+
+// searchOrder returns the ORDER BY clause of a search, always ending with
+// the ID so that pages are stable
+func (r *bookRepository) searchOrder(filter *book.Filter, fulltext bool) clause.OrderBy {
+	var terms []string
+	var vars []interface{}
+	for _, field := range filter.Sort {
+		direction := " ASC"
+		if field.Desc {
+			direction = " DESC"
+		}
+
+		switch {
+		case field.Field != book.SortRelevance:
+			if column, ok := sortColumns[field.Field]; ok {
+				terms = append(terms, column+direction)
+			}
+		case filter.Query == "":
+		case fulltext:
+			terms = append(terms, "MATCH("+fulltextColumns+") AGAINST (? IN NATURAL LANGUAGE MODE)"+direction)
+			vars = append(vars, filter.Query)
+		default:
+			// Without a relevance score, matches in the title come first
+			terms = append(terms, "CASE WHEN books.title LIKE ? THEN 1 ELSE 0 END"+direction)
+			vars = append(vars, containsPattern(filter.Query))
+		}
+	}
+	terms = append(terms, "books.id")
+
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:                strings.Join(terms, ", "),
+		Vars:               vars,
+		WithoutParentheses: true,
+	}}
+}
+
+// containsPattern returns a LIKE pattern matching values containing s
+func containsPattern(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + replacer.Replace(s) + "%"
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"clean-arch-go/internal/domain/book"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const fulltextMatch = "MATCH(books.title, books.description, books.author) AGAINST (? IN NATURAL LANGUAGE MODE)"
+
+var (
+	countColumns = []string{"count(*)"}
+	facetColumns = []string{"value", "count"}
+)
+
+func TestSearchUsesTheFulltextIndex(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewBookRepository(db.database())
+
+	filter := &book.Filter{
+		Query: "history world",
+		Lang:  "en",
+		Sort:  []book.SortField{{Field: book.SortRelevance, Desc: true}, {Field: book.SortYear}},
+		Page:  2,
+		Limit: 10,
+	}
+	db.expect("SELECT count(*) FROM books WHERE books.lang = ? AND "+fulltextMatch).
+		withArgs("en", "history world").
+		returns(countColumns, []driver.Value{int64(11)})
+	db.expect("SELECT * FROM books WHERE books.lang = ? AND "+fulltextMatch,
+		"ORDER BY "+fulltextMatch+" DESC, books.published_year ASC, books.id LIMIT ? OFFSET ?").
+		withArgs("en", "history world", "history world", 10, 10).
+		returns([]string{"id", "title"}, []driver.Value{"book-11", "A World History"})
+	db.expect("SELECT * FROM book_tags WHERE book_tags.book_id = ?").withArgs("book-11")
+
+	result, err := r.Search(context.Background(), filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 11 || len(result.Books) != 1 || result.Books[0].ID != "book-11" {
+		t.Errorf("result = %d %+v, want the last of 11 books", result.Total, result.Books)
+	}
+}
+
+func TestSearchFallsBackToLikeWithoutTheFulltextIndex(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewBookRepository(db.database())
+	filter := &book.Filter{Query: "history", Page: 1, Limit: 20}
+
+	db.expect("SELECT count(*) FROM books WHERE " + fulltextMatch).
+		fails(&mysql.MySQLError{Number: 1191, Message: "Can't find FULLTEXT index matching the column list"})
+	db.expect("SELECT count(*) FROM books WHERE ((books.title LIKE ? OR books.description LIKE ? OR books.author LIKE ?))").
+		withArgs("%history%", "%history%", "%history%").
+		returns(countColumns, []driver.Value{int64(0)})
+	db.expect("SELECT * FROM books WHERE ((books.title LIKE ?", "ORDER BY books.id LIMIT ?")
+	if _, err := r.Search(context.Background(), filter); err != nil {
+		t.Fatal(err)
+	}
+
+	// The missing index is remembered, later searches go straight to LIKE
+	db.expect("SELECT count(*) FROM books WHERE ((books.title LIKE ?").returns(countColumns, []driver.Value{int64(0)})
+	db.expect("SELECT * FROM books WHERE ((books.title LIKE ?")
+	if _, err := r.Search(context.Background(), filter); err != nil {
+		t.Fatal(err)
+	}
+	if matches := db.statements("MATCH("); len(matches) != 1 {
+		t.Errorf("FULLTEXT was tried %d times, want once", len(matches))
+	}
+}
+
+func TestSearchReportsOtherErrors(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewBookRepository(db.database())
+
+	db.expect("SELECT count(*) FROM books WHERE " + fulltextMatch).
+		fails(&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"})
+	if _, err := r.Search(context.Background(), &book.Filter{Query: "history", Page: 1, Limit: 20}); appErrorCode(err) != "INTERNAL_ERROR" {
+		t.Errorf("search = %v, want an internal error", err)
+	}
+}
+
+func TestSearchUsesLikeForShortWords(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewBookRepository(db.database())
+
+	// "go" is shorter than the indexed words, each word must then match
+	// with LIKE, its wildcards escaped
+	filter := &book.Filter{
+		Query: "go 100%",
+		Sort:  []book.SortField{{Field: book.SortRelevance, Desc: true}},
+		Page:  1,
+		Limit: 20,
+	}
+	like := "((books.title LIKE ? OR books.description LIKE ? OR books.author LIKE ?))"
+	db.expect("SELECT count(*) FROM books WHERE "+like+" AND "+like).
+		withArgs("%go%", "%go%", "%go%", `%100\%%`, `%100\%%`, `%100\%%`).
+		returns(countColumns, []driver.Value{int64(0)})
+	db.expect("SELECT * FROM books WHERE "+like+" AND "+like, "ORDER BY CASE WHEN books.title LIKE ? THEN 1 ELSE 0 END DESC, books.id").
+		withArgs("%go%", "%go%", "%go%", `%100\%%`, `%100\%%`, `%100\%%`, `%go 100\%%`, 20)
+
+	if _, err := r.Search(context.Background(), filter); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchScopesTheViewer(t *testing.T) {
+	tests := []struct {
+		access string
+		where  string
+		args   []interface{}
+	}{
+		{"", "WHERE ((books.user_id = ? OR books.visibility = ? OR EXISTS (SELECT 1 FROM book_grants", []interface{}{"user-1", book.VisibilityPublic, "user-1"}},
+		{book.ScopeOwned, "WHERE books.user_id = ?", []interface{}{"user-1"}},
+		{book.ScopeShared, "WHERE (books.user_id <> ? AND EXISTS (SELECT 1 FROM book_grants", []interface{}{"user-1", "user-1"}},
+		{book.ScopePublic, "WHERE (books.user_id <> ? AND books.visibility = ?)", []interface{}{"user-1", book.VisibilityPublic}},
+	}
+	for _, tt := range tests {
+		db := newFakeDB(t)
+		r := NewBookRepository(db.database())
+
+		db.expect("SELECT count(*) FROM books "+tt.where).withArgs(tt.args...).returns(countColumns, []driver.Value{int64(0)})
+		db.expect("SELECT * FROM books " + tt.where)
+		if _, err := r.Search(context.Background(), &book.Filter{ViewerID: "user-1", Access: tt.access, Page: 1, Limit: 20}); err != nil {
+			t.Fatalf("access %q: %v", tt.access, err)
+		}
+	}
+}
+
+func TestSearchFacets(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewBookRepository(db.database())
+
+	filter := &book.Filter{Query: "history", Tags: []string{"classic"}}
+	tagged := "books.id IN (SELECT book_tags.book_id FROM book_tags JOIN tags ON tags.id = book_tags.tag_id WHERE tags.name IN (?) GROUP BY book_tags.book_id HAVING COUNT(DISTINCT tags.name) = ?)"
+	db.expect("SELECT books.author AS value, COUNT(*) AS count FROM books WHERE "+tagged+" AND "+fulltextMatch,
+		"GROUP BY books.author ORDER BY count DESC, value LIMIT ?").
+		withArgs("classic", 1, "history", facetLimit).
+		returns(facetColumns, []driver.Value{"Tolstoy", int64(3)}, []driver.Value{"Dumas", int64(1)})
+	db.expect("SELECT books.published_year AS value, COUNT(*) AS count FROM books WHERE "+tagged+" AND "+fulltextMatch+" AND books.published_year > 0",
+		"GROUP BY books.published_year ORDER BY books.published_year DESC").
+		returns(facetColumns, []driver.Value{"1869", int64(3)}, []driver.Value{"1844", int64(1)})
+	db.expect("SELECT tags.name AS value, COUNT(*) AS count FROM books JOIN book_tags ON book_tags.book_id = books.id JOIN tags ON tags.id = book_tags.tag_id WHERE "+tagged,
+		"GROUP BY tags.name ORDER BY count DESC, value").
+		returns(facetColumns, []driver.Value{"classic", int64(4)}, []driver.Value{"war", int64(3)})
+
+	facets, err := r.SearchFacets(context.Background(), filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]book.FacetCount{
+		FacetAuthor: {{Value: "Tolstoy", Count: 3}, {Value: "Dumas", Count: 1}},
+		FacetYear:   {{Value: "1869", Count: 3}, {Value: "1844", Count: 1}},
+		FacetTag:    {{Value: "classic", Count: 4}, {Value: "war", Count: 3}},
+	}
+	for name, counts := range want {
+		got := facets[name]
+		if len(got) != len(counts) {
+			t.Errorf("facet %s = %v, want %v", name, got, counts)
+			continue
+		}
+		for i := range counts {
+			if got[i] != counts[i] {
+				t.Errorf("facet %s = %v, want %v", name, got, counts)
+				break
+			}
+		}
+	}
+}
+
+func TestSearchFacetsFallBackToLike(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewBookRepository(db.database())
+
+	db.expect("SELECT books.author AS value", fulltextMatch).fails(&mysql.MySQLError{Number: 1191})
+	db.expect("SELECT books.author AS value", "books.title LIKE ?")
+	db.expect("SELECT books.published_year AS value", "books.title LIKE ?")
+	db.expect("SELECT tags.name AS value", "books.title LIKE ?")
+
+	facets, err := r.SearchFacets(context.Background(), &book.Filter{Query: "history"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(facets) != 3 {
+		t.Errorf("facets = %v, want the author, year and tag ones", facets)
+	}
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Cursor is a position in a list ordered by creation time, newest first,
+// with the ID breaking ties between rows created at the same time
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// CursorQuery selects a page of a list: the rows after After, the rows
+// before Before, or the first rows when neither is set
+type CursorQuery struct {
+	After  *Cursor
+	Before *Cursor
+	Limit  int
+}
+
+// CursorPage is a page of a list with the cursors of the adjacent pages,
+// nil when there's no such page
+type CursorPage[T any] struct {
+	Items []*T
+	Next  *Cursor
+	Prev  *Cursor
+}
+
+// findPage loads a page of the rows of db matching query with keyset
+// pagination on (created_at, id)
+func (r *baseRepository[T]) findPage(ctx context.Context, db *gorm.DB, query interface{}, q CursorQuery) (*CursorPage[T], error) {
+	db = db.WithContext(ctx).Model(new(T))
+	if query != nil {
+		db = db.Where(query)
+	}
+
+	// Rows before the cursor are read backwards from it, then reversed
+	backwards := q.Before != nil
+	switch {
+	case q.After != nil:
+		db = db.Where("created_at < ? OR (created_at = ? AND id < ?)", q.After.CreatedAt, q.After.CreatedAt, q.After.ID)
+	case q.Before != nil:
+		db = db.Where("created_at > ? OR (created_at = ? AND id > ?)", q.Before.CreatedAt, q.Before.CreatedAt, q.Before.ID)
+	}
+
+	var items []*T
+	// One more row than needed tells whether there's a page beyond this one
+	if err := db.Order(clause.OrderBy{Columns: []clause.OrderByColumn{
+		{Column: clause.Column{Name: "created_at"}, Desc: !backwards},
+		{Column: clause.Column{Name: "id"}, Desc: !backwards},
+	}}).Limit(q.Limit + 1).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to find page of entities: %w", err)
+	}
+
+	more := len(items) > q.Limit
+	if more {
+		items = items[:q.Limit]
+	}
+	if backwards {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	page := &CursorPage[T]{Items: items}
+	if len(items) == 0 {
+		return page, nil
+	}
+	first, err := r.cursorOf(ctx, items[0])
+	if err != nil {
+		return nil, err
+	}
+	last, err := r.cursorOf(ctx, items[len(items)-1])
+	if err != nil {
+		return nil, err
+	}
+	if backwards {
+		// The page the cursor came from follows this one
+		page.Next = last
+		if more {
+			page.Prev = first
+		}
+	} else {
+		if more {
+			page.Next = last
+		}
+		if q.After != nil {
+			page.Prev = first
+		}
+	}
+	return page, nil
+}
+
+// cursorOf returns the position of an entity, read from its CreatedAt and ID fields
+func (r *baseRepository[T]) cursorOf(ctx context.Context, entity *T) (*Cursor, error) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(entity); err != nil {
+		return nil, fmt.Errorf("failed to parse entity schema: %w", err)
+	}
+	createdAtField := stmt.Schema.LookUpField("CreatedAt")
+	idField := stmt.Schema.LookUpField("ID")
+	if createdAtField == nil || idField == nil {
+		return nil, fmt.Errorf("entity %s has no CreatedAt or ID field", stmt.Schema.Name)
+	}
+
+	value := reflect.ValueOf(entity)
+	createdAt, _ := createdAtField.ValueOf(ctx, value)
+	id, _ := idField.ValueOf(ctx, value)
+	t, ok := createdAt.(time.Time)
+	if !ok {
+		return nil, fmt.Errorf("entity %s CreatedAt is not a time", stmt.Schema.Name)
+	}
+	return &Cursor{CreatedAt: t, ID: fmt.Sprint(id)}, nil
+}
@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"clean-arch-go/internal/pkg/database"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeDB stands in for MySQL in the repository tests: it answers the
+// statements run, in order, with the results the test scripted for them
+type fakeDB struct {
+	t        *testing.T
+	mu       sync.Mutex
+	expected []*fakeStatement
+	// log holds the statements run, with BEGIN, COMMIT and ROLLBACK
+	log []string
+}
+
+// fakeStatement is a statement a test expects, and its result
+type fakeStatement struct {
+	// fragments must all appear in the statement, in order
+	fragments []string
+	args      []interface{}
+	columns   []string
+	rows      [][]driver.Value
+	affected  int64
+	insertID  int64
+	err       error
+}
+
+// anyArg matches any argument, like the timestamps GORM sets
+var anyArg = anyValue{}
+
+type anyValue struct{}
+
+func newFakeDB(t *testing.T) *fakeDB {
+	t.Helper()
+	db := &fakeDB{t: t}
+	t.Cleanup(func() {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		for _, statement := range db.expected {
+			t.Errorf("expected statement not run: %s", strings.Join(statement.fragments, " … "))
+		}
+	})
+	return db
+}
+
+// database returns a MySQL database running its statements on db
+func (db *fakeDB) database() *database.Database {
+	db.t.Helper()
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(fakeConnector{db}),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		db.t.Fatal(err)
+	}
+	return &database.Database{DB: gormDB}
+}
+
+// expect adds a statement containing fragments, backquotes left out, which
+// returns no rows and affects none unless told otherwise
+func (db *fakeDB) expect(fragments ...string) *fakeStatement {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	statement := &fakeStatement{fragments: fragments}
+	db.expected = append(db.expected, statement)
+	return statement
+}
+
+// withArgs makes the statement expect these arguments
+func (s *fakeStatement) withArgs(args ...interface{}) *fakeStatement {
+	s.args = args
+	return s
+}
+
+// returns makes the statement return rows of the given columns
+func (s *fakeStatement) returns(columns []string, rows ...[]driver.Value) *fakeStatement {
+	s.columns = columns
+	s.rows = rows
+	return s
+}
+
+// affects makes the statement change n rows
+func (s *fakeStatement) affects(n int64) *fakeStatement {
+	s.affected = n
+	return s
+}
+
+// inserts makes the statement insert a row with the given ID
+func (s *fakeStatement) inserts(id int64) *fakeStatement {
+	s.affected = 1
+	s.insertID = id
+	return s
+}
+
+// fails makes the statement fail with err
+func (s *fakeStatement) fails(err error) *fakeStatement {
+	s.err = err
+	return s
+}
+
+// statements returns the statements run that contain fragment
+func (db *fakeDB) statements(fragment string) []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var found []string
+	for _, query := range db.log {
+		if strings.Contains(query, fragment) {
+			found = append(found, query)
+		}
+	}
+	return found
+}
+
+func (db *fakeDB) record(query string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.log = append(db.log, query)
+}
+
+// run checks a statement against the next expected one, returning its result
+func (db *fakeDB) run(query string, args []driver.NamedValue) (*fakeStatement, error) {
+	query = strings.ReplaceAll(query, "`", "")
+	db.record(query)
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.expected) == 0 {
+		db.t.Errorf("unexpected statement: %s", query)
+		return nil, fmt.Errorf("unexpected statement: %s", query)
+	}
+	statement := db.expected[0]
+	db.expected = db.expected[1:]
+
+	rest := query
+	for _, fragment := range statement.fragments {
+		i := strings.Index(rest, fragment)
+		if i < 0 {
+			db.t.Errorf("statement %s\ndoesn't contain %q after the previous fragments", query, fragment)
+			return nil, fmt.Errorf("unexpected statement: %s", query)
+		}
+		rest = rest[i+len(fragment):]
+	}
+	if statement.args != nil {
+		got := make([]string, len(args))
+		for i, arg := range args {
+			got[i] = fmt.Sprint(arg.Value)
+		}
+		want := make([]string, len(statement.args))
+		for i, arg := range statement.args {
+			want[i] = fmt.Sprint(arg)
+			if arg == anyArg && i < len(got) {
+				want[i] = got[i]
+			}
+		}
+		if strings.Join(got, ", ") != strings.Join(want, ", ") {
+			db.t.Errorf("statement %s\nhas arguments %v, want %v", query, got, want)
+		}
+	}
+	return statement, statement.err
+}
+
+type fakeConnector struct {
+	db *fakeDB
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{db: c.db}, nil
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, fmt.Errorf("fake driver connections come from their connector")
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.db.record("BEGIN")
+	return fakeTx{db: c.db}, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	statement, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: statement.columns, rows: statement.rows}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	statement, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return fakeResult{statement}, nil
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx fakeTx) Commit() error {
+	tx.db.record("COMMIT")
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.record("ROLLBACK")
+	return nil
+}
+
+type fakeResult struct {
+	statement *fakeStatement
+}
+
+func (r fakeResult) LastInsertId() (int64, error) {
+	return r.statement.insertID, nil
+}
+
+func (r fakeResult) RowsAffected() (int64, error) {
+	return r.statement.affected, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/errors"
+
+	"clean-arch-go/internal/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GlossaryRepository interface {
+	BaseRepository[entities.Glossary]
+	ListForUser(ctx context.Context, userID, tenantID string) ([]*entities.Glossary, error)
+	FindTerm(ctx context.Context, glossaryID, termID uint) (*entities.GlossaryTerm, error)
+	CreateTerms(ctx context.Context, terms []*entities.GlossaryTerm) error
+	UpdateTerm(ctx context.Context, term *entities.GlossaryTerm) error
+	DeleteTerm(ctx context.Context, glossaryID, termID uint) error
+	ListTermsForUser(ctx context.Context, userID, tenantID string, targetLang string) ([]*entities.GlossaryTerm, error)
+}
+
+type glossaryRepository struct {
+	*baseRepository[entities.Glossary]
+}
+
+func NewGlossaryRepository(db *database.Database) GlossaryRepository {
+	return &glossaryRepository{
+		baseRepository: NewBaseRepository[entities.Glossary](db.DB).(*baseRepository[entities.Glossary]),
+	}
+}
+
+func (r *glossaryRepository) FindByID(ctx context.Context, id string) (*entities.Glossary, error) {
+	var glossary entities.Glossary
+	if err := r.db.WithContext(ctx).Preload("Terms").Where("id = ?", id).First(&glossary).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewNotFoundError("glossary")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &glossary, nil
+}
+
+func (r *glossaryRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("glossary_id = ?", id).Delete(&entities.GlossaryTerm{}).Error; err != nil {
+			return errors.NewInternalServerError(err.Error())
+		}
+		if err := tx.Where("id = ?", id).Delete(&entities.Glossary{}).Error; err != nil {
+			return errors.NewInternalServerError(err.Error())
+		}
+		return nil
+	})
+}
+
+// ListForUser lists the glossaries of a user and the ones shared with their
+// tenant, if any
+func (r *glossaryRepository) ListForUser(ctx context.Context, userID, tenantID string) ([]*entities.Glossary, error) {
+	var glossaries []*entities.Glossary
+	if err := r.db.WithContext(ctx).
+		Scopes(glossariesOf(userID, tenantID)).
+		Order("name").
+		Find(&glossaries).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return glossaries, nil
+}
+
+func (r *glossaryRepository) FindTerm(ctx context.Context, glossaryID, termID uint) (*entities.GlossaryTerm, error) {
+	var term entities.GlossaryTerm
+	if err := r.db.WithContext(ctx).
+		Where("id = ? AND glossary_id = ?", termID, glossaryID).
+		First(&term).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewNotFoundError("glossary term")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &term, nil
+}
+
+func (r *glossaryRepository) CreateTerms(ctx context.Context, terms []*entities.GlossaryTerm) error {
+	if len(terms) == 0 {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).Create(terms).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+func (r *glossaryRepository) UpdateTerm(ctx context.Context, term *entities.GlossaryTerm) error {
+	if err := r.db.WithContext(ctx).Save(term).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+func (r *glossaryRepository) DeleteTerm(ctx context.Context, glossaryID, termID uint) error {
+	if err := r.db.WithContext(ctx).
+		Where("id = ? AND glossary_id = ?", termID, glossaryID).
+		Delete(&entities.GlossaryTerm{}).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// ListTermsForUser returns the terms that apply to targetLang of all the
+// user's glossaries and of the ones shared with their tenant. The user's own
+// terms come first, so that they win over the tenant's for the same text.
+func (r *glossaryRepository) ListTermsForUser(ctx context.Context, userID, tenantID string, targetLang string) ([]*entities.GlossaryTerm, error) {
+	var terms []*entities.GlossaryTerm
+	if err := r.db.WithContext(ctx).
+		Joins("JOIN glossaries ON glossaries.id = glossary_terms.glossary_id AND glossaries.deleted_at IS NULL").
+		Scopes(glossariesOf(userID, tenantID)).
+		Where("glossary_terms.target_lang = ? OR glossary_terms.target_lang = ''", targetLang).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "glossaries.user_id = ? DESC, glossary_terms.id",
+			Vars:               []interface{}{userID},
+			WithoutParentheses: true,
+		}}).
+		Find(&terms).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return terms, nil
+}
+
+// glossariesOf restricts a query to the glossaries of a user and the ones
+// shared with their tenant
+func glossariesOf(userID, tenantID string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if tenantID == "" {
+			return db.Where("glossaries.user_id = ?", userID)
+		}
+		return db.Where("glossaries.user_id = ? OR glossaries.tenant_id = ?", userID, tenantID)
+	}
+}
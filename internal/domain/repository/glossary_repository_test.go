@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+)
+
+var termColumns = []string{"id", "glossary_id", "term", "target_lang", "translation", "protected", "case_sensitive"}
+
+func TestListTermsForUserAddsTheTenantTermsAfterTheUsers(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewGlossaryRepository(db.database())
+
+	db.expect("SELECT glossary_terms.",
+		"JOIN glossaries ON glossaries.id = glossary_terms.glossary_id",
+		"(glossary_terms.target_lang = ? OR glossary_terms.target_lang = '')",
+		"(glossaries.user_id = ? OR glossaries.tenant_id = ?)",
+		"ORDER BY glossaries.user_id = ? DESC, glossary_terms.id").
+		withArgs("fr", "user-1", "acme", "user-1").
+		returns(termColumns,
+			[]driver.Value{int64(1), int64(1), "Acme", "fr", "Acmé", false, false},
+			[]driver.Value{int64(2), int64(2), "Acme", "", "", true, false})
+
+	terms, err := r.ListTermsForUser(context.Background(), "user-1", "acme", "fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(terms) != 2 || terms[0].Translation != "Acmé" || !terms[1].Protected {
+		t.Errorf("terms = %+v", terms)
+	}
+}
+
+func TestListTermsForUserWithoutTenant(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewGlossaryRepository(db.database())
+
+	db.expect("AND glossaries.user_id = ? AND").withArgs("fr", "user-1", "user-1").returns(termColumns)
+	if _, err := r.ListTermsForUser(context.Background(), "user-1", "", "fr"); err != nil {
+		t.Fatal(err)
+	}
+	if shared := db.statements("tenant_id"); len(shared) != 0 {
+		t.Errorf("the terms of a user without tenant were looked up in tenants: %v", shared)
+	}
+}
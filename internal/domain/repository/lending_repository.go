@@ -0,0 +1,585 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/errors"
+
+	"clean-arch-go/internal/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Loan statuses of LoanFilter
+const (
+	LoanActive   = "active"
+	LoanOverdue  = "overdue"
+	LoanReturned = "returned"
+)
+
+// LoanFilter selects loans, ignoring the empty criteria
+type LoanFilter struct {
+	UserID string
+	BookID string
+	// Status is active, overdue or returned
+	Status string
+	// Now is when the loans are overdue at
+	Now time.Time
+}
+
+// HoldFilter selects the waiting and ready holds, ignoring the empty criteria
+type HoldFilter struct {
+	UserID string
+	BookID string
+}
+
+// LibraryEntry counts the copies of a book that can be borrowed
+type LibraryEntry struct {
+	BookID    string `json:"book_id"`
+	Title     string `json:"title"`
+	Author    string `json:"author"`
+	Copies    int64  `json:"copies"`
+	Available int64  `json:"available"`
+	Waiting   int64  `json:"waiting"`
+}
+
+type LendingRepository interface {
+	CreateCopy(ctx context.Context, bookCopy *entities.BookCopy, readyUntil time.Time) (*entities.Hold, error)
+	FindCopy(ctx context.Context, id uint) (*entities.BookCopy, error)
+	ListCopies(ctx context.Context, bookID string) ([]*entities.BookCopy, error)
+	DeleteCopy(ctx context.Context, id uint) error
+	ListLibrary(ctx context.Context, viewerID string, page, limit int) ([]LibraryEntry, int64, error)
+	Checkout(ctx context.Context, loan *entities.Loan) error
+	FindLoan(ctx context.Context, id uint) (*entities.Loan, error)
+	ListLoans(ctx context.Context, filter LoanFilter, page, limit int) ([]*entities.Loan, int64, error)
+	RenewLoan(ctx context.Context, id uint, dueAt time.Time, maxRenewals int) error
+	ReturnLoan(ctx context.Context, id uint, at, readyUntil time.Time) (*entities.Loan, *entities.Hold, error)
+	ListUnnotifiedOverdue(ctx context.Context, now time.Time) ([]*entities.Loan, error)
+	MarkOverdueNotified(ctx context.Context, id uint, at time.Time) error
+	PlaceHold(ctx context.Context, hold *entities.Hold) error
+	FindHold(ctx context.Context, id uint) (*entities.Hold, error)
+	ListHolds(ctx context.Context, filter HoldFilter) ([]*entities.Hold, error)
+	CountWaitingHolds(ctx context.Context, bookID string) (int64, error)
+	CancelHold(ctx context.Context, id uint, at, readyUntil time.Time) (*entities.Hold, error)
+	ExpireHolds(ctx context.Context, now, readyUntil time.Time) (expired, ready []*entities.Hold, err error)
+}
+
+type lendingRepository struct {
+	db *gorm.DB
+}
+
+func NewLendingRepository(db *database.Database) LendingRepository {
+	return &lendingRepository{db: db.DB}
+}
+
+// transactionError passes the application errors of a transaction through and
+// wraps the database ones
+func transactionError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*errors.AppError); ok {
+		return err
+	}
+	return errors.NewInternalServerError(err.Error())
+}
+
+// passOn hands a copy that's back to the first waiting hold of its book,
+// returning that hold, or makes it available when nobody is waiting
+func passOn(tx *gorm.DB, bookCopy *entities.BookCopy, now, readyUntil time.Time) (*entities.Hold, error) {
+	var hold entities.Hold
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("book_id = ? AND status = ?", bookCopy.BookID, entities.HoldWaiting).
+		Order("id").
+		First(&hold).Error
+	if err == gorm.ErrRecordNotFound {
+		bookCopy.Status = entities.CopyAvailable
+		return nil, tx.Model(bookCopy).Update("status", bookCopy.Status).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hold.Status = entities.HoldReady
+	hold.CopyID = &bookCopy.ID
+	hold.ReadyAt = &now
+	hold.ExpiresAt = &readyUntil
+	if err := tx.Model(&hold).Updates(map[string]interface{}{
+		"status":     hold.Status,
+		"copy_id":    bookCopy.ID,
+		"ready_at":   now,
+		"expires_at": readyUntil,
+	}).Error; err != nil {
+		return nil, err
+	}
+	bookCopy.Status = entities.CopyOnHold
+	return &hold, tx.Model(bookCopy).Update("status", bookCopy.Status).Error
+}
+
+// CreateCopy adds a copy of a book, handing it to the first waiting hold
+// when there's one, which is returned
+func (r *lendingRepository) CreateCopy(ctx context.Context, bookCopy *entities.BookCopy, readyUntil time.Time) (*entities.Hold, error) {
+	var hold *entities.Hold
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		bookCopy.Status = entities.CopyAvailable
+		if err := tx.Create(bookCopy).Error; err != nil {
+			return err
+		}
+		var err error
+		hold, err = passOn(tx, bookCopy, bookCopy.CreatedAt, readyUntil)
+		return err
+	})
+	return hold, transactionError(err)
+}
+
+func (r *lendingRepository) FindCopy(ctx context.Context, id uint) (*entities.BookCopy, error) {
+	var bookCopy entities.BookCopy
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&bookCopy).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewNotFoundError("copy")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &bookCopy, nil
+}
+
+func (r *lendingRepository) ListCopies(ctx context.Context, bookID string) ([]*entities.BookCopy, error) {
+	var copies []*entities.BookCopy
+	if err := r.db.WithContext(ctx).Where("book_id = ?", bookID).Order("id").Find(&copies).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return copies, nil
+}
+
+// DeleteCopy removes a copy unless it's on loan or kept for a hold
+func (r *lendingRepository) DeleteCopy(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND status = ?", id, entities.CopyAvailable).
+		Delete(&entities.BookCopy{})
+	if result.Error != nil {
+		return errors.NewInternalServerError(result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewAppError("CONFLICT", "Only available copies can be removed", nil)
+	}
+	return nil
+}
+
+// ListLibrary returns a page of the books with copies the viewer can see
+// by title, with the number of their copies, available copies and waiting
+// holds
+func (r *lendingRepository) ListLibrary(ctx context.Context, viewerID string, page, limit int) ([]LibraryEntry, int64, error) {
+	lent := func() *gorm.DB {
+		shared := r.db.Table("book_grants").Select("1").
+			Where("book_grants.book_id = books.id AND book_grants.user_id = ?", viewerID)
+		return r.db.WithContext(ctx).
+			Table("books").
+			Where("books.deleted_at IS NULL").
+			Where("(books.user_id = ? OR books.visibility = ? OR EXISTS (?))", viewerID, book.VisibilityPublic, shared).
+			Where("EXISTS (?)", r.db.Table("book_copies").Select("1").Where("book_copies.book_id = books.id"))
+	}
+
+	var total int64
+	if err := lent().Count(&total).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+
+	var entries []LibraryEntry
+	if err := lent().
+		Select("books.id AS book_id, books.title, books.author, (?) AS copies, (?) AS available, (?) AS waiting",
+			r.db.Table("book_copies").Select("COUNT(*)").Where("book_copies.book_id = books.id"),
+			r.db.Table("book_copies").Select("COUNT(*)").Where("book_copies.book_id = books.id AND book_copies.status = ?", entities.CopyAvailable),
+			r.db.Table("holds").Select("COUNT(*)").Where("holds.book_id = books.id AND holds.status = ?", entities.HoldWaiting)).
+		Order("books.title, books.id").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Scan(&entries).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+	return entries, total, nil
+}
+
+// Checkout lends a copy of loan.BookID to loan.UserID. The copy kept for a
+// ready hold of the user is taken first, then loan.CopyID when set or any
+// available copy, whose ID is set on the loan. Copies are claimed with a
+// conditional update, so a copy is never lent twice, and the borrower is
+// locked, so that two checkouts of theirs can't both find no active loan.
+func (r *lendingRepository) Checkout(ctx context.Context, loan *entities.Loan) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var borrower []string
+		if err := tx.Table("users").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", loan.UserID).
+			Pluck("id", &borrower).Error; err != nil {
+			return err
+		}
+
+		var active int64
+		if err := tx.Model(&entities.Loan{}).
+			Where("book_id = ? AND user_id = ? AND returned_at IS NULL", loan.BookID, loan.UserID).
+			Count(&active).Error; err != nil {
+			return err
+		}
+		if active > 0 {
+			return errors.NewAppError("CONFLICT", "You already borrowed this book", nil)
+		}
+
+		var hold entities.Hold
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("book_id = ? AND user_id = ? AND status = ?", loan.BookID, loan.UserID, entities.HoldReady).
+			First(&hold).Error
+		switch {
+		case err == nil && hold.CopyID != nil:
+			claimed := tx.Model(&entities.BookCopy{}).
+				Where("id = ? AND status = ?", *hold.CopyID, entities.CopyOnHold).
+				Update("status", entities.CopyOnLoan)
+			if claimed.Error != nil {
+				return claimed.Error
+			}
+			if claimed.RowsAffected == 0 {
+				return errors.NewAppError("CONFLICT", "The copy kept for your hold is no longer available", nil)
+			}
+			if err := tx.Model(&hold).Update("status", entities.HoldFulfilled).Error; err != nil {
+				return err
+			}
+			loan.CopyID = *hold.CopyID
+		case err == nil || err == gorm.ErrRecordNotFound:
+			var bookCopy entities.BookCopy
+			query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("book_id = ? AND status = ?", loan.BookID, entities.CopyAvailable)
+			if loan.CopyID != 0 {
+				query = query.Where("id = ?", loan.CopyID)
+			}
+			if err := query.Order("id").First(&bookCopy).Error; err != nil {
+				if err == gorm.ErrRecordNotFound {
+					return errors.NewAppError("CONFLICT", "No copy of this book is available", nil)
+				}
+				return err
+			}
+			claimed := tx.Model(&bookCopy).
+				Where("status = ?", entities.CopyAvailable).
+				Update("status", entities.CopyOnLoan)
+			if claimed.Error != nil {
+				return claimed.Error
+			}
+			if claimed.RowsAffected == 0 {
+				return errors.NewAppError("CONFLICT", "No copy of this book is available", nil)
+			}
+			loan.CopyID = bookCopy.ID
+		default:
+			return err
+		}
+
+		return tx.Create(loan).Error
+	})
+	return transactionError(err)
+}
+
+func (r *lendingRepository) FindLoan(ctx context.Context, id uint) (*entities.Loan, error) {
+	var loan entities.Loan
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&loan).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewNotFoundError("loan")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &loan, nil
+}
+
+// ListLoans returns a page of the loans matching filter, latest first, with
+// the number of matching loans
+func (r *lendingRepository) ListLoans(ctx context.Context, filter LoanFilter, page, limit int) ([]*entities.Loan, int64, error) {
+	matching := func() *gorm.DB {
+		db := r.db.WithContext(ctx).Model(&entities.Loan{})
+		if filter.UserID != "" {
+			db = db.Where("user_id = ?", filter.UserID)
+		}
+		if filter.BookID != "" {
+			db = db.Where("book_id = ?", filter.BookID)
+		}
+		switch filter.Status {
+		case LoanActive:
+			db = db.Where("returned_at IS NULL")
+		case LoanOverdue:
+			db = db.Where("returned_at IS NULL AND due_at < ?", filter.Now)
+		case LoanReturned:
+			db = db.Where("returned_at IS NOT NULL")
+		}
+		return db
+	}
+
+	var total int64
+	if err := matching().Count(&total).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+
+	var loans []*entities.Loan
+	if err := matching().
+		Order("checked_out_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&loans).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+	return loans, total, nil
+}
+
+// RenewLoan moves the due date of an active loan that has been renewed
+// fewer than maxRenewals times and whose book nobody is waiting for. Holds
+// are checked by the update itself, so a hold placed meanwhile isn't skipped.
+func (r *lendingRepository) RenewLoan(ctx context.Context, id uint, dueAt time.Time, maxRenewals int) error {
+	waiting := r.db.Table("holds").Select("1").
+		Where("holds.book_id = loans.book_id AND holds.status = ?", entities.HoldWaiting)
+	result := r.db.WithContext(ctx).
+		Model(&entities.Loan{}).
+		Where("id = ? AND returned_at IS NULL AND renewals < ?", id, maxRenewals).
+		Where("NOT EXISTS (?)", waiting).
+		Updates(map[string]interface{}{
+			"due_at":              dueAt,
+			"renewals":            gorm.Expr("renewals + 1"),
+			"overdue_notified_at": nil,
+		})
+	if result.Error != nil {
+		return errors.NewInternalServerError(result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewAppError("CONFLICT", "This loan can't be renewed", nil)
+	}
+	return nil
+}
+
+// ReturnLoan ends a loan and hands its copy to the first waiting hold of the
+// book, returning the loan and that hold, nil when nobody was waiting
+func (r *lendingRepository) ReturnLoan(ctx context.Context, id uint, at, readyUntil time.Time) (*entities.Loan, *entities.Hold, error) {
+	var loan entities.Loan
+	var hold *entities.Hold
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&loan).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errors.NewNotFoundError("loan")
+			}
+			return err
+		}
+		if loan.ReturnedAt != nil {
+			return errors.NewAppError("CONFLICT", "This loan was already returned", nil)
+		}
+		loan.ReturnedAt = &at
+		if err := tx.Model(&loan).Update("returned_at", at).Error; err != nil {
+			return err
+		}
+
+		var bookCopy entities.BookCopy
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", loan.CopyID).First(&bookCopy).Error; err != nil {
+			return err
+		}
+		var err error
+		hold, err = passOn(tx, &bookCopy, at, readyUntil)
+		return err
+	})
+	if err != nil {
+		return nil, nil, transactionError(err)
+	}
+	return &loan, hold, nil
+}
+
+// ListUnnotifiedOverdue returns the active loans due before now whose
+// borrower wasn't told yet
+func (r *lendingRepository) ListUnnotifiedOverdue(ctx context.Context, now time.Time) ([]*entities.Loan, error) {
+	var loans []*entities.Loan
+	if err := r.db.WithContext(ctx).
+		Where("returned_at IS NULL AND due_at < ? AND overdue_notified_at IS NULL", now).
+		Order("due_at").
+		Find(&loans).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return loans, nil
+}
+
+func (r *lendingRepository) MarkOverdueNotified(ctx context.Context, id uint, at time.Time) error {
+	if err := r.db.WithContext(ctx).
+		Model(&entities.Loan{}).
+		Where("id = ?", id).
+		Update("overdue_notified_at", at).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// PlaceHold queues a user for a book without available copies. The copies
+// of the book are locked so that none comes back unnoticed meanwhile.
+func (r *lendingRepository) PlaceHold(ctx context.Context, hold *entities.Hold) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var copies []*entities.BookCopy
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("book_id = ?", hold.BookID).Find(&copies).Error; err != nil {
+			return err
+		}
+		if len(copies) == 0 {
+			return errors.NewAppError("CONFLICT", "This book has no copies to lend", nil)
+		}
+		for _, bookCopy := range copies {
+			if bookCopy.Status == entities.CopyAvailable {
+				return errors.NewAppError("CONFLICT", "A copy of this book is available, borrow it instead", nil)
+			}
+		}
+
+		var existing int64
+		if err := tx.Model(&entities.Hold{}).
+			Where("book_id = ? AND user_id = ? AND status IN ?", hold.BookID, hold.UserID, []string{entities.HoldWaiting, entities.HoldReady}).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return errors.NewAppError("CONFLICT", "You already have a hold on this book", nil)
+		}
+		var borrowed int64
+		if err := tx.Model(&entities.Loan{}).
+			Where("book_id = ? AND user_id = ? AND returned_at IS NULL", hold.BookID, hold.UserID).
+			Count(&borrowed).Error; err != nil {
+			return err
+		}
+		if borrowed > 0 {
+			return errors.NewAppError("CONFLICT", "You already borrowed this book", nil)
+		}
+
+		hold.Status = entities.HoldWaiting
+		return tx.Create(hold).Error
+	})
+	return transactionError(err)
+}
+
+// withPosition selects the holds with the place of the waiting ones in the
+// queue of their book
+func (r *lendingRepository) withPosition(db *gorm.DB) *gorm.DB {
+	return db.Select("holds.*, CASE WHEN holds.status = ? THEN (?) ELSE 0 END AS position",
+		entities.HoldWaiting,
+		r.db.Table("holds AS queued").
+			Select("COUNT(*)").
+			Where("queued.book_id = holds.book_id AND queued.status = ? AND queued.id <= holds.id", entities.HoldWaiting))
+}
+
+func (r *lendingRepository) FindHold(ctx context.Context, id uint) (*entities.Hold, error) {
+	var hold entities.Hold
+	if err := r.withPosition(r.db.WithContext(ctx)).Where("id = ?", id).First(&hold).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewNotFoundError("hold")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &hold, nil
+}
+
+// ListHolds returns the waiting and ready holds matching filter in the
+// order they were placed
+func (r *lendingRepository) ListHolds(ctx context.Context, filter HoldFilter) ([]*entities.Hold, error) {
+	db := r.withPosition(r.db.WithContext(ctx)).
+		Where("status IN ?", []string{entities.HoldWaiting, entities.HoldReady})
+	if filter.UserID != "" {
+		db = db.Where("user_id = ?", filter.UserID)
+	}
+	if filter.BookID != "" {
+		db = db.Where("book_id = ?", filter.BookID)
+	}
+
+	var holds []*entities.Hold
+	if err := db.Order("id").Find(&holds).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return holds, nil
+}
+
+func (r *lendingRepository) CountWaitingHolds(ctx context.Context, bookID string) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).
+		Model(&entities.Hold{}).
+		Where("book_id = ? AND status = ?", bookID, entities.HoldWaiting).
+		Count(&count).Error; err != nil {
+		return 0, errors.NewInternalServerError(err.Error())
+	}
+	return count, nil
+}
+
+// CancelHold withdraws a waiting or ready hold. The copy kept for a ready
+// hold goes to the next waiting hold, which is returned.
+func (r *lendingRepository) CancelHold(ctx context.Context, id uint, at, readyUntil time.Time) (*entities.Hold, error) {
+	var next *entities.Hold
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		next, err = endHold(tx, id, entities.HoldCancelled, at, readyUntil)
+		return err
+	})
+	return next, transactionError(err)
+}
+
+// ExpireHolds ends the ready holds not picked up by now, handing their
+// copies on. It returns the expired holds and the holds that became ready.
+func (r *lendingRepository) ExpireHolds(ctx context.Context, now, readyUntil time.Time) ([]*entities.Hold, []*entities.Hold, error) {
+	var due []*entities.Hold
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND expires_at < ?", entities.HoldReady, now).
+		Order("expires_at").
+		Find(&due).Error; err != nil {
+		return nil, nil, errors.NewInternalServerError(err.Error())
+	}
+
+	var expired, ready []*entities.Hold
+	for _, hold := range due {
+		var next *entities.Hold
+		err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			next, err = endHold(tx, hold.ID, entities.HoldExpired, now, readyUntil)
+			return err
+		})
+		if err != nil {
+			// Picked up or cancelled meanwhile
+			if _, ok := err.(*errors.AppError); ok {
+				continue
+			}
+			return expired, ready, transactionError(err)
+		}
+		hold.Status = entities.HoldExpired
+		expired = append(expired, hold)
+		if next != nil {
+			ready = append(ready, next)
+		}
+	}
+	return expired, ready, nil
+}
+
+// endHold moves an active hold to status, handing the copy kept for it to
+// the next waiting hold, which is returned
+func endHold(tx *gorm.DB, id uint, status string, at, readyUntil time.Time) (*entities.Hold, error) {
+	var hold entities.Hold
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&hold).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewNotFoundError("hold")
+		}
+		return nil, err
+	}
+	wasReady := hold.Status == entities.HoldReady
+	switch {
+	case hold.Status == entities.HoldWaiting && status == entities.HoldCancelled:
+	case wasReady && (status == entities.HoldCancelled || hold.ExpiresAt != nil && hold.ExpiresAt.Before(at)):
+	default:
+		return nil, errors.NewAppError("CONFLICT", "This hold is no longer active", nil)
+	}
+
+	if err := tx.Model(&hold).Update("status", status).Error; err != nil {
+		return nil, err
+	}
+	if !wasReady || hold.CopyID == nil {
+		return nil, nil
+	}
+
+	var bookCopy entities.BookCopy
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", *hold.CopyID).First(&bookCopy).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return passOn(tx, &bookCopy, at, readyUntil)
+}
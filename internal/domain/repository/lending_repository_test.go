@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/errors"
+)
+
+var (
+	lendingNow = time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	readyUntil = lendingNow.Add(72 * time.Hour)
+
+	copyColumns = []string{"id", "book_id", "label", "status", "created_at", "updated_at"}
+	loanColumns = []string{"id", "copy_id", "book_id", "user_id", "checked_out_at", "due_at", "returned_at", "renewals", "created_at", "updated_at"}
+	holdColumns = []string{"id", "book_id", "user_id", "status", "copy_id", "ready_at", "expires_at", "created_at", "updated_at"}
+)
+
+func copyRow(id int64, status string) []driver.Value {
+	return []driver.Value{id, "book-1", "", status, lendingNow, lendingNow}
+}
+
+func loanRow(id, copyID int64, userID string) []driver.Value {
+	return []driver.Value{id, copyID, "book-1", userID, lendingNow, lendingNow.Add(time.Hour), nil, int64(0), lendingNow, lendingNow}
+}
+
+func holdRow(id int64, userID, status string, copyID interface{}, expiresAt interface{}) []driver.Value {
+	return []driver.Value{id, "book-1", userID, status, copyID, nil, expiresAt, lendingNow, lendingNow}
+}
+
+func appErrorCode(err error) string {
+	if appErr, ok := err.(*errors.AppError); ok {
+		return appErr.Code
+	}
+	return ""
+}
+
+// expectBorrower scripts the start of a checkout by a user without an active
+// loan of book-1
+func expectBorrower(db *fakeDB, userID string) {
+	db.expect("SELECT id FROM users WHERE id = ? FOR UPDATE").withArgs(userID).returns([]string{"id"}, []driver.Value{userID})
+	db.expect("SELECT count(*) FROM loans", "returned_at IS NULL").returns([]string{"count(*)"}, []driver.Value{int64(0)})
+}
+
+func TestCheckoutLendsTheLastCopyOnce(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewLendingRepository(db.database())
+	ctx := context.Background()
+
+	expectBorrower(db, "user-1")
+	db.expect("SELECT * FROM holds", "FOR UPDATE").withArgs("book-1", "user-1", entities.HoldReady, 1)
+	db.expect("SELECT * FROM book_copies", "FOR UPDATE SKIP LOCKED").
+		withArgs("book-1", entities.CopyAvailable, 1).
+		returns(copyColumns, copyRow(1, entities.CopyAvailable))
+	db.expect("UPDATE book_copies SET status=?", "WHERE status = ? AND id = ?").affects(1)
+	db.expect("INSERT INTO loans").inserts(10)
+
+	first := &entities.Loan{BookID: "book-1", UserID: "user-1", CheckedOutAt: lendingNow, DueAt: lendingNow}
+	if err := r.Checkout(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != 10 || first.CopyID != 1 {
+		t.Errorf("loan = %+v, want loan 10 of copy 1", first)
+	}
+
+	// The copy taken, the next borrower finds none
+	expectBorrower(db, "user-2")
+	db.expect("SELECT * FROM holds", "FOR UPDATE")
+	db.expect("SELECT * FROM book_copies", "FOR UPDATE SKIP LOCKED")
+	second := &entities.Loan{BookID: "book-1", UserID: "user-2", CheckedOutAt: lendingNow, DueAt: lendingNow}
+	if err := r.Checkout(ctx, second); appErrorCode(err) != "CONFLICT" {
+		t.Errorf("second checkout = %v, want CONFLICT", err)
+	}
+
+	// A borrower that read the copy before it was claimed loses the update
+	expectBorrower(db, "user-3")
+	db.expect("SELECT * FROM holds", "FOR UPDATE")
+	db.expect("SELECT * FROM book_copies", "FOR UPDATE SKIP LOCKED").returns(copyColumns, copyRow(1, entities.CopyAvailable))
+	db.expect("UPDATE book_copies SET status=?", "WHERE status = ? AND id = ?").affects(0)
+	third := &entities.Loan{BookID: "book-1", UserID: "user-3", CheckedOutAt: lendingNow, DueAt: lendingNow}
+	if err := r.Checkout(ctx, third); appErrorCode(err) != "CONFLICT" {
+		t.Errorf("third checkout = %v, want CONFLICT", err)
+	}
+
+	if commits, rollbacks := len(db.statements("COMMIT")), len(db.statements("ROLLBACK")); commits != 1 || rollbacks != 2 {
+		t.Errorf("%d commits and %d rollbacks, want the first checkout alone saved", commits, rollbacks)
+	}
+}
+
+func TestCheckoutRefusesASecondLoanOfTheBorrower(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewLendingRepository(db.database())
+
+	db.expect("SELECT id FROM users WHERE id = ? FOR UPDATE").returns([]string{"id"}, []driver.Value{"user-1"})
+	db.expect("SELECT count(*) FROM loans").withArgs("book-1", "user-1").returns([]string{"count(*)"}, []driver.Value{int64(1)})
+	loan := &entities.Loan{BookID: "book-1", UserID: "user-1", CheckedOutAt: lendingNow, DueAt: lendingNow}
+	if err := r.Checkout(context.Background(), loan); appErrorCode(err) != "CONFLICT" {
+		t.Errorf("checkout = %v, want CONFLICT", err)
+	}
+}
+
+func TestCheckoutTakesTheCopyOfAReadyHoldFirst(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewLendingRepository(db.database())
+	ctx := context.Background()
+
+	// The copy kept for the hold is lent even though another one is asked for
+	expectBorrower(db, "user-1")
+	db.expect("SELECT * FROM holds", "FOR UPDATE").returns(holdColumns, holdRow(5, "user-1", entities.HoldReady, int64(2), readyUntil))
+	db.expect("UPDATE book_copies SET status=?", "WHERE id = ? AND status = ?").affects(1)
+	db.expect("UPDATE holds SET status=?").affects(1)
+	db.expect("INSERT INTO loans").inserts(10)
+	loan := &entities.Loan{CopyID: 3, BookID: "book-1", UserID: "user-1", CheckedOutAt: lendingNow, DueAt: lendingNow}
+	if err := r.Checkout(ctx, loan); err != nil {
+		t.Fatal(err)
+	}
+	if loan.CopyID != 2 {
+		t.Errorf("lent copy %d, want the copy 2 kept for the hold", loan.CopyID)
+	}
+	if fulfilled := db.statements("UPDATE holds"); len(fulfilled) != 1 {
+		t.Errorf("hold updates = %v, want it fulfilled", fulfilled)
+	}
+
+	// A kept copy that's gone isn't replaced by a free one
+	expectBorrower(db, "user-1")
+	db.expect("SELECT * FROM holds", "FOR UPDATE").returns(holdColumns, holdRow(5, "user-1", entities.HoldReady, int64(2), readyUntil))
+	db.expect("UPDATE book_copies SET status=?", "WHERE id = ? AND status = ?").affects(0)
+	loan = &entities.Loan{BookID: "book-1", UserID: "user-1", CheckedOutAt: lendingNow, DueAt: lendingNow}
+	if err := r.Checkout(ctx, loan); appErrorCode(err) != "CONFLICT" {
+		t.Errorf("checkout = %v, want CONFLICT", err)
+	}
+}
+
+// expectPassOn scripts a copy going to hold 6 of user-3, the first waiting
+func expectPassOn(db *fakeDB) {
+	db.expect("SELECT * FROM holds WHERE book_id = ? AND status = ? ORDER BY id", "FOR UPDATE").
+		withArgs("book-1", entities.HoldWaiting, 1).
+		returns(holdColumns, holdRow(6, "user-3", entities.HoldWaiting, nil, nil))
+	db.expect("UPDATE holds SET copy_id=?,expires_at=?,ready_at=?,status=?").affects(1)
+	db.expect("UPDATE book_copies SET status=?").affects(1)
+}
+
+func checkPassedOn(t *testing.T, hold *entities.Hold, copyID uint) {
+	t.Helper()
+	if hold == nil || hold.ID != 6 || hold.Status != entities.HoldReady || hold.CopyID == nil || *hold.CopyID != copyID ||
+		hold.ExpiresAt == nil || !hold.ExpiresAt.Equal(readyUntil) {
+		t.Errorf("next hold = %+v, want hold 6 ready with copy %d until %s", hold, copyID, readyUntil)
+	}
+}
+
+func TestReturnedCopiesGoToTheFirstWaitingHold(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewLendingRepository(db.database())
+	ctx := context.Background()
+
+	db.expect("SELECT * FROM loans WHERE id = ?", "FOR UPDATE").returns(loanColumns, loanRow(10, 1, "user-1"))
+	db.expect("UPDATE loans SET returned_at=?").affects(1)
+	db.expect("SELECT * FROM book_copies WHERE id = ?", "FOR UPDATE").returns(copyColumns, copyRow(1, entities.CopyOnLoan))
+	expectPassOn(db)
+	loan, hold, err := r.ReturnLoan(ctx, 10, lendingNow, readyUntil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loan.ReturnedAt == nil || !loan.ReturnedAt.Equal(lendingNow) {
+		t.Errorf("returned loan = %+v", loan)
+	}
+	checkPassedOn(t, hold, 1)
+	if kept := db.statements("UPDATE book_copies"); len(kept) != 1 {
+		t.Errorf("copy updates = %v, want it kept for the hold", kept)
+	}
+
+	// Nobody waiting, the copy is available again
+	db.expect("SELECT * FROM loans WHERE id = ?", "FOR UPDATE").returns(loanColumns, loanRow(11, 2, "user-1"))
+	db.expect("UPDATE loans SET returned_at=?").affects(1)
+	db.expect("SELECT * FROM book_copies WHERE id = ?", "FOR UPDATE").returns(copyColumns, copyRow(2, entities.CopyOnLoan))
+	db.expect("SELECT * FROM holds WHERE book_id = ? AND status = ? ORDER BY id", "FOR UPDATE")
+	db.expect("UPDATE book_copies SET status=?").withArgs(entities.CopyAvailable, anyArg, 2).affects(1)
+	if _, hold, err = r.ReturnLoan(ctx, 11, lendingNow, readyUntil); err != nil || hold != nil {
+		t.Errorf("return = %v, %v, want the copy back in the library", hold, err)
+	}
+
+	// A loan returned twice is a conflict
+	returned := loanRow(10, 1, "user-1")
+	returned[6] = lendingNow
+	db.expect("SELECT * FROM loans WHERE id = ?", "FOR UPDATE").returns(loanColumns, returned)
+	if _, _, err := r.ReturnLoan(ctx, 10, lendingNow, readyUntil); appErrorCode(err) != "CONFLICT" {
+		t.Errorf("second return = %v, want CONFLICT", err)
+	}
+}
+
+func TestCancelledHoldsPassTheirCopyOn(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewLendingRepository(db.database())
+	ctx := context.Background()
+
+	db.expect("SELECT * FROM holds WHERE id = ?", "FOR UPDATE").returns(holdColumns, holdRow(5, "user-2", entities.HoldReady, int64(1), readyUntil))
+	db.expect("UPDATE holds SET status=?").withArgs(entities.HoldCancelled, anyArg, 5).affects(1)
+	db.expect("SELECT * FROM book_copies WHERE id = ?", "FOR UPDATE").returns(copyColumns, copyRow(1, entities.CopyOnHold))
+	expectPassOn(db)
+	next, err := r.CancelHold(ctx, 5, lendingNow, readyUntil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPassedOn(t, next, 1)
+
+	// A waiting hold had no copy to pass on
+	db.expect("SELECT * FROM holds WHERE id = ?", "FOR UPDATE").returns(holdColumns, holdRow(6, "user-3", entities.HoldWaiting, nil, nil))
+	db.expect("UPDATE holds SET status=?").affects(1)
+	if next, err := r.CancelHold(ctx, 6, lendingNow, readyUntil); err != nil || next != nil {
+		t.Errorf("cancel = %v, %v, want no hold made ready", next, err)
+	}
+
+	db.expect("SELECT * FROM holds WHERE id = ?", "FOR UPDATE").returns(holdColumns, holdRow(7, "user-3", entities.HoldFulfilled, int64(1), nil))
+	if _, err := r.CancelHold(ctx, 7, lendingNow, readyUntil); appErrorCode(err) != "CONFLICT" {
+		t.Errorf("cancel of a fulfilled hold = %v, want CONFLICT", err)
+	}
+}
+
+func TestExpiredHoldsPassTheirCopyOn(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewLendingRepository(db.database())
+	expired := lendingNow.Add(-time.Hour)
+
+	db.expect("SELECT * FROM holds WHERE status = ? AND expires_at < ? ORDER BY expires_at").
+		withArgs(entities.HoldReady, lendingNow).
+		returns(holdColumns,
+			holdRow(5, "user-2", entities.HoldReady, int64(1), expired),
+			holdRow(8, "user-4", entities.HoldReady, int64(4), expired))
+	db.expect("SELECT * FROM holds WHERE id = ?", "FOR UPDATE").returns(holdColumns, holdRow(5, "user-2", entities.HoldReady, int64(1), expired))
+	db.expect("UPDATE holds SET status=?").withArgs(entities.HoldExpired, anyArg, 5).affects(1)
+	db.expect("SELECT * FROM book_copies WHERE id = ?", "FOR UPDATE").returns(copyColumns, copyRow(1, entities.CopyOnHold))
+	expectPassOn(db)
+	// Hold 8 was picked up before the sweep got to it
+	db.expect("SELECT * FROM holds WHERE id = ?", "FOR UPDATE").returns(holdColumns, holdRow(8, "user-4", entities.HoldFulfilled, int64(4), expired))
+
+	expiredHolds, ready, err := r.ExpireHolds(context.Background(), lendingNow, readyUntil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expiredHolds) != 1 || expiredHolds[0].ID != 5 || expiredHolds[0].Status != entities.HoldExpired {
+		t.Errorf("expired = %+v, want hold 5 alone", expiredHolds)
+	}
+	if len(ready) != 1 {
+		t.Fatalf("%d holds made ready, want 1", len(ready))
+	}
+	checkPassedOn(t, ready[0], 1)
+}
+
+func TestRenewLoanIsRefusedWhileHoldsWait(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewLendingRepository(db.database())
+	dueAt := lendingNow.Add(14 * 24 * time.Hour)
+
+	renewal := []string{
+		"UPDATE loans SET due_at=?",
+		"WHERE (id = ? AND returned_at IS NULL AND renewals < ?)",
+		"AND NOT EXISTS (SELECT 1 FROM holds WHERE holds.book_id = loans.book_id AND holds.status = ?)",
+	}
+	db.expect(renewal...).affects(0)
+	if err := r.RenewLoan(context.Background(), 10, dueAt, 2); appErrorCode(err) != "CONFLICT" {
+		t.Errorf("renewal = %v, want CONFLICT", err)
+	}
+
+	db.expect(renewal...).affects(1)
+	if err := r.RenewLoan(context.Background(), 10, dueAt, 2); err != nil {
+		t.Errorf("renewal = %v", err)
+	}
+	if renewed := db.statements("renewals=renewals + 1"); len(renewed) != 2 {
+		t.Errorf("renewals = %v", renewed)
+	}
+}
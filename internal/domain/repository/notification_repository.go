@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/errors"
+
+	"clean-arch-go/internal/pkg/database"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *entities.Notification) error
+	ListByUserID(ctx context.Context, userID string, unreadOnly bool, page, limit int) ([]*entities.Notification, int64, error)
+	MarkRead(ctx context.Context, userID string, id uint, at time.Time) error
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *database.Database) NotificationRepository {
+	return &notificationRepository{db: db.DB}
+}
+
+func (r *notificationRepository) Create(ctx context.Context, notification *entities.Notification) error {
+	if err := r.db.WithContext(ctx).Create(notification).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// ListByUserID returns a page of the notifications of a user, newest first,
+// with their number
+func (r *notificationRepository) ListByUserID(ctx context.Context, userID string, unreadOnly bool, page, limit int) ([]*entities.Notification, int64, error) {
+	matching := func() *gorm.DB {
+		db := r.db.WithContext(ctx).Model(&entities.Notification{}).Where("user_id = ?", userID)
+		if unreadOnly {
+			db = db.Where("read_at IS NULL")
+		}
+		return db
+	}
+
+	var total int64
+	if err := matching().Count(&total).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+
+	var notifications []*entities.Notification
+	if err := matching().
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&notifications).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+	return notifications, total, nil
+}
+
+// MarkRead marks a notification of a user as read, keeping the time it was
+// first read at
+func (r *notificationRepository) MarkRead(ctx context.Context, userID string, id uint, at time.Time) error {
+	var notification entities.Notification
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&notification).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return errors.NewNotFoundError("notification")
+		}
+		return errors.NewInternalServerError(err.Error())
+	}
+	if notification.ReadAt != nil {
+		return nil
+	}
+	if err := r.db.WithContext(ctx).Model(&notification).Update("read_at", at).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
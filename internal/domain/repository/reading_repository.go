@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/errors"
+
+	"clean-arch-go/internal/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MonthlyReading counts the books a user finished in a month and their pages
+type MonthlyReading struct {
+	Month int   `json:"month"`
+	Books int64 `json:"books"`
+	Pages int64 `json:"pages"`
+}
+
+type ReadingRepository interface {
+	FindProgress(ctx context.Context, userID, bookID string) (*entities.ReadingProgress, error)
+	SaveProgress(ctx context.Context, progress *entities.ReadingProgress) error
+	DeleteProgress(ctx context.Context, userID, bookID string) error
+	ListSessions(ctx context.Context, userID, bookID string) ([]*entities.ReadingSession, error)
+	CreateSession(ctx context.Context, session *entities.ReadingSession, progress *entities.ReadingProgress) error
+	FinishedByMonth(ctx context.Context, userID string, from, to time.Time) ([]MonthlyReading, error)
+}
+
+type readingRepository struct {
+	db *gorm.DB
+}
+
+func NewReadingRepository(db *database.Database) ReadingRepository {
+	return &readingRepository{db: db.DB}
+}
+
+// FindProgress returns the progress of a user in a book, nil if there's none
+func (r *readingRepository) FindProgress(ctx context.Context, userID, bookID string) (*entities.ReadingProgress, error) {
+	var progress entities.ReadingProgress
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND book_id = ?", userID, bookID).
+		First(&progress).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &progress, nil
+}
+
+// SaveProgress creates the progress or replaces the existing one of the same user and book
+func (r *readingRepository) SaveProgress(ctx context.Context, progress *entities.ReadingProgress) error {
+	if err := saveProgress(r.db.WithContext(ctx), progress); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+func saveProgress(db *gorm.DB, progress *entities.ReadingProgress) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "book_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "current_page", "percent", "started_at", "finished_at", "updated_at"}),
+	}).Create(progress).Error
+}
+
+// DeleteProgress removes the progress of a user in a book with its sessions
+func (r *readingRepository) DeleteProgress(ctx context.Context, userID, bookID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND book_id = ?", userID, bookID).Delete(&entities.ReadingSession{}).Error; err != nil {
+			return errors.NewInternalServerError(err.Error())
+		}
+		if err := tx.Where("user_id = ? AND book_id = ?", userID, bookID).Delete(&entities.ReadingProgress{}).Error; err != nil {
+			return errors.NewInternalServerError(err.Error())
+		}
+		return nil
+	})
+}
+
+// ListSessions returns the reading sessions of a user in a book, latest first
+func (r *readingRepository) ListSessions(ctx context.Context, userID, bookID string) ([]*entities.ReadingSession, error) {
+	var sessions []*entities.ReadingSession
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND book_id = ?", userID, bookID).
+		Order("started_at DESC, id DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return sessions, nil
+}
+
+// CreateSession records a reading session along with the progress it leads to
+func (r *readingRepository) CreateSession(ctx context.Context, session *entities.ReadingSession, progress *entities.ReadingProgress) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		return saveProgress(tx, progress)
+	})
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// FinishedByMonth counts the books a user finished between from and to by
+// month, with their pages, leaving out the months without any
+func (r *readingRepository) FinishedByMonth(ctx context.Context, userID string, from, to time.Time) ([]MonthlyReading, error) {
+	var months []MonthlyReading
+	if err := r.db.WithContext(ctx).
+		Model(&entities.ReadingProgress{}).
+		Select("MONTH(reading_progress.finished_at) AS month, COUNT(*) AS books, COALESCE(SUM(books.page_count), 0) AS pages").
+		Joins("LEFT JOIN books ON books.id = reading_progress.book_id").
+		Where("reading_progress.user_id = ? AND reading_progress.status = ?", userID, entities.ReadingFinished).
+		Where("reading_progress.finished_at >= ? AND reading_progress.finished_at < ?", from, to).
+		Group("month").
+		Order("month").
+		Scan(&months).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return months, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/errors"
+
+	"clean-arch-go/internal/pkg/database"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+const (
+	// errDuplicateEntry is returned by MySQL when a row breaks a unique index
+	errDuplicateEntry = 1062
+	// reviewBookUserIndex keeps users from reviewing a book twice
+	reviewBookUserIndex = "idx_review_book_user"
+)
+
+// ReviewFilter selects reviews by their moderation state, ignoring nil criteria
+type ReviewFilter struct {
+	BookID  string
+	Hidden  *bool
+	Flagged *bool
+}
+
+type ReviewRepository interface {
+	BaseRepository[entities.Review]
+	FindByBookAndUser(ctx context.Context, bookID, userID string) (*entities.Review, error)
+	List(ctx context.Context, filter ReviewFilter, page, limit int) ([]*entities.Review, int64, error)
+}
+
+type reviewRepository struct {
+	*baseRepository[entities.Review]
+}
+
+func NewReviewRepository(db *database.Database) ReviewRepository {
+	return &reviewRepository{
+		baseRepository: NewBaseRepository[entities.Review](db.DB).(*baseRepository[entities.Review]),
+	}
+}
+
+func (r *reviewRepository) FindByID(ctx context.Context, id string) (*entities.Review, error) {
+	var review entities.Review
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&review).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewNotFoundError("review")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &review, nil
+}
+
+// Create saves a review, a CONFLICT error being returned when its author
+// already reviewed the book
+func (r *reviewRepository) Create(ctx context.Context, review *entities.Review) error {
+	if err := r.db.WithContext(ctx).Create(review).Error; err != nil {
+		if isDuplicateEntry(err, reviewBookUserIndex) {
+			return errors.NewAppError("CONFLICT", "You already reviewed this book", nil)
+		}
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
+
+// FindByBookAndUser returns the review of a user on a book, nil if there's none
+func (r *reviewRepository) FindByBookAndUser(ctx context.Context, bookID, userID string) (*entities.Review, error) {
+	var review entities.Review
+	if err := r.db.WithContext(ctx).Where("book_id = ? AND user_id = ?", bookID, userID).First(&review).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &review, nil
+}
+
+// List returns a page of the reviews matching filter, newest first, with the
+// number of matching reviews
+func (r *reviewRepository) List(ctx context.Context, filter ReviewFilter, page, limit int) ([]*entities.Review, int64, error) {
+	matching := func() *gorm.DB {
+		db := r.db.WithContext(ctx).Model(&entities.Review{})
+		if filter.BookID != "" {
+			db = db.Where("book_id = ?", filter.BookID)
+		}
+		if filter.Hidden != nil {
+			db = db.Where("hidden = ?", *filter.Hidden)
+		}
+		if filter.Flagged != nil {
+			db = db.Where("flagged = ?", *filter.Flagged)
+		}
+		return db
+	}
+
+	var total int64
+	if err := matching().Count(&total).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+
+	var reviews []*entities.Review
+	if err := matching().
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&reviews).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+	return reviews, total, nil
+}
+
+// isDuplicateEntry tells whether err is MySQL rejecting a row that breaks
+// the unique index
+func isDuplicateEntry(err error, index string) bool {
+	var mysqlErr *mysql.MySQLError
+	if !stderrors.As(err, &mysqlErr) || mysqlErr.Number != errDuplicateEntry {
+		return false
+	}
+	// MySQL 8 prefixes the index with its table, as in 'reviews.idx_review_book_user'
+	return strings.HasSuffix(mysqlErr.Message, "'"+index+"'") || strings.HasSuffix(mysqlErr.Message, "."+index+"'")
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestIsDuplicateEntry(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'book-1-user-1' for key 'reviews.idx_review_book_user'"}, true},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'book-1-user-1' for key 'idx_review_book_user'"}, true},
+		{fmt.Errorf("create: %w", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'book-1-user-1' for key 'idx_review_book_user'"}), true},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'reviews.PRIMARY'"}, false},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'x' for key 'reviews.idx_review_book_user_2'"}, false},
+		{&mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: idx_review_book_user'"}, false},
+		{fmt.Errorf("Duplicate entry for key 'idx_review_book_user'"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := isDuplicateEntry(tt.err, reviewBookUserIndex); got != tt.want {
+			t.Errorf("isDuplicateEntry(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/errors"
+
+	"clean-arch-go/internal/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ShelfRepository interface {
+	BaseRepository[entities.Shelf]
+	ListByUserID(ctx context.Context, userID string) ([]*entities.Shelf, error)
+	FindByName(ctx context.Context, userID, name string) (*entities.Shelf, error)
+	ListBooks(ctx context.Context, shelfID uint, page, limit int) ([]*entities.Book, int64, error)
+	ListBookIDs(ctx context.Context, shelfID uint) ([]string, error)
+	PlaceBook(ctx context.Context, shelfID uint, bookID string, position int) error
+	RemoveBook(ctx context.Context, shelfID uint, bookID string) error
+	ReorderBooks(ctx context.Context, shelfID uint, bookIDs []string) error
+}
+
+type shelfRepository struct {
+	*baseRepository[entities.Shelf]
+}
+
+func NewShelfRepository(db *database.Database) ShelfRepository {
+	return &shelfRepository{
+		baseRepository: NewBaseRepository[entities.Shelf](db.DB).(*baseRepository[entities.Shelf]),
+	}
+}
+
+// withBookCount selects the shelves with the number of their books
+func (r *shelfRepository) withBookCount(db *gorm.DB) *gorm.DB {
+	return db.Select("shelves.*, (?) AS book_count", r.db.Table("shelf_books").
+		Select("COUNT(*)").
+		Joins("JOIN books ON books.id = shelf_books.book_id AND books.deleted_at IS NULL").
+		Where("shelf_books.shelf_id = shelves.id"))
+}
+
+func (r *shelfRepository) FindByID(ctx context.Context, id string) (*entities.Shelf, error) {
+	var shelf entities.Shelf
+	if err := r.withBookCount(r.db.WithContext(ctx)).Where("id = ?", id).First(&shelf).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.NewNotFoundError("shelf")
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &shelf, nil
+}
+
+// FindByName returns the shelf of the user with a name, nil if there's none
+func (r *shelfRepository) FindByName(ctx context.Context, userID, name string) (*entities.Shelf, error) {
+	var shelf entities.Shelf
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND name = ?", userID, name).First(&shelf).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &shelf, nil
+}
+
+// Delete removes the books from the shelf and deletes it
+func (r *shelfRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("shelf_id = ?", id).Delete(&entities.ShelfBook{}).Error; err != nil {
+			return errors.NewInternalServerError(err.Error())
+		}
+		if err := tx.Where("id = ?", id).Delete(&entities.Shelf{}).Error; err != nil {
+			return errors.NewInternalServerError(err.Error())
+		}
+		return nil
+	})
+}
+
+// ListByUserID returns the shelves of a user in their order
+func (r *shelfRepository) ListByUserID(ctx context.Context, userID string) ([]*entities.Shelf, error) {
+	var shelves []*entities.Shelf
+	if err := r.withBookCount(r.db.WithContext(ctx)).
+		Where("user_id = ?", userID).
+		Order("position, name").
+		Find(&shelves).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return shelves, nil
+}
+
+// ListBooks returns a page of the books on a shelf in the shelf order, with
+// the number of books on the shelf
+func (r *shelfRepository) ListBooks(ctx context.Context, shelfID uint, page, limit int) ([]*entities.Book, int64, error) {
+	shelved := func() *gorm.DB {
+		return r.db.WithContext(ctx).
+			Model(&entities.Book{}).
+			Joins("JOIN shelf_books ON shelf_books.book_id = books.id").
+			Where("shelf_books.shelf_id = ?", shelfID)
+	}
+
+	var total int64
+	if err := shelved().Count(&total).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+
+	var books []*entities.Book
+	if err := shelved().
+		Select("books.*").
+		Preload("Tags").
+		Order("shelf_books.position, shelf_books.added_at").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&books).Error; err != nil {
+		return nil, 0, errors.NewInternalServerError(err.Error())
+	}
+	return books, total, nil
+}
+
+// ListBookIDs returns the IDs of the books on a shelf, deleted ones included
+func (r *shelfRepository) ListBookIDs(ctx context.Context, shelfID uint) ([]string, error) {
+	ids := []string{}
+	if err := r.db.WithContext(ctx).
+		Model(&entities.ShelfBook{}).
+		Where("shelf_id = ?", shelfID).
+		Order("position").
+		Pluck("book_id", &ids).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return ids, nil
+}
+
+// PlaceBook puts a book on a shelf at a position, or moves it there when it's
+// already on the shelf. Positions past the end append the book.
+func (r *shelfRepository) PlaceBook(ctx context.Context, shelfID uint, bookID string, position int) error {
+	return r.reorder(ctx, shelfID, func(ids []string) []string {
+		for i, id := range ids {
+			if id == bookID {
+				ids = append(ids[:i], ids[i+1:]...)
+				break
+			}
+		}
+		if position < 0 || position > len(ids) {
+			position = len(ids)
+		}
+		ids = append(ids, "")
+		copy(ids[position+1:], ids[position:])
+		ids[position] = bookID
+		return ids
+	})
+}
+
+// RemoveBook takes a book off a shelf
+func (r *shelfRepository) RemoveBook(ctx context.Context, shelfID uint, bookID string) error {
+	return r.reorder(ctx, shelfID, func(ids []string) []string {
+		for i, id := range ids {
+			if id == bookID {
+				return append(ids[:i], ids[i+1:]...)
+			}
+		}
+		return ids
+	})
+}
+
+// ReorderBooks sets the books of a shelf in the given order
+func (r *shelfRepository) ReorderBooks(ctx context.Context, shelfID uint, bookIDs []string) error {
+	return r.reorder(ctx, shelfID, func([]string) []string {
+		return bookIDs
+	})
+}
+
+// reorder replaces the books of a shelf with the result of change applied to
+// the current ones, numbering their positions from 0. The shelf row is
+// locked so that concurrent changes apply one after the other.
+func (r *shelfRepository) reorder(ctx context.Context, shelfID uint, change func(ids []string) []string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var shelf entities.Shelf
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", shelfID).First(&shelf).Error; err != nil {
+			return err
+		}
+
+		var entries []*entities.ShelfBook
+		if err := tx.Where("shelf_id = ?", shelfID).Order("position, added_at").Find(&entries).Error; err != nil {
+			return err
+		}
+		addedAt := make(map[string]time.Time, len(entries))
+		ids := make([]string, len(entries))
+		for i, entry := range entries {
+			addedAt[entry.BookID] = entry.AddedAt
+			ids[i] = entry.BookID
+		}
+
+		if err := tx.Where("shelf_id = ?", shelfID).Delete(&entities.ShelfBook{}).Error; err != nil {
+			return err
+		}
+		ids = change(ids)
+		if len(ids) == 0 {
+			return nil
+		}
+
+		placed := make([]*entities.ShelfBook, len(ids))
+		for i, id := range ids {
+			placed[i] = &entities.ShelfBook{ShelfID: shelfID, BookID: id, Position: i, AddedAt: addedAt[id]}
+		}
+		return tx.Create(placed).Error
+	})
+	if err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+)
+
+var (
+	shelfColumns     = []string{"id", "user_id", "name", "position"}
+	shelfBookColumns = []string{"shelf_id", "book_id", "position", "added_at"}
+)
+
+// expectShelf scripts the locking of shelf 1 and the reading of its books,
+// which are then taken off to be placed again
+func expectShelf(db *fakeDB, bookIDs ...string) {
+	db.expect("SELECT * FROM shelves WHERE id = ?", "FOR UPDATE").withArgs(1, 1).
+		returns(shelfColumns, []driver.Value{int64(1), "user-1", "Favourites", int64(0)})
+	rows := make([][]driver.Value, len(bookIDs))
+	for i, id := range bookIDs {
+		rows[i] = []driver.Value{int64(1), id, int64(i), lendingNow}
+	}
+	db.expect("SELECT * FROM shelf_books WHERE shelf_id = ? ORDER BY position, added_at").returns(shelfBookColumns, rows...)
+	db.expect("DELETE FROM shelf_books WHERE shelf_id = ?").withArgs(1).affects(int64(len(bookIDs)))
+}
+
+// expectPlaced scripts placing the books on shelf 1 in the given order
+func expectPlaced(db *fakeDB, bookIDs ...string) {
+	var args []interface{}
+	for i, id := range bookIDs {
+		args = append(args, 1, id, i, anyArg)
+	}
+	db.expect("INSERT INTO shelf_books (shelf_id,book_id,position,added_at)").withArgs(args...).affects(int64(len(bookIDs)))
+}
+
+func TestPlaceBook(t *testing.T) {
+	tests := []struct {
+		name     string
+		book     string
+		position int
+		want     []string
+	}{
+		{"new book in the middle", "book-4", 1, []string{"book-1", "book-4", "book-2", "book-3"}},
+		{"new book first", "book-4", 0, []string{"book-4", "book-1", "book-2", "book-3"}},
+		{"new book past the end", "book-4", 10, []string{"book-1", "book-2", "book-3", "book-4"}},
+		{"new book without a position", "book-4", -1, []string{"book-1", "book-2", "book-3", "book-4"}},
+		{"moved forward", "book-3", 0, []string{"book-3", "book-1", "book-2"}},
+		{"moved back", "book-1", 2, []string{"book-2", "book-3", "book-1"}},
+		{"moved past the end", "book-1", 5, []string{"book-2", "book-3", "book-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(t)
+			r := NewShelfRepository(db.database())
+
+			expectShelf(db, "book-1", "book-2", "book-3")
+			expectPlaced(db, tt.want...)
+			if err := r.PlaceBook(context.Background(), 1, tt.book, tt.position); err != nil {
+				t.Fatal(err)
+			}
+			if commits := db.statements("COMMIT"); len(commits) != 1 {
+				t.Errorf("placing wasn't committed")
+			}
+		})
+	}
+}
+
+func TestPlaceBookKeepsTheDatesBooksWereAdded(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewShelfRepository(db.database())
+
+	expectShelf(db, "book-1", "book-2")
+	db.expect("INSERT INTO shelf_books").withArgs(1, "book-2", 0, lendingNow, 1, "book-1", 1, lendingNow)
+	if err := r.PlaceBook(context.Background(), 1, "book-2", 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoveBook(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewShelfRepository(db.database())
+
+	expectShelf(db, "book-1", "book-2", "book-3")
+	expectPlaced(db, "book-1", "book-3")
+	if err := r.RemoveBook(context.Background(), 1, "book-2"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoveTheLastBook(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewShelfRepository(db.database())
+
+	expectShelf(db, "book-1")
+	if err := r.RemoveBook(context.Background(), 1, "book-1"); err != nil {
+		t.Fatal(err)
+	}
+	if inserts := db.statements("INSERT"); len(inserts) != 0 {
+		t.Errorf("emptied shelf got %v", inserts)
+	}
+}
+
+func TestReorderBooks(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewShelfRepository(db.database())
+
+	expectShelf(db, "book-1", "book-2", "book-3")
+	expectPlaced(db, "book-3", "book-1", "book-2")
+	if err := r.ReorderBooks(context.Background(), 1, []string{"book-3", "book-1", "book-2"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReorderMissingShelf(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewShelfRepository(db.database())
+
+	db.expect("SELECT * FROM shelves WHERE id = ?", "FOR UPDATE")
+	if err := r.ReorderBooks(context.Background(), 1, []string{"book-1"}); appErrorCode(err) != "INTERNAL_ERROR" {
+		t.Errorf("reorder = %v, want an internal error", err)
+	}
+	if rollbacks := db.statements("ROLLBACK"); len(rollbacks) != 1 {
+		t.Errorf("reorder of a missing shelf wasn't rolled back")
+	}
+}
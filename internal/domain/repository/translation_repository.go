@@ -9,31 +9,49 @@ import (
 	"clean-arch-go/internal/pkg/database"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TranslationRepository interface {
 	BaseRepository[entities.Translation]
-	Translate(ctx context.Context, text string, targetLang string) (string, error)
+	Translate(ctx context.Context, text string, sourceLang string, targetLang string) (string, error)
 	GetTranslation(ctx context.Context, text string, targetLang string) (string, error)
 	SaveTranslation(ctx context.Context, text string, targetLang string, translation string) error
+	ConfiguredLanguages(ctx context.Context) ([]string, error)
+	FindTranslation(ctx context.Context, text string, targetLang string) (*entities.Translation, error)
+	ListTranslations(ctx context.Context, targetLang string) ([]*entities.Translation, error)
+	SaveTranslations(ctx context.Context, translations []*entities.Translation) error
+	ListLocaleMessages(ctx context.Context) ([]*entities.LocaleMessage, error)
+	SaveLocaleMessages(ctx context.Context, messages []*entities.LocaleMessage) error
 }
 
 type translationRepository struct {
 	*baseRepository[entities.Translation]
+	languages []string
 }
 
-func NewTranslationRepository(db *database.Database) TranslationRepository {
+// NewTranslationRepository creates a translation repository that translates
+// into the given configured language codes
+func NewTranslationRepository(db *database.Database, languages []string) TranslationRepository {
 	return &translationRepository{
 		baseRepository: NewBaseRepository[entities.Translation](db.DB).(*baseRepository[entities.Translation]),
+		languages:      languages,
 	}
 }
 
-func (r *translationRepository) Translate(ctx context.Context, text string, targetLang string) (string, error) {
+func (r *translationRepository) Translate(ctx context.Context, text string, sourceLang string, targetLang string) (string, error) {
 	// In a real implementation, this would call an external translation API
 	// For now, we'll just return the text as-is
 	return text, nil
 }
 
+// ConfiguredLanguages returns the target languages configured in
+// TRANSLATION_LANGUAGES; the provider isn't asked, so the list must match the
+// languages it supports
+func (r *translationRepository) ConfiguredLanguages(ctx context.Context) ([]string, error) {
+	return r.languages, nil
+}
+
 func (r *translationRepository) GetTranslation(ctx context.Context, text string, targetLang string) (string, error) {
 	var translation entities.Translation
 	if err := r.baseRepository.db.WithContext(ctx).
@@ -58,3 +76,70 @@ func (r *translationRepository) SaveTranslation(ctx context.Context, text string
 	}
 	return nil
 }
+
+// FindTranslation returns the stored translation of a text, or nil when there is none
+func (r *translationRepository) FindTranslation(ctx context.Context, text string, targetLang string) (*entities.Translation, error) {
+	var translation entities.Translation
+	if err := r.baseRepository.db.WithContext(ctx).
+		Where("source_text = ? AND target_lang = ?", text, targetLang).
+		First(&translation).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return &translation, nil
+}
+
+// ListTranslations returns all stored translations, optionally only those into targetLang
+func (r *translationRepository) ListTranslations(ctx context.Context, targetLang string) ([]*entities.Translation, error) {
+	query := r.baseRepository.db.WithContext(ctx).Order("id")
+	if targetLang != "" {
+		query = query.Where("target_lang = ?", targetLang)
+	}
+
+	var translations []*entities.Translation
+	if err := query.Find(&translations).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return translations, nil
+}
+
+// SaveTranslations creates the new translations and updates the others, all
+// in one transaction
+func (r *translationRepository) SaveTranslations(ctx context.Context, translations []*entities.Translation) error {
+	if len(translations) == 0 {
+		return nil
+	}
+	return r.baseRepository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, translation := range translations {
+			if err := tx.Save(translation).Error; err != nil {
+				return errors.NewInternalServerError(err.Error())
+			}
+		}
+		return nil
+	})
+}
+
+// ListLocaleMessages returns all UI message overrides
+func (r *translationRepository) ListLocaleMessages(ctx context.Context) ([]*entities.LocaleMessage, error) {
+	var messages []*entities.LocaleMessage
+	if err := r.baseRepository.db.WithContext(ctx).Order("lang, message_id").Find(&messages).Error; err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	return messages, nil
+}
+
+// SaveLocaleMessages inserts or updates UI message overrides by language and message ID
+func (r *translationRepository) SaveLocaleMessages(ctx context.Context, messages []*entities.LocaleMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	if err := r.baseRepository.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "lang"}, {Name: "message_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"text", "forms", "updated_at"}),
+	}).Create(messages).Error; err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return nil
+}
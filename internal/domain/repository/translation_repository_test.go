@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"clean-arch-go/internal/domain/entities"
+
+	"gorm.io/gorm"
+)
+
+func TestSaveTranslationsRollsBackOnFailure(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewTranslationRepository(db.database(), nil)
+
+	db.expect("INSERT INTO translations").inserts(7)
+	db.expect("UPDATE translations SET").fails(errors.New("deadlock"))
+	err := r.SaveTranslations(context.Background(), []*entities.Translation{
+		{SourceText: "Book", TargetLang: "fr", TranslatedText: "Livre"},
+		{Model: gorm.Model{ID: 3}, SourceText: "Hello", TargetLang: "fr", TranslatedText: "Bonjour"},
+	})
+	if appErrorCode(err) != "INTERNAL_ERROR" {
+		t.Errorf("SaveTranslations = %v, want the update error", err)
+	}
+	if len(db.statements("ROLLBACK")) != 1 || len(db.statements("COMMIT")) != 0 {
+		t.Errorf("the failed save wasn't rolled back: %v", db.statements("O"))
+	}
+}
+
+func TestSaveTranslationsCommitsOnce(t *testing.T) {
+	db := newFakeDB(t)
+	r := NewTranslationRepository(db.database(), nil)
+
+	db.expect("INSERT INTO translations").inserts(7)
+	db.expect("INSERT INTO translations").inserts(8)
+	if err := r.SaveTranslations(context.Background(), []*entities.Translation{
+		{SourceText: "Book", TargetLang: "fr", TranslatedText: "Livre"},
+		{SourceText: "Hello", TargetLang: "fr", TranslatedText: "Bonjour"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.statements("BEGIN")) != 1 || len(db.statements("COMMIT")) != 1 {
+		t.Errorf("statements %v, want one transaction", db.statements(""))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"clean-arch-go/internal/errors"
 	"clean-arch-go/internal/pkg/redis"
 	"context"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -23,6 +24,7 @@ type AuthService interface {
 	RefreshToken(ctx context.Context, token string) (string, error)
 	RevokeToken(ctx context.Context, token string) error
 	GetUserFromToken(ctx context.Context, token string) (*user.User, error)
+	SetTenant(ctx context.Context, userID, tenantID string) (*user.User, error)
 }
 
 type authService struct {
@@ -223,3 +225,26 @@ func (s *authService) Logout(ctx context.Context, token string) error {
 	sessionKey := "session:" + token
 	return s.redisClient.Del(ctx, sessionKey)
 }
+
+// SetTenant moves a user to a tenant, or out of any when tenantID is empty
+func (s *authService) SetTenant(ctx context.Context, userID, tenantID string) (*user.User, error) {
+	u, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.NewAppError("USER_NOT_FOUND", "User not found", nil)
+	}
+
+	u.TenantID = strings.TrimSpace(tenantID)
+	if len(u.TenantID) > 36 {
+		return nil, errors.NewValidationError("tenant_id", "Tenant ID must be at most 36 characters")
+	}
+	u.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+
+	u.Password = ""
+	return u, nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+)
+
+// BookAccessPolicy decides what users may do with books, from their
+// ownership, the roles granted on them and their visibility
+type BookAccessPolicy interface {
+	// Authorize returns the book when the user has at least the needed access to it
+	Authorize(ctx context.Context, bookID, userID string, need book.Access) (*entities.Book, error)
+	// AccessOf returns the access of the user to a loaded book
+	AccessOf(ctx context.Context, b *entities.Book, userID string) (book.Access, error)
+}
+
+type bookAccessPolicy struct {
+	bookRepo repository.BookRepository
+}
+
+func NewBookAccessPolicy(bookRepo repository.BookRepository) BookAccessPolicy {
+	return &bookAccessPolicy{bookRepo: bookRepo}
+}
+
+func (p *bookAccessPolicy) Authorize(ctx context.Context, bookID, userID string, need book.Access) (*entities.Book, error) {
+	b, err := p.bookRepo.FindByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, errors.NewAppError("NOT_FOUND", "Book not found", nil)
+	}
+
+	access, err := p.AccessOf(ctx, b, userID)
+	if err != nil {
+		return nil, err
+	}
+	if access < need {
+		return nil, errors.NewAppError("UNAUTHORIZED", "You are not authorized to access this book", nil)
+	}
+	return b, nil
+}
+
+func (p *bookAccessPolicy) AccessOf(ctx context.Context, b *entities.Book, userID string) (book.Access, error) {
+	// Owners need no grant lookup
+	if userID == "" || b.UserID == userID {
+		return book.AccessOf(userID, b.UserID, b.Visibility, ""), nil
+	}
+
+	grant, err := p.bookRepo.FindGrant(ctx, b.ID, userID)
+	if err != nil {
+		return book.AccessNone, err
+	}
+	role := ""
+	if grant != nil {
+		role = grant.Role
+	}
+	return book.AccessOf(userID, b.UserID, b.Visibility, role), nil
+}
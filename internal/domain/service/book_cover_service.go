@@ -0,0 +1,194 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+	"clean-arch-go/internal/pkg/imaging"
+	"clean-arch-go/internal/pkg/storage"
+)
+
+// Cover sizes, besides the original image
+const (
+	CoverOriginal = "original"
+	CoverSmall    = "small"
+	CoverMedium   = "medium"
+	CoverLarge    = "large"
+)
+
+// coverSizes bounds the width and height of each cover thumbnail
+var coverSizes = []struct {
+	name          string
+	width, height int
+}{
+	{CoverSmall, 120, 180},
+	{CoverMedium, 300, 450},
+	{CoverLarge, 600, 900},
+}
+
+// coverTypes maps the accepted cover content types to their file extension
+var coverTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// maxCoverPixels bounds the dimensions of uploaded covers
+const maxCoverPixels = 40_000_000
+
+type BookCoverService interface {
+	UploadCover(ctx context.Context, bookID string, r io.Reader) (*entities.Book, error)
+	DeleteCover(ctx context.Context, bookID string) error
+	DeleteCoverFiles(ctx context.Context, book *entities.Book)
+	CoverURLs(book *entities.Book) map[string]string
+}
+
+type bookCoverService struct {
+	bookRepo repository.BookRepository
+	storage  storage.Storage
+	maxBytes int64
+}
+
+// NewBookCoverService creates a service storing covers of at most maxBytes
+func NewBookCoverService(bookRepo repository.BookRepository, storage storage.Storage, maxBytes int64) BookCoverService {
+	return &bookCoverService{
+		bookRepo: bookRepo,
+		storage:  storage,
+		maxBytes: maxBytes,
+	}
+}
+
+// UploadCover validates a JPEG, PNG or GIF image, stores it with its
+// thumbnails and replaces the previous cover of the book
+func (s *bookCoverService) UploadCover(ctx context.Context, bookID string, r io.Reader) (*entities.Book, error) {
+	book, err := s.bookRepo.FindByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if book == nil {
+		return nil, errors.NewAppError("NOT_FOUND", "Book not found", nil)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.maxBytes+1))
+	if err != nil {
+		return nil, errors.NewBadRequestError("Failed to read the cover image")
+	}
+	if int64(len(data)) > s.maxBytes {
+		return nil, errors.NewValidationError("file", fmt.Sprintf("Cover images must be at most %d KB", s.maxBytes/1024))
+	}
+	contentType := http.DetectContentType(data)
+	ext, ok := coverTypes[contentType]
+	if !ok {
+		return nil, errors.NewValidationError("file", "Cover images must be JPEG, PNG or GIF")
+	}
+	img, _, err := imaging.Decode(bytes.NewReader(data), maxCoverPixels)
+	if err == imaging.ErrTooLarge {
+		return nil, errors.NewValidationError("file", "Cover image dimensions are too large")
+	}
+	if err != nil {
+		return nil, errors.NewValidationError("file", "Invalid cover image")
+	}
+
+	// Each upload gets a new directory, so that the cover URLs change with
+	// the image and can be cached for good
+	dir := path.Join("covers", book.ID, strconv.FormatInt(time.Now().UnixNano(), 36))
+	key := path.Join(dir, CoverOriginal+ext)
+	if err := s.storeCover(ctx, key, data, contentType, img); err != nil {
+		s.deleteObjects(ctx, key)
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	previous := book.CoverKey
+	book.CoverKey = key
+	if err := s.bookRepo.Update(ctx, book); err != nil {
+		s.deleteObjects(ctx, key)
+		return nil, err
+	}
+	s.deleteObjects(ctx, previous)
+	return book, nil
+}
+
+// storeCover stores the original image of a cover at key and its
+// thumbnails next to it
+func (s *bookCoverService) storeCover(ctx context.Context, key string, data []byte, contentType string, img image.Image) error {
+	if err := s.storage.Put(ctx, key, data, contentType); err != nil {
+		return err
+	}
+	for _, size := range coverSizes {
+		thumbnail, err := imaging.Thumbnail(img, size.width, size.height)
+		if err != nil {
+			return err
+		}
+		if err := s.storage.Put(ctx, path.Join(path.Dir(key), size.name+".jpg"), thumbnail, "image/jpeg"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteCover removes the cover of a book
+func (s *bookCoverService) DeleteCover(ctx context.Context, bookID string) error {
+	book, err := s.bookRepo.FindByID(ctx, bookID)
+	if err != nil {
+		return err
+	}
+	if book == nil {
+		return errors.NewAppError("NOT_FOUND", "Book not found", nil)
+	}
+	if book.CoverKey == "" {
+		return nil
+	}
+
+	previous := book.CoverKey
+	book.CoverKey = ""
+	if err := s.bookRepo.Update(ctx, book); err != nil {
+		return err
+	}
+	s.deleteObjects(ctx, previous)
+	return nil
+}
+
+// DeleteCoverFiles removes the stored cover images of a book being purged,
+// leaving the book itself alone
+func (s *bookCoverService) DeleteCoverFiles(ctx context.Context, book *entities.Book) {
+	s.deleteObjects(ctx, book.CoverKey)
+}
+
+// CoverURLs returns the URL of the original cover and its thumbnails by
+// size, nil when the book has no cover
+func (s *bookCoverService) CoverURLs(book *entities.Book) map[string]string {
+	if book.CoverKey == "" {
+		return nil
+	}
+	urls := map[string]string{CoverOriginal: s.storage.URL(book.CoverKey)}
+	for _, size := range coverSizes {
+		urls[size.name] = s.storage.URL(path.Join(path.Dir(book.CoverKey), size.name+".jpg"))
+	}
+	return urls
+}
+
+// deleteObjects removes a replaced cover and its thumbnails. Failures only
+// leave unreferenced files behind, so they are logged rather than returned.
+func (s *bookCoverService) deleteObjects(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	keys := []string{key}
+	for _, size := range coverSizes {
+		keys = append(keys, path.Join(path.Dir(key), size.name+".jpg"))
+	}
+	if err := s.storage.Delete(ctx, keys...); err != nil {
+		log.Printf("Failed to delete cover %s: %v", key, err)
+	}
+}
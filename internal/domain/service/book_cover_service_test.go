@@ -0,0 +1,80 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"testing"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/errors"
+)
+
+func (r *fakeBookRepository) Update(ctx context.Context, b *entities.Book) error {
+	if r.books[b.ID].Version != b.Version {
+		return errors.NewAppError("PRECONDITION_FAILED", "The book was changed in the meantime", nil)
+	}
+	b.Version++
+	r.books[b.ID] = b
+	return nil
+}
+
+// changingBookRepository has its books changed by someone else between
+// reading and saving them
+type changingBookRepository struct {
+	*fakeBookRepository
+}
+
+func (r changingBookRepository) FindByID(ctx context.Context, id string) (*entities.Book, error) {
+	b, err := r.fakeBookRepository.FindByID(ctx, id)
+	r.books[id].Version++
+	return b, err
+}
+
+func coverImage(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 60))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadCoverReplacesThePreviousOne(t *testing.T) {
+	books := newFakeBookRepository(&entities.Book{ID: "book-1", Version: 1})
+	files := &memoryStorage{objects: make(map[string][]byte)}
+	s := NewBookCoverService(books, files, 1<<20)
+
+	first, err := s.UploadCover(context.Background(), "book-1", bytes.NewReader(coverImage(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files.objects[first.CoverKey]; !ok || len(files.objects) != 1+len(coverSizes) {
+		t.Fatalf("%d objects stored for %s, want the original and the thumbnails", len(files.objects), first.CoverKey)
+	}
+
+	second, err := s.UploadCover(context.Background(), "book-1", bytes.NewReader(coverImage(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.CoverKey == first.CoverKey || len(files.objects) != 1+len(coverSizes) {
+		t.Errorf("%d objects after replacing the cover, want the new ones alone", len(files.objects))
+	}
+	if _, ok := files.objects[first.CoverKey]; ok {
+		t.Error("the previous cover was kept")
+	}
+}
+
+func TestUploadCoverRemovesTheImagesOfAFailedSave(t *testing.T) {
+	books := newFakeBookRepository(&entities.Book{ID: "book-1", Version: 1})
+	files := &memoryStorage{objects: make(map[string][]byte)}
+	s := NewBookCoverService(changingBookRepository{books}, files, 1<<20)
+
+	if _, err := s.UploadCover(context.Background(), "book-1", bytes.NewReader(coverImage(t))); errorCode(err) != "PRECONDITION_FAILED" {
+		t.Fatalf("upload = %v, want PRECONDITION_FAILED", err)
+	}
+	if len(files.objects) != 0 {
+		t.Errorf("%d objects left by the failed upload", len(files.objects))
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+)
+
+// ListReviews returns a page of the visible reviews of a book the user can
+// see, newest first, with their number
+func (s *bookService) ListReviews(ctx context.Context, bookID, userID string, page, limit int) ([]*entities.Review, int64, error) {
+	if _, err := s.visibleBook(ctx, bookID, userID); err != nil {
+		return nil, 0, err
+	}
+	hidden := false
+	return s.reviewRepo.List(ctx, repository.ReviewFilter{BookID: bookID, Hidden: &hidden}, page, limit)
+}
+
+// GetReviewByUser returns the review of a user on a book, hidden or not
+func (s *bookService) GetReviewByUser(ctx context.Context, bookID, userID string) (*entities.Review, error) {
+	if _, err := s.visibleBook(ctx, bookID, userID); err != nil {
+		return nil, err
+	}
+	review, err := s.reviewRepo.FindByBookAndUser(ctx, bookID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if review == nil {
+		return nil, errors.NewNotFoundError("review")
+	}
+	return review, nil
+}
+
+// CreateReview adds the review of a user on a book they can see, once per book
+func (s *bookService) CreateReview(ctx context.Context, review *entities.Review) error {
+	if _, err := s.visibleBook(ctx, review.BookID, review.UserID); err != nil {
+		return err
+	}
+	if err := validateReview(review); err != nil {
+		return err
+	}
+
+	existing, err := s.reviewRepo.FindByBookAndUser(ctx, review.BookID, review.UserID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errors.NewAppError("CONFLICT", "You already reviewed this book", nil)
+	}
+
+	// The unique index of the reviews catches the concurrent reviews of
+	// the user the check above missed
+	if err := s.reviewRepo.Create(ctx, review); err != nil {
+		return err
+	}
+	return s.bookRepo.RefreshRating(ctx, review.BookID)
+}
+
+// UpdateReview changes the rating, title and body of a review by its author
+func (s *bookService) UpdateReview(ctx context.Context, bookID string, id uint, userID string, review *entities.Review) error {
+	existing, err := s.ownReview(ctx, bookID, id, userID)
+	if err != nil {
+		return err
+	}
+
+	existing.Rating = review.Rating
+	existing.Title = review.Title
+	existing.Body = review.Body
+	if err := validateReview(existing); err != nil {
+		return err
+	}
+
+	if err := s.reviewRepo.Update(ctx, existing); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	if err := s.bookRepo.RefreshRating(ctx, existing.BookID); err != nil {
+		return err
+	}
+	*review = *existing
+	return nil
+}
+
+// DeleteReview removes a review by its author
+func (s *bookService) DeleteReview(ctx context.Context, bookID string, id uint, userID string) error {
+	review, err := s.ownReview(ctx, bookID, id, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.reviewRepo.Delete(ctx, strconv.FormatUint(uint64(id), 10)); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	return s.bookRepo.RefreshRating(ctx, review.BookID)
+}
+
+// ListReviewsForModeration returns a page of the reviews of all books
+// matching filter, newest first, with their number
+func (s *bookService) ListReviewsForModeration(ctx context.Context, filter repository.ReviewFilter, page, limit int) ([]*entities.Review, int64, error) {
+	return s.reviewRepo.List(ctx, filter, page, limit)
+}
+
+// ModerateReview hides, flags or annotates a review on behalf of a moderator
+func (s *bookService) ModerateReview(ctx context.Context, id uint, moderatorID string, moderation ReviewModeration) (*entities.Review, error) {
+	review, err := s.reviewRepo.FindByID(ctx, strconv.FormatUint(uint64(id), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	if moderation.Note != nil {
+		note := strings.TrimSpace(*moderation.Note)
+		if utf8.RuneCountInString(note) > maxModerationNote {
+			return nil, errors.NewValidationError("note", "Note must be at most 500 characters")
+		}
+		review.ModerationNote = note
+	}
+	wasHidden := review.Hidden
+	if moderation.Hidden != nil {
+		review.Hidden = *moderation.Hidden
+	}
+	if moderation.Flagged != nil {
+		review.Flagged = *moderation.Flagged
+	}
+	now := time.Now()
+	review.ModeratedBy = moderatorID
+	review.ModeratedAt = &now
+
+	if err := s.reviewRepo.Update(ctx, review); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+	if review.Hidden != wasHidden {
+		if err := s.bookRepo.RefreshRating(ctx, review.BookID); err != nil {
+			return nil, err
+		}
+	}
+	return review, nil
+}
+
+// ownReview returns a review of a book written by the user
+func (s *bookService) ownReview(ctx context.Context, bookID string, id uint, userID string) (*entities.Review, error) {
+	review, err := s.reviewRepo.FindByID(ctx, strconv.FormatUint(uint64(id), 10))
+	if err != nil {
+		return nil, err
+	}
+	if review.BookID != bookID {
+		return nil, errors.NewNotFoundError("review")
+	}
+	if review.UserID != userID {
+		return nil, errors.NewAppError("UNAUTHORIZED", "You are not authorized to change this review", nil)
+	}
+	return review, nil
+}
+
+// visibleBook returns a book the user can see
+func (s *bookService) visibleBook(ctx context.Context, bookID, userID string) (*entities.Book, error) {
+	return s.accessPolicy.Authorize(ctx, bookID, userID, book.AccessView)
+}
+
+func validateReview(review *entities.Review) error {
+	if review.Rating < 1 || review.Rating > 5 {
+		return errors.NewValidationError("rating", "Rating must be between 1 and 5")
+	}
+	review.Title = strings.TrimSpace(review.Title)
+	review.Body = strings.TrimSpace(review.Body)
+	if utf8.RuneCountInString(review.Title) > maxReviewTitle {
+		return errors.NewValidationError("title", "Title must be at most 200 characters")
+	}
+	if utf8.RuneCountInString(review.Body) > maxReviewBody {
+		return errors.NewValidationError("body", "Review must be at most 10000 characters")
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+)
+
+// ListRevisions returns a page of the revisions of a book, latest first
+func (s *bookService) ListRevisions(ctx context.Context, bookID string, page, limit int) ([]*entities.BookRevision, int64, error) {
+	return s.revisionRepo.ListByBookID(ctx, bookID, page, limit)
+}
+
+// GetRevision returns a revision of a book, with the book as it was then
+func (s *bookService) GetRevision(ctx context.Context, bookID string, number int) (*entities.BookRevision, error) {
+	revision, err := s.revisionRepo.FindByNumber(ctx, bookID, number)
+	if err != nil {
+		return nil, err
+	}
+	if revision == nil {
+		return nil, errors.NewNotFoundError("Revision")
+	}
+	return revision, nil
+}
+
+// RevertBook brings the details of a book back to the ones of a revision,
+// recording a new revision made by userID
+func (s *bookService) RevertBook(ctx context.Context, bookID, userID string, number int) (*entities.Book, error) {
+	revision, err := s.GetRevision(ctx, bookID, number)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.bookRepo.FindByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, errors.NewAppError("NOT_FOUND", "Book not found", nil)
+	}
+
+	snapshot := revision.Snapshot
+	reverted := &entities.Book{
+		Title:         snapshot.Title,
+		Author:        snapshot.Author,
+		Description:   snapshot.Description,
+		ISBN:          snapshot.ISBN,
+		PublishedYear: snapshot.PublishedYear,
+		Publisher:     snapshot.Publisher,
+		PageCount:     snapshot.PageCount,
+		Edition:       snapshot.Edition,
+		Lang:          snapshot.Lang,
+	}
+	for _, name := range snapshot.Tags {
+		reverted.Tags = append(reverted.Tags, entities.Tag{Name: name})
+	}
+	if err := s.saveBookDetails(ctx, existing, reverted, userID, entities.BookRevisionRevert, number); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// reviser returns the revisions of the changes made to books by userID,
+// leaving out the changes that keep the details as they were
+func (s *bookService) reviser(userID, action string, revertedTo int) repository.BookReviser {
+	return func(before, after *entities.Book) *entities.BookRevision {
+		snapshot := bookSnapshot(after)
+		previous := entities.BookSnapshot{}
+		if before != nil {
+			previous = bookSnapshot(before)
+		}
+		changes := diffBookSnapshots(&previous, &snapshot)
+		if len(changes) == 0 && action != entities.BookRevisionCreate {
+			return nil
+		}
+		return &entities.BookRevision{
+			BookID:     after.ID,
+			UserID:     userID,
+			Action:     action,
+			RevertedTo: revertedTo,
+			Changes:    changes,
+			Snapshot:   snapshot,
+		}
+	}
+}
+
+// bookSnapshot returns the details of a book tracked by revisions, with the
+// tags sorted by name
+func bookSnapshot(b *entities.Book) entities.BookSnapshot {
+	tags := make([]string, len(b.Tags))
+	for i, tag := range b.Tags {
+		tags[i] = tag.Name
+	}
+	sort.Slice(tags, func(i, j int) bool { return strings.ToLower(tags[i]) < strings.ToLower(tags[j]) })
+
+	snapshot := entities.BookSnapshot{
+		Title:         b.Title,
+		Author:        b.Author,
+		Description:   b.Description,
+		PublishedYear: b.PublishedYear,
+		Publisher:     b.Publisher,
+		PageCount:     b.PageCount,
+		Edition:       b.Edition,
+		Lang:          b.Lang,
+		Tags:          tags,
+	}
+	if b.ISBN != nil {
+		isbn := *b.ISBN
+		snapshot.ISBN = &isbn
+	}
+	return snapshot
+}
+
+// diffBookSnapshots lists the fields whose value differs between two
+// snapshots, named as in JSON
+func diffBookSnapshots(before, after *entities.BookSnapshot) []entities.BookFieldChange {
+	changes := []entities.BookFieldChange{}
+	from, to := reflect.ValueOf(before).Elem(), reflect.ValueOf(after).Elem()
+	for i := 0; i < from.NumField(); i++ {
+		a, b := snapshotValue(from.Field(i)), snapshotValue(to.Field(i))
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+		field := strings.Split(from.Type().Field(i).Tag.Get("json"), ",")[0]
+		changes = append(changes, entities.BookFieldChange{Field: field, From: a, To: b})
+	}
+	return changes
+}
+
+// snapshotValue returns the value of a snapshot field, nil for nil pointers
+// and empty lists so that they compare equal
+func snapshotValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return v.Elem().Interface()
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return nil
+		}
+	}
+	return v.Interface()
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"clean-arch-go/internal/domain/entities"
+	domaintranslation "clean-arch-go/internal/domain/translation"
+)
+
+func (r *fakeBookRepository) FindTranslation(ctx context.Context, bookID, lang string) (*entities.BookTranslation, error) {
+	return nil, nil
+}
+
+func (r *fakeBookRepository) SaveTranslation(ctx context.Context, translation *entities.BookTranslation) error {
+	return nil
+}
+
+// fakeTranslationService detects English and leaves the texts as they are
+type fakeTranslationService struct {
+	TranslationService
+}
+
+func (fakeTranslationService) DetectLanguage(ctx context.Context, text string) (*domaintranslation.Detection, error) {
+	return &domaintranslation.Detection{Lang: "en", Confidence: 1}, nil
+}
+
+func (fakeTranslationService) Translate(ctx context.Context, req *TranslationRequest) (*TranslationResult, error) {
+	return &TranslationResult{Text: req.Text, SourceLang: req.SourceLang, TargetLang: req.TargetLang}, nil
+}
+
+// revisionFields returns the fields changed by a revision
+func revisionFields(revision *entities.BookRevision) []string {
+	fields := make([]string, len(revision.Changes))
+	for i, change := range revision.Changes {
+		fields[i] = change.Field
+	}
+	return fields
+}
+
+func newRevisedBook(t *testing.T, s BookService) *entities.Book {
+	t.Helper()
+	b := &entities.Book{UserID: "user-1", Title: "Original", Author: "Author", Tags: []entities.Tag{{Name: "go"}, {Name: "draft"}}}
+	if err := s.CreateBook(context.Background(), b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestUpdateBookRecordsItsRevision(t *testing.T) {
+	books := newFakeBookRepository()
+	s := NewBookService(books, nil, nil, books.revisions, nil, nil, nil, nil, nil)
+	b := newRevisedBook(t, s)
+
+	changes := &entities.Book{Title: "Changed", Author: "Author", Version: b.Version, Tags: []entities.Tag{{Name: "go"}}}
+	if err := s.UpdateBook(context.Background(), b.ID, "user-2", changes); err != nil {
+		t.Fatal(err)
+	}
+	revisions := books.revisions.revisions
+	if len(revisions) != 2 {
+		t.Fatalf("%d revisions, want the creation and the update", len(revisions))
+	}
+	update := revisions[1]
+	if update.Action != entities.BookRevisionUpdate || update.UserID != "user-2" || update.Number != 2 ||
+		update.Snapshot.Title != "Changed" || len(update.Snapshot.Tags) != 1 {
+		t.Errorf("revision = %+v", update)
+	}
+	if fields := revisionFields(update); len(fields) != 2 || fields[0] != "title" || fields[1] != "tags" {
+		t.Errorf("changed fields = %v, want title and tags", fields)
+	}
+
+	// A stale update changes neither the book nor its revisions
+	stale := &entities.Book{Title: "Stale", Author: "Author", Version: b.Version}
+	if err := s.UpdateBook(context.Background(), b.ID, "user-2", stale); errorCode(err) != "PRECONDITION_FAILED" {
+		t.Errorf("stale update = %v, want PRECONDITION_FAILED", err)
+	}
+	if len(books.revisions.revisions) != 2 || books.books[b.ID].Title != "Changed" {
+		t.Errorf("the stale update was saved")
+	}
+
+	// Saving the same details isn't a revision
+	same := &entities.Book{Title: "Changed", Author: "Author", Tags: []entities.Tag{{Name: "go"}}}
+	if err := s.UpdateBook(context.Background(), b.ID, "user-2", same); err != nil {
+		t.Fatal(err)
+	}
+	if len(books.revisions.revisions) != 2 {
+		t.Errorf("%d revisions after an update without changes, want 2", len(books.revisions.revisions))
+	}
+}
+
+func TestTagChangesAreRevised(t *testing.T) {
+	books := newFakeBookRepository()
+	s := NewBookService(books, nil, nil, books.revisions, nil, nil, nil, nil, nil)
+	b := newRevisedBook(t, s)
+	untagged := &entities.Book{UserID: "user-1", Title: "Untagged", Author: "Author"}
+	if err := s.CreateBook(context.Background(), untagged); err != nil {
+		t.Fatal(err)
+	}
+	draft, _ := books.FindTagByName(context.Background(), "user-1", "draft")
+
+	if _, err := s.RenameTag(context.Background(), draft.ID, "user-1", "review"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteTag(context.Background(), draft.ID, "user-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions := books.revisions.revisions
+	if len(revisions) != 4 {
+		t.Fatalf("%d revisions, want the creations, the rename and the deletion", len(revisions))
+	}
+	for i, want := range [][]string{{"go", "review"}, {"go"}} {
+		revision := revisions[2+i]
+		if revision.BookID != b.ID || revision.UserID != "user-1" || revision.Action != entities.BookRevisionUpdate {
+			t.Errorf("revision %d = %+v", i, revision)
+		}
+		if tags := revision.Snapshot.Tags; len(tags) != len(want) || tags[0] != want[0] || tags[len(tags)-1] != want[len(want)-1] {
+			t.Errorf("revision %d has tags %v, want %v", i, tags, want)
+		}
+	}
+	if books.books[b.ID].Version != 3 || books.books[untagged.ID].Version != 1 {
+		t.Errorf("versions = %d and %d, want 3 and 1", books.books[b.ID].Version, books.books[untagged.ID].Version)
+	}
+}
+
+func TestDetectedLanguageIsRevised(t *testing.T) {
+	books := newFakeBookRepository()
+	s := NewBookService(books, nil, nil, books.revisions, nil, nil, fakeTranslationService{}, nil, nil)
+	b := newRevisedBook(t, s)
+
+	if _, err := s.MachineTranslateBook(context.Background(), b.ID, "user-2", "vi", false); err != nil {
+		t.Fatal(err)
+	}
+	revisions := books.revisions.revisions
+	if len(revisions) != 2 {
+		t.Fatalf("%d revisions, want the creation and the detected language", len(revisions))
+	}
+	revision := revisions[1]
+	if fields := revisionFields(revision); len(fields) != 1 || fields[0] != "lang" || revision.UserID != "user-2" {
+		t.Errorf("revision = %+v, want the language set by user-2", revision)
+	}
+	if saved := books.books[b.ID]; saved.Lang != "en" || len(saved.Tags) != 2 {
+		t.Errorf("book = %+v, want English with its tags kept", saved)
+	}
+}
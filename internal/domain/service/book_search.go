@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+)
+
+// BookSearchBackend finds the books matching a search filter
+type BookSearchBackend interface {
+	Search(ctx context.Context, filter *book.Filter, withFacets bool) (*repository.BookSearchResult, error)
+}
+
+// BookEventListener is notified after books are created, updated or deleted
+type BookEventListener interface {
+	BookSaved(ctx context.Context, book *entities.Book)
+	BookDeleted(ctx context.Context, id string)
+}
+
+type sqlSearchBackend struct {
+	bookRepo repository.BookRepository
+}
+
+// NewSQLSearchBackend creates a search backend querying the database
+func NewSQLSearchBackend(bookRepo repository.BookRepository) BookSearchBackend {
+	return &sqlSearchBackend{bookRepo: bookRepo}
+}
+
+func (b *sqlSearchBackend) Search(ctx context.Context, filter *book.Filter, withFacets bool) (*repository.BookSearchResult, error) {
+	result, err := b.bookRepo.Search(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if withFacets {
+		if result.Facets, err = b.bookRepo.SearchFacets(ctx, filter); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
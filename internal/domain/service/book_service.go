@@ -1,51 +1,201 @@
 package service
 
 import (
+	"clean-arch-go/internal/domain/book"
 	"clean-arch-go/internal/domain/entities"
 	"clean-arch-go/internal/domain/repository"
 	"clean-arch-go/internal/errors"
 	"context"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
 )
 
 type BookService interface {
 	CreateBook(ctx context.Context, book *entities.Book) error
-	UpdateBook(ctx context.Context, id string, book *entities.Book) error
-	DeleteBook(ctx context.Context, id string) error
+	UpdateBook(ctx context.Context, id, userID string, book *entities.Book) error
+	DeleteBook(ctx context.Context, id string, version int) error
 	GetBookByID(ctx context.Context, id string) (*entities.Book, error)
 	ListBooksByUserID(ctx context.Context, userID string, page, limit int) ([]*entities.Book, error)
-	CheckBookOwnership(ctx context.Context, bookID, userID string) error
+	ListBooks(ctx context.Context, filter *book.Filter) (*repository.BookSearchResult, error)
+	ListBooksPage(ctx context.Context, filter *book.Filter, cursor repository.CursorQuery) (*repository.CursorPage[entities.Book], error)
+	CountBooks(ctx context.Context, filter *book.Filter) (int64, error)
+	CountBooksByUserID(ctx context.Context, userID string) (int64, error)
+	AuthorizeBook(ctx context.Context, bookID, userID string, need book.Access) (*entities.Book, error)
+	BookAccess(ctx context.Context, b *entities.Book, userID string) (book.Access, error)
+	ListGrants(ctx context.Context, bookID, userID string) ([]*entities.BookGrant, error)
+	GrantAccess(ctx context.Context, bookID, userID, grantee, role string) (*entities.BookGrant, error)
+	RevokeAccess(ctx context.Context, bookID, userID, granteeID string) error
+	SetVisibility(ctx context.Context, bookID, userID, visibility string) (*entities.Book, error)
+	RotateShareLink(ctx context.Context, bookID, userID string) (*entities.Book, error)
+	GetSharedBook(ctx context.Context, token string) (*entities.Book, error)
+	ListBookTranslations(ctx context.Context, bookID string) ([]*entities.BookTranslation, error)
+	SaveBookTranslation(ctx context.Context, translation *entities.BookTranslation) error
+	DeleteBookTranslation(ctx context.Context, bookID, lang string) error
+	MachineTranslateBook(ctx context.Context, bookID, userID, targetLang string, overwrite bool) (*entities.BookTranslation, error)
+	LocalizeBooks(ctx context.Context, prefs []language.Tag, books ...*entities.Book) error
+	SearchBooks(ctx context.Context, filter *book.Filter, withFacets bool) (*repository.BookSearchResult, error)
+	LookupISBN(ctx context.Context, isbn string) (*book.Metadata, error)
+	CreateBookFromISBN(ctx context.Context, userID, isbn string) (*entities.Book, error)
+	ListTags(ctx context.Context, userID string) ([]*entities.Tag, error)
+	RenameTag(ctx context.Context, id uint, userID, name string) (*entities.Tag, error)
+	DeleteTag(ctx context.Context, id uint, userID string) error
+	ListReviews(ctx context.Context, bookID, userID string, page, limit int) ([]*entities.Review, int64, error)
+	GetReviewByUser(ctx context.Context, bookID, userID string) (*entities.Review, error)
+	CreateReview(ctx context.Context, review *entities.Review) error
+	UpdateReview(ctx context.Context, bookID string, id uint, userID string, review *entities.Review) error
+	DeleteReview(ctx context.Context, bookID string, id uint, userID string) error
+	ListReviewsForModeration(ctx context.Context, filter repository.ReviewFilter, page, limit int) ([]*entities.Review, int64, error)
+	ModerateReview(ctx context.Context, id uint, moderatorID string, moderation ReviewModeration) (*entities.Review, error)
+	ListRevisions(ctx context.Context, bookID string, page, limit int) ([]*entities.BookRevision, int64, error)
+	GetRevision(ctx context.Context, bookID string, number int) (*entities.BookRevision, error)
+	RevertBook(ctx context.Context, bookID, userID string, number int) (*entities.Book, error)
+}
+
+// ReviewModeration changes the moderation state of a review, leaving the
+// nil fields as they are
+type ReviewModeration struct {
+	Hidden  *bool
+	Flagged *bool
+	Note    *string
 }
 
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 100
+	maxTagLength       = 50
+	maxTitleLength     = 255
+	maxAuthorLength    = 100
+	maxPublisherLength = 255
+	maxEditionLength   = 50
+	maxPageCount       = 100000
+	maxReviewTitle     = 200
+	maxReviewBody      = 10000
+	maxModerationNote  = 500
+	// minPublishedYear is the year of the earliest printed books
+	minPublishedYear = 1450
+	// unknownAuthor is the author of books created from metadata without authors
+	unknownAuthor = "Unknown"
+)
+
 type bookService struct {
-	bookRepo repository.BookRepository
+	bookRepo         repository.BookRepository
+	reviewRepo       repository.ReviewRepository
+	userRepo         repository.UserRepository
+	revisionRepo     repository.BookRevisionRepository
+	shelfRepo        repository.ShelfRepository
+	accessPolicy     BookAccessPolicy
+	translationSvc   TranslationService
+	metadataProvider book.MetadataProvider
+	searchBackend    BookSearchBackend
+	listeners        []BookEventListener
 }
 
-func NewBookService(bookRepo repository.BookRepository) BookService {
+// NewBookService creates a book service authorizing users with
+// accessPolicy, looking ISBNs up with metadataProvider, disabled when nil,
+// searching with searchBackend, the database when nil, and notifying
+// listeners of book changes
+func NewBookService(
+	bookRepo repository.BookRepository,
+	reviewRepo repository.ReviewRepository,
+	userRepo repository.UserRepository,
+	revisionRepo repository.BookRevisionRepository,
+	shelfRepo repository.ShelfRepository,
+	accessPolicy BookAccessPolicy,
+	translationSvc TranslationService,
+	metadataProvider book.MetadataProvider,
+	searchBackend BookSearchBackend,
+	listeners ...BookEventListener,
+) BookService {
+	if searchBackend == nil {
+		searchBackend = NewSQLSearchBackend(bookRepo)
+	}
 	return &bookService{
-		bookRepo: bookRepo,
+		bookRepo:         bookRepo,
+		reviewRepo:       reviewRepo,
+		userRepo:         userRepo,
+		revisionRepo:     revisionRepo,
+		shelfRepo:        shelfRepo,
+		accessPolicy:     accessPolicy,
+		translationSvc:   translationSvc,
+		metadataProvider: metadataProvider,
+		searchBackend:    searchBackend,
+		listeners:        listeners,
 	}
 }
 
 func (s *bookService) CreateBook(ctx context.Context, book *entities.Book) error {
-	return s.bookRepo.Create(ctx, book)
+	if err := s.validateBookDetails(ctx, book, book.UserID, ""); err != nil {
+		return err
+	}
+
+	tags, err := tagNames(book.Tags)
+	if err != nil {
+		return err
+	}
+	book.Tags = nil
+
+	if err := s.bookRepo.CreateDetails(ctx, book, tags, s.reviser(book.UserID, entities.BookRevisionCreate, 0)); err != nil {
+		return err
+	}
+	s.bookSaved(ctx, book)
+	return nil
 }
 
-func (s *bookService) UpdateBook(ctx context.Context, id string, book *entities.Book) error {
+// UpdateBook replaces the details of a book, recording the change as a
+// revision made by userID
+func (s *bookService) UpdateBook(ctx context.Context, id, userID string, book *entities.Book) error {
 	// Kiểm tra xem sách có tồn tại không
 	existingBook, err := s.bookRepo.FindByID(ctx, id)
 	if err != nil {
+		return err
+	}
+	if existingBook == nil {
 		return errors.NewAppError("NOT_FOUND", "Book not found", nil)
 	}
+	return s.saveBookDetails(ctx, existingBook, book, userID, entities.BookRevisionUpdate, 0)
+}
+
+// saveBookDetails replaces the details of existingBook with the ones of
+// book, recording the change as a revision. When book has a version, the
+// change is only made to that version of the book.
+func (s *bookService) saveBookDetails(ctx context.Context, existingBook, book *entities.Book, userID, action string, revertedTo int) error {
+	if book.Version != 0 && book.Version != existingBook.Version {
+		return errors.NewAppError("PRECONDITION_FAILED", "The book was changed in the meantime", nil)
+	}
+	if err := s.validateBookDetails(ctx, book, existingBook.UserID, existingBook.ID); err != nil {
+		return err
+	}
+	tags, err := tagNames(book.Tags)
+	if err != nil {
+		return err
+	}
+	before := *existingBook
 
 	// Cập nhật thông tin sách
 	existingBook.Title = book.Title
 	existingBook.Description = book.Description
 	existingBook.Author = book.Author
+	existingBook.Lang = book.Lang
+	existingBook.ISBN = book.ISBN
+	existingBook.PublishedYear = book.PublishedYear
+	existingBook.Publisher = book.Publisher
+	existingBook.PageCount = book.PageCount
+	existingBook.Edition = book.Edition
 
-	return s.bookRepo.Update(ctx, existingBook)
+	if err := s.bookRepo.UpdateDetails(ctx, &before, existingBook, tags, s.reviser(userID, action, revertedTo)); err != nil {
+		return err
+	}
+	s.bookSaved(ctx, existingBook)
+	return nil
 }
 
-func (s *bookService) DeleteBook(ctx context.Context, id string) error {
+// DeleteBook deletes a book if it's still at version, or at any version when
+// it's 0
+func (s *bookService) DeleteBook(ctx context.Context, id string, version int) error {
 	// Kiểm tra xem sách có tồn tại không
 	book, err := s.bookRepo.FindByID(ctx, id)
 	if err != nil {
@@ -56,7 +206,14 @@ func (s *bookService) DeleteBook(ctx context.Context, id string) error {
 		return errors.NewAppError("NOT_FOUND", "Book not found", nil)
 	}
 
-	return s.bookRepo.Delete(ctx, id)
+	if err := s.bookRepo.DeleteVersion(ctx, id, version); err != nil {
+		return err
+	}
+
+	for _, listener := range s.listeners {
+		listener.BookDeleted(ctx, id)
+	}
+	return nil
 }
 
 func (s *bookService) GetBookByID(ctx context.Context, id string) (*entities.Book, error) {
@@ -72,23 +229,537 @@ func (s *bookService) GetBookByID(ctx context.Context, id string) (*entities.Boo
 	return book, nil
 }
 
-func (s *bookService) CheckBookOwnership(ctx context.Context, bookID, userID string) error {
-	book, err := s.bookRepo.FindByID(ctx, bookID)
+func (s *bookService) ListBooksByUserID(ctx context.Context, userID string, page, limit int) ([]*entities.Book, error) {
+	return s.bookRepo.ListByUserID(ctx, userID, page, limit)
+}
+
+// ListBooks returns an offset-based page of the books matching filter,
+// newest first, read from the database whatever the search backend
+func (s *bookService) ListBooks(ctx context.Context, filter *book.Filter) (*repository.BookSearchResult, error) {
+	if err := s.checkFilter(ctx, filter); err != nil {
+		return nil, err
+	}
+	filter.Query = ""
+	filter.Sort = []book.SortField{{Field: book.SortCreatedAt, Desc: true}}
+	return s.bookRepo.Search(ctx, filter)
+}
+
+// ListBooksPage returns a page of the books matching filter, newest first
+func (s *bookService) ListBooksPage(ctx context.Context, filter *book.Filter, cursor repository.CursorQuery) (*repository.CursorPage[entities.Book], error) {
+	if cursor.After != nil && cursor.Before != nil {
+		return nil, errors.NewValidationError("cursor", "Only one of the next and previous cursors can be set")
+	}
+	if err := s.checkFilter(ctx, filter); err != nil {
+		return nil, err
+	}
+	return s.bookRepo.ListPage(ctx, filter, cursor)
+}
+
+// CountBooks counts the books matching filter
+func (s *bookService) CountBooks(ctx context.Context, filter *book.Filter) (int64, error) {
+	if err := s.checkFilter(ctx, filter); err != nil {
+		return 0, err
+	}
+	return s.bookRepo.CountMatching(ctx, filter)
+}
+
+func (s *bookService) CountBooksByUserID(ctx context.Context, userID string) (int64, error) {
+	return s.bookRepo.Count(ctx, map[string]interface{}{"user_id": userID})
+}
+
+// ListBookTranslations returns the stored translations of a book
+func (s *bookService) ListBookTranslations(ctx context.Context, bookID string) ([]*entities.BookTranslation, error) {
+	if _, err := s.GetBookByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	return s.bookRepo.ListTranslations(ctx, bookID)
+}
+
+// SaveBookTranslation creates or replaces the translation of a book into translation.Lang
+func (s *bookService) SaveBookTranslation(ctx context.Context, translation *entities.BookTranslation) error {
+	book, err := s.GetBookByID(ctx, translation.BookID)
 	if err != nil {
 		return err
 	}
 
-	if book == nil {
-		return errors.NewAppError("NOT_FOUND", "Book not found", nil)
+	lang, err := normalizeBookLang(translation.Lang)
+	if err != nil {
+		return err
+	}
+	if lang == "" {
+		return errors.NewValidationError("lang", "Language is required")
+	}
+	if lang == book.Lang {
+		return errors.NewValidationError("lang", "Book is already written in this language")
+	}
+	translation.Lang = lang
+	if translation.Source == "" {
+		translation.Source = entities.BookTranslationHuman
+	}
+
+	return s.bookRepo.SaveTranslation(ctx, translation)
+}
+
+// DeleteBookTranslation deletes the translation of a book into lang
+func (s *bookService) DeleteBookTranslation(ctx context.Context, bookID, lang string) error {
+	lang, err := normalizeBookLang(lang)
+	if err != nil {
+		return err
+	}
+	if lang == "" {
+		return errors.NewValidationError("lang", "Language is required")
+	}
+
+	existing, err := s.bookRepo.FindTranslation(ctx, bookID, lang)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errors.NewNotFoundError("Book translation")
+	}
+	return s.bookRepo.DeleteTranslation(ctx, bookID, lang)
+}
+
+// MachineTranslateBook translates the title and description of a book into
+// targetLang with the owner's glossaries. A human translation is only
+// replaced when overwrite is set. The language detected for a book without
+// one is recorded as a change made by userID.
+func (s *bookService) MachineTranslateBook(ctx context.Context, bookID, userID, targetLang string, overwrite bool) (*entities.BookTranslation, error) {
+	book, err := s.GetBookByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := normalizeBookLang(targetLang)
+	if err != nil {
+		return nil, err
+	}
+	if target == "" {
+		return nil, errors.NewValidationError("target_lang", "Target language is required")
+	}
+
+	// Remember the language of books created without one
+	if book.Lang == "" {
+		detection, err := s.translationSvc.DetectLanguage(ctx, book.Title+"\n"+book.Description)
+		if err != nil {
+			return nil, err
+		}
+		tags, err := tagNames(book.Tags)
+		if err != nil {
+			return nil, err
+		}
+		before := *book
+		book.Lang = detection.Lang
+		if err := s.bookRepo.UpdateDetails(ctx, &before, book, tags, s.reviser(userID, entities.BookRevisionUpdate, 0)); err != nil {
+			return nil, err
+		}
+		s.bookSaved(ctx, book)
+	}
+	if target == book.Lang {
+		return nil, errors.NewValidationError("target_lang", "Book is already written in this language")
+	}
+
+	existing, err := s.bookRepo.FindTranslation(ctx, book.ID, target)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Source == entities.BookTranslationHuman && !overwrite {
+		return nil, errors.NewAppError("CONFLICT", "Book already has a human translation in this language", nil)
+	}
+
+	translation := &entities.BookTranslation{
+		BookID: book.ID,
+		Lang:   target,
+		Source: entities.BookTranslationMachine,
+	}
+	for _, field := range []struct {
+		text string
+		dest *string
+	}{
+		{book.Title, &translation.Title},
+		{book.Description, &translation.Description},
+	} {
+		if field.text == "" {
+			continue
+		}
+		result, err := s.translationSvc.Translate(ctx, &TranslationRequest{
+			Text:       field.text,
+			SourceLang: book.Lang,
+			TargetLang: target,
+			UserID:     book.UserID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		*field.dest = result.Text
 	}
 
-	if book.UserID != userID {
-		return errors.NewAppError("UNAUTHORIZED", "You are not authorized to access this book", nil)
+	if err := s.bookRepo.SaveTranslation(ctx, translation); err != nil {
+		return nil, err
+	}
+	return translation, nil
+}
+
+// LocalizeBooks replaces the title and description of each book with the
+// translation best matching the reader's preferred languages, keeping the
+// original when no translation matches
+func (s *bookService) LocalizeBooks(ctx context.Context, prefs []language.Tag, books ...*entities.Book) error {
+	for _, book := range books {
+		book.ContentLang = book.Lang
+	}
+	if len(prefs) == 0 || len(books) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(books))
+	for i, book := range books {
+		ids[i] = book.ID
+	}
+	translations, err := s.bookRepo.ListTranslations(ctx, ids...)
+	if err != nil {
+		return err
 	}
 
+	byBook := make(map[string][]*entities.BookTranslation)
+	for _, translation := range translations {
+		byBook[translation.BookID] = append(byBook[translation.BookID], translation)
+	}
+	for _, book := range books {
+		localizeBook(book, byBook[book.ID], prefs)
+	}
 	return nil
 }
 
-func (s *bookService) ListBooksByUserID(ctx context.Context, userID string, page, limit int) ([]*entities.Book, error) {
-	return s.bookRepo.ListByUserID(ctx, userID, page, limit)
+// localizeBook applies the translation matching prefs. The original text is
+// the matcher's default so it's kept when nothing matches.
+func localizeBook(book *entities.Book, translations []*entities.BookTranslation, prefs []language.Tag) {
+	if len(translations) == 0 {
+		return
+	}
+
+	original, err := language.Parse(book.Lang)
+	if err != nil {
+		original = language.Und
+	}
+	supported := []language.Tag{original}
+	candidates := []*entities.BookTranslation{nil}
+	for _, translation := range translations {
+		tag, err := language.Parse(translation.Lang)
+		if err != nil {
+			continue
+		}
+		supported = append(supported, tag)
+		candidates = append(candidates, translation)
+	}
+
+	_, index, confidence := language.NewMatcher(supported).Match(prefs...)
+	if confidence == language.No || candidates[index] == nil {
+		return
+	}
+	book.Title = candidates[index].Title
+	book.Description = candidates[index].Description
+	book.ContentLang = candidates[index].Lang
+}
+
+// normalizeBookLang validates a language code and returns its canonical form
+func normalizeBookLang(lang string) (string, error) {
+	if lang == "" {
+		return "", nil
+	}
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return "", errors.NewValidationError("lang", "Invalid language code")
+	}
+	return tag.String(), nil
+}
+
+// SearchBooks returns the page of books matching filter, with the facet
+// counts of all matches when withFacets is set. Results are sorted by
+// relevance when there's a query and by creation date otherwise.
+func (s *bookService) SearchBooks(ctx context.Context, filter *book.Filter, withFacets bool) (*repository.BookSearchResult, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = defaultSearchLimit
+	}
+	if filter.Limit > maxSearchLimit {
+		filter.Limit = maxSearchLimit
+	}
+	if err := s.checkFilter(ctx, filter); err != nil {
+		return nil, err
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+	if len(filter.Sort) == 0 {
+		if filter.Query != "" {
+			filter.Sort = []book.SortField{{Field: book.SortRelevance, Desc: true}}
+		} else {
+			filter.Sort = []book.SortField{{Field: book.SortCreatedAt, Desc: true}}
+		}
+	}
+
+	return s.searchBackend.Search(ctx, filter, withFacets)
+}
+
+// LookupISBN returns the metadata of an ISBN-10 or ISBN-13
+func (s *bookService) LookupISBN(ctx context.Context, isbn string) (*book.Metadata, error) {
+	isbn, err := normalizeISBN(isbn)
+	if err != nil {
+		return nil, err
+	}
+	if s.metadataProvider == nil {
+		return nil, book.ErrMetadataUnavailable
+	}
+	return s.metadataProvider.LookupISBN(ctx, isbn)
+}
+
+// CreateBookFromISBN creates a book for the user from the metadata of an ISBN
+func (s *bookService) CreateBookFromISBN(ctx context.Context, userID, isbn string) (*entities.Book, error) {
+	metadata, err := s.LookupISBN(ctx, isbn)
+	if err != nil {
+		return nil, err
+	}
+
+	author := truncateRunes(strings.Join(metadata.Authors, ", "), maxAuthorLength)
+	if author == "" {
+		author = unknownAuthor
+	}
+	b := &entities.Book{
+		Title:         truncateRunes(metadata.Title, maxTitleLength),
+		Author:        author,
+		ISBN:          &metadata.ISBN,
+		PublishedYear: metadata.PublishedYear,
+		Publisher:     truncateRunes(metadata.Publisher, maxPublisherLength),
+		PageCount:     metadata.PageCount,
+		UserID:        userID,
+	}
+	if err := s.CreateBook(ctx, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ListTags returns the tags of a user with the number of books having them
+func (s *bookService) ListTags(ctx context.Context, userID string) ([]*entities.Tag, error) {
+	return s.bookRepo.ListTags(ctx, userID)
+}
+
+// RenameTag renames a tag of the user on all its books
+func (s *bookService) RenameTag(ctx context.Context, id uint, userID, name string) (*entities.Tag, error) {
+	names, err := tagNames([]entities.Tag{{Name: name}})
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, errors.NewValidationError("name", "Name is required")
+	}
+
+	tag, err := s.findTag(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.Name == names[0] {
+		return tag, nil
+	}
+	existing, err := s.bookRepo.FindTagByName(ctx, userID, names[0])
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.ID != tag.ID {
+		return nil, errors.NewAppError("CONFLICT", "You already have a tag with this name", nil)
+	}
+
+	tag.Name = names[0]
+	bookIDs, err := s.bookRepo.RenameTag(ctx, tag, s.reviser(userID, entities.BookRevisionUpdate, 0))
+	if err != nil {
+		return nil, err
+	}
+	s.booksChanged(ctx, bookIDs)
+	return tag, nil
+}
+
+// DeleteTag removes a tag of the user from all its books
+func (s *bookService) DeleteTag(ctx context.Context, id uint, userID string) error {
+	tag, err := s.findTag(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	bookIDs, err := s.bookRepo.DeleteTag(ctx, tag, s.reviser(userID, entities.BookRevisionUpdate, 0))
+	if err != nil {
+		return err
+	}
+	s.booksChanged(ctx, bookIDs)
+	return nil
+}
+
+func (s *bookService) findTag(ctx context.Context, id uint, userID string) (*entities.Tag, error) {
+	tag, err := s.bookRepo.FindTag(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if tag == nil {
+		return nil, errors.NewNotFoundError("tag")
+	}
+	return tag, nil
+}
+
+// booksChanged notifies the listeners of books changed through their tags
+func (s *bookService) booksChanged(ctx context.Context, ids []string) {
+	if len(s.listeners) == 0 || len(ids) == 0 {
+		return
+	}
+	books, err := s.bookRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		log.Printf("Failed to reload the books of a changed tag: %v", err)
+		return
+	}
+	for _, b := range books {
+		s.bookSaved(ctx, b)
+	}
+}
+
+// truncateRunes shortens s to at most n characters
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+func (s *bookService) bookSaved(ctx context.Context, book *entities.Book) {
+	for _, listener := range s.listeners {
+		listener.BookSaved(ctx, book)
+	}
+}
+
+// validateBookDetails checks and normalizes the bibliographic details of a
+// book owned by userID. The ISBN must be unique among the user's books, the
+// one with excludeID aside.
+func (s *bookService) validateBookDetails(ctx context.Context, book *entities.Book, userID, excludeID string) error {
+	if err := normalizeBookDetails(book); err != nil {
+		return err
+	}
+	if book.ISBN != nil {
+		existing, err := s.bookRepo.FindByISBN(ctx, userID, *book.ISBN)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.ID != excludeID {
+			if existing.DeletedAt.Valid {
+				return errors.NewAppError("CONFLICT", "A book with this ISBN is in your trash", nil)
+			}
+			return errors.NewAppError("CONFLICT", "You already have a book with this ISBN", nil)
+		}
+	}
+	return nil
+}
+
+// normalizeBookDetails checks the bibliographic details of a book and brings
+// its ISBN and language to their stored form
+func normalizeBookDetails(book *entities.Book) error {
+	lang, err := normalizeBookLang(book.Lang)
+	if err != nil {
+		return err
+	}
+	book.Lang = lang
+
+	if book.ISBN != nil && strings.TrimSpace(*book.ISBN) == "" {
+		book.ISBN = nil
+	}
+	if book.ISBN != nil {
+		isbn, err := normalizeISBN(*book.ISBN)
+		if err != nil {
+			return err
+		}
+		book.ISBN = &isbn
+	}
+
+	if book.PublishedYear != 0 && (book.PublishedYear < minPublishedYear || book.PublishedYear > time.Now().Year()+1) {
+		return errors.NewValidationError("published_year", "Invalid publication year")
+	}
+	if book.PageCount < 0 || book.PageCount > maxPageCount {
+		return errors.NewValidationError("page_count", "Invalid page count")
+	}
+
+	book.Publisher = strings.TrimSpace(book.Publisher)
+	if utf8.RuneCountInString(book.Publisher) > maxPublisherLength {
+		return errors.NewValidationError("publisher", "Publisher must be at most 255 characters")
+	}
+	book.Edition = strings.TrimSpace(book.Edition)
+	if utf8.RuneCountInString(book.Edition) > maxEditionLength {
+		return errors.NewValidationError("edition", "Edition must be at most 50 characters")
+	}
+	return nil
+}
+
+// checkFilter normalizes a book filter and checks that its shelf belongs to
+// the viewer, so that the shelves of others can't be listed
+func (s *bookService) checkFilter(ctx context.Context, filter *book.Filter) error {
+	if err := normalizeFilter(filter); err != nil {
+		return err
+	}
+	if filter.ShelfID == 0 {
+		return nil
+	}
+	shelves, err := s.shelfRepo.ListByUserID(ctx, filter.ViewerID)
+	if err != nil {
+		return err
+	}
+	for _, shelf := range shelves {
+		if shelf.ID == filter.ShelfID {
+			return nil
+		}
+	}
+	return errors.NewNotFoundError("shelf")
+}
+
+// normalizeFilter validates the criteria of a book filter and brings the
+// ISBN and language to their stored form
+func normalizeFilter(filter *book.Filter) error {
+	if filter.YearFrom > 0 && filter.YearTo > 0 && filter.YearFrom > filter.YearTo {
+		return errors.NewValidationError("year", "year_from must not be after year_to")
+	}
+	if filter.ISBN != "" {
+		isbn, err := normalizeISBN(filter.ISBN)
+		if err != nil {
+			return err
+		}
+		filter.ISBN = isbn
+	}
+	lang, err := normalizeBookLang(filter.Lang)
+	if err != nil {
+		return err
+	}
+	filter.Lang = lang
+
+	switch filter.Access {
+	case "", book.ScopeOwned, book.ScopeShared, book.ScopePublic:
+	default:
+		return errors.NewValidationError("access", "Access must be owned, shared or public")
+	}
+	return nil
+}
+
+func normalizeISBN(value string) (string, error) {
+	isbn, err := book.NormalizeISBN(value)
+	if err != nil {
+		return "", errors.NewValidationError("isbn", "Invalid ISBN-10 or ISBN-13")
+	}
+	return isbn, nil
+}
+
+// tagNames returns the trimmed, non-empty names of tags
+func tagNames(tags []entities.Tag) ([]string, error) {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		name := strings.TrimSpace(tag.Name)
+		if name == "" {
+			continue
+		}
+		if utf8.RuneCountInString(name) > maxTagLength {
+			return nil, errors.NewValidationError("tags", "Tags must be at most 50 characters")
+		}
+		names = append(names, name)
+	}
+	return names, nil
 }
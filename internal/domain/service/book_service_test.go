@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+	"clean-arch-go/internal/pkg/bookmeta"
+)
+
+// fakeBookRepository keeps the books, their tags and revisions in memory
+type fakeBookRepository struct {
+	repository.BookRepository
+	books     map[string]*entities.Book
+	tags      []*entities.Tag
+	revisions *fakeRevisionRepository
+}
+
+func newFakeBookRepository(books ...*entities.Book) *fakeBookRepository {
+	r := &fakeBookRepository{books: make(map[string]*entities.Book), revisions: &fakeRevisionRepository{}}
+	for _, b := range books {
+		r.books[b.ID] = b
+	}
+	return r
+}
+
+// FindByID returns a copy of a book, as read from the database
+func (r *fakeBookRepository) FindByID(ctx context.Context, id string) (*entities.Book, error) {
+	b, ok := r.books[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *b
+	return &found, nil
+}
+
+func (r *fakeBookRepository) FindByISBN(ctx context.Context, userID, isbn string) (*entities.Book, error) {
+	for _, b := range r.books {
+		if b.UserID == userID && b.ISBN != nil && *b.ISBN == isbn {
+			return b, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeBookRepository) CreateDetails(ctx context.Context, b *entities.Book, tags []string, revise repository.BookReviser) error {
+	b.ID = fmt.Sprint("book-", len(r.books)+1)
+	b.Version = 1
+	r.books[b.ID] = b
+	return r.saveDetails(nil, b, tags, revise)
+}
+
+func (r *fakeBookRepository) UpdateDetails(ctx context.Context, before, b *entities.Book, tags []string, revise repository.BookReviser) error {
+	if r.books[b.ID].Version != b.Version {
+		return errors.NewAppError("PRECONDITION_FAILED", "The book was changed in the meantime", nil)
+	}
+	b.Version++
+	r.books[b.ID] = b
+	return r.saveDetails(before, b, tags, revise)
+}
+
+func (r *fakeBookRepository) saveDetails(before, b *entities.Book, names []string, revise repository.BookReviser) error {
+	b.Tags = nil
+	for _, name := range names {
+		b.Tags = append(b.Tags, *r.tag(b.UserID, name))
+	}
+	if revision := revise(before, b); revision != nil {
+		return r.revisions.Create(context.Background(), revision)
+	}
+	return nil
+}
+
+// tag returns the tag of a user with a name, creating it when missing
+func (r *fakeBookRepository) tag(userID, name string) *entities.Tag {
+	for _, tag := range r.tags {
+		if tag.UserID == userID && strings.EqualFold(tag.Name, name) {
+			return tag
+		}
+	}
+	tag := &entities.Tag{ID: uint(len(r.tags) + 1), UserID: userID, Name: name}
+	r.tags = append(r.tags, tag)
+	return tag
+}
+
+func (r *fakeBookRepository) FindTag(ctx context.Context, userID string, id uint) (*entities.Tag, error) {
+	for _, tag := range r.tags {
+		if tag.UserID == userID && tag.ID == id {
+			return tag, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeBookRepository) FindTagByName(ctx context.Context, userID, name string) (*entities.Tag, error) {
+	for _, tag := range r.tags {
+		if tag.UserID == userID && tag.Name == name {
+			return tag, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeBookRepository) RenameTag(ctx context.Context, tag *entities.Tag, revise repository.BookReviser) ([]string, error) {
+	return r.retag(tag, revise, []entities.Tag{*tag}), nil
+}
+
+func (r *fakeBookRepository) DeleteTag(ctx context.Context, tag *entities.Tag, revise repository.BookReviser) ([]string, error) {
+	return r.retag(tag, revise, nil), nil
+}
+
+// retag replaces tag by tags on its books, moving them to their next version
+// with the revisions revise returns
+func (r *fakeBookRepository) retag(tag *entities.Tag, revise repository.BookReviser, tags []entities.Tag) []string {
+	var ids []string
+	for id, before := range r.books {
+		if !hasTag(before, tag.ID) {
+			continue
+		}
+		after := *before
+		after.Tags = nil
+		for _, t := range before.Tags {
+			if t.ID == tag.ID {
+				after.Tags = append(after.Tags, tags...)
+			} else {
+				after.Tags = append(after.Tags, t)
+			}
+		}
+		after.Version++
+		r.books[id] = &after
+		if revision := revise(before, &after); revision != nil {
+			r.revisions.Create(context.Background(), revision)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func hasTag(b *entities.Book, tagID uint) bool {
+	for _, t := range b.Tags {
+		if t.ID == tagID {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeRevisionRepository records the revisions
+type fakeRevisionRepository struct {
+	revisions []*entities.BookRevision
+}
+
+func (r *fakeRevisionRepository) Create(ctx context.Context, revision *entities.BookRevision) error {
+	revision.Number = len(r.revisions) + 1
+	r.revisions = append(r.revisions, revision)
+	return nil
+}
+
+func (r *fakeRevisionRepository) ListByBookID(ctx context.Context, bookID string, page, limit int) ([]*entities.BookRevision, int64, error) {
+	return r.revisions, int64(len(r.revisions)), nil
+}
+
+func (r *fakeRevisionRepository) FindByNumber(ctx context.Context, bookID string, number int) (*entities.BookRevision, error) {
+	if number < 1 || number > len(r.revisions) {
+		return nil, nil
+	}
+	return r.revisions[number-1], nil
+}
+
+func newFixtureBookService(t *testing.T, books *fakeBookRepository) BookService {
+	t.Helper()
+	provider, err := bookmeta.LoadFixtureProvider("../../pkg/bookmeta/testdata/books.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewBookService(books, nil, nil, books.revisions, nil, nil, nil, provider, nil)
+}
+
+// errorCode returns the code of an application error, "" for other errors
+func errorCode(err error) string {
+	if appErr, ok := err.(*errors.AppError); ok {
+		return appErr.Code
+	}
+	return ""
+}
+
+func TestLookupISBN(t *testing.T) {
+	s := newFixtureBookService(t, newFakeBookRepository())
+
+	for _, isbn := range []string{"9780201633610", "0-201-63361-2", "0201633612"} {
+		metadata, err := s.LookupISBN(context.Background(), isbn)
+		if err != nil {
+			t.Fatalf("LookupISBN(%q): %v", isbn, err)
+		}
+		if metadata.ISBN != "9780201633610" || metadata.Title != "Design Patterns: Elements of Reusable Object-Oriented Software" || len(metadata.Authors) != 4 {
+			t.Errorf("LookupISBN(%q) = %+v", isbn, metadata)
+		}
+	}
+
+	// The records aren't shared with the callers
+	metadata, _ := s.LookupISBN(context.Background(), "9780134190440")
+	metadata.Authors[0] = "Someone else"
+	if again, _ := s.LookupISBN(context.Background(), "9780134190440"); again.Authors[0] != "Alan A. A. Donovan" {
+		t.Errorf("a caller changed the fixture: %v", again.Authors)
+	}
+
+	tests := []struct {
+		isbn string
+		want string
+	}{
+		{"9780306406157", "NOT_FOUND"},
+		{"9780134190441", "VALIDATION_ERROR"},
+		{"", "VALIDATION_ERROR"},
+	}
+	for _, tt := range tests {
+		if _, err := s.LookupISBN(context.Background(), tt.isbn); errorCode(err) != tt.want {
+			t.Errorf("LookupISBN(%q) = %v, want %s", tt.isbn, err, tt.want)
+		}
+	}
+
+	disabled := NewBookService(newFakeBookRepository(), nil, nil, nil, nil, nil, nil, nil, nil)
+	if _, err := disabled.LookupISBN(context.Background(), "9780134190440"); err != book.ErrMetadataUnavailable {
+		t.Errorf("LookupISBN without a provider = %v, want ErrMetadataUnavailable", err)
+	}
+}
+
+func TestCreateBookFromISBN(t *testing.T) {
+	books := newFakeBookRepository()
+	revisions := books.revisions
+	s := newFixtureBookService(t, books)
+
+	b, err := s.CreateBookFromISBN(context.Background(), "user-1", "0-201-63361-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.ID == "" || b.UserID != "user-1" || b.ISBN == nil || *b.ISBN != "9780201633610" {
+		t.Fatalf("book = %+v", b)
+	}
+	if b.Title != "Design Patterns: Elements of Reusable Object-Oriented Software" ||
+		b.Author != "Erich Gamma, Richard Helm, Ralph Johnson, John Vlissides" ||
+		b.Publisher != "Addison-Wesley" || b.PublishedYear != 1994 || b.PageCount != 395 {
+		t.Errorf("book = %+v, want the details of the fixture", b)
+	}
+	if len(revisions.revisions) != 1 || revisions.revisions[0].Action != entities.BookRevisionCreate || revisions.revisions[0].BookID != b.ID {
+		t.Errorf("revisions = %+v, want the creation", revisions.revisions)
+	}
+
+	// The ISBN is unique among the books of a user, not across users
+	if _, err := s.CreateBookFromISBN(context.Background(), "user-1", "9780201633610"); errorCode(err) != "CONFLICT" {
+		t.Errorf("second copy = %v, want CONFLICT", err)
+	}
+	if _, err := s.CreateBookFromISBN(context.Background(), "user-2", "9780201633610"); err != nil {
+		t.Errorf("copy of another user: %v", err)
+	}
+
+	if _, err := s.CreateBookFromISBN(context.Background(), "user-1", "9780306406157"); errorCode(err) != "NOT_FOUND" {
+		t.Errorf("unknown ISBN = %v, want NOT_FOUND", err)
+	}
+	if len(books.books) != 2 {
+		t.Errorf("%d books, want 2", len(books.books))
+	}
+}
+
+func TestCreateBookFromISBNWithoutAuthors(t *testing.T) {
+	provider, err := bookmeta.NewFixtureProvider(map[string]*book.Metadata{
+		"9780306406157": {Title: "Anonymous"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	books := newFakeBookRepository()
+	s := NewBookService(books, nil, nil, books.revisions, nil, nil, nil, provider, nil)
+
+	b, err := s.CreateBookFromISBN(context.Background(), "user-1", "0306406152")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Author != unknownAuthor {
+		t.Errorf("author = %q, want %q", b.Author, unknownAuthor)
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/user"
+	"clean-arch-go/internal/errors"
+)
+
+// shareTokenBytes is the number of random bytes of a share link token
+const shareTokenBytes = 24
+
+// AuthorizeBook returns a book the user has at least the needed access to
+func (s *bookService) AuthorizeBook(ctx context.Context, bookID, userID string, need book.Access) (*entities.Book, error) {
+	return s.accessPolicy.Authorize(ctx, bookID, userID, need)
+}
+
+// BookAccess returns the access of the user to a loaded book
+func (s *bookService) BookAccess(ctx context.Context, b *entities.Book, userID string) (book.Access, error) {
+	return s.accessPolicy.AccessOf(ctx, b, userID)
+}
+
+// ListGrants returns the grants on a book of the user
+func (s *bookService) ListGrants(ctx context.Context, bookID, userID string) ([]*entities.BookGrant, error) {
+	if _, err := s.accessPolicy.Authorize(ctx, bookID, userID, book.AccessOwner); err != nil {
+		return nil, err
+	}
+	return s.bookRepo.ListGrants(ctx, bookID)
+}
+
+// GrantAccess gives a role on a book of the user to the grantee, a user ID
+// or an email address, replacing the role they had
+func (s *bookService) GrantAccess(ctx context.Context, bookID, userID, grantee, role string) (*entities.BookGrant, error) {
+	b, err := s.accessPolicy.Authorize(ctx, bookID, userID, book.AccessOwner)
+	if err != nil {
+		return nil, err
+	}
+	if !book.ValidRole(role) {
+		return nil, errors.NewValidationError("role", "Role must be viewer or editor")
+	}
+
+	target, err := s.findGrantee(ctx, strings.TrimSpace(grantee))
+	if err != nil {
+		return nil, err
+	}
+	if target.ID == b.UserID {
+		return nil, errors.NewValidationError("user", "The owner of a book can't be granted a role on it")
+	}
+
+	grant := &entities.BookGrant{
+		BookID:    b.ID,
+		UserID:    target.ID,
+		Role:      role,
+		GrantedBy: userID,
+	}
+	if err := s.bookRepo.SaveGrant(ctx, grant); err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// RevokeAccess removes the grant of a user on a book. Owners revoke any
+// grant on their books and grantees their own.
+func (s *bookService) RevokeAccess(ctx context.Context, bookID, userID, granteeID string) error {
+	if granteeID != userID {
+		if _, err := s.accessPolicy.Authorize(ctx, bookID, userID, book.AccessOwner); err != nil {
+			return err
+		}
+	}
+
+	grant, err := s.bookRepo.FindGrant(ctx, bookID, granteeID)
+	if err != nil {
+		return err
+	}
+	if grant == nil {
+		return errors.NewNotFoundError("grant")
+	}
+	return s.bookRepo.DeleteGrant(ctx, bookID, granteeID)
+}
+
+// SetVisibility changes the visibility of a book of the user. Unlisted and
+// public books keep their share link, private ones lose it.
+func (s *bookService) SetVisibility(ctx context.Context, bookID, userID, visibility string) (*entities.Book, error) {
+	b, err := s.accessPolicy.Authorize(ctx, bookID, userID, book.AccessOwner)
+	if err != nil {
+		return nil, err
+	}
+	if !book.ValidVisibility(visibility) {
+		return nil, errors.NewValidationError("visibility", "Visibility must be private, unlisted or public")
+	}
+
+	token := b.ShareToken
+	switch {
+	case visibility == book.VisibilityPrivate:
+		token = nil
+	case token == nil:
+		if token, err = newShareToken(); err != nil {
+			return nil, err
+		}
+	}
+	return s.saveSharing(ctx, b, visibility, token)
+}
+
+// RotateShareLink replaces the share link of a book of the user, the
+// previous one no longer giving access to it
+func (s *bookService) RotateShareLink(ctx context.Context, bookID, userID string) (*entities.Book, error) {
+	b, err := s.accessPolicy.Authorize(ctx, bookID, userID, book.AccessOwner)
+	if err != nil {
+		return nil, err
+	}
+	if b.Visibility == book.VisibilityPrivate {
+		return nil, errors.NewValidationError("visibility", "Private books have no share link")
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return nil, err
+	}
+	return s.saveSharing(ctx, b, b.Visibility, token)
+}
+
+// GetSharedBook returns the unlisted or public book with a share link
+func (s *bookService) GetSharedBook(ctx context.Context, token string) (*entities.Book, error) {
+	b, err := s.bookRepo.FindByShareToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil || b.Visibility == book.VisibilityPrivate {
+		return nil, errors.NewAppError("NOT_FOUND", "Book not found", nil)
+	}
+	return b, nil
+}
+
+func (s *bookService) saveSharing(ctx context.Context, b *entities.Book, visibility string, token *string) (*entities.Book, error) {
+	if err := s.bookRepo.SetSharing(ctx, b.ID, visibility, token); err != nil {
+		return nil, err
+	}
+	b.Visibility = visibility
+	b.ShareToken = token
+	b.Version++
+	s.bookSaved(ctx, b)
+	return b, nil
+}
+
+// findGrantee returns the user with an email address, or else an ID
+func (s *bookService) findGrantee(ctx context.Context, grantee string) (*user.User, error) {
+	if grantee == "" {
+		return nil, errors.NewValidationError("user", "User ID or email is required")
+	}
+
+	var target *user.User
+	var err error
+	if strings.Contains(grantee, "@") {
+		target, err = s.userRepo.FindByEmail(ctx, grantee)
+	} else {
+		target, err = s.userRepo.FindByID(ctx, grantee)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, errors.NewNotFoundError("user")
+	}
+	return target, nil
+}
+
+func newShareToken() (*string, error) {
+	raw := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate share token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return &token, nil
+}
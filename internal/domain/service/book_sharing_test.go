@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+)
+
+// ownerPolicy lets the owners of the books of a fake repository do anything
+type ownerPolicy struct {
+	books *fakeBookRepository
+}
+
+func (p ownerPolicy) Authorize(ctx context.Context, bookID, userID string, need book.Access) (*entities.Book, error) {
+	b, _ := p.books.FindByID(ctx, bookID)
+	if b == nil || b.UserID != userID {
+		return nil, errors.NewNotFoundError("book")
+	}
+	return b, nil
+}
+
+func (p ownerPolicy) AccessOf(ctx context.Context, b *entities.Book, userID string) (book.Access, error) {
+	return book.AccessOwner, nil
+}
+
+func (r *fakeBookRepository) SetSharing(ctx context.Context, bookID, visibility string, shareToken *string) error {
+	b := r.books[bookID]
+	b.Visibility = visibility
+	b.ShareToken = shareToken
+	b.Version++
+	return nil
+}
+
+func (r *fakeBookRepository) CountMatching(ctx context.Context, filter *book.Filter) (int64, error) {
+	return int64(len(r.books)), nil
+}
+
+// fakeShelfRepository holds the shelves of the users
+type fakeShelfRepository struct {
+	repository.ShelfRepository
+	shelves []*entities.Shelf
+}
+
+func (r *fakeShelfRepository) ListByUserID(ctx context.Context, userID string) ([]*entities.Shelf, error) {
+	var shelves []*entities.Shelf
+	for _, shelf := range r.shelves {
+		if shelf.UserID == userID {
+			shelves = append(shelves, shelf)
+		}
+	}
+	return shelves, nil
+}
+
+func TestSharingReturnsTheSavedVersion(t *testing.T) {
+	books := newFakeBookRepository(&entities.Book{ID: "book-1", UserID: "user-1", Visibility: book.VisibilityPrivate, Version: 3})
+	s := NewBookService(books, nil, nil, books.revisions, nil, ownerPolicy{books}, nil, nil, nil)
+
+	b, err := s.SetVisibility(context.Background(), "book-1", "user-1", book.VisibilityUnlisted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Version != 4 || b.ShareToken == nil {
+		t.Errorf("shared book = version %d with token %v, want version 4 with a token", b.Version, b.ShareToken)
+	}
+	if b, err = s.RotateShareLink(context.Background(), "book-1", "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	if b.Version != books.books["book-1"].Version {
+		t.Errorf("rotated book = version %d, saved at %d", b.Version, books.books["book-1"].Version)
+	}
+}
+
+func TestShelfFilterIsLimitedToTheViewer(t *testing.T) {
+	books := newFakeBookRepository()
+	shelves := &fakeShelfRepository{shelves: []*entities.Shelf{
+		{ID: 1, UserID: "user-1"},
+		{ID: 2, UserID: "user-2"},
+	}}
+	s := NewBookService(books, nil, nil, books.revisions, shelves, nil, nil, nil, nil)
+
+	if _, err := s.CountBooks(context.Background(), &book.Filter{ViewerID: "user-1", ShelfID: 1}); err != nil {
+		t.Errorf("own shelf: %v", err)
+	}
+	for _, shelfID := range []uint{2, 3} {
+		if _, err := s.CountBooks(context.Background(), &book.Filter{ViewerID: "user-1", ShelfID: shelfID}); errorCode(err) != "NOT_FOUND" {
+			t.Errorf("shelf %d = %v, want NOT_FOUND", shelfID, err)
+		}
+		page, err := s.ListBooksPage(context.Background(), &book.Filter{ViewerID: "user-1", ShelfID: shelfID}, repository.CursorQuery{Limit: 10})
+		if errorCode(err) != "NOT_FOUND" {
+			t.Errorf("page of shelf %d = %v, %v, want NOT_FOUND", shelfID, page, err)
+		}
+	}
+}
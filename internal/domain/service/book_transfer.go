@@ -0,0 +1,560 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+	"clean-arch-go/internal/pkg/bookio"
+	"clean-arch-go/internal/pkg/storage"
+)
+
+const (
+	// maxJobRowErrors bounds the row errors kept on an import job
+	maxJobRowErrors = 100
+	// jobProgressRows is how many rows are processed between progress saves
+	jobProgressRows = 100
+	// exportPageSize is how many books are read at once by exports
+	exportPageSize = 500
+	// jobPollInterval is how often the workers look for jobs they missed,
+	// such as the ones queued while the queue was full, and renew the lease
+	// of their running jobs
+	jobPollInterval = 30 * time.Second
+	// jobLease is how long a running job is left to its worker without a
+	// heartbeat before it's failed
+	jobLease = 4 * jobPollInterval
+)
+
+// BookTransferOptions configures the import and export jobs
+type BookTransferOptions struct {
+	// Storage keeps the imported and exported files, shared by the
+	// instances and not public
+	Storage storage.Storage
+	// MaxBytes is the maximum size of imported files
+	MaxBytes int64
+	// Workers is how many jobs run at once
+	Workers int
+}
+
+// BookTransferService imports books from CSV and JSON files and exports the
+// books of a user to them, as jobs run in the background
+type BookTransferService interface {
+	StartImport(ctx context.Context, userID, format string, dryRun bool, r io.Reader) (*entities.BookJob, error)
+	StartExport(ctx context.Context, userID, format string) (*entities.BookJob, error)
+	GetJob(ctx context.Context, id uint, userID string) (*entities.BookJob, error)
+	ListJobs(ctx context.Context, userID string, page, limit int) ([]*entities.BookJob, int64, error)
+	OpenExport(ctx context.Context, id uint, userID string) (*entities.BookJob, io.ReadCloser, error)
+	DeleteJob(ctx context.Context, id uint, userID string) error
+	// Run runs the queued jobs until ctx is done
+	Run(ctx context.Context)
+}
+
+type bookTransferService struct {
+	jobRepo  repository.BookJobRepository
+	bookRepo repository.BookRepository
+	bookSvc  BookService
+	options  BookTransferOptions
+	queue    chan uint
+	// worker identifies the jobs run by this instance
+	worker string
+}
+
+func NewBookTransferService(
+	jobRepo repository.BookJobRepository,
+	bookRepo repository.BookRepository,
+	bookSvc BookService,
+	options BookTransferOptions,
+) BookTransferService {
+	if options.Workers < 1 {
+		options.Workers = 1
+	}
+	return &bookTransferService{
+		jobRepo:  jobRepo,
+		bookRepo: bookRepo,
+		bookSvc:  bookSvc,
+		options:  options,
+		queue:    make(chan uint, 100),
+		worker:   newJobWorker(),
+	}
+}
+
+// StartImport saves an uploaded file of books and queues its import into
+// the library of the user. Dry runs only validate the rows.
+func (s *bookTransferService) StartImport(ctx context.Context, userID, format string, dryRun bool, r io.Reader) (*entities.BookJob, error) {
+	if err := validateJobFormat(format); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.options.MaxBytes+1))
+	if err != nil {
+		return nil, errors.NewBadRequestError("Failed to read the import file")
+	}
+	if int64(len(data)) > s.options.MaxBytes {
+		return nil, errors.NewValidationError("file", fmt.Sprintf("Import files must be at most %d KB", s.options.MaxBytes/1024))
+	}
+	if len(data) == 0 {
+		return nil, errors.NewValidationError("file", "Import file is empty")
+	}
+
+	job := &entities.BookJob{
+		UserID: userID,
+		Kind:   entities.BookJobImport,
+		Format: format,
+		Status: entities.BookJobPending,
+		DryRun: dryRun,
+	}
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	// The file is stored once the job has an ID to name it after
+	if err := s.options.Storage.Put(ctx, importKey(job), data, bookio.ContentTypes[format]); err != nil {
+		s.jobRepo.Delete(ctx, job.ID)
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	s.enqueue(job.ID)
+	return job, nil
+}
+
+// StartExport queues the export of the books of the user
+func (s *bookTransferService) StartExport(ctx context.Context, userID, format string) (*entities.BookJob, error) {
+	if err := validateJobFormat(format); err != nil {
+		return nil, err
+	}
+
+	job := &entities.BookJob{
+		UserID: userID,
+		Kind:   entities.BookJobExport,
+		Format: format,
+		Status: entities.BookJobPending,
+	}
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	s.enqueue(job.ID)
+	return job, nil
+}
+
+// GetJob returns a job of the user
+func (s *bookTransferService) GetJob(ctx context.Context, id uint, userID string) (*entities.BookJob, error) {
+	job, err := s.jobRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// The jobs of other users are none of the caller's business
+	if job == nil || job.UserID != userID {
+		return nil, errors.NewNotFoundError("Job")
+	}
+	return job, nil
+}
+
+func (s *bookTransferService) ListJobs(ctx context.Context, userID string, page, limit int) ([]*entities.BookJob, int64, error) {
+	return s.jobRepo.ListByUserID(ctx, userID, page, limit)
+}
+
+// OpenExport opens the file written by a succeeded export of the user
+func (s *bookTransferService) OpenExport(ctx context.Context, id uint, userID string) (*entities.BookJob, io.ReadCloser, error) {
+	job, err := s.GetJob(ctx, id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Kind != entities.BookJobExport {
+		return nil, nil, errors.NewBadRequestError("Only exports can be downloaded")
+	}
+	if job.Status != entities.BookJobSucceeded {
+		return nil, nil, errors.NewAppError("CONFLICT", "The export isn't done", nil)
+	}
+
+	file, err := s.options.Storage.Get(ctx, exportKey(job))
+	if err == storage.ErrNotFound {
+		return nil, nil, errors.NewNotFoundError("Export file")
+	}
+	if err != nil {
+		return nil, nil, errors.NewInternalServerError(err.Error())
+	}
+	return job, file, nil
+}
+
+// DeleteJob deletes a job of the user with its files, unless it's running
+func (s *bookTransferService) DeleteJob(ctx context.Context, id uint, userID string) error {
+	job, err := s.GetJob(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if job.Status == entities.BookJobRunning {
+		return errors.NewAppError("CONFLICT", "The job is running", nil)
+	}
+
+	if err := s.jobRepo.Delete(ctx, job.ID); err != nil {
+		return err
+	}
+	s.removeFile(ctx, job)
+	return nil
+}
+
+// Run runs the pending jobs with the configured number of workers until ctx
+// is done. The jobs of stopped instances, whose lease expired, are failed
+// on the way.
+func (s *bookTransferService) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < s.options.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-s.queue:
+					s.runJob(ctx, id)
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+	for {
+		s.renewLeases(ctx)
+		s.enqueuePending(ctx)
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueue queues a job unless the queue is full, leaving it to the next poll
+func (s *bookTransferService) enqueue(id uint) {
+	select {
+	case s.queue <- id:
+	default:
+	}
+}
+
+// renewLeases renews the lease of the jobs of this instance, then fails the
+// ones of other instances whose lease expired
+func (s *bookTransferService) renewLeases(ctx context.Context) {
+	now := time.Now()
+	if err := s.jobRepo.Heartbeat(ctx, s.worker, now); err != nil {
+		log.Printf("Failed to renew the lease of book jobs: %v", err)
+	}
+	if n, err := s.jobRepo.FailExpired(ctx, "Interrupted by a server stop", now.Add(-jobLease), now); err != nil {
+		log.Printf("Failed to fail interrupted book jobs: %v", err)
+	} else if n > 0 {
+		log.Printf("Failed %d interrupted book jobs", n)
+	}
+}
+
+func (s *bookTransferService) enqueuePending(ctx context.Context) {
+	ids, err := s.jobRepo.ListPendingIDs(ctx)
+	if err != nil {
+		log.Printf("Failed to list pending book jobs: %v", err)
+		return
+	}
+	for _, id := range ids {
+		s.enqueue(id)
+	}
+}
+
+// runJob runs a pending job, which another worker may have claimed already
+func (s *bookTransferService) runJob(ctx context.Context, id uint) {
+	claimed, err := s.jobRepo.Claim(ctx, id, s.worker, time.Now())
+	if err != nil {
+		log.Printf("Failed to claim book job %d: %v", id, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+	job, err := s.jobRepo.FindByID(ctx, id)
+	if err != nil || job == nil {
+		log.Printf("Failed to load book job %d: %v", id, err)
+		return
+	}
+
+	switch job.Kind {
+	case entities.BookJobImport:
+		err = s.runImport(ctx, job)
+	case entities.BookJobExport:
+		err = s.runExport(ctx, job)
+	default:
+		err = fmt.Errorf("unknown job kind %q", job.Kind)
+	}
+	// A job stopped by the shutdown stays running, to be failed once its
+	// lease expires
+	if ctx.Err() != nil {
+		return
+	}
+
+	job.Status = entities.BookJobSucceeded
+	if err != nil {
+		job.Status = entities.BookJobFailed
+		job.Error = truncateRunes(err.Error(), 500)
+		log.Printf("Book job %d failed: %v", job.ID, err)
+	}
+	finishedAt := time.Now()
+	job.FinishedAt = &finishedAt
+	if err := s.jobRepo.SaveProgress(context.WithoutCancel(ctx), job); err != nil {
+		log.Printf("Failed to save book job %d: %v", job.ID, err)
+	}
+}
+
+// runImport reads the rows of an import file one at a time, creating the
+// valid books whose ISBN isn't in the library yet
+func (s *bookTransferService) runImport(ctx context.Context, job *entities.BookJob) error {
+	defer s.removeFile(context.WithoutCancel(ctx), job)
+
+	file, err := s.options.Storage.Get(ctx, importKey(job))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader, err := bookio.NewReader(job.Format, bufio.NewReader(file))
+	if err != nil {
+		return err
+	}
+
+	// The ISBNs met so far, as the file may list a book twice
+	seen := make(map[string]bool)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		record, row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var rowErr *bookio.RowError
+		if stderrors.As(err, &rowErr) {
+			job.Processed++
+			rejectRow(job, rowErr.Row, rowErr.Field, rowErr.Message)
+		} else if err != nil {
+			return err
+		} else {
+			job.Processed++
+			if err := s.importRecord(ctx, job, record, row, seen); err != nil {
+				return err
+			}
+		}
+
+		if job.Processed%jobProgressRows == 0 {
+			if err := s.jobRepo.SaveProgress(ctx, job); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// importRecord imports a row, counting it as created, skipped or failed.
+// Errors other than invalid rows stop the import.
+func (s *bookTransferService) importRecord(ctx context.Context, job *entities.BookJob, record *bookio.Record, row int, seen map[string]bool) error {
+	b := recordBook(record, job.UserID)
+	if field, message := validateRecordBook(b); message != "" {
+		rejectRow(job, row, field, message)
+		return nil
+	}
+	if err := normalizeBookDetails(b); err != nil {
+		return rejectRowError(job, row, err)
+	}
+	if _, err := tagNames(b.Tags); err != nil {
+		return rejectRowError(job, row, err)
+	}
+
+	// Books are identified by their ISBN, the ones already imported are skipped
+	if b.ISBN != nil {
+		if seen[*b.ISBN] {
+			job.Skipped++
+			return nil
+		}
+		seen[*b.ISBN] = true
+		existing, err := s.bookRepo.FindByISBN(ctx, job.UserID, *b.ISBN)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			job.Skipped++
+			return nil
+		}
+	}
+
+	if !job.DryRun {
+		err := s.bookSvc.CreateBook(ctx, b)
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == "CONFLICT" {
+			// Another request created the book in the meantime
+			job.Skipped++
+			return nil
+		}
+		if err != nil {
+			return rejectRowError(job, row, err)
+		}
+	}
+	job.Created++
+	return nil
+}
+
+// runExport writes the books of the user to the export file, which is only
+// stored once complete
+func (s *bookTransferService) runExport(ctx context.Context, job *entities.BookJob) error {
+	var file bytes.Buffer
+	writer, err := bookio.NewWriter(job.Format, &file)
+	if err != nil {
+		return err
+	}
+	filter := &book.Filter{OwnerID: job.UserID}
+	cursor := repository.CursorQuery{Limit: exportPageSize}
+	for {
+		page, err := s.bookRepo.ListPage(ctx, filter, cursor)
+		if err != nil {
+			return err
+		}
+		for _, b := range page.Items {
+			if err := writer.Write(bookRecord(b)); err != nil {
+				return err
+			}
+		}
+		job.Processed += len(page.Items)
+		if page.Next == nil {
+			break
+		}
+		cursor.After = page.Next
+		if err := s.jobRepo.SaveProgress(ctx, job); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return s.options.Storage.Put(ctx, exportKey(job), file.Bytes(), bookio.ContentTypes[job.Format])
+}
+
+func importKey(job *entities.BookJob) string {
+	return "imports/" + strconv.FormatUint(uint64(job.ID), 10) + "." + job.Format
+}
+
+func exportKey(job *entities.BookJob) string {
+	return "exports/" + strconv.FormatUint(uint64(job.ID), 10) + "." + job.Format
+}
+
+func (s *bookTransferService) removeFile(ctx context.Context, job *entities.BookJob) {
+	key := importKey(job)
+	if job.Kind == entities.BookJobExport {
+		key = exportKey(job)
+	}
+	if err := s.options.Storage.Delete(ctx, key); err != nil {
+		log.Printf("Failed to remove the file of book job %d: %v", job.ID, err)
+	}
+}
+
+// newJobWorker returns a random identifier for the jobs of this instance
+func newJobWorker() string {
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
+
+func validateJobFormat(format string) error {
+	if format != bookio.FormatCSV && format != bookio.FormatJSON {
+		return errors.NewValidationError("format", "Format must be csv or json")
+	}
+	return nil
+}
+
+// rejectRow counts a row as failed, keeping its error while there's room
+func rejectRow(job *entities.BookJob, row int, field, message string) {
+	job.Failed++
+	if len(job.RowErrors) < maxJobRowErrors {
+		job.RowErrors = append(job.RowErrors, entities.BookJobRowError{Row: row, Field: field, Message: message})
+	}
+}
+
+// rejectRowError rejects a row for a validation error, returning the other
+// errors
+func rejectRowError(job *entities.BookJob, row int, err error) error {
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != "VALIDATION_ERROR" {
+		return err
+	}
+	field := ""
+	if detail, ok := appErr.Detail.(map[string]interface{}); ok {
+		field, _ = detail["field"].(string)
+	}
+	rejectRow(job, row, field, appErr.Message)
+	return nil
+}
+
+// validateRecordBook checks the fields of an imported book the API requires
+// by binding, returning the invalid field and why
+func validateRecordBook(b *entities.Book) (field, message string) {
+	switch {
+	case b.Title == "":
+		return bookio.FieldTitle, "Title is required"
+	case utf8.RuneCountInString(b.Title) > maxTitleLength:
+		return bookio.FieldTitle, "Title must be at most 255 characters"
+	case b.Author == "":
+		return bookio.FieldAuthor, "Author is required"
+	case utf8.RuneCountInString(b.Author) > maxAuthorLength:
+		return bookio.FieldAuthor, "Author must be at most 100 characters"
+	}
+	return "", ""
+}
+
+func recordBook(record *bookio.Record, userID string) *entities.Book {
+	b := &entities.Book{
+		Title:         strings.TrimSpace(record.Title),
+		Author:        strings.TrimSpace(record.Author),
+		Description:   strings.TrimSpace(record.Description),
+		PublishedYear: record.PublishedYear,
+		Publisher:     record.Publisher,
+		PageCount:     record.PageCount,
+		Edition:       record.Edition,
+		Lang:          record.Lang,
+		UserID:        userID,
+	}
+	if isbn := strings.TrimSpace(record.ISBN); isbn != "" {
+		b.ISBN = &isbn
+	}
+	for _, tag := range record.Tags {
+		b.Tags = append(b.Tags, entities.Tag{Name: tag})
+	}
+	return b
+}
+
+func bookRecord(b *entities.Book) *bookio.Record {
+	record := &bookio.Record{
+		Title:         b.Title,
+		Author:        b.Author,
+		Description:   b.Description,
+		PublishedYear: b.PublishedYear,
+		Publisher:     b.Publisher,
+		PageCount:     b.PageCount,
+		Edition:       b.Edition,
+		Lang:          b.Lang,
+	}
+	if b.ISBN != nil {
+		record.ISBN = *b.ISBN
+	}
+	for _, tag := range b.Tags {
+		record.Tags = append(record.Tags, tag.Name)
+	}
+	return record
+}
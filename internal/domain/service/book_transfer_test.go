@@ -0,0 +1,246 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/pkg/storage"
+)
+
+// fakeJobRepository keeps the jobs in memory
+type fakeJobRepository struct {
+	repository.BookJobRepository
+	mu   sync.Mutex
+	jobs map[uint]*entities.BookJob
+}
+
+func (r *fakeJobRepository) Create(ctx context.Context, job *entities.BookJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job.ID = uint(len(r.jobs) + 1)
+	stored := *job
+	r.jobs[job.ID] = &stored
+	return nil
+}
+
+func (r *fakeJobRepository) FindByID(ctx context.Context, id uint) (*entities.BookJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *job
+	return &found, nil
+}
+
+func (r *fakeJobRepository) SaveProgress(ctx context.Context, job *entities.BookJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := *job
+	r.jobs[job.ID] = &saved
+	return nil
+}
+
+func (r *fakeJobRepository) Delete(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.jobs, id)
+	return nil
+}
+
+func (r *fakeJobRepository) Claim(ctx context.Context, id uint, worker string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job := r.jobs[id]
+	if job == nil || job.Status != entities.BookJobPending {
+		return false, nil
+	}
+	job.Status, job.Worker, job.HeartbeatAt, job.StartedAt = entities.BookJobRunning, worker, &at, &at
+	return true, nil
+}
+
+func (r *fakeJobRepository) Heartbeat(ctx context.Context, worker string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, job := range r.jobs {
+		if job.Status == entities.BookJobRunning && job.Worker == worker {
+			job.HeartbeatAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *fakeJobRepository) FailExpired(ctx context.Context, message string, before, at time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for _, job := range r.jobs {
+		if job.Status == entities.BookJobRunning && (job.HeartbeatAt == nil || job.HeartbeatAt.Before(before)) {
+			job.Status, job.Error, job.FinishedAt = entities.BookJobFailed, message, &at
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestRenewLeasesOnlyFailsExpiredJobs(t *testing.T) {
+	jobs := &fakeJobRepository{jobs: map[uint]*entities.BookJob{
+		1: {ID: 1, Status: entities.BookJobPending},
+		2: {ID: 2, Status: entities.BookJobPending},
+		// Left running by a server older than the leases
+		3: {ID: 3, Status: entities.BookJobRunning},
+	}}
+	first := NewBookTransferService(jobs, nil, nil, BookTransferOptions{}).(*bookTransferService)
+	second := NewBookTransferService(jobs, nil, nil, BookTransferOptions{}).(*bookTransferService)
+	if first.worker == second.worker {
+		t.Fatal("two instances share a worker ID")
+	}
+
+	for id, s := range map[uint]*bookTransferService{1: first, 2: second} {
+		if claimed, _ := jobs.Claim(context.Background(), id, s.worker, time.Now().Add(-jobLease/2)); !claimed {
+			t.Fatalf("job %d not claimed", id)
+		}
+	}
+
+	// The first instance keeps its job alive while the second one stopped
+	// renewing its own
+	first.renewLeases(context.Background())
+	if jobs.jobs[1].Status != entities.BookJobRunning || jobs.jobs[2].Status != entities.BookJobRunning {
+		t.Fatal("a job whose lease is still valid was failed")
+	}
+	if jobs.jobs[3].Status != entities.BookJobFailed {
+		t.Error("a job without lease wasn't failed")
+	}
+
+	expired := time.Now().Add(-jobLease - time.Second)
+	jobs.jobs[2].HeartbeatAt = &expired
+	first.renewLeases(context.Background())
+	if jobs.jobs[1].Status != entities.BookJobRunning {
+		t.Error("the job of a live instance was failed")
+	}
+	if jobs.jobs[2].Status != entities.BookJobFailed || jobs.jobs[2].Error == "" {
+		t.Errorf("job 2 = %s, want failed once its lease expired", jobs.jobs[2].Status)
+	}
+}
+
+// memoryStorage keeps the objects in memory
+type memoryStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *memoryStorage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *memoryStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memoryStorage) Delete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.objects, key)
+	}
+	return nil
+}
+
+func (s *memoryStorage) URL(key string) string { return "/" + key }
+
+// ListPage returns the books of the owner of filter at once
+func (r *fakeBookRepository) ListPage(ctx context.Context, filter *book.Filter, cursor repository.CursorQuery) (*repository.CursorPage[entities.Book], error) {
+	page := &repository.CursorPage[entities.Book]{}
+	for _, b := range r.books {
+		if b.UserID == filter.OwnerID {
+			page.Items = append(page.Items, b)
+		}
+	}
+	sort.Slice(page.Items, func(i, j int) bool { return page.Items[i].ID < page.Items[j].ID })
+	return page, nil
+}
+
+func TestJobFilesAreSharedByTheInstances(t *testing.T) {
+	jobs := &fakeJobRepository{jobs: make(map[uint]*entities.BookJob)}
+	books := newFakeBookRepository()
+	files := &memoryStorage{objects: make(map[string][]byte)}
+	bookSvc := NewBookService(books, nil, nil, books.revisions, nil, nil, nil, nil, nil)
+	options := BookTransferOptions{Storage: files, MaxBytes: 1 << 20}
+	// The instance receiving the requests isn't the one running the jobs
+	front := NewBookTransferService(jobs, books, bookSvc, options).(*bookTransferService)
+	worker := NewBookTransferService(jobs, books, bookSvc, options).(*bookTransferService)
+	ctx := context.Background()
+
+	csv := "title,author,isbn\nThe Go Programming Language,Donovan,9780134190440\n,Nobody,\nGo in Action,Kennedy,\n"
+	job, err := front.StartImport(ctx, "user-1", "csv", false, strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	worker.runJob(ctx, job.ID)
+	imported, _ := front.GetJob(ctx, job.ID, "user-1")
+	if imported.Status != entities.BookJobSucceeded || imported.Created != 2 || imported.Failed != 1 {
+		t.Fatalf("import = %s with %d created and %d failed: %s", imported.Status, imported.Created, imported.Failed, imported.Error)
+	}
+	if len(files.objects) != 0 {
+		t.Errorf("import file kept after the import: %v", files.objects)
+	}
+
+	job, err = front.StartExport(ctx, "user-1", "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := front.OpenExport(ctx, job.ID, "user-1"); errorCode(err) != "CONFLICT" {
+		t.Errorf("OpenExport before the export = %v, want CONFLICT", err)
+	}
+	worker.runJob(ctx, job.ID)
+	_, file, err := front.OpenExport(ctx, job.ID, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, _ := io.ReadAll(file)
+	file.Close()
+	if !strings.Contains(string(exported), "The Go Programming Language") || !strings.Contains(string(exported), "Go in Action") {
+		t.Errorf("export = %s", exported)
+	}
+
+	if err := front.DeleteJob(ctx, job.ID, "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(files.objects) != 0 {
+		t.Errorf("export file kept after the job was deleted: %v", files.objects)
+	}
+}
+
+func TestStartImportRejectsInvalidFiles(t *testing.T) {
+	jobs := &fakeJobRepository{jobs: make(map[uint]*entities.BookJob)}
+	files := &memoryStorage{objects: make(map[string][]byte)}
+	s := NewBookTransferService(jobs, nil, nil, BookTransferOptions{Storage: files, MaxBytes: 10})
+
+	for _, content := range []string{"", "title,author\nx"} {
+		if _, err := s.StartImport(context.Background(), "user-1", "csv", false, strings.NewReader(content)); errorCode(err) != "VALIDATION_ERROR" {
+			t.Errorf("StartImport(%q) = %v, want VALIDATION_ERROR", content, err)
+		}
+	}
+	if len(jobs.jobs) != 0 || len(files.objects) != 0 {
+		t.Errorf("%d jobs and %d files left by rejected imports", len(jobs.jobs), len(files.objects))
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"clean-arch-go/internal/domain/entities"
+
+	"golang.org/x/text/language"
+)
+
+// translatedBookRepository keeps the translations of the books in memory
+type translatedBookRepository struct {
+	*fakeBookRepository
+	translations []*entities.BookTranslation
+}
+
+func (r *translatedBookRepository) ListTranslations(ctx context.Context, bookIDs ...string) ([]*entities.BookTranslation, error) {
+	var found []*entities.BookTranslation
+	for _, translation := range r.translations {
+		for _, id := range bookIDs {
+			if translation.BookID == id {
+				found = append(found, translation)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (r *translatedBookRepository) FindTranslation(ctx context.Context, bookID, lang string) (*entities.BookTranslation, error) {
+	for _, translation := range r.translations {
+		if translation.BookID == bookID && translation.Lang == lang {
+			return translation, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *translatedBookRepository) DeleteTranslation(ctx context.Context, bookID, lang string) error {
+	for i, translation := range r.translations {
+		if translation.BookID == bookID && translation.Lang == lang {
+			r.translations = append(r.translations[:i], r.translations[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func newTranslatedBookService(translations ...*entities.BookTranslation) (BookService, *translatedBookRepository) {
+	books := &translatedBookRepository{
+		fakeBookRepository: newFakeBookRepository(&entities.Book{ID: "book-1", UserID: "user-1", Title: "Dune", Description: "A desert planet", Lang: "en"}),
+		translations:       translations,
+	}
+	return NewBookService(books, nil, nil, books.revisions, nil, nil, nil, nil, nil), books
+}
+
+func TestLocalizeBooksMatchesAcceptLanguage(t *testing.T) {
+	s, _ := newTranslatedBookService(
+		&entities.BookTranslation{BookID: "book-1", Lang: "fr", Title: "Dune (fr)", Description: "Une planète désert"},
+		&entities.BookTranslation{BookID: "book-1", Lang: "pt-BR", Title: "Duna", Description: "Um planeta deserto"},
+		&entities.BookTranslation{BookID: "book-1", Lang: "zh-Hant", Title: "沙丘", Description: "沙漠星球"},
+	)
+
+	tests := []struct {
+		acceptLanguage string
+		title          string
+		contentLang    string
+	}{
+		{acceptLanguage: "", title: "Dune", contentLang: "en"},
+		{acceptLanguage: "fr-CA,fr;q=0.9", title: "Dune (fr)", contentLang: "fr"},
+		{acceptLanguage: "de,fr;q=0.5", title: "Dune (fr)", contentLang: "fr"},
+		{acceptLanguage: "pt", title: "Duna", contentLang: "pt-BR"},
+		{acceptLanguage: "zh-TW", title: "沙丘", contentLang: "zh-Hant"},
+		// The original wins over a less preferred translation
+		{acceptLanguage: "en-GB,fr;q=0.8", title: "Dune", contentLang: "en"},
+		{acceptLanguage: "fr;q=0.2,en;q=0.9", title: "Dune", contentLang: "en"},
+		// Nothing matches, the original is kept
+		{acceptLanguage: "ja", title: "Dune", contentLang: "en"},
+	}
+	for _, test := range tests {
+		prefs, _, err := language.ParseAcceptLanguage(test.acceptLanguage)
+		if err != nil {
+			t.Fatalf("ParseAcceptLanguage(%q): %v", test.acceptLanguage, err)
+		}
+		b := &entities.Book{ID: "book-1", Title: "Dune", Description: "A desert planet", Lang: "en"}
+		if err := s.LocalizeBooks(context.Background(), prefs, b); err != nil {
+			t.Fatal(err)
+		}
+		if b.Title != test.title || b.ContentLang != test.contentLang {
+			t.Errorf("Accept-Language %q: %q in %q, want %q in %q", test.acceptLanguage, b.Title, b.ContentLang, test.title, test.contentLang)
+		}
+	}
+}
+
+func TestLocalizeBooksKeepsUntranslatedBooks(t *testing.T) {
+	s, _ := newTranslatedBookService(&entities.BookTranslation{BookID: "book-1", Lang: "fr", Title: "Dune (fr)"})
+
+	translated := &entities.Book{ID: "book-1", Title: "Dune", Lang: "en"}
+	untranslated := &entities.Book{ID: "book-2", Title: "Emma", Lang: "en"}
+	if err := s.LocalizeBooks(context.Background(), []language.Tag{language.French}, translated, untranslated); err != nil {
+		t.Fatal(err)
+	}
+	if translated.Title != "Dune (fr)" || untranslated.Title != "Emma" || untranslated.ContentLang != "en" {
+		t.Errorf("localized %q and %q in %q", translated.Title, untranslated.Title, untranslated.ContentLang)
+	}
+}
+
+func TestDeleteBookTranslationNormalizesTheLanguage(t *testing.T) {
+	s, books := newTranslatedBookService(&entities.BookTranslation{BookID: "book-1", Lang: "pt-BR", Title: "Duna"})
+
+	if err := s.DeleteBookTranslation(context.Background(), "book-1", "PT-br"); err != nil {
+		t.Fatalf("DeleteBookTranslation(PT-br) = %v", err)
+	}
+	if len(books.translations) != 0 {
+		t.Errorf("translations left: %+v", books.translations)
+	}
+
+	for lang, code := range map[string]string{"": "VALIDATION_ERROR", "not a language!": "VALIDATION_ERROR", "fr": "NOT_FOUND"} {
+		if err := s.DeleteBookTranslation(context.Background(), "book-1", lang); errorCode(err) != code {
+			t.Errorf("DeleteBookTranslation(%q) = %v, want %s", lang, err, code)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+)
+
+// purgeBatchSize is how many expired books are purged at once
+const purgeBatchSize = 100
+
+// BookTrashService manages the deleted books, which stay in the trash of
+// their owner until restored or purged, by hand or once the retention
+// period is over
+type BookTrashService interface {
+	ListTrash(ctx context.Context, userID string, page, limit int) ([]*entities.Book, int64, error)
+	RestoreBook(ctx context.Context, userID, id string) (*entities.Book, error)
+	PurgeBook(ctx context.Context, userID, id string) error
+	PurgeExpired(ctx context.Context) error
+}
+
+type bookTrashService struct {
+	bookRepo        repository.BookRepository
+	coverSvc        BookCoverService
+	notificationSvc NotificationService
+	retention       time.Duration
+	listeners       []BookEventListener
+}
+
+// NewBookTrashService creates a service purging the books deleted for longer
+// than retention, never when it isn't positive
+func NewBookTrashService(
+	bookRepo repository.BookRepository,
+	coverSvc BookCoverService,
+	notificationSvc NotificationService,
+	retention time.Duration,
+	listeners ...BookEventListener,
+) BookTrashService {
+	return &bookTrashService{
+		bookRepo:        bookRepo,
+		coverSvc:        coverSvc,
+		notificationSvc: notificationSvc,
+		retention:       retention,
+		listeners:       listeners,
+	}
+}
+
+// ListTrash returns a page of the deleted books of the user, last deleted first
+func (s *bookTrashService) ListTrash(ctx context.Context, userID string, page, limit int) ([]*entities.Book, int64, error) {
+	return s.bookRepo.ListDeleted(ctx, userID, page, limit)
+}
+
+// RestoreBook takes a book of the user out of the trash
+func (s *bookTrashService) RestoreBook(ctx context.Context, userID, id string) (*entities.Book, error) {
+	if _, err := s.trashedBook(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	if err := s.bookRepo.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+
+	restored, err := s.bookRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if restored == nil {
+		return nil, errors.NewAppError("NOT_FOUND", "Book not found", nil)
+	}
+	for _, listener := range s.listeners {
+		listener.BookSaved(ctx, restored)
+	}
+	return restored, nil
+}
+
+// PurgeBook deletes a book of the user in the trash for good, unless a copy
+// of it is on loan
+func (s *bookTrashService) PurgeBook(ctx context.Context, userID, id string) error {
+	b, err := s.trashedBook(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	return s.purge(ctx, b)
+}
+
+// PurgeExpired purges the books deleted for longer than the retention
+// period, except the ones on loan, which wait for their copies to be returned
+func (s *bookTrashService) PurgeExpired(ctx context.Context) error {
+	if s.retention <= 0 {
+		return nil
+	}
+
+	before := time.Now().Add(-s.retention)
+	purged := 0
+	for {
+		books, err := s.bookRepo.ListDeletedBefore(ctx, before, purgeBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, b := range books {
+			if err := s.purge(ctx, b); err != nil {
+				return err
+			}
+			purged++
+		}
+		if len(books) < purgeBatchSize {
+			break
+		}
+	}
+
+	if purged > 0 {
+		log.Printf("Purged %d books deleted before %s", purged, before.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// purge deletes a book for good and tells the users waiting for it that
+// their hold is cancelled. Failed notifications are logged: the book is gone.
+func (s *bookTrashService) purge(ctx context.Context, b *entities.Book) error {
+	holds, err := s.bookRepo.Purge(ctx, b.ID)
+	if err != nil {
+		return err
+	}
+	s.coverSvc.DeleteCoverFiles(ctx, b)
+
+	for _, hold := range holds {
+		if err := s.notificationSvc.Notify(ctx, &entities.Notification{
+			UserID:  hold.UserID,
+			Kind:    entities.NotificationHoldCancelled,
+			Message: fmt.Sprintf("%q was removed from the library, so your hold on it is cancelled", b.Title),
+		}); err != nil {
+			log.Printf("Failed to notify user %s of cancelled hold %d: %v", hold.UserID, hold.ID, err)
+		}
+	}
+	return nil
+}
+
+// trashedBook returns a deleted book of the user. The trash of other users
+// is none of the caller's business.
+func (s *bookTrashService) trashedBook(ctx context.Context, userID, id string) (*entities.Book, error) {
+	b, err := s.bookRepo.FindDeleted(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil || b.UserID != userID {
+		return nil, errors.NewAppError("NOT_FOUND", "Book not found in the trash", nil)
+	}
+	return b, nil
+}
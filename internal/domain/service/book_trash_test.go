@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+)
+
+// trashRepository keeps deleted books, some of them on loan or held
+type trashRepository struct {
+	repository.BookRepository
+	deleted  map[string]*entities.Book
+	restored map[string]*entities.Book
+	onLoan   map[string]bool
+	holds    map[string][]*entities.Hold
+	purged   []string
+	// batches holds the limits ListDeletedBefore was called with
+	batches []int
+}
+
+func newTrashRepository(books ...*entities.Book) *trashRepository {
+	r := &trashRepository{
+		deleted:  make(map[string]*entities.Book),
+		restored: make(map[string]*entities.Book),
+		onLoan:   make(map[string]bool),
+		holds:    make(map[string][]*entities.Hold),
+	}
+	for _, b := range books {
+		r.deleted[b.ID] = b
+	}
+	return r
+}
+
+func (r *trashRepository) FindDeleted(ctx context.Context, id string) (*entities.Book, error) {
+	return r.deleted[id], nil
+}
+
+func (r *trashRepository) FindByID(ctx context.Context, id string) (*entities.Book, error) {
+	return r.restored[id], nil
+}
+
+func (r *trashRepository) Restore(ctx context.Context, id string) error {
+	r.restored[id] = r.deleted[id]
+	delete(r.deleted, id)
+	return nil
+}
+
+// ListDeletedBefore returns the deleted books in the order of their IDs,
+// leaving the ones on loan out
+func (r *trashRepository) ListDeletedBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Book, error) {
+	r.batches = append(r.batches, limit)
+	var books []*entities.Book
+	for i := 0; len(books) < limit && i < 1000; i++ {
+		if b := r.deleted[fmt.Sprintf("book-%03d", i)]; b != nil && !r.onLoan[b.ID] {
+			books = append(books, b)
+		}
+	}
+	return books, nil
+}
+
+func (r *trashRepository) Purge(ctx context.Context, id string) ([]*entities.Hold, error) {
+	if r.onLoan[id] {
+		return nil, errors.NewAppError("CONFLICT", "A copy of this book is on loan, it can be purged once returned", nil)
+	}
+	delete(r.deleted, id)
+	r.purged = append(r.purged, id)
+	holds := r.holds[id]
+	delete(r.holds, id)
+	return holds, nil
+}
+
+// coverFiles records the books whose cover files were deleted
+type coverFiles struct {
+	BookCoverService
+	deleted []string
+}
+
+func (c *coverFiles) DeleteCoverFiles(ctx context.Context, b *entities.Book) {
+	c.deleted = append(c.deleted, b.ID)
+}
+
+// sentNotifications records the notifications sent
+type sentNotifications struct {
+	NotificationService
+	sent []*entities.Notification
+}
+
+func (n *sentNotifications) Notify(ctx context.Context, notification *entities.Notification) error {
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+// savedBooks records the books the listeners were told about
+type savedBooks struct {
+	ids []string
+}
+
+func (l *savedBooks) BookSaved(ctx context.Context, b *entities.Book) {
+	l.ids = append(l.ids, b.ID)
+}
+
+func (l *savedBooks) BookDeleted(ctx context.Context, id string) {}
+
+func TestRestoreBook(t *testing.T) {
+	books := newTrashRepository(&entities.Book{ID: "book-1", UserID: "user-1", Title: "Dune"})
+	listener := &savedBooks{}
+	s := NewBookTrashService(books, &coverFiles{}, &sentNotifications{}, 0, listener)
+
+	if _, err := s.RestoreBook(context.Background(), "user-2", "book-1"); errorCode(err) != "NOT_FOUND" {
+		t.Errorf("restore by another user = %v, want NOT_FOUND", err)
+	}
+	b, err := s.RestoreBook(context.Background(), "user-1", "book-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Title != "Dune" || books.deleted["book-1"] != nil {
+		t.Errorf("restored %+v, still deleted: %v", b, books.deleted["book-1"] != nil)
+	}
+	if len(listener.ids) != 1 || listener.ids[0] != "book-1" {
+		t.Errorf("listeners told about %v, want the restored book", listener.ids)
+	}
+	if _, err := s.RestoreBook(context.Background(), "user-1", "book-1"); errorCode(err) != "NOT_FOUND" {
+		t.Errorf("second restore = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestPurgeBookOfAnotherUser(t *testing.T) {
+	books := newTrashRepository(&entities.Book{ID: "book-1", UserID: "user-1"})
+	s := NewBookTrashService(books, &coverFiles{}, &sentNotifications{}, 0)
+
+	if err := s.PurgeBook(context.Background(), "user-2", "book-1"); errorCode(err) != "NOT_FOUND" {
+		t.Errorf("purge = %v, want NOT_FOUND", err)
+	}
+	if len(books.purged) != 0 {
+		t.Errorf("purged %v", books.purged)
+	}
+}
+
+func TestPurgeExpiredInBatches(t *testing.T) {
+	var deleted []*entities.Book
+	for i := 0; i < 2*purgeBatchSize+50; i++ {
+		deleted = append(deleted, &entities.Book{ID: fmt.Sprintf("book-%03d", i), UserID: "user-1"})
+	}
+	books := newTrashRepository(deleted...)
+	books.onLoan["book-007"] = true
+	covers := &coverFiles{}
+	s := NewBookTrashService(books, covers, &sentNotifications{}, 30*24*time.Hour)
+
+	if err := s.PurgeExpired(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(books.purged) != 2*purgeBatchSize+49 || len(covers.deleted) != len(books.purged) {
+		t.Errorf("purged %d books and %d covers, want all but the one on loan", len(books.purged), len(covers.deleted))
+	}
+	if len(books.batches) != 3 || books.batches[0] != purgeBatchSize {
+		t.Errorf("batches = %v, want three of %d", books.batches, purgeBatchSize)
+	}
+	if books.deleted["book-007"] == nil {
+		t.Error("the book on loan was purged")
+	}
+}
+
+func TestPurgeExpiredWithoutRetention(t *testing.T) {
+	books := newTrashRepository(&entities.Book{ID: "book-000", UserID: "user-1"})
+	s := NewBookTrashService(books, &coverFiles{}, &sentNotifications{}, 0)
+
+	if err := s.PurgeExpired(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(books.batches) != 0 || len(books.purged) != 0 {
+		t.Errorf("purged %v without a retention period", books.purged)
+	}
+}
+
+func TestPurgeTellsTheWaitingUsers(t *testing.T) {
+	books := newTrashRepository(&entities.Book{ID: "book-1", UserID: "user-1", Title: "Dune"})
+	books.holds["book-1"] = []*entities.Hold{
+		{ID: 5, BookID: "book-1", UserID: "user-2", Status: entities.HoldReady},
+		{ID: 6, BookID: "book-1", UserID: "user-3", Status: entities.HoldWaiting},
+	}
+	covers := &coverFiles{}
+	notifications := &sentNotifications{}
+	s := NewBookTrashService(books, covers, notifications, 0)
+
+	if err := s.PurgeBook(context.Background(), "user-1", "book-1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications.sent) != 2 {
+		t.Fatalf("%d notifications, want one per hold", len(notifications.sent))
+	}
+	for i, userID := range []string{"user-2", "user-3"} {
+		if sent := notifications.sent[i]; sent.UserID != userID || sent.Kind != entities.NotificationHoldCancelled {
+			t.Errorf("notification %d = %+v, want the hold of %s cancelled", i, sent, userID)
+		}
+	}
+	if len(covers.deleted) != 1 {
+		t.Errorf("cover files deleted for %v", covers.deleted)
+	}
+}
+
+func TestPurgeOfABookOnLoanIsRefused(t *testing.T) {
+	books := newTrashRepository(&entities.Book{ID: "book-1", UserID: "user-1"})
+	books.onLoan["book-1"] = true
+	covers := &coverFiles{}
+	s := NewBookTrashService(books, covers, &sentNotifications{}, 0)
+
+	if err := s.PurgeBook(context.Background(), "user-1", "book-1"); errorCode(err) != "CONFLICT" {
+		t.Errorf("purge = %v, want CONFLICT", err)
+	}
+	if books.deleted["book-1"] == nil || len(covers.deleted) != 0 {
+		t.Error("the book on loan was purged")
+	}
+}
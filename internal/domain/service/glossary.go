@@ -0,0 +1,138 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"clean-arch-go/internal/domain/entities"
+)
+
+// glossaryPlaceholder is the token substituted for glossary terms before the
+// text is sent to the translation provider. Providers leave it untouched.
+const glossaryPlaceholder = "⟦%d⟧"
+
+// glossaryPlaceholders matches the placeholders put in place of the terms
+var glossaryPlaceholders = regexp.MustCompile(`⟦(\d+)⟧`)
+
+// glossaryMask records the replacements made in a text so they can be
+// restored in the translated text
+type glossaryMask struct {
+	replacements []string
+}
+
+// applyGlossary replaces every glossary term found in text with a placeholder.
+// All terms are matched in a single pass, longest first, so "New York Times"
+// wins over "New York" and a replacement is never matched again. When the
+// longest term found at a position is part of a larger word, the shorter
+// terms found there are tried in turn.
+func applyGlossary(text string, terms []*entities.GlossaryTerm) (string, *glossaryMask) {
+	mask := &glossaryMask{}
+
+	sorted := make([]*entities.GlossaryTerm, 0, len(terms))
+	for _, term := range terms {
+		if strings.TrimSpace(term.Term) != "" {
+			sorted = append(sorted, term)
+		}
+	}
+	if len(sorted) == 0 {
+		return text, mask
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return utf8.RuneCountInString(sorted[i].Term) > utf8.RuneCountInString(sorted[j].Term)
+	})
+
+	// candidates finds where a term may start, anchored which terms match there
+	alternatives := make([]string, len(sorted))
+	anchored := make([]*regexp.Regexp, len(sorted))
+	for i, term := range sorted {
+		flags := "i"
+		if term.CaseSensitive {
+			flags = "-i"
+		}
+		alternatives[i] = fmt.Sprintf("(?%s:%s)", flags, regexp.QuoteMeta(term.Term))
+		anchored[i] = regexp.MustCompile(`\A` + alternatives[i])
+	}
+	candidates := regexp.MustCompile(strings.Join(alternatives, "|"))
+
+	var b strings.Builder
+	last, pos := 0, 0
+	for pos < len(text) {
+		loc := candidates.FindStringIndex(text[pos:])
+		if loc == nil {
+			break
+		}
+		start := pos + loc[0]
+
+		term, end := matchTermAt(text, start, sorted, anchored)
+		if term == nil {
+			_, size := utf8.DecodeRuneInString(text[start:])
+			pos = start + size
+			continue
+		}
+
+		replacement := term.Translation
+		if term.Protected {
+			replacement = text[start:end]
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(fmt.Sprintf(glossaryPlaceholder, len(mask.replacements)))
+		mask.replacements = append(mask.replacements, replacement)
+		last, pos = end, end
+	}
+	b.WriteString(text[last:])
+
+	return b.String(), mask
+}
+
+// matchTermAt returns the longest term found at text[start:] that is not part
+// of a larger word, with where it ends
+func matchTermAt(text string, start int, sorted []*entities.GlossaryTerm, anchored []*regexp.Regexp) (*entities.GlossaryTerm, int) {
+	for i, re := range anchored {
+		loc := re.FindStringIndex(text[start:])
+		if loc != nil && isWordBoundary(text, start, start+loc[1]) {
+			return sorted[i], start + loc[1]
+		}
+	}
+	return nil, 0
+}
+
+// restore puts the glossary translations back in place of the placeholders,
+// in a single pass so that a translation is never taken for a placeholder
+func (m *glossaryMask) restore(text string) string {
+	if len(m.replacements) == 0 {
+		return text
+	}
+	return glossaryPlaceholders.ReplaceAllStringFunc(text, func(placeholder string) string {
+		i, err := strconv.Atoi(glossaryPlaceholders.FindStringSubmatch(placeholder)[1])
+		if err != nil || i >= len(m.replacements) {
+			return placeholder
+		}
+		return m.replacements[i]
+	})
+}
+
+// isWordBoundary reports whether text[start:end] is not part of a larger word
+func isWordBoundary(text string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(text[:start])
+		if isWordRune(r) {
+			return false
+		}
+	}
+	if end < len(text) {
+		r, _ := utf8.DecodeRuneInString(text[end:])
+		if isWordRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+)
+
+// GlossaryService defines the interface for managing translation glossaries.
+// The users of a tenant can read the glossaries shared with it, only their
+// owner can change them.
+type GlossaryService interface {
+	CreateGlossary(ctx context.Context, glossary *entities.Glossary) error
+	GetGlossary(ctx context.Context, id uint, userID, tenantID string) (*entities.Glossary, error)
+	ListGlossaries(ctx context.Context, userID, tenantID string) ([]*entities.Glossary, error)
+	UpdateGlossary(ctx context.Context, id uint, userID, name, tenantID string) (*entities.Glossary, error)
+	DeleteGlossary(ctx context.Context, id uint, userID string) error
+	AddTerm(ctx context.Context, glossaryID uint, userID string, term *entities.GlossaryTerm) error
+	UpdateTerm(ctx context.Context, glossaryID uint, userID string, term *entities.GlossaryTerm) error
+	DeleteTerm(ctx context.Context, glossaryID, termID uint, userID string) error
+	ImportTermsCSV(ctx context.Context, glossaryID uint, userID string, r io.Reader) (int, error)
+}
+
+type glossaryService struct {
+	glossaryRepo repository.GlossaryRepository
+}
+
+func NewGlossaryService(glossaryRepo repository.GlossaryRepository) GlossaryService {
+	return &glossaryService{
+		glossaryRepo: glossaryRepo,
+	}
+}
+
+func (s *glossaryService) CreateGlossary(ctx context.Context, glossary *entities.Glossary) error {
+	if strings.TrimSpace(glossary.Name) == "" {
+		return errors.NewValidationError("name", "Name is required")
+	}
+	for i := range glossary.Terms {
+		if err := validateTerm(&glossary.Terms[i]); err != nil {
+			return err
+		}
+	}
+	return s.glossaryRepo.Create(ctx, glossary)
+}
+
+// GetGlossary returns a glossary of the user or shared with their tenant
+func (s *glossaryService) GetGlossary(ctx context.Context, id uint, userID, tenantID string) (*entities.Glossary, error) {
+	glossary, err := s.glossaryRepo.FindByID(ctx, strconv.FormatUint(uint64(id), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	if glossary.UserID != userID && (tenantID == "" || glossary.TenantID != tenantID) {
+		return nil, errors.NewAppError("UNAUTHORIZED", "You are not authorized to access this glossary", nil)
+	}
+
+	return glossary, nil
+}
+
+// ownGlossary returns a glossary the user owns, for them to change it
+func (s *glossaryService) ownGlossary(ctx context.Context, id uint, userID string) (*entities.Glossary, error) {
+	glossary, err := s.glossaryRepo.FindByID(ctx, strconv.FormatUint(uint64(id), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	if glossary.UserID != userID {
+		return nil, errors.NewAppError("UNAUTHORIZED", "You are not authorized to change this glossary", nil)
+	}
+
+	return glossary, nil
+}
+
+func (s *glossaryService) ListGlossaries(ctx context.Context, userID, tenantID string) ([]*entities.Glossary, error) {
+	return s.glossaryRepo.ListForUser(ctx, userID, tenantID)
+}
+
+// UpdateGlossary renames a glossary and shares it with tenantID, or stops
+// sharing it when tenantID is empty
+func (s *glossaryService) UpdateGlossary(ctx context.Context, id uint, userID, name, tenantID string) (*entities.Glossary, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.NewValidationError("name", "Name is required")
+	}
+
+	glossary, err := s.ownGlossary(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	glossary.Name = name
+	glossary.TenantID = tenantID
+	glossary.Terms = nil
+	if err := s.glossaryRepo.Update(ctx, glossary); err != nil {
+		return nil, err
+	}
+	return glossary, nil
+}
+
+func (s *glossaryService) DeleteGlossary(ctx context.Context, id uint, userID string) error {
+	if _, err := s.ownGlossary(ctx, id, userID); err != nil {
+		return err
+	}
+	return s.glossaryRepo.Delete(ctx, strconv.FormatUint(uint64(id), 10))
+}
+
+func (s *glossaryService) AddTerm(ctx context.Context, glossaryID uint, userID string, term *entities.GlossaryTerm) error {
+	if _, err := s.ownGlossary(ctx, glossaryID, userID); err != nil {
+		return err
+	}
+
+	term.GlossaryID = glossaryID
+	if err := validateTerm(term); err != nil {
+		return err
+	}
+	return s.glossaryRepo.CreateTerms(ctx, []*entities.GlossaryTerm{term})
+}
+
+func (s *glossaryService) UpdateTerm(ctx context.Context, glossaryID uint, userID string, term *entities.GlossaryTerm) error {
+	if _, err := s.ownGlossary(ctx, glossaryID, userID); err != nil {
+		return err
+	}
+
+	existingTerm, err := s.glossaryRepo.FindTerm(ctx, glossaryID, term.ID)
+	if err != nil {
+		return err
+	}
+
+	existingTerm.Term = term.Term
+	existingTerm.TargetLang = term.TargetLang
+	existingTerm.Translation = term.Translation
+	existingTerm.Protected = term.Protected
+	existingTerm.CaseSensitive = term.CaseSensitive
+	if err := validateTerm(existingTerm); err != nil {
+		return err
+	}
+
+	if err := s.glossaryRepo.UpdateTerm(ctx, existingTerm); err != nil {
+		return err
+	}
+	*term = *existingTerm
+	return nil
+}
+
+func (s *glossaryService) DeleteTerm(ctx context.Context, glossaryID, termID uint, userID string) error {
+	if _, err := s.ownGlossary(ctx, glossaryID, userID); err != nil {
+		return err
+	}
+	if _, err := s.glossaryRepo.FindTerm(ctx, glossaryID, termID); err != nil {
+		return err
+	}
+	return s.glossaryRepo.DeleteTerm(ctx, glossaryID, termID)
+}
+
+// ImportTermsCSV adds the terms of a CSV file to a glossary. The columns are
+// term, target_lang, translation, protected and an optional case_sensitive;
+// a header row is skipped. No term is imported when any row is invalid.
+func (s *glossaryService) ImportTermsCSV(ctx context.Context, glossaryID uint, userID string, r io.Reader) (int, error) {
+	if _, err := s.ownGlossary(ctx, glossaryID, userID); err != nil {
+		return 0, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var terms []*entities.GlossaryTerm
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, errors.NewBadRequestError(fmt.Sprintf("Invalid CSV: %v", err))
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "term") {
+			continue
+		}
+
+		term, err := parseTermRecord(record)
+		if err != nil {
+			return 0, errors.NewValidationError(fmt.Sprintf("line %d", line), err.Error())
+		}
+		term.GlossaryID = glossaryID
+		if err := validateTerm(term); err != nil {
+			return 0, errors.NewValidationError(fmt.Sprintf("line %d", line), err.(*errors.AppError).Message)
+		}
+		terms = append(terms, term)
+	}
+
+	if err := s.glossaryRepo.CreateTerms(ctx, terms); err != nil {
+		return 0, err
+	}
+	return len(terms), nil
+}
+
+func parseTermRecord(record []string) (*entities.GlossaryTerm, error) {
+	if len(record) < 3 {
+		return nil, fmt.Errorf("expected at least 3 columns, got %d", len(record))
+	}
+
+	term := &entities.GlossaryTerm{
+		Term:        strings.TrimSpace(record[0]),
+		TargetLang:  strings.TrimSpace(record[1]),
+		Translation: strings.TrimSpace(record[2]),
+	}
+
+	var err error
+	if len(record) > 3 && strings.TrimSpace(record[3]) != "" {
+		if term.Protected, err = strconv.ParseBool(strings.TrimSpace(record[3])); err != nil {
+			return nil, fmt.Errorf("invalid protected value %q", record[3])
+		}
+	}
+	if len(record) > 4 && strings.TrimSpace(record[4]) != "" {
+		if term.CaseSensitive, err = strconv.ParseBool(strings.TrimSpace(record[4])); err != nil {
+			return nil, fmt.Errorf("invalid case_sensitive value %q", record[4])
+		}
+	}
+	return term, nil
+}
+
+func validateTerm(term *entities.GlossaryTerm) error {
+	if strings.TrimSpace(term.Term) == "" {
+		return errors.NewValidationError("term", "Term is required")
+	}
+	if !term.Protected && strings.TrimSpace(term.Translation) == "" {
+		return errors.NewValidationError("translation", "Translation is required unless the term is protected")
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+)
+
+// fakeGlossaryRepository keeps glossaries in memory
+type fakeGlossaryRepository struct {
+	repository.GlossaryRepository
+	glossaries map[string]*entities.Glossary
+	terms      []*entities.GlossaryTerm
+}
+
+func newFakeGlossaryRepository(glossaries ...*entities.Glossary) *fakeGlossaryRepository {
+	r := &fakeGlossaryRepository{glossaries: make(map[string]*entities.Glossary)}
+	for _, g := range glossaries {
+		r.glossaries[strconv.FormatUint(uint64(g.ID), 10)] = g
+	}
+	return r
+}
+
+func (r *fakeGlossaryRepository) FindByID(ctx context.Context, id string) (*entities.Glossary, error) {
+	g, ok := r.glossaries[id]
+	if !ok {
+		return nil, errors.NewNotFoundError("glossary")
+	}
+	copied := *g
+	return &copied, nil
+}
+
+func (r *fakeGlossaryRepository) Update(ctx context.Context, g *entities.Glossary) error {
+	r.glossaries[strconv.FormatUint(uint64(g.ID), 10)] = g
+	return nil
+}
+
+func (r *fakeGlossaryRepository) CreateTerms(ctx context.Context, terms []*entities.GlossaryTerm) error {
+	r.terms = append(r.terms, terms...)
+	return nil
+}
+
+func TestSharedGlossariesAreReadByTheTenant(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeGlossaryRepository(&entities.Glossary{ID: 1, UserID: "owner", TenantID: "acme", Name: "Brands"})
+	s := NewGlossaryService(repo)
+
+	if _, err := s.GetGlossary(ctx, 1, "member", "acme"); err != nil {
+		t.Errorf("GetGlossary by a member of the tenant = %v", err)
+	}
+	for _, tenantID := range []string{"", "other"} {
+		if _, err := s.GetGlossary(ctx, 1, "stranger", tenantID); errorCode(err) != "UNAUTHORIZED" {
+			t.Errorf("GetGlossary from tenant %q = %v, want UNAUTHORIZED", tenantID, err)
+		}
+	}
+}
+
+func TestSharedGlossariesAreChangedByTheirOwnerOnly(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeGlossaryRepository(&entities.Glossary{ID: 1, UserID: "owner", TenantID: "acme", Name: "Brands"})
+	s := NewGlossaryService(repo)
+
+	if _, err := s.UpdateGlossary(ctx, 1, "member", "Mine", "acme"); errorCode(err) != "UNAUTHORIZED" {
+		t.Errorf("UpdateGlossary by a member = %v, want UNAUTHORIZED", err)
+	}
+	if err := s.AddTerm(ctx, 1, "member", &entities.GlossaryTerm{Term: "Acme", Protected: true}); errorCode(err) != "UNAUTHORIZED" {
+		t.Errorf("AddTerm by a member = %v, want UNAUTHORIZED", err)
+	}
+	if len(repo.terms) != 0 {
+		t.Errorf("terms added: %v", repo.terms)
+	}
+
+	g, err := s.UpdateGlossary(ctx, 1, "owner", "Brands", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.TenantID != "" || repo.glossaries["1"].TenantID != "" {
+		t.Errorf("the glossary is still shared with %q", repo.glossaries["1"].TenantID)
+	}
+	if _, err := s.GetGlossary(ctx, 1, "member", "acme"); errorCode(err) != "UNAUTHORIZED" {
+		t.Errorf("GetGlossary by a member after unsharing = %v, want UNAUTHORIZED", err)
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"clean-arch-go/internal/domain/entities"
+)
+
+func TestApplyGlossary(t *testing.T) {
+	forced := func(term, translation string) *entities.GlossaryTerm {
+		return &entities.GlossaryTerm{Term: term, Translation: translation}
+	}
+	protected := func(term string) *entities.GlossaryTerm {
+		return &entities.GlossaryTerm{Term: term, Protected: true}
+	}
+
+	tests := []struct {
+		name         string
+		text         string
+		terms        []*entities.GlossaryTerm
+		masked       string
+		replacements []string
+	}{
+		{
+			name:   "no terms",
+			text:   "Read Dune",
+			masked: "Read Dune",
+		},
+		{
+			name:         "forced translation",
+			text:         "Read Dune today",
+			terms:        []*entities.GlossaryTerm{forced("Dune", "Duna")},
+			masked:       "Read ⟦0⟧ today",
+			replacements: []string{"Duna"},
+		},
+		{
+			name:         "protected terms keep the text found",
+			text:         "ACME and acme",
+			terms:        []*entities.GlossaryTerm{protected("Acme")},
+			masked:       "⟦0⟧ and ⟦1⟧",
+			replacements: []string{"ACME", "acme"},
+		},
+		{
+			name:         "case-sensitive terms",
+			text:         "Go go",
+			terms:        []*entities.GlossaryTerm{{Term: "Go", Protected: true, CaseSensitive: true}},
+			masked:       "⟦0⟧ go",
+			replacements: []string{"Go"},
+		},
+		{
+			name:         "longest first",
+			text:         "The New York Times in New York",
+			terms:        []*entities.GlossaryTerm{forced("New York", "NY"), forced("New York Times", "NYT")},
+			masked:       "The ⟦0⟧ in ⟦1⟧",
+			replacements: []string{"NYT", "NY"},
+		},
+		{
+			name:         "shorter terms when the longest is part of a word",
+			text:         "New Yorkers love New York",
+			terms:        []*entities.GlossaryTerm{forced("New York", "NY"), forced("New", "Nouveau")},
+			masked:       "⟦0⟧ Yorkers love ⟦1⟧",
+			replacements: []string{"Nouveau", "NY"},
+		},
+		{
+			name:   "terms inside words",
+			text:   "Dunes and dunegrass",
+			terms:  []*entities.GlossaryTerm{forced("Dune", "Duna")},
+			masked: "Dunes and dunegrass",
+		},
+		{
+			name:         "terms after a rejected match",
+			text:         "Ago go",
+			terms:        []*entities.GlossaryTerm{protected("go")},
+			masked:       "Ago ⟦0⟧",
+			replacements: []string{"go"},
+		},
+		{
+			name:         "non-ASCII words",
+			text:         "Đà Lạt, Đà Lạtx",
+			terms:        []*entities.GlossaryTerm{protected("Đà Lạt")},
+			masked:       "⟦0⟧, Đà Lạtx",
+			replacements: []string{"Đà Lạt"},
+		},
+		{
+			name:         "the user's terms win over equally long ones",
+			text:         "Acme",
+			terms:        []*entities.GlossaryTerm{forced("Acme", "Mine"), forced("acme", "Tenant's")},
+			masked:       "⟦0⟧",
+			replacements: []string{"Mine"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			masked, mask := applyGlossary(test.text, test.terms)
+			if masked != test.masked {
+				t.Errorf("masked %q, want %q", masked, test.masked)
+			}
+			if !reflect.DeepEqual(mask.replacements, test.replacements) {
+				t.Errorf("replacements %q, want %q", mask.replacements, test.replacements)
+			}
+		})
+	}
+}
+
+func TestGlossaryMaskRestore(t *testing.T) {
+	mask := &glossaryMask{replacements: []string{"Duna", "⟦0⟧ NY", "x"}}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Lire ⟦0⟧", want: "Lire Duna"},
+		{text: "⟦1⟧, ⟦0⟧", want: "⟦0⟧ NY, Duna"},
+		{text: "⟦2⟧⟦2⟧", want: "xx"},
+		{text: "⟦3⟧ ⟦a⟧", want: "⟦3⟧ ⟦a⟧"},
+		{text: "no placeholder", want: "no placeholder"},
+	}
+	for _, test := range tests {
+		if got := mask.restore(test.text); got != test.want {
+			t.Errorf("restore(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+
+	if got := (&glossaryMask{}).restore("⟦0⟧"); got != "⟦0⟧" {
+		t.Errorf("restore without replacements = %q", got)
+	}
+}
+
+func TestApplyGlossaryRoundTrip(t *testing.T) {
+	terms := []*entities.GlossaryTerm{
+		{Term: "Go", Protected: true, CaseSensitive: true},
+		{Term: "The Go Programming Language", Translation: "Ngôn ngữ lập trình Go"},
+	}
+	masked, mask := applyGlossary("The Go Programming Language teaches Go.", terms)
+	if masked != "⟦0⟧ teaches ⟦1⟧." {
+		t.Fatalf("masked %q", masked)
+	}
+	// The provider translates around the placeholders
+	translated := "⟦0⟧ dạy ⟦1⟧."
+	if got, want := mask.restore(translated), "Ngôn ngữ lập trình Go dạy Go."; got != want {
+		t.Errorf("restored %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+)
+
+const maxCopyLabel = 100
+
+// LendingPolicy sets how long books are lent and kept for holds
+type LendingPolicy struct {
+	// LoanPeriod is how long a loan or a renewal lasts
+	LoanPeriod time.Duration
+	// MaxRenewals is how many times a loan can be renewed
+	MaxRenewals int
+	// HoldPickupPeriod is how long a returned copy is kept for the next hold
+	HoldPickupPeriod time.Duration
+}
+
+// LendingService lends the physical copies of books. Book owners manage
+// the copies, which the users who can see the book can borrow or queue for.
+type LendingService interface {
+	ListLibrary(ctx context.Context, userID string, page, limit int) ([]repository.LibraryEntry, int64, error)
+	AddCopy(ctx context.Context, userID string, bookCopy *entities.BookCopy) error
+	ListCopies(ctx context.Context, userID, bookID string) ([]*entities.BookCopy, error)
+	RemoveCopy(ctx context.Context, userID, bookID string, id uint) error
+	Borrow(ctx context.Context, userID, bookID string, copyID uint) (*entities.Loan, error)
+	ListLoans(ctx context.Context, filter repository.LoanFilter, page, limit int) ([]*entities.Loan, int64, error)
+	ListBookLoans(ctx context.Context, userID, bookID, status string, page, limit int) ([]*entities.Loan, int64, error)
+	RenewLoan(ctx context.Context, userID string, id uint) (*entities.Loan, error)
+	ReturnLoan(ctx context.Context, userID string, id uint) (*entities.Loan, error)
+	PlaceHold(ctx context.Context, userID, bookID string) (*entities.Hold, error)
+	ListHolds(ctx context.Context, userID string) ([]*entities.Hold, error)
+	ListBookHolds(ctx context.Context, userID, bookID string) ([]*entities.Hold, error)
+	CancelHold(ctx context.Context, userID string, id uint) error
+	Sweep(ctx context.Context) error
+}
+
+type lendingService struct {
+	lendingRepo     repository.LendingRepository
+	bookRepo        repository.BookRepository
+	accessPolicy    BookAccessPolicy
+	notificationSvc NotificationService
+	policy          LendingPolicy
+}
+
+func NewLendingService(
+	lendingRepo repository.LendingRepository,
+	bookRepo repository.BookRepository,
+	accessPolicy BookAccessPolicy,
+	notificationSvc NotificationService,
+	policy LendingPolicy,
+) LendingService {
+	return &lendingService{
+		lendingRepo:     lendingRepo,
+		bookRepo:        bookRepo,
+		accessPolicy:    accessPolicy,
+		notificationSvc: notificationSvc,
+		policy:          policy,
+	}
+}
+
+// ListLibrary returns a page of the lent books the user can see
+func (s *lendingService) ListLibrary(ctx context.Context, userID string, page, limit int) ([]repository.LibraryEntry, int64, error) {
+	return s.lendingRepo.ListLibrary(ctx, userID, page, limit)
+}
+
+// AddCopy adds a copy of a book of the user, handing it straight to the
+// first waiting hold
+func (s *lendingService) AddCopy(ctx context.Context, userID string, bookCopy *entities.BookCopy) error {
+	book, err := s.ownedBook(ctx, userID, bookCopy.BookID)
+	if err != nil {
+		return err
+	}
+	bookCopy.Label = strings.TrimSpace(bookCopy.Label)
+	if utf8.RuneCountInString(bookCopy.Label) > maxCopyLabel {
+		return errors.NewValidationError("label", "Label must be at most 100 characters")
+	}
+
+	hold, err := s.lendingRepo.CreateCopy(ctx, bookCopy, time.Now().Add(s.policy.HoldPickupPeriod))
+	if err != nil {
+		return err
+	}
+	if hold != nil {
+		s.notifyHold(ctx, hold, book)
+	}
+	return nil
+}
+
+func (s *lendingService) ListCopies(ctx context.Context, userID, bookID string) ([]*entities.BookCopy, error) {
+	if _, err := s.lentBook(ctx, userID, bookID); err != nil {
+		return nil, err
+	}
+	return s.lendingRepo.ListCopies(ctx, bookID)
+}
+
+// RemoveCopy removes an available copy of a book of the user
+func (s *lendingService) RemoveCopy(ctx context.Context, userID, bookID string, id uint) error {
+	if _, err := s.ownedBook(ctx, userID, bookID); err != nil {
+		return err
+	}
+	bookCopy, err := s.lendingRepo.FindCopy(ctx, id)
+	if err != nil {
+		return err
+	}
+	if bookCopy.BookID != bookID {
+		return errors.NewNotFoundError("copy")
+	}
+	return s.lendingRepo.DeleteCopy(ctx, id)
+}
+
+// Borrow lends a copy of a book to the user for the loan period: the copy
+// kept for their ready hold, copyID when set, or any available copy
+func (s *lendingService) Borrow(ctx context.Context, userID, bookID string, copyID uint) (*entities.Loan, error) {
+	if _, err := s.lentBook(ctx, userID, bookID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	loan := &entities.Loan{
+		CopyID:       copyID,
+		BookID:       bookID,
+		UserID:       userID,
+		CheckedOutAt: now,
+		DueAt:        now.Add(s.policy.LoanPeriod),
+	}
+	if err := s.lendingRepo.Checkout(ctx, loan); err != nil {
+		return nil, err
+	}
+	return loan, nil
+}
+
+func (s *lendingService) ListLoans(ctx context.Context, filter repository.LoanFilter, page, limit int) ([]*entities.Loan, int64, error) {
+	if err := validateLoanStatus(filter.Status); err != nil {
+		return nil, 0, err
+	}
+	filter.Now = time.Now()
+	loans, total, err := s.lendingRepo.ListLoans(ctx, filter, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, loan := range loans {
+		markOverdue(loan, filter.Now)
+	}
+	return loans, total, nil
+}
+
+// ListBookLoans returns the loans of a book of the user
+func (s *lendingService) ListBookLoans(ctx context.Context, userID, bookID, status string, page, limit int) ([]*entities.Loan, int64, error) {
+	if _, err := s.ownedBook(ctx, userID, bookID); err != nil {
+		return nil, 0, err
+	}
+	return s.ListLoans(ctx, repository.LoanFilter{BookID: bookID, Status: status}, page, limit)
+}
+
+// RenewLoan extends a loan of the user by the loan period from now, unless
+// it was renewed too many times or other users are waiting for the book
+func (s *lendingService) RenewLoan(ctx context.Context, userID string, id uint) (*entities.Loan, error) {
+	loan, err := s.lendingRepo.FindLoan(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if loan.UserID != userID {
+		return nil, errors.NewAppError("UNAUTHORIZED", "You are not authorized to renew this loan", nil)
+	}
+	if loan.ReturnedAt != nil {
+		return nil, errors.NewAppError("CONFLICT", "This loan was already returned", nil)
+	}
+	if loan.Renewals >= s.policy.MaxRenewals {
+		return nil, errors.NewAppError("CONFLICT", fmt.Sprintf("A loan can be renewed at most %d times", s.policy.MaxRenewals), nil)
+	}
+	waiting, err := s.lendingRepo.CountWaitingHolds(ctx, loan.BookID)
+	if err != nil {
+		return nil, err
+	}
+	if waiting > 0 {
+		return nil, errors.NewAppError("CONFLICT", "Other users are waiting for this book", nil)
+	}
+
+	now := time.Now()
+	dueAt := now.Add(s.policy.LoanPeriod)
+	if err := s.lendingRepo.RenewLoan(ctx, id, dueAt, s.policy.MaxRenewals); err != nil {
+		return nil, err
+	}
+	loan.DueAt = dueAt
+	loan.Renewals++
+	markOverdue(loan, now)
+	return loan, nil
+}
+
+// ReturnLoan ends a loan, by its borrower or the owner of the book, and
+// notifies the first user waiting for the book. The borrower can return the
+// copy even if they can no longer see the book.
+func (s *lendingService) ReturnLoan(ctx context.Context, userID string, id uint) (*entities.Loan, error) {
+	loan, err := s.lendingRepo.FindLoan(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	book, err := s.bookRepo.FindByID(ctx, loan.BookID)
+	if err != nil {
+		return nil, err
+	}
+	if book == nil {
+		return nil, errors.NewAppError("NOT_FOUND", "Book not found", nil)
+	}
+	if loan.UserID != userID && book.UserID != userID {
+		return nil, errors.NewAppError("UNAUTHORIZED", "You are not authorized to return this loan", nil)
+	}
+
+	now := time.Now()
+	returned, hold, err := s.lendingRepo.ReturnLoan(ctx, id, now, now.Add(s.policy.HoldPickupPeriod))
+	if err != nil {
+		return nil, err
+	}
+	if hold != nil {
+		s.notifyHold(ctx, hold, book)
+	}
+	return returned, nil
+}
+
+// PlaceHold queues the user for a book without available copies
+func (s *lendingService) PlaceHold(ctx context.Context, userID, bookID string) (*entities.Hold, error) {
+	if _, err := s.lentBook(ctx, userID, bookID); err != nil {
+		return nil, err
+	}
+	hold := &entities.Hold{BookID: bookID, UserID: userID}
+	if err := s.lendingRepo.PlaceHold(ctx, hold); err != nil {
+		return nil, err
+	}
+	return s.lendingRepo.FindHold(ctx, hold.ID)
+}
+
+// ListHolds returns the active holds of the user with their place in the queue
+func (s *lendingService) ListHolds(ctx context.Context, userID string) ([]*entities.Hold, error) {
+	return s.lendingRepo.ListHolds(ctx, repository.HoldFilter{UserID: userID})
+}
+
+// ListBookHolds returns the queue of a book of the user
+func (s *lendingService) ListBookHolds(ctx context.Context, userID, bookID string) ([]*entities.Hold, error) {
+	if _, err := s.ownedBook(ctx, userID, bookID); err != nil {
+		return nil, err
+	}
+	return s.lendingRepo.ListHolds(ctx, repository.HoldFilter{BookID: bookID})
+}
+
+// CancelHold withdraws a hold of the user, handing the copy kept for it to
+// the next user waiting
+func (s *lendingService) CancelHold(ctx context.Context, userID string, id uint) error {
+	hold, err := s.lendingRepo.FindHold(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hold.UserID != userID {
+		return errors.NewAppError("UNAUTHORIZED", "You are not authorized to cancel this hold", nil)
+	}
+
+	now := time.Now()
+	next, err := s.lendingRepo.CancelHold(ctx, id, now, now.Add(s.policy.HoldPickupPeriod))
+	if err != nil {
+		return err
+	}
+	if next != nil {
+		s.notifyHold(ctx, next, nil)
+	}
+	return nil
+}
+
+// Sweep expires the holds not picked up in time, passing their copies on,
+// and tells the borrowers of overdue loans once
+func (s *lendingService) Sweep(ctx context.Context) error {
+	now := time.Now()
+	expired, ready, err := s.lendingRepo.ExpireHolds(ctx, now, now.Add(s.policy.HoldPickupPeriod))
+	for _, hold := range expired {
+		s.notifyHold(ctx, hold, nil)
+	}
+	for _, hold := range ready {
+		s.notifyHold(ctx, hold, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	overdue, err := s.lendingRepo.ListUnnotifiedOverdue(ctx, now)
+	if err != nil {
+		return err
+	}
+	for _, loan := range overdue {
+		title := loan.BookID
+		if book, err := s.bookRepo.FindByID(ctx, loan.BookID); err == nil && book != nil {
+			title = book.Title
+		}
+		if err := s.notificationSvc.Notify(ctx, &entities.Notification{
+			UserID:  loan.UserID,
+			Kind:    entities.NotificationLoanOverdue,
+			BookID:  loan.BookID,
+			Message: fmt.Sprintf("%q was due back on %s, please return it", title, loan.DueAt.Format("2006-01-02")),
+		}); err != nil {
+			return err
+		}
+		if err := s.lendingRepo.MarkOverdueNotified(ctx, loan.ID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyHold tells the user of a hold that it's ready or expired, loading
+// the book when nil. Failures are logged: the hold itself went through.
+func (s *lendingService) notifyHold(ctx context.Context, hold *entities.Hold, book *entities.Book) {
+	title := hold.BookID
+	if book == nil {
+		book, _ = s.bookRepo.FindByID(ctx, hold.BookID)
+	}
+	if book != nil {
+		title = book.Title
+	}
+
+	notification := &entities.Notification{UserID: hold.UserID, BookID: hold.BookID}
+	switch hold.Status {
+	case entities.HoldReady:
+		notification.Kind = entities.NotificationHoldReady
+		notification.Message = fmt.Sprintf("A copy of %q is waiting for you", title)
+		if hold.ExpiresAt != nil {
+			notification.Message += " until " + hold.ExpiresAt.Format("2006-01-02 15:04")
+		}
+	case entities.HoldExpired:
+		notification.Kind = entities.NotificationHoldExpired
+		notification.Message = fmt.Sprintf("Your hold on %q expired as the copy wasn't picked up", title)
+	default:
+		return
+	}
+	if err := s.notificationSvc.Notify(ctx, notification); err != nil {
+		log.Printf("Failed to notify user %s of hold %d: %v", hold.UserID, hold.ID, err)
+	}
+}
+
+// lentBook returns a book the user can see, whose copies they can borrow
+func (s *lendingService) lentBook(ctx context.Context, userID, bookID string) (*entities.Book, error) {
+	return s.accessPolicy.Authorize(ctx, bookID, userID, book.AccessView)
+}
+
+// ownedBook returns a book of the user, whose copies they manage
+func (s *lendingService) ownedBook(ctx context.Context, userID, bookID string) (*entities.Book, error) {
+	return s.accessPolicy.Authorize(ctx, bookID, userID, book.AccessOwner)
+}
+
+func validateLoanStatus(status string) error {
+	switch status {
+	case "", repository.LoanActive, repository.LoanOverdue, repository.LoanReturned:
+		return nil
+	}
+	return errors.NewValidationError("status", "Status must be active, overdue or returned")
+}
+
+func markOverdue(loan *entities.Loan, now time.Time) {
+	loan.Overdue = loan.ReturnedAt == nil && loan.DueAt.Before(now)
+}
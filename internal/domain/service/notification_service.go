@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+)
+
+// NotificationService keeps the messages telling users about the things
+// that happened without them
+type NotificationService interface {
+	Notify(ctx context.Context, notification *entities.Notification) error
+	ListNotifications(ctx context.Context, userID string, unreadOnly bool, page, limit int) ([]*entities.Notification, int64, error)
+	MarkRead(ctx context.Context, userID string, id uint) error
+}
+
+type notificationService struct {
+	notificationRepo repository.NotificationRepository
+}
+
+func NewNotificationService(notificationRepo repository.NotificationRepository) NotificationService {
+	return &notificationService{notificationRepo: notificationRepo}
+}
+
+func (s *notificationService) Notify(ctx context.Context, notification *entities.Notification) error {
+	return s.notificationRepo.Create(ctx, notification)
+}
+
+func (s *notificationService) ListNotifications(ctx context.Context, userID string, unreadOnly bool, page, limit int) ([]*entities.Notification, int64, error) {
+	return s.notificationRepo.ListByUserID(ctx, userID, unreadOnly, page, limit)
+}
+
+func (s *notificationService) MarkRead(ctx context.Context, userID string, id uint) error {
+	return s.notificationRepo.MarkRead(ctx, userID, id, time.Now())
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+)
+
+var readingStatuses = map[string]bool{
+	entities.ReadingWantToRead: true,
+	entities.ReadingReading:    true,
+	entities.ReadingFinished:   true,
+	entities.ReadingAbandoned:  true,
+}
+
+// ReadingStats summarises the books a user finished in a year
+type ReadingStats struct {
+	Year  int   `json:"year"`
+	Books int64 `json:"books"`
+	Pages int64 `json:"pages"`
+	// Months holds the twelve months of the year in order, empty ones included
+	Months []repository.MonthlyReading `json:"months"`
+}
+
+// ReadingService tracks where users are in their books
+type ReadingService interface {
+	GetProgress(ctx context.Context, userID, bookID string) (*entities.ReadingProgress, error)
+	UpdateProgress(ctx context.Context, progress *entities.ReadingProgress) error
+	DeleteProgress(ctx context.Context, userID, bookID string) error
+	ListSessions(ctx context.Context, userID, bookID string) ([]*entities.ReadingSession, error)
+	LogSession(ctx context.Context, session *entities.ReadingSession) (*entities.ReadingProgress, error)
+	YearlyStats(ctx context.Context, userID string, year int) (*ReadingStats, error)
+}
+
+type readingService struct {
+	readingRepo  repository.ReadingRepository
+	accessPolicy BookAccessPolicy
+}
+
+func NewReadingService(readingRepo repository.ReadingRepository, accessPolicy BookAccessPolicy) ReadingService {
+	return &readingService{
+		readingRepo:  readingRepo,
+		accessPolicy: accessPolicy,
+	}
+}
+
+func (s *readingService) GetProgress(ctx context.Context, userID, bookID string) (*entities.ReadingProgress, error) {
+	if _, err := s.readableBook(ctx, userID, bookID); err != nil {
+		return nil, err
+	}
+
+	progress, err := s.readingRepo.FindProgress(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		return nil, errors.NewNotFoundError("reading progress")
+	}
+	return progress, nil
+}
+
+// UpdateProgress saves the status and position of a user in a book. The
+// page and percentage are derived from each other when the page count of
+// the book is known, and the start and finish dates default to when the
+// book is started and finished.
+func (s *readingService) UpdateProgress(ctx context.Context, progress *entities.ReadingProgress) error {
+	book, err := s.readableBook(ctx, progress.UserID, progress.BookID)
+	if err != nil {
+		return err
+	}
+	existing, err := s.readingRepo.FindProgress(ctx, progress.UserID, progress.BookID)
+	if err != nil {
+		return err
+	}
+
+	if !readingStatuses[progress.Status] {
+		return errors.NewValidationError("status", "Status must be want_to_read, reading, finished or abandoned")
+	}
+	if progress.CurrentPage < 0 || (book.PageCount > 0 && progress.CurrentPage > book.PageCount) {
+		return errors.NewValidationError("current_page", "Current page must be between 0 and the page count of the book")
+	}
+	if progress.Percent < 0 || progress.Percent > 100 {
+		return errors.NewValidationError("percent", "Percent must be between 0 and 100")
+	}
+
+	if book.PageCount > 0 {
+		if progress.CurrentPage > 0 {
+			progress.Percent = pagePercent(progress.CurrentPage, book.PageCount)
+		} else if progress.Percent > 0 {
+			progress.CurrentPage = int(math.Round(progress.Percent * float64(book.PageCount) / 100))
+		}
+	}
+
+	now := time.Now()
+	if progress.StartedAt == nil && existing != nil {
+		progress.StartedAt = existing.StartedAt
+	}
+	switch progress.Status {
+	case entities.ReadingReading:
+		if progress.StartedAt == nil {
+			progress.StartedAt = &now
+		}
+		progress.FinishedAt = nil
+	case entities.ReadingFinished:
+		if progress.FinishedAt == nil && existing != nil {
+			progress.FinishedAt = existing.FinishedAt
+		}
+		if progress.FinishedAt == nil {
+			progress.FinishedAt = &now
+		}
+		progress.Percent = 100
+		if book.PageCount > 0 {
+			progress.CurrentPage = book.PageCount
+		}
+	default:
+		progress.FinishedAt = nil
+	}
+	if progress.StartedAt != nil && progress.FinishedAt != nil && progress.FinishedAt.Before(*progress.StartedAt) {
+		return errors.NewValidationError("finished_at", "A book can't be finished before it's started")
+	}
+
+	if err := s.readingRepo.SaveProgress(ctx, progress); err != nil {
+		return err
+	}
+	if existing != nil {
+		progress.ID = existing.ID
+		progress.CreatedAt = existing.CreatedAt
+	}
+	return nil
+}
+
+// DeleteProgress forgets the progress of a user in a book and its sessions
+func (s *readingService) DeleteProgress(ctx context.Context, userID, bookID string) error {
+	if _, err := s.readableBook(ctx, userID, bookID); err != nil {
+		return err
+	}
+	return s.readingRepo.DeleteProgress(ctx, userID, bookID)
+}
+
+func (s *readingService) ListSessions(ctx context.Context, userID, bookID string) ([]*entities.ReadingSession, error) {
+	if _, err := s.readableBook(ctx, userID, bookID); err != nil {
+		return nil, err
+	}
+	return s.readingRepo.ListSessions(ctx, userID, bookID)
+}
+
+// LogSession records a reading session and moves the progress to its last
+// page, starting the book if needed and finishing it on its last page
+func (s *readingService) LogSession(ctx context.Context, session *entities.ReadingSession) (*entities.ReadingProgress, error) {
+	book, err := s.readableBook(ctx, session.UserID, session.BookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.StartedAt.IsZero() {
+		return nil, errors.NewValidationError("started_at", "Start time is required")
+	}
+	if session.EndedAt != nil && session.EndedAt.Before(session.StartedAt) {
+		return nil, errors.NewValidationError("ended_at", "A session can't end before it starts")
+	}
+	if session.StartPage < 0 || session.EndPage < session.StartPage {
+		return nil, errors.NewValidationError("end_page", "End page must not be before the start page")
+	}
+	if book.PageCount > 0 && session.EndPage > book.PageCount {
+		return nil, errors.NewValidationError("end_page", "End page must not be after the last page of the book")
+	}
+
+	progress, err := s.readingRepo.FindProgress(ctx, session.UserID, session.BookID)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		progress = &entities.ReadingProgress{UserID: session.UserID, BookID: session.BookID}
+	}
+	if progress.StartedAt == nil || session.StartedAt.Before(*progress.StartedAt) {
+		progress.StartedAt = &session.StartedAt
+	}
+	if session.EndPage > 0 {
+		progress.CurrentPage = session.EndPage
+		if book.PageCount > 0 {
+			progress.Percent = pagePercent(session.EndPage, book.PageCount)
+		}
+	}
+	if progress.Status != entities.ReadingFinished {
+		progress.Status = entities.ReadingReading
+		if book.PageCount > 0 && session.EndPage == book.PageCount {
+			progress.Status = entities.ReadingFinished
+			progress.FinishedAt = session.EndedAt
+			if progress.FinishedAt == nil {
+				progress.FinishedAt = &session.StartedAt
+			}
+		}
+	}
+
+	if err := s.readingRepo.CreateSession(ctx, session, progress); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// YearlyStats counts the books a user finished in a year by month
+func (s *readingService) YearlyStats(ctx context.Context, userID string, year int) (*ReadingStats, error) {
+	if year < minPublishedYear || year > time.Now().Year()+1 {
+		return nil, errors.NewValidationError("year", "Invalid year")
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	found, err := s.readingRepo.FinishedByMonth(ctx, userID, from, from.AddDate(1, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &ReadingStats{Year: year, Months: make([]repository.MonthlyReading, 12)}
+	for i := range stats.Months {
+		stats.Months[i].Month = i + 1
+	}
+	for _, month := range found {
+		if month.Month < 1 || month.Month > 12 {
+			continue
+		}
+		stats.Months[month.Month-1] = month
+		stats.Books += month.Books
+		stats.Pages += month.Pages
+	}
+	return stats, nil
+}
+
+// readableBook returns a book the user may track their reading of, that is
+// one they can see
+func (s *readingService) readableBook(ctx context.Context, userID, bookID string) (*entities.Book, error) {
+	return s.accessPolicy.Authorize(ctx, bookID, userID, book.AccessView)
+}
+
+// pagePercent returns the share of a book read at a page, rounded to 0.1
+func pagePercent(page, pageCount int) float64 {
+	return math.Round(float64(page)*1000/float64(pageCount)) / 10
+}
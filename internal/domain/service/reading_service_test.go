@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+)
+
+// fakeReadingRepository keeps the progress of the users by user and book
+type fakeReadingRepository struct {
+	repository.ReadingRepository
+	progress map[[2]string]*entities.ReadingProgress
+	sessions []*entities.ReadingSession
+	// finished is what FinishedByMonth returns, from and to what it got
+	finished []repository.MonthlyReading
+	from, to time.Time
+}
+
+func newFakeReadingRepository() *fakeReadingRepository {
+	return &fakeReadingRepository{progress: make(map[[2]string]*entities.ReadingProgress)}
+}
+
+func (r *fakeReadingRepository) FindProgress(ctx context.Context, userID, bookID string) (*entities.ReadingProgress, error) {
+	if progress, ok := r.progress[[2]string{userID, bookID}]; ok {
+		saved := *progress
+		return &saved, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeReadingRepository) SaveProgress(ctx context.Context, progress *entities.ReadingProgress) error {
+	saved := *progress
+	r.progress[[2]string{progress.UserID, progress.BookID}] = &saved
+	return nil
+}
+
+func (r *fakeReadingRepository) CreateSession(ctx context.Context, session *entities.ReadingSession, progress *entities.ReadingProgress) error {
+	r.sessions = append(r.sessions, session)
+	return r.SaveProgress(ctx, progress)
+}
+
+func (r *fakeReadingRepository) FinishedByMonth(ctx context.Context, userID string, from, to time.Time) ([]repository.MonthlyReading, error) {
+	r.from, r.to = from, to
+	return r.finished, nil
+}
+
+func newReadingFixture() (ReadingService, *fakeReadingRepository) {
+	books := newFakeBookRepository(
+		&entities.Book{ID: "book-1", UserID: "user-1", PageCount: 320},
+		&entities.Book{ID: "book-2", UserID: "user-1"},
+	)
+	readings := newFakeReadingRepository()
+	return NewReadingService(readings, ownerPolicy{books}), readings
+}
+
+func TestUpdateProgressDerivesThePosition(t *testing.T) {
+	tests := []struct {
+		name        string
+		progress    entities.ReadingProgress
+		wantPage    int
+		wantPercent float64
+	}{
+		{"page of a known length", entities.ReadingProgress{BookID: "book-1", Status: entities.ReadingReading, CurrentPage: 80}, 80, 25},
+		{"rounded percent", entities.ReadingProgress{BookID: "book-1", Status: entities.ReadingReading, CurrentPage: 1}, 1, 0.3},
+		{"page wins over percent", entities.ReadingProgress{BookID: "book-1", Status: entities.ReadingReading, CurrentPage: 160, Percent: 10}, 160, 50},
+		{"percent of a known length", entities.ReadingProgress{BookID: "book-1", Status: entities.ReadingReading, Percent: 33.3}, 107, 33.3},
+		{"unknown length", entities.ReadingProgress{BookID: "book-2", Status: entities.ReadingReading, CurrentPage: 80, Percent: 12}, 80, 12},
+		{"finished", entities.ReadingProgress{BookID: "book-1", Status: entities.ReadingFinished, CurrentPage: 12}, 320, 100},
+		{"finished of unknown length", entities.ReadingProgress{BookID: "book-2", Status: entities.ReadingFinished, CurrentPage: 12}, 12, 100},
+	}
+	for _, tt := range tests {
+		s, _ := newReadingFixture()
+		progress := tt.progress
+		progress.UserID = "user-1"
+		if err := s.UpdateProgress(context.Background(), &progress); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if progress.CurrentPage != tt.wantPage || progress.Percent != tt.wantPercent {
+			t.Errorf("%s: page %d at %v%%, want page %d at %v%%", tt.name, progress.CurrentPage, progress.Percent, tt.wantPage, tt.wantPercent)
+		}
+	}
+}
+
+func TestUpdateProgressDates(t *testing.T) {
+	s, readings := newReadingFixture()
+	ctx := context.Background()
+
+	reading := &entities.ReadingProgress{UserID: "user-1", BookID: "book-1", Status: entities.ReadingReading}
+	if err := s.UpdateProgress(ctx, reading); err != nil {
+		t.Fatal(err)
+	}
+	if reading.StartedAt == nil || reading.FinishedAt != nil {
+		t.Fatalf("started book = %v to %v, want a start only", reading.StartedAt, reading.FinishedAt)
+	}
+	started := *reading.StartedAt
+
+	// The start is kept by later updates which don't give one
+	finished := &entities.ReadingProgress{UserID: "user-1", BookID: "book-1", Status: entities.ReadingFinished}
+	if err := s.UpdateProgress(ctx, finished); err != nil {
+		t.Fatal(err)
+	}
+	if finished.StartedAt == nil || !finished.StartedAt.Equal(started) || finished.FinishedAt == nil {
+		t.Fatalf("finished book = %v to %v, want %v to now", finished.StartedAt, finished.FinishedAt, started)
+	}
+	finishedAt := *finished.FinishedAt
+
+	again := &entities.ReadingProgress{UserID: "user-1", BookID: "book-1", Status: entities.ReadingFinished}
+	if err := s.UpdateProgress(ctx, again); err != nil {
+		t.Fatal(err)
+	}
+	if again.FinishedAt == nil || !again.FinishedAt.Equal(finishedAt) {
+		t.Errorf("finished again at %v, want the first finish %v", again.FinishedAt, finishedAt)
+	}
+
+	// Reading it again drops the finish
+	reread := &entities.ReadingProgress{UserID: "user-1", BookID: "book-1", Status: entities.ReadingReading, CurrentPage: 10}
+	if err := s.UpdateProgress(ctx, reread); err != nil {
+		t.Fatal(err)
+	}
+	if saved := readings.progress[[2]string{"user-1", "book-1"}]; saved.FinishedAt != nil || !saved.StartedAt.Equal(started) {
+		t.Errorf("reread book = %v to %v, want %v without a finish", saved.StartedAt, saved.FinishedAt, started)
+	}
+}
+
+func TestUpdateProgressValidation(t *testing.T) {
+	startedAt := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	finishedAt := startedAt.AddDate(0, 0, -1)
+	tests := []struct {
+		name     string
+		progress entities.ReadingProgress
+		want     string
+	}{
+		{"unknown status", entities.ReadingProgress{BookID: "book-1", Status: "skimmed"}, "VALIDATION_ERROR"},
+		{"past the last page", entities.ReadingProgress{BookID: "book-1", Status: entities.ReadingReading, CurrentPage: 321}, "VALIDATION_ERROR"},
+		{"negative page", entities.ReadingProgress{BookID: "book-2", Status: entities.ReadingReading, CurrentPage: -1}, "VALIDATION_ERROR"},
+		{"over 100%", entities.ReadingProgress{BookID: "book-1", Status: entities.ReadingReading, Percent: 101}, "VALIDATION_ERROR"},
+		{"finished before started", entities.ReadingProgress{BookID: "book-1", Status: entities.ReadingFinished, StartedAt: &startedAt, FinishedAt: &finishedAt}, "VALIDATION_ERROR"},
+		{"book of another user", entities.ReadingProgress{BookID: "book-3", Status: entities.ReadingReading}, "NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		s, readings := newReadingFixture()
+		progress := tt.progress
+		progress.UserID = "user-1"
+		if err := s.UpdateProgress(context.Background(), &progress); errorCode(err) != tt.want {
+			t.Errorf("%s: %v, want %s", tt.name, err, tt.want)
+		}
+		if len(readings.progress) != 0 {
+			t.Errorf("%s: saved %v", tt.name, readings.progress)
+		}
+	}
+}
+
+func TestLogSessionFinishesOnTheLastPage(t *testing.T) {
+	s, _ := newReadingFixture()
+	startedAt := time.Date(2026, 3, 10, 20, 0, 0, 0, time.UTC)
+	endedAt := startedAt.Add(time.Hour)
+
+	progress, err := s.LogSession(context.Background(), &entities.ReadingSession{
+		UserID: "user-1", BookID: "book-1", StartPage: 300, EndPage: 320, StartedAt: startedAt, EndedAt: &endedAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if progress.Status != entities.ReadingFinished || progress.Percent != 100 || progress.FinishedAt == nil || !progress.FinishedAt.Equal(endedAt) {
+		t.Errorf("progress = %s at %v%% finished %v, want finished at %v", progress.Status, progress.Percent, progress.FinishedAt, endedAt)
+	}
+}
+
+func TestYearlyStats(t *testing.T) {
+	s, readings := newReadingFixture()
+	readings.finished = []repository.MonthlyReading{
+		{Month: 2, Books: 1, Pages: 320},
+		{Month: 11, Books: 3, Pages: 900},
+	}
+
+	stats, err := s.YearlyStats(context.Background(), "user-1", 2025)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Year != 2025 || stats.Books != 4 || stats.Pages != 1220 || len(stats.Months) != 12 {
+		t.Fatalf("stats = %+v, want 4 books and 1220 pages over 12 months", stats)
+	}
+	for i, month := range stats.Months {
+		if month.Month != i+1 {
+			t.Errorf("month %d is numbered %d", i, month.Month)
+		}
+	}
+	if stats.Months[1].Books != 1 || stats.Months[10].Pages != 900 || stats.Months[0].Books != 0 {
+		t.Errorf("months = %+v, want February and November filled", stats.Months)
+	}
+	wantFrom := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if !readings.from.Equal(wantFrom) || !readings.to.Equal(wantFrom.AddDate(1, 0, 0)) {
+		t.Errorf("stats read from %v to %v, want the year 2025", readings.from, readings.to)
+	}
+
+	for _, year := range []int{0, time.Now().Year() + 2} {
+		if _, err := s.YearlyStats(context.Background(), "user-1", year); errorCode(err) != "VALIDATION_ERROR" {
+			t.Errorf("year %d = %v, want VALIDATION_ERROR", year, err)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+)
+
+const (
+	maxShelfNameLength        = 100
+	maxShelfDescriptionLength = 500
+)
+
+// ShelfService defines the interface for managing the shelves books are collected on
+type ShelfService interface {
+	CreateShelf(ctx context.Context, shelf *entities.Shelf) error
+	GetShelf(ctx context.Context, id uint, userID string) (*entities.Shelf, error)
+	ListShelves(ctx context.Context, userID string) ([]*entities.Shelf, error)
+	UpdateShelf(ctx context.Context, id uint, userID string, shelf *entities.Shelf) error
+	DeleteShelf(ctx context.Context, id uint, userID string) error
+	ListShelfBooks(ctx context.Context, id uint, userID string, page, limit int) ([]*entities.Book, int64, error)
+	PlaceBook(ctx context.Context, id uint, userID, bookID string, position *int) error
+	RemoveBook(ctx context.Context, id uint, userID, bookID string) error
+	ReorderBooks(ctx context.Context, id uint, userID string, bookIDs []string) error
+}
+
+type shelfService struct {
+	shelfRepo    repository.ShelfRepository
+	accessPolicy BookAccessPolicy
+}
+
+func NewShelfService(shelfRepo repository.ShelfRepository, accessPolicy BookAccessPolicy) ShelfService {
+	return &shelfService{
+		shelfRepo:    shelfRepo,
+		accessPolicy: accessPolicy,
+	}
+}
+
+func (s *shelfService) CreateShelf(ctx context.Context, shelf *entities.Shelf) error {
+	if err := s.validateShelf(ctx, shelf, 0); err != nil {
+		return err
+	}
+	return s.shelfRepo.Create(ctx, shelf)
+}
+
+func (s *shelfService) GetShelf(ctx context.Context, id uint, userID string) (*entities.Shelf, error) {
+	shelf, err := s.shelfRepo.FindByID(ctx, strconv.FormatUint(uint64(id), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	if shelf.UserID != userID {
+		return nil, errors.NewAppError("UNAUTHORIZED", "You are not authorized to access this shelf", nil)
+	}
+
+	return shelf, nil
+}
+
+func (s *shelfService) ListShelves(ctx context.Context, userID string) ([]*entities.Shelf, error) {
+	return s.shelfRepo.ListByUserID(ctx, userID)
+}
+
+// UpdateShelf saves the name, description and position of a shelf
+func (s *shelfService) UpdateShelf(ctx context.Context, id uint, userID string, shelf *entities.Shelf) error {
+	existingShelf, err := s.GetShelf(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	existingShelf.Name = shelf.Name
+	existingShelf.Description = shelf.Description
+	existingShelf.Position = shelf.Position
+	if err := s.validateShelf(ctx, existingShelf, existingShelf.ID); err != nil {
+		return err
+	}
+
+	if err := s.shelfRepo.Update(ctx, existingShelf); err != nil {
+		return errors.NewInternalServerError(err.Error())
+	}
+	*shelf = *existingShelf
+	return nil
+}
+
+func (s *shelfService) DeleteShelf(ctx context.Context, id uint, userID string) error {
+	if _, err := s.GetShelf(ctx, id, userID); err != nil {
+		return err
+	}
+	return s.shelfRepo.Delete(ctx, strconv.FormatUint(uint64(id), 10))
+}
+
+// ListShelfBooks returns a page of the books on a shelf in the shelf order,
+// with the number of books on the shelf
+func (s *shelfService) ListShelfBooks(ctx context.Context, id uint, userID string, page, limit int) ([]*entities.Book, int64, error) {
+	if _, err := s.GetShelf(ctx, id, userID); err != nil {
+		return nil, 0, err
+	}
+	return s.shelfRepo.ListBooks(ctx, id, page, limit)
+}
+
+// PlaceBook puts a book of the user on a shelf, or moves it when it's
+// already there. The book goes at position, counted from 0, or last when
+// position is nil.
+func (s *shelfService) PlaceBook(ctx context.Context, id uint, userID, bookID string, position *int) error {
+	if _, err := s.GetShelf(ctx, id, userID); err != nil {
+		return err
+	}
+	if err := s.checkBook(ctx, userID, bookID); err != nil {
+		return err
+	}
+
+	at := -1
+	if position != nil {
+		if *position < 0 {
+			return errors.NewValidationError("position", "Position must not be negative")
+		}
+		at = *position
+	}
+	return s.shelfRepo.PlaceBook(ctx, id, bookID, at)
+}
+
+// RemoveBook takes a book off a shelf
+func (s *shelfService) RemoveBook(ctx context.Context, id uint, userID, bookID string) error {
+	if _, err := s.GetShelf(ctx, id, userID); err != nil {
+		return err
+	}
+	return s.shelfRepo.RemoveBook(ctx, id, bookID)
+}
+
+// ReorderBooks sets the order of the books on a shelf. bookIDs must hold
+// each book of the shelf exactly once.
+func (s *shelfService) ReorderBooks(ctx context.Context, id uint, userID string, bookIDs []string) error {
+	if _, err := s.GetShelf(ctx, id, userID); err != nil {
+		return err
+	}
+
+	current, err := s.shelfRepo.ListBookIDs(ctx, id)
+	if err != nil {
+		return err
+	}
+	onShelf := make(map[string]bool, len(current))
+	for _, bookID := range current {
+		onShelf[bookID] = true
+	}
+	if len(bookIDs) != len(current) {
+		return errors.NewValidationError("book_ids", "The order must list each book of the shelf exactly once")
+	}
+	for _, bookID := range bookIDs {
+		if !onShelf[bookID] {
+			return errors.NewValidationError("book_ids", "The order must list each book of the shelf exactly once")
+		}
+		delete(onShelf, bookID)
+	}
+
+	return s.shelfRepo.ReorderBooks(ctx, id, bookIDs)
+}
+
+// checkBook makes sure a book exists and the user can see it
+func (s *shelfService) checkBook(ctx context.Context, userID, bookID string) error {
+	_, err := s.accessPolicy.Authorize(ctx, bookID, userID, book.AccessView)
+	return err
+}
+
+// validateShelf checks the details of a shelf, whose name must be unique
+// among the user's shelves, the one with excludeID aside
+func (s *shelfService) validateShelf(ctx context.Context, shelf *entities.Shelf, excludeID uint) error {
+	shelf.Name = strings.TrimSpace(shelf.Name)
+	if shelf.Name == "" {
+		return errors.NewValidationError("name", "Name is required")
+	}
+	if utf8.RuneCountInString(shelf.Name) > maxShelfNameLength {
+		return errors.NewValidationError("name", "Name must be at most 100 characters")
+	}
+	if utf8.RuneCountInString(shelf.Description) > maxShelfDescriptionLength {
+		return errors.NewValidationError("description", "Description must be at most 500 characters")
+	}
+
+	existing, err := s.shelfRepo.FindByName(ctx, shelf.UserID, shelf.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != excludeID {
+		return errors.NewAppError("CONFLICT", "You already have a shelf with this name", nil)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/errors"
+)
+
+// orderedShelfRepository keeps the order of the books on the fake shelves
+type orderedShelfRepository struct {
+	fakeShelfRepository
+	books map[uint][]string
+}
+
+func (r *orderedShelfRepository) FindByID(ctx context.Context, id string) (*entities.Shelf, error) {
+	for _, shelf := range r.shelves {
+		if strconv.FormatUint(uint64(shelf.ID), 10) == id {
+			return shelf, nil
+		}
+	}
+	return nil, errors.NewNotFoundError("shelf")
+}
+
+func (r *orderedShelfRepository) ListBookIDs(ctx context.Context, shelfID uint) ([]string, error) {
+	return r.books[shelfID], nil
+}
+
+func (r *orderedShelfRepository) PlaceBook(ctx context.Context, shelfID uint, bookID string, position int) error {
+	ids := r.books[shelfID]
+	if position < 0 || position > len(ids) {
+		position = len(ids)
+	}
+	r.books[shelfID] = append(ids[:position:position], append([]string{bookID}, ids[position:]...)...)
+	return nil
+}
+
+func (r *orderedShelfRepository) RemoveBook(ctx context.Context, shelfID uint, bookID string) error {
+	var ids []string
+	for _, id := range r.books[shelfID] {
+		if id != bookID {
+			ids = append(ids, id)
+		}
+	}
+	r.books[shelfID] = ids
+	return nil
+}
+
+func (r *orderedShelfRepository) ReorderBooks(ctx context.Context, shelfID uint, bookIDs []string) error {
+	r.books[shelfID] = bookIDs
+	return nil
+}
+
+func newShelfFixture() (ShelfService, *orderedShelfRepository) {
+	books := newFakeBookRepository(
+		&entities.Book{ID: "book-1", UserID: "user-1"},
+		&entities.Book{ID: "book-2", UserID: "user-1"},
+		&entities.Book{ID: "book-3", UserID: "user-1"},
+		&entities.Book{ID: "book-9", UserID: "user-2"},
+	)
+	shelves := &orderedShelfRepository{
+		fakeShelfRepository: fakeShelfRepository{shelves: []*entities.Shelf{
+			{ID: 1, UserID: "user-1", Name: "Favourites"},
+			{ID: 2, UserID: "user-2", Name: "Classics"},
+		}},
+		books: map[uint][]string{1: {"book-1", "book-2"}},
+	}
+	return NewShelfService(shelves, ownerPolicy{books}), shelves
+}
+
+func TestReorderBooksMustListTheShelf(t *testing.T) {
+	s, shelves := newShelfFixture()
+	ctx := context.Background()
+
+	for _, order := range [][]string{
+		{"book-2"},
+		{"book-2", "book-1", "book-3"},
+		{"book-2", "book-3"},
+		{"book-2", "book-2"},
+	} {
+		if err := s.ReorderBooks(ctx, 1, "user-1", order); errorCode(err) != "VALIDATION_ERROR" {
+			t.Errorf("reorder %v = %v, want VALIDATION_ERROR", order, err)
+		}
+	}
+	if err := s.ReorderBooks(ctx, 1, "user-1", []string{"book-2", "book-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := shelves.books[1]; len(got) != 2 || got[0] != "book-2" || got[1] != "book-1" {
+		t.Errorf("shelf = %v, want [book-2 book-1]", got)
+	}
+}
+
+func TestPlaceAndRemoveBooks(t *testing.T) {
+	s, shelves := newShelfFixture()
+	ctx := context.Background()
+
+	first := 0
+	if err := s.PlaceBook(ctx, 1, "user-1", "book-3", &first); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveBook(ctx, 1, "user-1", "book-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PlaceBook(ctx, 1, "user-1", "book-1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := shelves.books[1]; len(got) != 3 || got[0] != "book-3" || got[1] != "book-2" || got[2] != "book-1" {
+		t.Errorf("shelf = %v, want [book-3 book-2 book-1]", got)
+	}
+
+	negative := -1
+	if err := s.PlaceBook(ctx, 1, "user-1", "book-3", &negative); errorCode(err) != "VALIDATION_ERROR" {
+		t.Errorf("negative position = %v, want VALIDATION_ERROR", err)
+	}
+	if err := s.PlaceBook(ctx, 1, "user-1", "book-9", nil); errorCode(err) != "NOT_FOUND" {
+		t.Errorf("book of another user = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestShelvesOfOtherUsers(t *testing.T) {
+	s, shelves := newShelfFixture()
+	ctx := context.Background()
+
+	if err := s.PlaceBook(ctx, 2, "user-1", "book-1", nil); errorCode(err) != "UNAUTHORIZED" {
+		t.Errorf("place = %v, want UNAUTHORIZED", err)
+	}
+	if err := s.RemoveBook(ctx, 2, "user-1", "book-9"); errorCode(err) != "UNAUTHORIZED" {
+		t.Errorf("remove = %v, want UNAUTHORIZED", err)
+	}
+	if err := s.ReorderBooks(ctx, 2, "user-1", nil); errorCode(err) != "UNAUTHORIZED" {
+		t.Errorf("reorder = %v, want UNAUTHORIZED", err)
+	}
+	if len(shelves.books[2]) != 0 {
+		t.Errorf("shelf of user-2 = %v, want it untouched", shelves.books[2])
+	}
+}
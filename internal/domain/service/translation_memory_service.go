@@ -0,0 +1,480 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+	"clean-arch-go/internal/pkg/i18n"
+	"clean-arch-go/internal/pkg/tmx"
+	"clean-arch-go/internal/pkg/xliff"
+
+	"golang.org/x/text/language"
+)
+
+// xliffBaselineNote is the note category holding the target text at export
+// time, used to detect messages changed while the file was being reviewed
+const xliffBaselineNote = "exported-target"
+
+// undeterminedLang is used in TMX for translations stored without source language
+const undeterminedLang = "und"
+
+// ImportOptions controls how translation files are imported
+type ImportOptions struct {
+	// DryRun reports what would change without saving anything
+	DryRun bool
+	// Overwrite applies entries that conflict with the stored ones
+	Overwrite bool
+}
+
+// ImportConflict describes an entry that was not imported
+type ImportConflict struct {
+	Key      string `json:"key"`
+	Lang     string `json:"lang"`
+	Reason   string `json:"reason"`
+	Current  string `json:"current,omitempty"`
+	Incoming string `json:"incoming"`
+}
+
+// ImportReport summarises the result of an import
+type ImportReport struct {
+	Created   int              `json:"created"`
+	Updated   int              `json:"updated"`
+	Unchanged int              `json:"unchanged"`
+	Conflicts []ImportConflict `json:"conflicts"`
+	DryRun    bool             `json:"dry_run"`
+}
+
+// TranslationMemoryService exchanges translations with CAT tools: the
+// translation memory as TMX and the UI messages as XLIFF 2.0
+type TranslationMemoryService interface {
+	ExportTMX(ctx context.Context, w io.Writer, targetLang string) error
+	ImportTMX(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error)
+	ExportXLIFF(ctx context.Context, w io.Writer, targetLang string) error
+	ImportXLIFF(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error)
+	LoadLocaleMessages(ctx context.Context) error
+}
+
+type translationMemoryService struct {
+	translationRepo repository.TranslationRepository
+	localizer       *i18n.Localizer
+}
+
+func NewTranslationMemoryService(translationRepo repository.TranslationRepository, localizer *i18n.Localizer) TranslationMemoryService {
+	return &translationMemoryService{
+		translationRepo: translationRepo,
+		localizer:       localizer,
+	}
+}
+
+// ExportTMX writes the stored translations, optionally only those into targetLang
+func (s *translationMemoryService) ExportTMX(ctx context.Context, w io.Writer, targetLang string) error {
+	translations, err := s.translationRepo.ListTranslations(ctx, targetLang)
+	if err != nil {
+		return err
+	}
+
+	doc := tmx.NewDocument("*all*")
+	for _, t := range translations {
+		srcLang := t.SourceLang
+		if srcLang == "" {
+			srcLang = undeterminedLang
+		}
+		doc.Units = append(doc.Units, tmx.Unit{
+			ID:         strconv.FormatUint(uint64(t.ID), 10),
+			SrcLang:    srcLang,
+			ChangeDate: t.UpdatedAt.UTC().Format(tmx.DateFormat),
+			Variants: []tmx.Variant{
+				{Lang: srcLang, Segment: t.SourceText},
+				{Lang: t.TargetLang, Segment: t.TranslatedText},
+			},
+		})
+	}
+
+	return tmx.Encode(w, doc)
+}
+
+// ImportTMX saves the translations of a TMX file. A stored translation that
+// differs from the file is a conflict when it was modified after the unit's
+// change date, or when the unit has none. The whole file is checked before
+// its translations are saved in one transaction, so that a failing import
+// leaves the translation memory untouched.
+func (s *translationMemoryService) ImportTMX(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	doc, err := tmx.Decode(r)
+	if err != nil {
+		return nil, errors.NewBadRequestError(err.Error())
+	}
+
+	report := &ImportReport{DryRun: opts.DryRun}
+	// pending holds the translations to save by text and language, so that
+	// a text repeated in the file only changes the translation saved
+	pending := make(map[[2]string]*entities.Translation)
+	var changes []*entities.Translation
+	for i := range doc.Units {
+		unit := &doc.Units[i]
+		source, ok := doc.Source(unit)
+		if !ok || source.Segment == "" {
+			continue
+		}
+		changedAt, hasChangeDate := unit.ChangedAt()
+
+		for _, variant := range unit.Variants {
+			if variant.Lang == source.Lang {
+				continue
+			}
+
+			key := [2]string{source.Segment, variant.Lang}
+			if translation, ok := pending[key]; ok {
+				translation.TranslatedText = variant.Segment
+				continue
+			}
+			existing, err := s.translationRepo.FindTranslation(ctx, source.Segment, variant.Lang)
+			if err != nil {
+				return nil, err
+			}
+
+			switch {
+			case existing == nil:
+				report.Created++
+				translation := &entities.Translation{
+					SourceText:     source.Segment,
+					SourceLang:     sourceLangOrEmpty(source.Lang),
+					TargetLang:     variant.Lang,
+					TranslatedText: variant.Segment,
+				}
+				pending[key] = translation
+				changes = append(changes, translation)
+
+			case existing.TranslatedText == variant.Segment:
+				report.Unchanged++
+
+			case !opts.Overwrite && (!hasChangeDate || existing.UpdatedAt.After(changedAt)):
+				reason := "translation differs and the unit has no change date"
+				if hasChangeDate {
+					reason = "translation was modified after the export"
+				}
+				report.Conflicts = append(report.Conflicts, ImportConflict{
+					Key:      source.Segment,
+					Lang:     variant.Lang,
+					Reason:   reason,
+					Current:  existing.TranslatedText,
+					Incoming: variant.Segment,
+				})
+
+			default:
+				report.Updated++
+				existing.TranslatedText = variant.Segment
+				pending[key] = existing
+				changes = append(changes, existing)
+			}
+		}
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+	if err := s.translationRepo.SaveTranslations(ctx, changes); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// ExportXLIFF writes the UI messages with their translation into targetLang.
+// A message with plural forms gets a segment per form of the target
+// language, identified by the form name.
+func (s *translationMemoryService) ExportXLIFF(ctx context.Context, w io.Writer, targetLang string) error {
+	target, err := language.Parse(targetLang)
+	if err != nil {
+		return errors.NewValidationError("lang", "Invalid language code")
+	}
+	source := s.localizer.DefaultLanguage()
+
+	sources := s.localizer.Messages(source)
+	targets := s.localizer.Messages(target)
+	targetForms := i18n.LanguageForms(target)
+
+	ids := make([]string, 0, len(sources))
+	for id := range sources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	file := xliff.File{ID: "messages"}
+	for _, id := range ids {
+		unit := xliff.Unit{ID: id}
+		translation := targets[id]
+		plural := sources[id].IsPlural() || translation.IsPlural()
+
+		forms := []string{i18n.FormOther}
+		if plural {
+			forms = messageForms(targetForms, translation)
+		}
+		for _, form := range forms {
+			segment := xliff.Segment{
+				State:  xliff.StateInitial,
+				Source: sources[id].Text(form),
+			}
+			if plural {
+				segment.ID = form
+			}
+			if text, ok := translation[form]; ok {
+				segment.State = xliff.StateTranslated
+				segment.Target = text
+				unit.Notes = append(unit.Notes, xliff.Note{ID: segment.ID, Category: xliffBaselineNote, Text: text})
+			}
+			unit.Segments = append(unit.Segments, segment)
+		}
+		file.Units = append(file.Units, unit)
+	}
+
+	doc := xliff.NewDocument(source.String(), target.String())
+	doc.Files = []xliff.File{file}
+	return xliff.Encode(w, doc)
+}
+
+// ImportXLIFF saves the reviewed UI messages of an XLIFF file. Units whose
+// source text changed since the export, or whose stored translation changed
+// while the file was being reviewed, are reported as conflicts. Plural forms
+// are checked and saved one by one, the forms missing from a unit being kept.
+func (s *translationMemoryService) ImportXLIFF(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	doc, err := xliff.Decode(r)
+	if err != nil {
+		return nil, errors.NewBadRequestError(err.Error())
+	}
+
+	target, err := language.Parse(doc.TrgLang)
+	if err != nil {
+		return nil, errors.NewValidationError("trgLang", "Invalid or missing target language")
+	}
+
+	sources := s.localizer.Messages(s.localizer.DefaultLanguage())
+	targets := s.localizer.Messages(target)
+
+	report := &ImportReport{DryRun: opts.DryRun}
+	changes := make(map[string]i18n.Message)
+	for _, file := range doc.Files {
+		for i := range file.Units {
+			unit := &file.Units[i]
+			unitSources, incoming, baselines := unitForms(unit)
+			if len(incoming) == 0 {
+				continue
+			}
+
+			source, known := sources[unit.ID]
+			current, translated := targets[unit.ID]
+			conflict := ImportConflict{
+				Key:      unit.ID,
+				Lang:     target.String(),
+				Current:  formatMessage(current),
+				Incoming: formatMessage(incoming),
+			}
+
+			switch {
+			case !known:
+				conflict.Reason = "unknown message ID"
+				report.Conflicts = append(report.Conflicts, conflict)
+			case sameForms(current, incoming):
+				report.Unchanged++
+			case !opts.Overwrite && !sameSources(source, unitSources, incoming):
+				conflict.Reason = fmt.Sprintf("source text changed to %q since the export", formatMessage(source))
+				report.Conflicts = append(report.Conflicts, conflict)
+			case !opts.Overwrite && translated && !sameForms(baselines, changedForms(current, incoming)):
+				conflict.Reason = "translation was modified after the export"
+				report.Conflicts = append(report.Conflicts, conflict)
+			case translated:
+				report.Updated++
+				changes[unit.ID] = mergeForms(current, incoming)
+			default:
+				report.Created++
+				changes[unit.ID] = incoming
+			}
+		}
+	}
+
+	if opts.DryRun || len(changes) == 0 {
+		return report, nil
+	}
+
+	messages := make([]*entities.LocaleMessage, 0, len(changes))
+	for id, message := range changes {
+		var forms map[string]string
+		for form, text := range message {
+			if form == i18n.FormOther {
+				continue
+			}
+			if forms == nil {
+				forms = make(map[string]string)
+			}
+			forms[form] = text
+		}
+		messages = append(messages, &entities.LocaleMessage{
+			Lang:      target.String(),
+			MessageID: id,
+			Text:      message[i18n.FormOther],
+			Forms:     forms,
+		})
+	}
+	if err := s.translationRepo.SaveLocaleMessages(ctx, messages); err != nil {
+		return nil, err
+	}
+	if err := s.localizer.SetMessages(target, changes); err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	return report, nil
+}
+
+// LoadLocaleMessages applies the stored UI message overrides to the localizer
+func (s *translationMemoryService) LoadLocaleMessages(ctx context.Context) error {
+	messages, err := s.translationRepo.ListLocaleMessages(ctx)
+	if err != nil {
+		return err
+	}
+
+	byLang := make(map[string]map[string]i18n.Message)
+	for _, message := range messages {
+		if byLang[message.Lang] == nil {
+			byLang[message.Lang] = make(map[string]i18n.Message)
+		}
+		texts := i18n.Message{i18n.FormOther: message.Text}
+		for form, text := range message.Forms {
+			texts[form] = text
+		}
+		byLang[message.Lang][message.MessageID] = texts
+	}
+
+	for lang, texts := range byLang {
+		tag, err := language.Parse(lang)
+		if err != nil {
+			return errors.NewInternalServerError(fmt.Sprintf("invalid locale message language %q", lang))
+		}
+		if err := s.localizer.SetMessages(tag, texts); err != nil {
+			return errors.NewInternalServerError(err.Error())
+		}
+	}
+	return nil
+}
+
+// messageForms returns the forms of a language along with the ones a
+// translation already has, in the CLDR order
+func messageForms(langForms []string, translation i18n.Message) []string {
+	used := make(map[string]bool, len(langForms))
+	for _, form := range langForms {
+		used[form] = true
+	}
+	var forms []string
+	for _, form := range i18n.PluralForms {
+		if _, ok := translation[form]; ok || used[form] {
+			forms = append(forms, form)
+		}
+	}
+	return forms
+}
+
+// unitForms returns the source texts, the translations and the exported
+// translations of a unit by plural form. Segments without a form name are
+// joined into the "other" form.
+func unitForms(unit *xliff.Unit) (sources, targets, baselines i18n.Message) {
+	sources, targets, baselines = make(i18n.Message), make(i18n.Message), make(i18n.Message)
+	for _, segment := range unit.Segments {
+		form := segment.ID
+		if !isPluralForm(form) {
+			form = i18n.FormOther
+		}
+		sources[form] += segment.Source
+		if segment.Target != "" {
+			targets[form] += segment.Target
+		}
+	}
+	for _, note := range unit.Notes {
+		if note.Category != xliffBaselineNote {
+			continue
+		}
+		form := note.ID
+		if !isPluralForm(form) {
+			form = i18n.FormOther
+		}
+		baselines[form] = note.Text
+	}
+	return sources, targets, baselines
+}
+
+func isPluralForm(name string) bool {
+	for _, form := range i18n.PluralForms {
+		if form == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sameForms tells whether the stored message has the texts of every form of incoming
+func sameForms(stored, incoming i18n.Message) bool {
+	for form, text := range incoming {
+		if current, ok := stored[form]; !ok || current != text {
+			return false
+		}
+	}
+	return true
+}
+
+// sameSources tells whether the source texts of the translated forms are
+// still the ones of the message
+func sameSources(source, unitSources, translated i18n.Message) bool {
+	for form := range translated {
+		if unitSources[form] != source.Text(form) {
+			return false
+		}
+	}
+	return true
+}
+
+// changedForms returns the stored texts of the forms incoming changes
+func changedForms(stored, incoming i18n.Message) i18n.Message {
+	changed := make(i18n.Message)
+	for form, text := range incoming {
+		if current, ok := stored[form]; ok && current != text {
+			changed[form] = current
+		}
+	}
+	return changed
+}
+
+// mergeForms returns the stored message with the forms of incoming replaced
+func mergeForms(stored, incoming i18n.Message) i18n.Message {
+	merged := make(i18n.Message, len(stored)+len(incoming))
+	for form, text := range stored {
+		merged[form] = text
+	}
+	for form, text := range incoming {
+		merged[form] = text
+	}
+	return merged
+}
+
+// formatMessage returns the text of a message, listing the plural forms when it has some
+func formatMessage(message i18n.Message) string {
+	if !message.IsPlural() {
+		return message[i18n.FormOther]
+	}
+	var parts []string
+	for _, form := range i18n.PluralForms {
+		if text, ok := message[form]; ok {
+			parts = append(parts, form+": "+text)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+func sourceLangOrEmpty(lang string) string {
+	if lang == undeterminedLang || lang == "*all*" {
+		return ""
+	}
+	return lang
+}
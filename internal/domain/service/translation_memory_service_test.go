@@ -0,0 +1,231 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/errors"
+	"clean-arch-go/internal/pkg/i18n"
+	"clean-arch-go/internal/pkg/tmx"
+	"clean-arch-go/internal/pkg/xliff"
+
+	"golang.org/x/text/language"
+)
+
+// fakeLocaleMessageRepository keeps the saved UI messages
+type fakeLocaleMessageRepository struct {
+	repository.TranslationRepository
+	messages []*entities.LocaleMessage
+}
+
+func (r *fakeLocaleMessageRepository) SaveLocaleMessages(ctx context.Context, messages []*entities.LocaleMessage) error {
+	r.messages = append(r.messages, messages...)
+	return nil
+}
+
+// exportUnit exports the UI messages into a language and returns the unit of a message
+func exportUnit(t *testing.T, s TranslationMemoryService, lang, id string) *xliff.Unit {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := s.ExportXLIFF(context.Background(), &buf, lang); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := xliff.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range doc.Files[0].Units {
+		if unit := &doc.Files[0].Units[i]; unit.ID == id {
+			return unit
+		}
+	}
+	t.Fatalf("no unit %s exported", id)
+	return nil
+}
+
+func importUnit(t *testing.T, s TranslationMemoryService, lang string, unit *xliff.Unit) *ImportReport {
+	t.Helper()
+	doc := xliff.NewDocument("en", lang)
+	doc.Files = []xliff.File{{ID: "messages", Units: []xliff.Unit{*unit}}}
+	var buf bytes.Buffer
+	if err := xliff.Encode(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	report, err := s.ImportXLIFF(context.Background(), &buf, ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestXLIFFPluralForms(t *testing.T) {
+	localizer := i18n.GetLocalizer()
+	ru := language.Russian
+	if err := localizer.SetMessages(language.English, map[string]i18n.Message{
+		"test.plural_books": {i18n.FormOne: "{{.Count}} book", i18n.FormOther: "{{.Count}} books"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := localizer.SetMessages(ru, map[string]i18n.Message{
+		"test.plural_books": {i18n.FormOne: "{{.Count}} книга", i18n.FormOther: "{{.Count}} книги"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	repo := &fakeLocaleMessageRepository{}
+	s := NewTranslationMemoryService(repo, localizer)
+
+	// Every form of the target language gets a segment, translated or not
+	unit := exportUnit(t, s, "ru", "test.plural_books")
+	want := []struct{ form, source, target string }{
+		{i18n.FormOne, "{{.Count}} book", "{{.Count}} книга"},
+		{i18n.FormFew, "{{.Count}} books", ""},
+		{i18n.FormMany, "{{.Count}} books", ""},
+		{i18n.FormOther, "{{.Count}} books", "{{.Count}} книги"},
+	}
+	if len(unit.Segments) != len(want) || len(unit.Notes) != 2 {
+		t.Fatalf("unit = %+v, want a segment per Russian form", unit)
+	}
+	for i, w := range want {
+		if segment := unit.Segments[i]; segment.ID != w.form || segment.Source != w.source || segment.Target != w.target {
+			t.Errorf("segment %d = %+v, want %+v", i, segment, w)
+		}
+	}
+
+	// Importing the reviewed forms saves them along with the ones left out
+	unit.Segments[1].Target = "{{.Count}} книги"
+	unit.Segments[2].Target = "{{.Count}} книг"
+	if report := importUnit(t, s, "ru", unit); report.Updated != 1 || len(report.Conflicts) != 0 {
+		t.Fatalf("import = %+v, want the message updated", report)
+	}
+	if len(repo.messages) != 1 || repo.messages[0].Text != "{{.Count}} книги" || len(repo.messages[0].Forms) != 3 ||
+		repo.messages[0].Forms[i18n.FormMany] != "{{.Count}} книг" {
+		t.Errorf("saved messages = %+v", repo.messages)
+	}
+	if message := localizer.Messages(ru)["test.plural_books"]; len(message) != 4 || message[i18n.FormFew] != "{{.Count}} книги" {
+		t.Errorf("localizer message = %v, want the four forms", message)
+	}
+	if report := importUnit(t, s, "ru", unit); report.Unchanged != 1 {
+		t.Errorf("second import = %+v, want the message unchanged", report)
+	}
+
+	// A form changed after the export is a conflict
+	exported := exportUnit(t, s, "ru", "test.plural_books")
+	if err := localizer.SetMessages(ru, map[string]i18n.Message{
+		"test.plural_books": {i18n.FormOne: "{{.Count}} книжка", i18n.FormFew: "{{.Count}} книги", i18n.FormMany: "{{.Count}} книг", i18n.FormOther: "{{.Count}} книги"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	exported.Segments[0].Target = "{{.Count}} том"
+	report := importUnit(t, s, "ru", exported)
+	if len(report.Conflicts) != 1 || report.Conflicts[0].Reason != "translation was modified after the export" {
+		t.Errorf("import = %+v, want a conflict", report)
+	}
+}
+
+// fakeTranslationMemory keeps the stored translations, failing the lookups
+// of failText and the saves while saveErr is set
+type fakeTranslationMemory struct {
+	repository.TranslationRepository
+	translations []*entities.Translation
+	saves        [][]*entities.Translation
+	failText     string
+	saveErr      error
+}
+
+func (r *fakeTranslationMemory) FindTranslation(ctx context.Context, text string, targetLang string) (*entities.Translation, error) {
+	if text == r.failText {
+		return nil, errors.NewInternalServerError("connection lost")
+	}
+	for _, translation := range r.translations {
+		if translation.SourceText == text && translation.TargetLang == targetLang {
+			found := *translation
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeTranslationMemory) SaveTranslations(ctx context.Context, translations []*entities.Translation) error {
+	if r.saveErr != nil {
+		return r.saveErr
+	}
+	r.saves = append(r.saves, translations)
+	return nil
+}
+
+// tmxFile encodes units of English texts and their French translations
+func tmxFile(t *testing.T, pairs ...string) *bytes.Buffer {
+	t.Helper()
+	doc := tmx.NewDocument("en")
+	for i := 0; i < len(pairs); i += 2 {
+		doc.Units = append(doc.Units, tmx.Unit{
+			ChangeDate: "20260101T000000Z",
+			Variants:   []tmx.Variant{{Lang: "en", Segment: pairs[i]}, {Lang: "fr", Segment: pairs[i+1]}},
+		})
+	}
+	var buf bytes.Buffer
+	if err := tmx.Encode(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestImportTMXSavesEverythingAtOnce(t *testing.T) {
+	repo := &fakeTranslationMemory{translations: []*entities.Translation{
+		{SourceText: "Hello", TargetLang: "fr", TranslatedText: "Salut"},
+		{SourceText: "Bye", TargetLang: "fr", TranslatedText: "Au revoir"},
+	}}
+	s := NewTranslationMemoryService(repo, i18n.GetLocalizer())
+
+	report, err := s.ImportTMX(context.Background(), tmxFile(t, "Hello", "Bonjour", "Bye", "Au revoir", "Book", "Libre", "Book", "Livre"), ImportOptions{Overwrite: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Created != 1 || report.Updated != 1 || report.Unchanged != 1 {
+		t.Errorf("report = %+v, want 1 created, 1 updated and 1 unchanged", report)
+	}
+	if len(repo.saves) != 1 {
+		t.Fatalf("%d saves, want one for the whole file", len(repo.saves))
+	}
+	saved := map[string]string{}
+	for _, translation := range repo.saves[0] {
+		saved[translation.SourceText] = translation.TranslatedText
+	}
+	// The last unit of a repeated text wins
+	if len(saved) != 2 || saved["Hello"] != "Bonjour" || saved["Book"] != "Livre" {
+		t.Errorf("saved %v", saved)
+	}
+}
+
+func TestImportTMXSavesNothingWhenItFails(t *testing.T) {
+	repo := &fakeTranslationMemory{failText: "Bye"}
+	s := NewTranslationMemoryService(repo, i18n.GetLocalizer())
+
+	if _, err := s.ImportTMX(context.Background(), tmxFile(t, "Hello", "Bonjour", "Bye", "Au revoir"), ImportOptions{}); err == nil {
+		t.Fatal("the import of a file whose lookups fail succeeded")
+	}
+	if len(repo.saves) != 0 {
+		t.Errorf("saved %v before the import failed", repo.saves)
+	}
+
+	repo.failText, repo.saveErr = "", errors.NewInternalServerError("deadlock")
+	if report, err := s.ImportTMX(context.Background(), tmxFile(t, "Hello", "Bonjour"), ImportOptions{}); err == nil || report != nil {
+		t.Errorf("ImportTMX = %+v, %v, want the save error", report, err)
+	}
+}
+
+func TestImportTMXDryRunSavesNothing(t *testing.T) {
+	repo := &fakeTranslationMemory{}
+	s := NewTranslationMemoryService(repo, i18n.GetLocalizer())
+
+	report, err := s.ImportTMX(context.Background(), tmxFile(t, "Hello", "Bonjour"), ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Created != 1 || len(repo.saves) != 0 {
+		t.Errorf("report = %+v, saves = %v, want 1 created and nothing saved", report, repo.saves)
+	}
+}
@@ -2,46 +2,118 @@ package service
 
 import (
 	"context"
+	"log"
+	"sort"
 	"sync"
+	"time"
 
 	"clean-arch-go/internal/application/translation"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	domaintranslation "clean-arch-go/internal/domain/translation"
 	"clean-arch-go/internal/errors"
+	"clean-arch-go/internal/pkg/i18n"
+
+	"golang.org/x/text/language"
 )
 
+// TranslationRequest describes a text to translate
+type TranslationRequest struct {
+	Text       string
+	SourceLang string
+	TargetLang string
+	// UserID selects whose glossaries are applied, none when empty
+	UserID string
+	// TenantID adds the glossaries shared with the tenant of the user
+	TenantID string
+}
+
+// TranslationResult holds a translated text together with the languages used
+type TranslationResult struct {
+	Text       string
+	SourceLang string
+	TargetLang string
+	// Detected is true when SourceLang was determined by the language detector
+	Detected bool
+	// Confidence of the detected source language, zero when it was provided
+	Confidence float64
+}
+
 // TranslationService defines the interface for translation operations
 type TranslationService interface {
-	Translate(ctx context.Context, text string, sourceLang string, targetLang string) (string, error)
-	GetSupportedLanguages(ctx context.Context) ([]string, error)
+	Translate(ctx context.Context, req *TranslationRequest) (*TranslationResult, error)
+	DetectLanguage(ctx context.Context, text string) (*domaintranslation.Detection, error)
+	GetSupportedLanguages(ctx context.Context) ([]*domaintranslation.Language, error)
 }
 
 type translationService struct {
-	usecase translation.TranslationUsecase
-	cache    map[string]string
-	mu       sync.RWMutex
+	usecase      translation.TranslationUsecase
+	detector     domaintranslation.LanguageDetector
+	glossaryRepo repository.GlossaryRepository
+	cache        map[string]string
+	mu           sync.RWMutex
+
+	// Supported languages are cached for languagesTTL
+	languages        []*domaintranslation.Language
+	languagesExpires time.Time
+	languagesTTL     time.Duration
+	languagesMu      sync.Mutex
 }
 
 // NewTranslationService creates a new translation service
-func NewTranslationService(usecase translation.TranslationUsecase) TranslationService {
+func NewTranslationService(
+	usecase translation.TranslationUsecase,
+	detector domaintranslation.LanguageDetector,
+	glossaryRepo repository.GlossaryRepository,
+	languagesTTL time.Duration,
+) TranslationService {
 	return &translationService{
-		usecase: usecase,
-		cache:   make(map[string]string),
+		usecase:      usecase,
+		detector:     detector,
+		glossaryRepo: glossaryRepo,
+		cache:        make(map[string]string),
+		languagesTTL: languagesTTL,
 	}
 }
 
-func (s *translationService) Translate(ctx context.Context, text string, sourceLang string, targetLang string) (string, error) {
+func (s *translationService) Translate(ctx context.Context, req *TranslationRequest) (*TranslationResult, error) {
+	result := &TranslationResult{
+		SourceLang: req.SourceLang,
+		TargetLang: req.TargetLang,
+	}
+
+	// Detect the source language when it's not provided
+	if req.SourceLang == "" {
+		detection, err := s.DetectLanguage(ctx, req.Text)
+		if err != nil {
+			return nil, err
+		}
+		result.SourceLang = detection.Lang
+		result.Detected = true
+		result.Confidence = detection.Confidence
+	}
+
+	// Protect glossary terms from the provider
+	terms, err := s.glossaryTerms(ctx, req.UserID, req.TenantID, req.TargetLang)
+	if err != nil {
+		return nil, err
+	}
+	text, mask := applyGlossary(req.Text, terms)
+
 	// Check cache first
-	cacheKey := s.generateCacheKey(text, sourceLang, targetLang)
+	cacheKey := s.generateCacheKey(text, result.SourceLang, req.TargetLang)
 	s.mu.RLock()
 	if translation, ok := s.cache[cacheKey]; ok {
 		s.mu.RUnlock()
-		return translation, nil
+		result.Text = mask.restore(translation)
+		return result, nil
 	}
 	s.mu.RUnlock()
 
 	// Get translation from usecase
-	translation, err := s.usecase.Translate(ctx, text, targetLang)
+	translation, err := s.usecase.Translate(ctx, text, result.SourceLang, req.TargetLang)
 	if err != nil {
-		return "", errors.NewInternalServerError(err.Error())
+		return nil, errors.NewInternalServerError(err.Error())
 	}
 
 	// Cache the result
@@ -49,12 +121,74 @@ func (s *translationService) Translate(ctx context.Context, text string, sourceL
 	s.cache[cacheKey] = translation
 	s.mu.Unlock()
 
-	return translation, nil
+	result.Text = mask.restore(translation)
+	return result, nil
+}
+
+// glossaryTerms returns the glossary terms of the user and their tenant that
+// apply to targetLang
+func (s *translationService) glossaryTerms(ctx context.Context, userID, tenantID string, targetLang string) ([]*entities.GlossaryTerm, error) {
+	if userID == "" || s.glossaryRepo == nil {
+		return nil, nil
+	}
+	return s.glossaryRepo.ListTermsForUser(ctx, userID, tenantID, targetLang)
+}
+
+func (s *translationService) DetectLanguage(ctx context.Context, text string) (*domaintranslation.Detection, error) {
+	if s.detector == nil {
+		return nil, domaintranslation.ErrLanguageUndetermined
+	}
+	return s.detector.Detect(ctx, text)
 }
 
-func (s *translationService) GetSupportedLanguages(ctx context.Context) ([]string, error) {
-	// In a real implementation, this would fetch supported languages from a configuration or API
-	return []string{"en", "vi", "fr", "es"}, nil
+// GetSupportedLanguages returns the configured translation languages and the
+// UI locales, sorted by code
+func (s *translationService) GetSupportedLanguages(ctx context.Context) ([]*domaintranslation.Language, error) {
+	s.languagesMu.Lock()
+	defer s.languagesMu.Unlock()
+
+	if s.languages != nil && time.Now().Before(s.languagesExpires) {
+		return s.languages, nil
+	}
+
+	codes, err := s.usecase.ConfiguredLanguages(ctx)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err.Error())
+	}
+
+	languages := make(map[string]*domaintranslation.Language)
+	lookup := func(tag language.Tag) *domaintranslation.Language {
+		lang, ok := languages[tag.String()]
+		if !ok {
+			lang = domaintranslation.NewLanguage(tag)
+			languages[tag.String()] = lang
+		}
+		return lang
+	}
+
+	for _, code := range codes {
+		tag, err := language.Parse(code)
+		if err != nil {
+			log.Printf("Ignoring invalid configured language %q: %v", code, err)
+			continue
+		}
+		lookup(tag).Translatable = true
+	}
+	for _, tag := range i18n.GetLocalizer().LanguageTags() {
+		lookup(tag).UILocale = true
+	}
+
+	result := make([]*domaintranslation.Language, 0, len(languages))
+	for _, lang := range languages {
+		result = append(result, lang)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Code < result[j].Code
+	})
+
+	s.languages = result
+	s.languagesExpires = time.Now().Add(s.languagesTTL)
+	return result, nil
 }
 
 func (s *translationService) generateCacheKey(text, sourceLang, targetLang string) string {
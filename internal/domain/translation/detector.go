@@ -0,0 +1,24 @@
+package translation
+
+import (
+	"clean-arch-go/internal/errors"
+	"context"
+)
+
+// Detection is the result of identifying the language of a text
+type Detection struct {
+	// Lang is the detected language code (ISO 639-1)
+	Lang string `json:"lang"`
+	// Confidence is the probability of the detected language, between 0 and 1
+	Confidence float64 `json:"confidence"`
+}
+
+// LanguageDetector defines the interface for source-language detection
+//go:generate mockery --name=LanguageDetector --output=../mocks/translation
+
+type LanguageDetector interface {
+	Detect(ctx context.Context, text string) (*Detection, error)
+}
+
+// ErrLanguageUndetermined is returned when the language of a text cannot be detected
+var ErrLanguageUndetermined = errors.NewAppError("LANGUAGE_UNDETERMINED", "Unable to detect source language", nil)
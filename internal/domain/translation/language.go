@@ -0,0 +1,63 @@
+package translation
+
+import (
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// Text directions of a language
+const (
+	DirectionLTR = "ltr"
+	DirectionRTL = "rtl"
+)
+
+// rtlScripts are the scripts written from right to left
+var rtlScripts = map[string]bool{
+	"Adlm": true,
+	"Arab": true,
+	"Hebr": true,
+	"Mand": true,
+	"Nkoo": true,
+	"Rohg": true,
+	"Samr": true,
+	"Syrc": true,
+	"Thaa": true,
+}
+
+// Language describes a language known to the application
+type Language struct {
+	// Code is the BCP-47 language tag
+	Code string `json:"code"`
+	// Name is the English name of the language
+	Name string `json:"name"`
+	// NativeName is the name of the language in the language itself
+	NativeName string `json:"native_name"`
+	// Direction is the text direction, ltr or rtl
+	Direction string `json:"direction"`
+	// UILocale is true when the user interface is available in the language
+	UILocale bool `json:"ui_locale"`
+	// Translatable is true when texts can be translated into the language
+	Translatable bool `json:"translatable"`
+}
+
+// NewLanguage builds the metadata of a language from its BCP-47 tag
+func NewLanguage(tag language.Tag) *Language {
+	lang := &Language{
+		Code:       tag.String(),
+		Name:       display.English.Tags().Name(tag),
+		NativeName: display.Self.Name(tag),
+		Direction:  DirectionLTR,
+	}
+
+	if script, _ := tag.Script(); rtlScripts[script.String()] {
+		lang.Direction = DirectionRTL
+	}
+	if lang.Name == "" {
+		lang.Name = lang.Code
+	}
+	if lang.NativeName == "" {
+		lang.NativeName = lang.Name
+	}
+
+	return lang
+}
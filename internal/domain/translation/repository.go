@@ -11,9 +11,10 @@ import (
 //go:generate mockery --name=Repository --output=../mocks/translation
 
 type Repository interface {
-	Translate(ctx context.Context, text string, targetLang string) (string, error)
+	Translate(ctx context.Context, text string, sourceLang string, targetLang string) (string, error)
 	GetTranslation(ctx context.Context, text string, targetLang string) (string, error)
 	SaveTranslation(ctx context.Context, text string, targetLang string, translation string) error
+	ConfiguredLanguages(ctx context.Context) ([]string, error)
 }
 
 // ErrTranslationNotFound is returned when translation is not found
@@ -30,7 +31,7 @@ type translationRepository struct {
 	db *gorm.DB
 }
 
-func (r *translationRepository) Translate(ctx context.Context, text string, targetLang string) (string, error) {
+func (r *translationRepository) Translate(ctx context.Context, text string, sourceLang string, targetLang string) (string, error) {
 	// Implementation here
 	return "", ErrTranslationNotFound
 }
@@ -44,3 +45,8 @@ func (r *translationRepository) SaveTranslation(ctx context.Context, text string
 	// Implementation here
 	return nil
 }
+
+func (r *translationRepository) ConfiguredLanguages(ctx context.Context) ([]string, error) {
+	// Implementation here
+	return nil, nil
+}
@@ -6,12 +6,14 @@ import (
 	"time"
 )
 
-// User represents a user entity
+// User represents a user entity. TenantID is the organization the user
+// belongs to, empty for none; the users of a tenant share its glossaries.
 type User struct {
 	ID        string    `json:"id" gorm:"primaryKey"`
 	Email     string    `json:"email" gorm:"unique;not null"`
 	Password  string    `json:"-" gorm:"not null"`
 	Name      string    `json:"name" gorm:"not null"`
+	TenantID  string    `json:"tenant_id,omitempty" gorm:"size:36;index"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
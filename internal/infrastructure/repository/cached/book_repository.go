@@ -2,143 +2,259 @@ package cached
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"log"
 	"time"
 
+	"clean-arch-go/internal/domain/book"
 	"clean-arch-go/internal/domain/entities"
 	"clean-arch-go/internal/domain/repository"
-	"clean-arch-go/internal/pkg/redis"
 )
 
+// cachedBookRepository caches the books found by ID
 type cachedBookRepository struct {
-	repo    repository.BookRepository
-	cache   *redis.RedisClient
-	keyFunc func(id string) string
+	*CachedRepository[entities.Book]
+	repo repository.BookRepository
 }
 
-// NewCachedBookRepository creates a new cached book repository
+// NewCachedBookRepository creates a new cached book repository, keeping
+// books at book:<id> unless options say otherwise
 func NewCachedBookRepository(
 	repo repository.BookRepository,
-	cache *redis.RedisClient,
+	client Client,
+	options Options[entities.Book],
 ) repository.BookRepository {
-	keyFunc := func(id string) string {
-		return fmt.Sprintf("book:%s", id)
+	if options.KeyFunc == nil {
+		options.KeyFunc = KeyPrefix("book")
+	}
+	if options.IDFunc == nil {
+		options.IDFunc = func(b *entities.Book) string { return b.ID }
 	}
 
 	return &cachedBookRepository{
-		repo:    repo,
-		cache:   cache,
-		keyFunc: keyFunc,
+		CachedRepository: NewCachedRepository[entities.Book](repo, client, options),
+		repo:             repo,
 	}
 }
 
-// Helper methods for caching
-func (r *cachedBookRepository) getCached(ctx context.Context, key string, dest *entities.Book) (bool, error) {
-	data, err := r.cache.Get(ctx, key)
-	if err != nil || data == "" {
-		return false, err
+// FindByISBN finds a user's book by ISBN (not cached)
+func (r *cachedBookRepository) FindByISBN(ctx context.Context, userID, isbn string) (*entities.Book, error) {
+	return r.repo.FindByISBN(ctx, userID, isbn)
+}
+
+// ListPage lists a page of the books matching filter with cursors
+func (r *cachedBookRepository) ListPage(ctx context.Context, filter *book.Filter, cursor repository.CursorQuery) (*repository.CursorPage[entities.Book], error) {
+	return r.repo.ListPage(ctx, filter, cursor)
+}
+
+// CountMatching counts the books matching filter
+func (r *cachedBookRepository) CountMatching(ctx context.Context, filter *book.Filter) (int64, error) {
+	return r.repo.CountMatching(ctx, filter)
+}
+
+// FindPage finds a page of books based on the query with cursors
+func (r *cachedBookRepository) FindPage(ctx context.Context, query interface{}, cursor repository.CursorQuery) (*repository.CursorPage[entities.Book], error) {
+	return r.repo.FindPage(ctx, query, cursor)
+}
+
+// ListByUserID lists books by user ID with pagination
+func (r *cachedBookRepository) ListByUserID(ctx context.Context, userID string, page, limit int) ([]*entities.Book, error) {
+	return r.repo.ListByUserID(ctx, userID, page, limit)
+}
+
+// ListTranslations lists the translations of the given books
+func (r *cachedBookRepository) ListTranslations(ctx context.Context, bookIDs ...string) ([]*entities.BookTranslation, error) {
+	return r.repo.ListTranslations(ctx, bookIDs...)
+}
+
+// FindTranslation finds the translation of a book into a language
+func (r *cachedBookRepository) FindTranslation(ctx context.Context, bookID, lang string) (*entities.BookTranslation, error) {
+	return r.repo.FindTranslation(ctx, bookID, lang)
+}
+
+// SaveTranslation creates or replaces the translation of a book and
+// invalidates the cache, the book version having changed
+func (r *cachedBookRepository) SaveTranslation(ctx context.Context, translation *entities.BookTranslation) error {
+	if err := r.repo.SaveTranslation(ctx, translation); err != nil {
+		return err
 	}
-	return true, json.Unmarshal([]byte(data), dest)
+	return r.Evict(ctx, r.Key(translation.BookID))
 }
 
-func (r *cachedBookRepository) setCached(ctx context.Context, key string, value entities.Book) error {
-	data, err := json.Marshal(value)
-	if err != nil {
-		return fmt.Errorf("failed to marshal book: %w", err)
+// DeleteTranslation deletes the translation of a book into a language and
+// invalidates the cache, the book version having changed
+func (r *cachedBookRepository) DeleteTranslation(ctx context.Context, bookID, lang string) error {
+	if err := r.repo.DeleteTranslation(ctx, bookID, lang); err != nil {
+		return err
 	}
-	return r.cache.Set(ctx, key, string(data), 5*time.Minute)
+	return r.Evict(ctx, r.Key(bookID))
 }
 
-func (r *cachedBookRepository) deleteCached(ctx context.Context, key string) error {
-	return r.cache.Del(ctx, key)
+// Search searches books, results are not cached
+func (r *cachedBookRepository) Search(ctx context.Context, filter *book.Filter) (*repository.BookSearchResult, error) {
+	return r.repo.Search(ctx, filter)
 }
 
-func (r *cachedBookRepository) FindByID(ctx context.Context, id string) (*entities.Book, error) {
-	key := r.keyFunc(id)
+// SearchFacets counts the books matching a search by facet
+func (r *cachedBookRepository) SearchFacets(ctx context.Context, filter *book.Filter) (map[string][]book.FacetCount, error) {
+	return r.repo.SearchFacets(ctx, filter)
+}
 
-	// Try to get from cache
-	var b entities.Book
-	found, err := r.getCached(ctx, key, &b)
-	if err != nil {
-		return nil, err
+// CreateDetails creates a book with its tags and revision and invalidates
+// the cache
+func (r *cachedBookRepository) CreateDetails(ctx context.Context, b *entities.Book, tags []string, revise repository.BookReviser) error {
+	if err := r.repo.CreateDetails(ctx, b, tags, revise); err != nil {
+		return err
 	}
-	if found {
-		return &b, nil
+	return r.Evict(ctx, r.Key(b.ID))
+}
+
+// UpdateDetails saves a book with its tags and revision and invalidates the
+// cache
+func (r *cachedBookRepository) UpdateDetails(ctx context.Context, before, b *entities.Book, tags []string, revise repository.BookReviser) error {
+	if err := r.repo.UpdateDetails(ctx, before, b, tags, revise); err != nil {
+		return err
 	}
+	return r.Evict(ctx, r.Key(b.ID))
+}
+
+// FindByIDs finds several books by ID, bypassing the cache
+func (r *cachedBookRepository) FindByIDs(ctx context.Context, ids []string) ([]*entities.Book, error) {
+	return r.repo.FindByIDs(ctx, ids)
+}
+
+// ListAfter lists books in ID order, bypassing the cache
+func (r *cachedBookRepository) ListAfter(ctx context.Context, afterID string, limit int) ([]*entities.Book, error) {
+	return r.repo.ListAfter(ctx, afterID, limit)
+}
+
+// LastChangedAt returns when a book was last updated or deleted
+func (r *cachedBookRepository) LastChangedAt(ctx context.Context) (time.Time, error) {
+	return r.repo.LastChangedAt(ctx)
+}
+
+// ListTags lists the tags of a user, bypassing the cache
+func (r *cachedBookRepository) ListTags(ctx context.Context, userID string) ([]*entities.Tag, error) {
+	return r.repo.ListTags(ctx, userID)
+}
+
+// FindTag finds a tag of a user, bypassing the cache
+func (r *cachedBookRepository) FindTag(ctx context.Context, userID string, id uint) (*entities.Tag, error) {
+	return r.repo.FindTag(ctx, userID, id)
+}
 
-	// Not in cache, get from repository
-	book, err := r.repo.FindByID(ctx, id)
+// FindTagByName finds a tag of a user by name, bypassing the cache
+func (r *cachedBookRepository) FindTagByName(ctx context.Context, userID, name string) (*entities.Tag, error) {
+	return r.repo.FindTagByName(ctx, userID, name)
+}
+
+// RenameTag renames a tag and invalidates the cache of its books
+func (r *cachedBookRepository) RenameTag(ctx context.Context, tag *entities.Tag, revise repository.BookReviser) ([]string, error) {
+	bookIDs, err := r.repo.RenameTag(ctx, tag, revise)
 	if err != nil {
 		return nil, err
 	}
+	return bookIDs, r.evictBooks(ctx, bookIDs)
+}
 
-	// Cache the result
-	if book != nil {
-		if err := r.setCached(ctx, key, *book); err != nil {
-			// Log error but don't fail the request
-			log.Printf("Failed to cache book %s: %v", id, err)
-		}
-		return book, nil
+// DeleteTag deletes a tag and invalidates the cache of its books
+func (r *cachedBookRepository) DeleteTag(ctx context.Context, tag *entities.Tag, revise repository.BookReviser) ([]string, error) {
+	bookIDs, err := r.repo.DeleteTag(ctx, tag, revise)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil, nil
+	return bookIDs, r.evictBooks(ctx, bookIDs)
 }
 
-func (r *cachedBookRepository) Create(ctx context.Context, b *entities.Book) error {
-	if err := r.repo.Create(ctx, b); err != nil {
+// RefreshRating recomputes the rating of a book and invalidates the cache
+func (r *cachedBookRepository) RefreshRating(ctx context.Context, bookID string) error {
+	if err := r.repo.RefreshRating(ctx, bookID); err != nil {
 		return err
 	}
+	return r.Evict(ctx, r.Key(bookID))
+}
 
-	// Invalidate cache
-	key := r.keyFunc(b.ID)
-	return r.deleteCached(ctx, key)
+// FindByShareToken finds the book with a share link, bypassing the cache
+func (r *cachedBookRepository) FindByShareToken(ctx context.Context, token string) (*entities.Book, error) {
+	return r.repo.FindByShareToken(ctx, token)
 }
 
-func (r *cachedBookRepository) Update(ctx context.Context, b *entities.Book) error {
-	if err := r.repo.Update(ctx, b); err != nil {
+// SetSharing saves the visibility and share link of a book and invalidates the cache
+func (r *cachedBookRepository) SetSharing(ctx context.Context, bookID, visibility string, shareToken *string) error {
+	if err := r.repo.SetSharing(ctx, bookID, visibility, shareToken); err != nil {
 		return err
 	}
+	return r.Evict(ctx, r.Key(bookID))
+}
 
-	// Invalidate cache
-	key := r.keyFunc(b.ID)
-	return r.deleteCached(ctx, key)
+// FindGrant finds the grant of a user on a book, bypassing the cache
+func (r *cachedBookRepository) FindGrant(ctx context.Context, bookID, userID string) (*entities.BookGrant, error) {
+	return r.repo.FindGrant(ctx, bookID, userID)
 }
 
-// Delete deletes a book by ID and invalidates the cache
-func (r *cachedBookRepository) Delete(ctx context.Context, id string) error {
-	// Invalidate cache first to avoid race conditions
-	key := r.keyFunc(id)
-	if err := r.deleteCached(ctx, key); err != nil {
-		log.Printf("Failed to invalidate cache for book %s: %v", id, err)
-	}
+// ListGrants lists the grants on a book, bypassing the cache
+func (r *cachedBookRepository) ListGrants(ctx context.Context, bookID string) ([]*entities.BookGrant, error) {
+	return r.repo.ListGrants(ctx, bookID)
+}
 
-	// Delete from repository
-	return r.repo.Delete(ctx, id)
+// SaveGrant creates or changes a grant on a book
+func (r *cachedBookRepository) SaveGrant(ctx context.Context, grant *entities.BookGrant) error {
+	return r.repo.SaveGrant(ctx, grant)
 }
 
-// Count returns the count of books matching the query
-func (r *cachedBookRepository) Count(ctx context.Context, query interface{}) (int64, error) {
-	return r.repo.Count(ctx, query)
+// DeleteGrant deletes the grant of a user on a book
+func (r *cachedBookRepository) DeleteGrant(ctx context.Context, bookID, userID string) error {
+	return r.repo.DeleteGrant(ctx, bookID, userID)
 }
 
-// FindAll returns all books with pagination
-func (r *cachedBookRepository) FindAll(ctx context.Context, page, limit int) ([]*entities.Book, error) {
-	return r.repo.FindAll(ctx, page, limit)
+// ListSharedBookIDs lists the books shared with a user, bypassing the cache
+func (r *cachedBookRepository) ListSharedBookIDs(ctx context.Context, userID string) ([]string, error) {
+	return r.repo.ListSharedBookIDs(ctx, userID)
 }
 
-// FindOne finds a single book based on the query
-func (r *cachedBookRepository) FindOne(ctx context.Context, query interface{}) (*entities.Book, error) {
-	return r.repo.FindOne(ctx, query)
+// ListDeleted lists the deleted books of a user, bypassing the cache
+func (r *cachedBookRepository) ListDeleted(ctx context.Context, userID string, page, limit int) ([]*entities.Book, int64, error) {
+	return r.repo.ListDeleted(ctx, userID, page, limit)
 }
 
-// FindMany finds multiple books based on the query with pagination
-func (r *cachedBookRepository) FindMany(ctx context.Context, query interface{}, page, limit int) ([]*entities.Book, error) {
-	return r.repo.FindMany(ctx, query, page, limit)
+// ListDeletedBefore lists the books deleted before a time, bypassing the cache
+func (r *cachedBookRepository) ListDeletedBefore(ctx context.Context, before time.Time, limit int) ([]*entities.Book, error) {
+	return r.repo.ListDeletedBefore(ctx, before, limit)
 }
 
-// ListByUserID lists books by user ID with pagination
-func (r *cachedBookRepository) ListByUserID(ctx context.Context, userID string, page, limit int) ([]*entities.Book, error) {
-	return r.repo.ListByUserID(ctx, userID, page, limit)
+// FindDeleted finds a deleted book, bypassing the cache
+func (r *cachedBookRepository) FindDeleted(ctx context.Context, id string) (*entities.Book, error) {
+	return r.repo.FindDeleted(ctx, id)
+}
+
+// Restore undeletes a book and invalidates the cache
+func (r *cachedBookRepository) Restore(ctx context.Context, id string) error {
+	if err := r.repo.Restore(ctx, id); err != nil {
+		return err
+	}
+	return r.Evict(ctx, r.Key(id))
+}
+
+// Purge deletes a book for good and invalidates the cache
+func (r *cachedBookRepository) Purge(ctx context.Context, id string) ([]*entities.Hold, error) {
+	holds, err := r.repo.Purge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return holds, r.Evict(ctx, r.Key(id))
+}
+
+// DeleteVersion deletes a book at a version and invalidates the cache
+func (r *cachedBookRepository) DeleteVersion(ctx context.Context, id string, version int) error {
+	if err := r.repo.DeleteVersion(ctx, id, version); err != nil {
+		return err
+	}
+	return r.Evict(ctx, r.Key(id))
+}
+
+// evictBooks invalidates the cache of books
+func (r *cachedBookRepository) evictBooks(ctx context.Context, ids []string) error {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.Key(id)
+	}
+	return r.Evict(ctx, keys...)
 }
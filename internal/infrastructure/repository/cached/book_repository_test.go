@@ -0,0 +1,141 @@
+package cached
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+)
+
+// fakeBookRepository keeps books in memory, counting the lookups by ID
+type fakeBookRepository struct {
+	repository.BookRepository
+	books   map[string]entities.Book
+	deleted map[string]entities.Book
+	finds   int
+}
+
+func newFakeBookRepository(books ...entities.Book) *fakeBookRepository {
+	r := &fakeBookRepository{books: make(map[string]entities.Book), deleted: make(map[string]entities.Book)}
+	for _, b := range books {
+		r.books[b.ID] = b
+	}
+	return r
+}
+
+func (r *fakeBookRepository) FindByID(ctx context.Context, id string) (*entities.Book, error) {
+	r.finds++
+	b, ok := r.books[id]
+	if !ok {
+		return nil, nil
+	}
+	return &b, nil
+}
+
+func (r *fakeBookRepository) UpdateDetails(ctx context.Context, before, b *entities.Book, tags []string, revise repository.BookReviser) error {
+	r.books[b.ID] = *b
+	return nil
+}
+
+func (r *fakeBookRepository) RenameTag(ctx context.Context, tag *entities.Tag, revise repository.BookReviser) ([]string, error) {
+	var ids []string
+	for id, b := range r.books {
+		b.Title += " (" + tag.Name + ")"
+		r.books[id] = b
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (r *fakeBookRepository) Delete(ctx context.Context, id string) error {
+	r.deleted[id] = r.books[id]
+	delete(r.books, id)
+	return nil
+}
+
+func (r *fakeBookRepository) Restore(ctx context.Context, id string) error {
+	r.books[id] = r.deleted[id]
+	delete(r.deleted, id)
+	return nil
+}
+
+func (r *fakeBookRepository) Purge(ctx context.Context, id string) ([]*entities.Hold, error) {
+	delete(r.deleted, id)
+	return []*entities.Hold{{ID: 5, BookID: id, UserID: "user-2"}}, nil
+}
+
+func TestCachedBookRepositoryKeepsBooksByID(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeBookRepository(entities.Book{ID: "1", Title: "Dune"})
+	client := newFakeClient()
+	books := NewCachedBookRepository(repo, client, Options[entities.Book]{})
+
+	books.FindByID(ctx, "1")
+	if b, err := books.FindByID(ctx, "1"); err != nil || b == nil || b.Title != "Dune" {
+		t.Fatalf("FindByID = %+v, %v", b, err)
+	}
+	if _, ok := client.entry("book:1"); !ok || repo.finds != 1 {
+		t.Errorf("book:1 cached %v, %d repository lookups, want it cached after one", ok, repo.finds)
+	}
+}
+
+func TestCachedBookRepositoryEvictsChangedBooks(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeBookRepository(entities.Book{ID: "1", Title: "Dune"}, entities.Book{ID: "2", Title: "Emma"})
+	books := NewCachedBookRepository(repo, newFakeClient(), Options[entities.Book]{})
+
+	books.FindByID(ctx, "1")
+	before, _ := books.FindByID(ctx, "1")
+	after := *before
+	after.Title = "Dune Messiah"
+	if err := books.UpdateDetails(ctx, before, &after, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := books.FindByID(ctx, "1"); b.Title != "Dune Messiah" {
+		t.Errorf("FindByID(1) = %q after the update", b.Title)
+	}
+
+	// Renaming a tag changes the books it's on
+	books.FindByID(ctx, "2")
+	if _, err := books.RenameTag(ctx, &entities.Tag{Name: "classic"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]string{"1": "Dune Messiah (classic)", "2": "Emma (classic)"} {
+		if b, _ := books.FindByID(ctx, id); b.Title != want {
+			t.Errorf("FindByID(%s) = %q after the tag rename, want %q", id, b.Title, want)
+		}
+	}
+}
+
+func TestCachedBookRepositoryEvictsRestoredAndPurgedBooks(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeBookRepository(entities.Book{ID: "1", Title: "Dune"})
+	client := newFakeClient()
+	books := NewCachedBookRepository(repo, client, Options[entities.Book]{NegativeTTL: time.Minute})
+
+	// The deleted book is remembered as missing until it's restored
+	repo.Delete(ctx, "1")
+	if b, _ := books.FindByID(ctx, "1"); b != nil {
+		t.Fatalf("FindByID(1) = %+v once deleted", b)
+	}
+	if err := books.Restore(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := books.FindByID(ctx, "1"); b == nil || b.Title != "Dune" {
+		t.Errorf("FindByID(1) = %+v after the restore, want Dune", b)
+	}
+
+	repo.Delete(ctx, "1")
+	holds, err := books.Purge(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holds) != 1 || holds[0].UserID != "user-2" {
+		t.Errorf("holds = %+v, want the hold of user-2", holds)
+	}
+	if _, ok := client.entry("book:1"); ok {
+		t.Error("book:1 still cached after the purge")
+	}
+}
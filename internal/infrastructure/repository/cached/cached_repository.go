@@ -1,53 +1,500 @@
 package cached
 
-// import (
-// 	"context"
-// 	"encoding/json"
-// 	"time"
-
-// 	"clean-arch-go/internal/domain/repository"
-// 	"clean-arch-go/internal/pkg/redis"
-// )
-
-// type cachedRepository[E any, R repository.BaseRepository[E]] struct {
-// 	repo    R
-// 	cache   *redis.RedisClient
-// 	keyFunc func(id string) string
-// 	ttl     time.Duration
-// }
-
-// func newCachedRepository[E any, R repository.BaseRepository[E]](
-// 	repo R,
-// 	cache *redis.RedisClient,
-// 	keyFunc func(id string) string,
-// ) *cachedRepository[E, R] {
-// 	return &cachedRepository[E, R]{
-// 		repo:    repo,
-// 		cache:   cache,
-// 		keyFunc: keyFunc,
-// 		ttl:     5 * time.Minute, // Default TTL
-// 	}
-// }
-
-// func (r *cachedRepository[E, R]) getCached(ctx context.Context, key string, dest *E) (bool, error) {
-// 	data, err := r.cache.Get(ctx, key)
-// 	if err != nil {
-// 		return false, err
-// 	}
-// 	if data == "" {
-// 		return false, nil
-// 	}
-// 	return true, json.Unmarshal([]byte(data), dest)
-// }
-
-// func (r *cachedRepository[E, R]) setCached(ctx context.Context, key string, value E) error {
-// 	data, err := json.Marshal(value)
-// 	if err != nil {
-// 		return err
-// 	}
-// 	return r.cache.Set(ctx, key, string(data), r.ttl)
-// }
-
-// func (r *cachedRepository[E, R]) deleteCached(ctx context.Context, key string) error {
-// 	return r.cache.Del(ctx, key)
-// }
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/pkg/redis"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// DefaultTTL is how long entities are cached unless said otherwise
+	DefaultTTL = 5 * time.Minute
+	// DefaultLoadTimeout bounds the loads shared by several callers and the
+	// background refreshes unless said otherwise
+	DefaultLoadTimeout = 10 * time.Second
+	// unavailableRetry is how long the cache is skipped after failing, when
+	// its errors are ignored
+	unavailableRetry = 5 * time.Second
+)
+
+// Client is the cache the repositories keep their entities in, Redis in
+// production. Get returns redis.Nil for missing keys.
+type Client interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Del(ctx context.Context, keys ...string) error
+}
+
+// Serializer encodes the cached values
+type Serializer interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonSerializer) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// JSON serializes the cached values as JSON
+var JSON Serializer = jsonSerializer{}
+
+// ErrorPolicy is what cached repositories do when the cache fails
+type ErrorPolicy int
+
+const (
+	// IgnoreErrors logs cache errors and carries on with the repository,
+	// skipping the cache for a few seconds, at the risk of serving stale
+	// entities until their TTL when an invalidation fails
+	IgnoreErrors ErrorPolicy = iota
+	// FailOnErrors returns cache errors to the caller
+	FailOnErrors
+)
+
+// Options configure a CachedRepository
+type Options[T any] struct {
+	// KeyFunc returns the cache key of the entity with an ID, required
+	KeyFunc func(id string) string
+	// IDFunc returns the ID of an entity, required
+	IDFunc func(entity *T) string
+	// TTL is how long entities are cached, DefaultTTL when not positive
+	TTL time.Duration
+	// StaleTTL is how long expired entities are still served while they're
+	// refreshed in the background, 0 to reload them before answering
+	StaleTTL time.Duration
+	// NegativeTTL is how long entities that weren't found are remembered as
+	// missing, 0 to look them up every time
+	NegativeTTL time.Duration
+	// EarlyExpiration weighs the load time of entities in the odds of
+	// refreshing them in the background before they expire, 0 to wait for
+	// their expiry and 1 for the usual odds
+	EarlyExpiration float64
+	// L1 keeps the entries in process memory in front of the cache, nil to
+	// always ask the cache. It can be shared by several repositories.
+	L1 *L1
+	// LoadTimeout bounds shared loads and background refreshes,
+	// DefaultLoadTimeout when not positive
+	LoadTimeout time.Duration
+	// Serializer encodes the cached entities, JSON when nil
+	Serializer Serializer
+	// ErrorPolicy is what to do when the cache fails
+	ErrorPolicy ErrorPolicy
+}
+
+// Stats counts the lookups of a cached repository since it was created
+type Stats struct {
+	// Hits are the lookups answered with a cached entity
+	Hits uint64 `json:"hits"`
+	// NegativeHits are the lookups answered with a cached tombstone
+	NegativeHits uint64 `json:"negative_hits"`
+	// Misses are the lookups that went to the repository
+	Misses uint64 `json:"misses"`
+	// L1Hits are the hits and negative hits answered from process memory
+	L1Hits uint64 `json:"l1_hits"`
+}
+
+// StatsReporter is a cache reporting the statistics of its lookups
+type StatsReporter interface {
+	Stats() Stats
+}
+
+// CachedRepository decorates a repository with a cache of the entities it
+// finds by ID, and of the IDs it doesn't find, invalidated as they're
+// changed or created. Other lookups go to the repository.
+type CachedRepository[T any] struct {
+	repo    repository.BaseRepository[T]
+	client  Client
+	options Options[T]
+	// loads coalesces the concurrent loads of a key
+	loads singleflight.Group
+	// generations tells the loads whether their key was evicted meanwhile
+	generations generations
+	// refreshing holds the keys refreshed in the background
+	refreshing sync.Map
+	// unavailableUntil is when to use the cache again after it failed, in
+	// Unix nanoseconds
+	unavailableUntil atomic.Int64
+
+	hits, negativeHits, misses, l1Hits atomic.Uint64
+}
+
+// Ensure CachedRepository implements repository.BaseRepository
+var _ repository.BaseRepository[struct{}] = (*CachedRepository[struct{}])(nil)
+
+// NewCachedRepository caches the entities of repo in client
+func NewCachedRepository[T any](repo repository.BaseRepository[T], client Client, options Options[T]) *CachedRepository[T] {
+	if options.KeyFunc == nil || options.IDFunc == nil {
+		panic("cached: KeyFunc and IDFunc are required")
+	}
+	if options.TTL <= 0 {
+		options.TTL = DefaultTTL
+	}
+	if options.Serializer == nil {
+		options.Serializer = JSON
+	}
+	if options.LoadTimeout <= 0 {
+		options.LoadTimeout = DefaultLoadTimeout
+	}
+	return &CachedRepository[T]{
+		repo:    repo,
+		client:  client,
+		options: options,
+	}
+}
+
+// KeyPrefix returns a key function prefixing IDs with prefix and a colon
+func KeyPrefix(prefix string) func(id string) string {
+	return func(id string) string {
+		return prefix + ":" + id
+	}
+}
+
+// Key returns the cache key of the entity with an ID
+func (r *CachedRepository[T]) Key(id string) string {
+	return r.options.KeyFunc(id)
+}
+
+// FindByID returns the cached entity, or finds it and caches it
+func (r *CachedRepository[T]) FindByID(ctx context.Context, id string) (*T, error) {
+	return r.Fetch(ctx, r.Key(id), func(ctx context.Context) (*T, error) {
+		return r.repo.FindByID(ctx, id)
+	})
+}
+
+// Fetch returns the entity cached at key, or loads it with load and caches
+// it, nil when it doesn't exist. Concurrent misses of a key share a single
+// load, and entities past or near their expiry are served while they're
+// refreshed in the background. Each caller gets its own copy of the entity.
+func (r *CachedRepository[T]) Fetch(ctx context.Context, key string, load func(ctx context.Context) (*T, error)) (*T, error) {
+	e, err := r.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if e != nil && e.tombstone {
+		r.negativeHits.Add(1)
+		return nil, nil
+	}
+	if e != nil {
+		var entity T
+		if err := r.options.Serializer.Unmarshal(e.value, &entity); err == nil {
+			r.hits.Add(1)
+			now := time.Now()
+			if e.expired(now) || e.expiresEarly(now, r.options.EarlyExpiration) {
+				r.refresh(key, load)
+			}
+			return &entity, nil
+		}
+		// Entries written by another version of the entity are replaced
+		log.Printf("Error decoding cached %s: %v", key, err)
+	}
+
+	r.misses.Add(1)
+	data, err := r.loadShared(ctx, key, load)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var entity T
+	if err := r.options.Serializer.Unmarshal(data, &entity); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", key, err)
+	}
+	return &entity, nil
+}
+
+// loadShared loads the entity of key and caches it, or its tombstone,
+// sharing the load with the concurrent callers. It returns the serialized
+// entity, nil when it doesn't exist. The load outlives the caller that
+// started it, up to the load timeout, so that it isn't cancelled for the
+// others.
+func (r *CachedRepository[T]) loadShared(ctx context.Context, key string, load func(ctx context.Context) (*T, error)) ([]byte, error) {
+	results := r.loads.DoChan(key, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.options.LoadTimeout)
+		defer cancel()
+		generation := r.generations.begin(key)
+		defer r.generations.end(key)
+
+		start := time.Now()
+		entity, err := load(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+		if entity == nil {
+			if r.options.NegativeTTL <= 0 {
+				return nil, nil
+			}
+			e := &entry{expiry: time.Now().Add(r.options.NegativeTTL), tombstone: true}
+			return nil, r.store(loadCtx, key, generation, e, r.options.NegativeTTL)
+		}
+		data, err := r.options.Serializer.Marshal(entity)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s: %w", key, err)
+		}
+		// Redis keeps entities for the TTL and then while they're served stale
+		e := &entry{expiry: time.Now().Add(r.options.TTL), delta: time.Since(start), value: data}
+		if err := r.store(loadCtx, key, generation, e, r.options.TTL+r.options.StaleTTL); err != nil {
+			return nil, err
+		}
+		return data, nil
+	})
+
+	select {
+	case result := <-results:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		data, _ := result.Val.([]byte)
+		return data, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh reloads the entity of key in the background, unless it's already
+// being refreshed
+func (r *CachedRepository[T]) refresh(key string, load func(ctx context.Context) (*T, error)) {
+	if _, busy := r.refreshing.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+	go func() {
+		defer r.refreshing.Delete(key)
+
+		ctx := context.Background()
+		data, err := r.loadShared(ctx, key, load)
+		if err != nil {
+			log.Printf("Error refreshing cached %s: %v", key, err)
+			return
+		}
+		// Stop serving entities that no longer exist, unless a tombstone
+		// already replaced them
+		if data == nil && r.options.NegativeTTL <= 0 {
+			if err := r.Evict(ctx, key); err != nil {
+				log.Printf("Error evicting cached %s: %v", key, err)
+			}
+		}
+	}()
+}
+
+// Create creates an entity and invalidates its cache
+func (r *CachedRepository[T]) Create(ctx context.Context, entity *T) error {
+	if err := r.repo.Create(ctx, entity); err != nil {
+		return err
+	}
+	return r.Evict(ctx, r.Key(r.options.IDFunc(entity)))
+}
+
+// Update updates an entity and invalidates its cache
+func (r *CachedRepository[T]) Update(ctx context.Context, entity *T) error {
+	if err := r.repo.Update(ctx, entity); err != nil {
+		return err
+	}
+	return r.Evict(ctx, r.Key(r.options.IDFunc(entity)))
+}
+
+// Delete deletes an entity and invalidates its cache
+func (r *CachedRepository[T]) Delete(ctx context.Context, id string) error {
+	if err := r.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	return r.Evict(ctx, r.Key(id))
+}
+
+// FindAll lists entities, bypassing the cache
+func (r *CachedRepository[T]) FindAll(ctx context.Context, page, limit int) ([]*T, error) {
+	return r.repo.FindAll(ctx, page, limit)
+}
+
+// FindOne finds an entity matching query, bypassing the cache
+func (r *CachedRepository[T]) FindOne(ctx context.Context, query interface{}) (*T, error) {
+	return r.repo.FindOne(ctx, query)
+}
+
+// FindMany finds the entities matching query, bypassing the cache
+func (r *CachedRepository[T]) FindMany(ctx context.Context, query interface{}, page, limit int) ([]*T, error) {
+	return r.repo.FindMany(ctx, query, page, limit)
+}
+
+// FindPage finds a page of the entities matching query, bypassing the cache
+func (r *CachedRepository[T]) FindPage(ctx context.Context, query interface{}, cursor repository.CursorQuery) (*repository.CursorPage[T], error) {
+	return r.repo.FindPage(ctx, query, cursor)
+}
+
+// Count counts the entities matching query, bypassing the cache
+func (r *CachedRepository[T]) Count(ctx context.Context, query interface{}) (int64, error) {
+	return r.repo.Count(ctx, query)
+}
+
+// Stats returns the statistics of the lookups of the cache
+func (r *CachedRepository[T]) Stats() Stats {
+	return Stats{
+		Hits:         r.hits.Load(),
+		NegativeHits: r.negativeHits.Load(),
+		Misses:       r.misses.Load(),
+		L1Hits:       r.l1Hits.Load(),
+	}
+}
+
+// get returns the entry kept at key by the L1 or the cache, nil when there's
+// none or the cache is unavailable
+func (r *CachedRepository[T]) get(ctx context.Context, key string) (*entry, error) {
+	if e := r.options.L1.get(key); e != nil {
+		r.l1Hits.Add(1)
+		return e, nil
+	}
+	if r.unavailable() {
+		return nil, nil
+	}
+	data, err := r.client.Get(ctx, key)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, r.failed("get", key, err)
+	}
+	e, err := decodeEntry(data)
+	if err != nil {
+		log.Printf("Error decoding cached %s: %v", key, err)
+		return nil, nil
+	}
+	r.options.L1.set(key, e)
+	return e, nil
+}
+
+// store caches an entry loaded at generation, unless its key was evicted
+// since: the load may have read the entity before the change that evicted
+// it, and caching it would serve the old entity, and its old version, until
+// the TTL
+func (r *CachedRepository[T]) store(ctx context.Context, key string, generation uint64, e *entry, ttl time.Duration) error {
+	if !r.generations.current(key, generation) {
+		return nil
+	}
+	if err := r.set(ctx, key, e, ttl); err != nil {
+		return err
+	}
+	// An eviction between the check and the write deleted nothing yet
+	if !r.generations.current(key, generation) {
+		r.options.L1.remove(key)
+		if err := r.client.Del(ctx, key); err != nil {
+			return r.failed("delete", key, err)
+		}
+	}
+	return nil
+}
+
+// set caches an entry at key, kept by Redis for ttl
+func (r *CachedRepository[T]) set(ctx context.Context, key string, e *entry, ttl time.Duration) error {
+	r.options.L1.set(key, e)
+	if r.unavailable() {
+		return nil
+	}
+	if err := r.client.Set(ctx, key, e.encode(), ttl); err != nil {
+		return r.failed("set", key, err)
+	}
+	return nil
+}
+
+// Evict removes the entities cached at keys, from the L1 of every instance
+// too. It's tried even when the cache failed recently, not to leave stale
+// entities behind.
+func (r *CachedRepository[T]) Evict(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	// Callers coming after the change don't join the loads started before,
+	// and these don't cache what they read
+	for _, key := range keys {
+		r.generations.bump(key)
+		r.loads.Forget(key)
+	}
+	var err error
+	if delErr := r.client.Del(ctx, keys...); delErr != nil {
+		err = r.failed("delete", fmt.Sprint(keys), delErr)
+	}
+	// Dropped from the L1 after the cache, not to be filled again from it
+	if pubErr := r.options.L1.Invalidate(ctx, keys...); pubErr != nil && err == nil {
+		err = r.failed("publish", fmt.Sprint(keys), pubErr)
+	}
+	return err
+}
+
+// unavailable tells whether the cache is skipped after failing
+func (r *CachedRepository[T]) unavailable() bool {
+	return time.Now().UnixNano() < r.unavailableUntil.Load()
+}
+
+// failed applies the error policy to a cache error. Ignored errors make the
+// cache unavailable for a few seconds, so that requests go to the
+// repository right away instead of waiting for the cache to time out.
+func (r *CachedRepository[T]) failed(op, key string, err error) error {
+	if r.options.ErrorPolicy == FailOnErrors {
+		return fmt.Errorf("cache %s %s: %w", op, key, err)
+	}
+	now := time.Now()
+	if r.unavailableUntil.Swap(now.Add(unavailableRetry).UnixNano()) < now.UnixNano() {
+		log.Printf("Cache unavailable for %s, using the repository: %s %s: %v", unavailableRetry, op, key, err)
+	}
+	return nil
+}
+
+// generations counts the evictions of the keys being loaded, forgetting the
+// keys once their loads are done
+type generations struct {
+	mu   sync.Mutex
+	keys map[string]*generation
+}
+
+type generation struct {
+	evictions uint64
+	loads     int
+}
+
+// begin records a load of key, returning the generation it started at
+func (g *generations) begin(key string) uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.keys == nil {
+		g.keys = make(map[string]*generation)
+	}
+	gen, ok := g.keys[key]
+	if !ok {
+		gen = &generation{}
+		g.keys[key] = gen
+	}
+	gen.loads++
+	return gen.evictions
+}
+
+// end records that a load of key is done
+func (g *generations) end(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if gen, ok := g.keys[key]; ok {
+		if gen.loads--; gen.loads == 0 {
+			delete(g.keys, key)
+		}
+	}
+}
+
+// bump records an eviction of key, if it's being loaded
+func (g *generations) bump(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if gen, ok := g.keys[key]; ok {
+		gen.evictions++
+	}
+}
+
+// current tells whether key wasn't evicted since a load began at generation
+func (g *generations) current(key string, generation uint64) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	gen, ok := g.keys[key]
+	return ok && gen.evictions == generation
+}
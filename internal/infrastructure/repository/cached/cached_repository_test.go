@@ -0,0 +1,467 @@
+package cached
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/pkg/redis"
+)
+
+type widget struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// fakeClient is an in-memory Client, failing every call while err is set
+type fakeClient struct {
+	mu      sync.Mutex
+	entries map[string]string
+	ttls    map[string]time.Duration
+	gets    int
+	err     error
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{entries: make(map[string]string), ttls: make(map[string]time.Duration)}
+}
+
+func (c *fakeClient) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gets++
+	if c.err != nil {
+		return "", c.err
+	}
+	value, ok := c.entries[key]
+	if !ok {
+		return "", redis.Nil
+	}
+	return value, nil
+}
+
+func (c *fakeClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.entries[key] = value.(string)
+	c.ttls[key] = expiration
+	return nil
+}
+
+func (c *fakeClient) Del(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+func (c *fakeClient) entry(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.entries[key]
+	return value, ok
+}
+
+func (c *fakeClient) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+// fakeRepository keeps widgets in memory, blocking FindByID on block when
+// it's set
+type fakeRepository struct {
+	repository.BaseRepository[widget]
+
+	mu      sync.Mutex
+	widgets map[string]widget
+	finds   int
+	block   chan struct{}
+	started chan struct{}
+}
+
+func newFakeRepository(widgets ...widget) *fakeRepository {
+	r := &fakeRepository{widgets: make(map[string]widget)}
+	for _, w := range widgets {
+		r.widgets[w.ID] = w
+	}
+	return r
+}
+
+func (r *fakeRepository) FindByID(ctx context.Context, id string) (*widget, error) {
+	r.mu.Lock()
+	r.finds++
+	w, ok := r.widgets[id]
+	block, started := r.block, r.started
+	r.mu.Unlock()
+
+	if block != nil {
+		started <- struct{}{}
+		<-block
+	}
+	if !ok {
+		return nil, nil
+	}
+	return &w, nil
+}
+
+func (r *fakeRepository) Create(ctx context.Context, w *widget) error {
+	return r.Update(ctx, w)
+}
+
+func (r *fakeRepository) Update(ctx context.Context, w *widget) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.widgets[w.ID] = *w
+	return nil
+}
+
+func (r *fakeRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.widgets, id)
+	return nil
+}
+
+func (r *fakeRepository) findCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.finds
+}
+
+func newWidgetCache(repo *fakeRepository, client *fakeClient, options Options[widget]) *CachedRepository[widget] {
+	options.KeyFunc = KeyPrefix("widget")
+	options.IDFunc = func(w *widget) string { return w.ID }
+	return NewCachedRepository[widget](repo, client, options)
+}
+
+func TestLoadEvictedWhileInFlightIsNotCached(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository(widget{ID: "1", Name: "old"})
+	block, started := make(chan struct{}), make(chan struct{})
+	repo.block, repo.started = block, started
+	client := newFakeClient()
+	cache := newWidgetCache(repo, client, Options[widget]{L1: NewL1(10, time.Minute)})
+
+	found := make(chan *widget)
+	go func() {
+		w, _ := cache.FindByID(ctx, "1")
+		found <- w
+	}()
+	// The load read the old widget, then the widget changes before it's cached
+	<-started
+	if err := cache.Update(ctx, &widget{ID: "1", Name: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	repo.mu.Lock()
+	repo.block, repo.started = nil, nil
+	repo.mu.Unlock()
+	close(block)
+	if w := <-found; w == nil || w.Name != "old" {
+		t.Fatalf("FindByID = %+v, want the widget it read", w)
+	}
+
+	if _, ok := client.entry("widget:1"); ok {
+		t.Fatal("the widget read before the update was cached")
+	}
+	w, err := cache.FindByID(ctx, "1")
+	if err != nil || w == nil || w.Name != "new" {
+		t.Fatalf("FindByID = %+v, %v, want the new widget", w, err)
+	}
+}
+
+func TestStoreSkipsKeysEvictedSinceTheLoadBegan(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeClient()
+	cache := newWidgetCache(newFakeRepository(), client, Options[widget]{})
+
+	generation := cache.generations.begin("widget:1")
+	defer cache.generations.end("widget:1")
+	cache.generations.bump("widget:1")
+	if err := cache.store(ctx, "widget:1", generation, &entry{expiry: time.Now().Add(time.Minute), value: []byte(`{}`)}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.entry("widget:1"); ok {
+		t.Fatal("an entry loaded before an eviction was cached")
+	}
+}
+
+func TestFindByIDCachesEntities(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository(widget{ID: "1", Name: "one"})
+	client := newFakeClient()
+	cache := newWidgetCache(repo, client, Options[widget]{})
+
+	for i := 0; i < 3; i++ {
+		w, err := cache.FindByID(ctx, "1")
+		if err != nil || w == nil || w.Name != "one" {
+			t.Fatalf("FindByID(1) = %+v, %v", w, err)
+		}
+	}
+	if repo.findCount() != 1 {
+		t.Errorf("%d repository lookups, want 1", repo.findCount())
+	}
+	if _, ok := client.entry("widget:1"); !ok || client.ttls["widget:1"] != DefaultTTL {
+		t.Errorf("widget:1 cached %v for %v, want it cached for %v", ok, client.ttls["widget:1"], DefaultTTL)
+	}
+}
+
+func TestFindByIDCachesHitsMissesAndTombstones(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository(widget{ID: "1", Name: "one"})
+	client := newFakeClient()
+	cache := newWidgetCache(repo, client, Options[widget]{TTL: time.Minute, StaleTTL: 10 * time.Second, NegativeTTL: 5 * time.Second})
+
+	for i := 0; i < 3; i++ {
+		w, err := cache.FindByID(ctx, "1")
+		if err != nil || w == nil || w.Name != "one" {
+			t.Fatalf("FindByID(1) = %+v, %v", w, err)
+		}
+		missing, err := cache.FindByID(ctx, "2")
+		if err != nil || missing != nil {
+			t.Fatalf("FindByID(2) = %+v, %v, want nil", missing, err)
+		}
+	}
+
+	if repo.findCount() != 2 {
+		t.Errorf("%d repository lookups, want 2", repo.findCount())
+	}
+	if got, want := cache.Stats(), (Stats{Hits: 2, NegativeHits: 2, Misses: 2}); got != want {
+		t.Errorf("Stats = %+v, want %+v", got, want)
+	}
+	if client.ttls["widget:1"] != 70*time.Second || client.ttls["widget:2"] != 5*time.Second {
+		t.Errorf("TTLs = %v, want 70s for the widget and 5s for the tombstone", client.ttls)
+	}
+}
+
+func TestFindByIDWithoutNegativeTTLDoesntCacheMisses(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository()
+	client := newFakeClient()
+	cache := newWidgetCache(repo, client, Options[widget]{})
+
+	cache.FindByID(ctx, "1")
+	cache.FindByID(ctx, "1")
+	if _, ok := client.entry("widget:1"); ok || repo.findCount() != 2 {
+		t.Errorf("the miss was cached, %d repository lookups", repo.findCount())
+	}
+	if got, want := cache.Stats(), (Stats{Misses: 2}); got != want {
+		t.Errorf("Stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestCreateEvictsTombstones(t *testing.T) {
+	ctx := context.Background()
+	cache := newWidgetCache(newFakeRepository(), newFakeClient(), Options[widget]{NegativeTTL: time.Minute})
+
+	if w, _ := cache.FindByID(ctx, "1"); w != nil {
+		t.Fatalf("FindByID = %+v before the create", w)
+	}
+	if err := cache.Create(ctx, &widget{ID: "1", Name: "one"}); err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := cache.FindByID(ctx, "1"); w == nil || w.Name != "one" {
+		t.Errorf("FindByID = %+v after the create", w)
+	}
+}
+
+func TestWritesEvict(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository(widget{ID: "1", Name: "one"})
+	client := newFakeClient()
+	cache := newWidgetCache(repo, client, Options[widget]{})
+
+	if err := cache.Create(ctx, &widget{ID: "2", Name: "two"}); err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := cache.FindByID(ctx, "2"); w == nil || w.Name != "two" {
+		t.Errorf("FindByID(2) = %+v after the create", w)
+	}
+
+	cache.FindByID(ctx, "1")
+	if err := cache.Update(ctx, &widget{ID: "1", Name: "changed"}); err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := cache.FindByID(ctx, "1"); w == nil || w.Name != "changed" {
+		t.Errorf("FindByID(1) = %+v after the update", w)
+	}
+
+	if err := cache.Delete(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := cache.FindByID(ctx, "1"); w != nil {
+		t.Errorf("FindByID(1) = %+v after the delete", w)
+	}
+}
+
+func TestFindByIDReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	cache := newWidgetCache(newFakeRepository(widget{ID: "1", Name: "one"}), newFakeClient(), Options[widget]{})
+
+	first, _ := cache.FindByID(ctx, "1")
+	first.Name = "changed by a caller"
+	if second, _ := cache.FindByID(ctx, "1"); second.Name != "one" {
+		t.Errorf("a caller's change leaked into the cache: %q", second.Name)
+	}
+}
+
+func TestCorruptEntriesAreReloaded(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository(widget{ID: "1", Name: "one"})
+	client := newFakeClient()
+	cache := newWidgetCache(repo, client, Options[widget]{})
+
+	for _, data := range []string{`{"id":"1","name":"one"}`, "e1 " + fmt.Sprint(time.Now().Add(time.Hour).UnixNano()) + " 0\n{not json"} {
+		client.entries["widget:1"] = data
+		if w, err := cache.FindByID(ctx, "1"); err != nil || w == nil || w.Name != "one" {
+			t.Fatalf("FindByID over %q = %+v, %v", data, w, err)
+		}
+		if _, err := decodeEntry(client.entries["widget:1"]); err != nil {
+			t.Errorf("%q wasn't replaced", data)
+		}
+	}
+}
+
+func TestConcurrentMissesShareALoad(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository(widget{ID: "1", Name: "one"})
+	block, started := make(chan struct{}), make(chan struct{}, 1)
+	repo.block, repo.started = block, started
+	cache := newWidgetCache(repo, newFakeClient(), Options[widget]{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if w, err := cache.FindByID(ctx, "1"); err != nil || w == nil {
+				t.Errorf("FindByID = %+v, %v", w, err)
+			}
+		}()
+	}
+	<-started
+	// Let the other callers join the load before it completes
+	time.Sleep(20 * time.Millisecond)
+	close(block)
+	wg.Wait()
+
+	if repo.findCount() != 1 {
+		t.Errorf("%d repository lookups, want 1", repo.findCount())
+	}
+}
+
+func TestExpiredEntriesAreServedWhileRefreshed(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository(widget{ID: "1", Name: "old"})
+	client := newFakeClient()
+	cache := newWidgetCache(repo, client, Options[widget]{TTL: time.Minute, StaleTTL: time.Minute})
+
+	expired := entry{expiry: time.Now().Add(-time.Second), value: []byte(`{"id":"1","name":"old"}`)}
+	client.entries["widget:1"] = expired.encode()
+	repo.Update(ctx, &widget{ID: "1", Name: "new"})
+
+	if w, _ := cache.FindByID(ctx, "1"); w == nil || w.Name != "old" {
+		t.Fatalf("FindByID = %+v, want the expired widget", w)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		data, _ := client.entry("widget:1")
+		if e, err := decodeEntry(data); err == nil && !e.expired(time.Now()) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the expired widget wasn't refreshed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if w, _ := cache.FindByID(ctx, "1"); w == nil || w.Name != "new" {
+		t.Errorf("FindByID = %+v, want the refreshed widget", w)
+	}
+}
+
+func TestIgnoreErrorsFallsBackToTheRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository(widget{ID: "1", Name: "one"})
+	client := newFakeClient()
+	client.fail(errDown)
+	cache := newWidgetCache(repo, client, Options[widget]{ErrorPolicy: IgnoreErrors})
+
+	for i := 0; i < 3; i++ {
+		if w, err := cache.FindByID(ctx, "1"); err != nil || w == nil {
+			t.Fatalf("FindByID = %+v, %v, want the widget from the repository", w, err)
+		}
+	}
+	// The cache is skipped after its first failure
+	if client.gets != 1 || repo.findCount() != 3 {
+		t.Errorf("%d cache gets and %d repository lookups, want 1 and 3", client.gets, repo.findCount())
+	}
+	if err := cache.Update(ctx, &widget{ID: "1", Name: "changed"}); err != nil {
+		t.Errorf("Update = %v, want the eviction error ignored", err)
+	}
+}
+
+func TestFailOnErrorsReturnsCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeRepository(widget{ID: "1", Name: "one"})
+	client := newFakeClient()
+	client.fail(errDown)
+	cache := newWidgetCache(repo, client, Options[widget]{ErrorPolicy: FailOnErrors})
+
+	if _, err := cache.FindByID(ctx, "1"); !errors.Is(err, errDown) {
+		t.Errorf("FindByID = %v, want %v", err, errDown)
+	}
+	if err := cache.Update(ctx, &widget{ID: "1", Name: "changed"}); !errors.Is(err, errDown) {
+		t.Errorf("Update = %v, want %v", err, errDown)
+	}
+	if repo.findCount() != 0 {
+		t.Errorf("%d repository lookups, want none", repo.findCount())
+	}
+}
+
+func TestL1Hits(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeClient()
+	cache := newWidgetCache(newFakeRepository(widget{ID: "1", Name: "one"}), client, Options[widget]{NegativeTTL: time.Minute, L1: NewL1(10, time.Minute)})
+
+	for i := 0; i < 3; i++ {
+		cache.FindByID(ctx, "1")
+		cache.FindByID(ctx, "2")
+	}
+	if client.gets != 2 {
+		t.Errorf("%d cache gets, want 2", client.gets)
+	}
+	if got, want := cache.Stats(), (Stats{Hits: 2, NegativeHits: 2, Misses: 2, L1Hits: 4}); got != want {
+		t.Errorf("Stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestL1EntriesAreCopiesEvictedByWrites(t *testing.T) {
+	ctx := context.Background()
+	cache := newWidgetCache(newFakeRepository(widget{ID: "1", Name: "one"}), newFakeClient(), Options[widget]{L1: NewL1(10, time.Minute)})
+
+	first, _ := cache.FindByID(ctx, "1")
+	first.Name = "changed by a caller"
+	if second, _ := cache.FindByID(ctx, "1"); second.Name != "one" {
+		t.Errorf("a caller's change leaked into the L1: %q", second.Name)
+	}
+	if err := cache.Update(ctx, &widget{ID: "1", Name: "changed"}); err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := cache.FindByID(ctx, "1"); w == nil || w.Name != "changed" {
+		t.Errorf("FindByID = %+v after the update", w)
+	}
+}
+
+var errDown = errors.New("connection refused")
@@ -0,0 +1,79 @@
+package cached
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// entryVersion and tombstoneVersion start the headers of the cached
+// entities and of the tombstones of missing ones, entries without either are
+// reloaded
+const (
+	entryVersion     = "e1"
+	tombstoneVersion = "n1"
+)
+
+var errInvalidEntry = errors.New("invalid cache entry")
+
+// entry is a cached value with when it expires and how long it took to
+// load. It's stored as a header line followed by the serialized value, so
+// that the value is encoded by the serializer alone. Tombstones record that
+// the entity doesn't exist and have no value.
+type entry struct {
+	expiry    time.Time
+	delta     time.Duration
+	value     []byte
+	tombstone bool
+}
+
+func (e *entry) encode() string {
+	version := entryVersion
+	if e.tombstone {
+		version = tombstoneVersion
+	}
+	return fmt.Sprintf("%s %d %d\n", version, e.expiry.UnixNano(), e.delta) + string(e.value)
+}
+
+func decodeEntry(data string) (*entry, error) {
+	header, value, ok := strings.Cut(data, "\n")
+	fields := strings.Fields(header)
+	if !ok || len(fields) != 3 || (fields[0] != entryVersion && fields[0] != tombstoneVersion) {
+		return nil, errInvalidEntry
+	}
+	expiry, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return nil, errInvalidEntry
+	}
+	delta, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, errInvalidEntry
+	}
+	return &entry{
+		expiry:    time.Unix(0, expiry),
+		delta:     time.Duration(delta),
+		value:     []byte(value),
+		tombstone: fields[0] == tombstoneVersion,
+	}, nil
+}
+
+// expired tells whether the entry is only served while it's refreshed
+func (e *entry) expired(now time.Time) bool {
+	return !now.Before(e.expiry)
+}
+
+// expiresEarly draws whether the entry is to be refreshed ahead of its
+// expiry, more likely as it nears and the longer it took to load, so that
+// the refreshes of popular entries are spread instead of happening at once
+// (the XFetch algorithm, with beta the weight of the load time)
+func (e *entry) expiresEarly(now time.Time, beta float64) bool {
+	if beta <= 0 {
+		return false
+	}
+	gap := time.Duration(float64(e.delta) * beta * -math.Log(1-rand.Float64()))
+	return !now.Add(gap).Before(e.expiry)
+}
@@ -0,0 +1,62 @@
+package cached
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEntryRoundTrip(t *testing.T) {
+	expiry := time.Unix(1700000000, 123456789)
+	tests := []entry{
+		{expiry: expiry, delta: 15 * time.Millisecond, value: []byte(`{"id":"1","name":"a\nb"}`)},
+		{expiry: expiry, value: []byte{}},
+		{expiry: expiry, tombstone: true},
+	}
+	for _, want := range tests {
+		got, err := decodeEntry(want.encode())
+		if err != nil {
+			t.Fatalf("decodeEntry(%q): %v", want.encode(), err)
+		}
+		if !got.expiry.Equal(want.expiry) || got.delta != want.delta || string(got.value) != string(want.value) || got.tombstone != want.tombstone {
+			t.Errorf("decodeEntry(%q) = %+v, want %+v", want.encode(), got, want)
+		}
+	}
+}
+
+func TestDecodeEntryInvalid(t *testing.T) {
+	for _, data := range []string{
+		``,
+		`{"id":"1"}`,
+		"e1 1 2",
+		"e2 1 2\n{}",
+		"e1 1\n{}",
+		"e1 x 2\n{}",
+		"e1 1 y\n{}",
+		"e1 1 2 3\n{}",
+	} {
+		if _, err := decodeEntry(data); err != errInvalidEntry {
+			t.Errorf("decodeEntry(%q) = %v, want errInvalidEntry", data, err)
+		}
+	}
+}
+
+func TestEntryExpiry(t *testing.T) {
+	now := time.Now()
+	e := entry{expiry: now.Add(time.Minute), delta: time.Second}
+	if e.expired(now) || !e.expired(now.Add(time.Minute)) {
+		t.Error("expired doesn't match the expiry")
+	}
+	if e.expiresEarly(now, 0) {
+		t.Error("expiresEarly without a beta")
+	}
+	// A minute ahead of the expiry, a one second load is virtually never
+	// refreshed, while right at the expiry it always is
+	for i := 0; i < 100; i++ {
+		if e.expiresEarly(now, 1) {
+			t.Fatal("refreshed a minute early after a one second load")
+		}
+		if !e.expiresEarly(now.Add(time.Minute), 1) {
+			t.Fatal("not refreshed at the expiry")
+		}
+	}
+}
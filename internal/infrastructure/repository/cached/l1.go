@@ -0,0 +1,174 @@
+package cached
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// InvalidationChannel is the channel the instances post the keys they
+// invalidate on, for the others to drop them from their L1
+const InvalidationChannel = "cache:invalidations"
+
+// Bus carries the invalidations of L1 entries between the instances, Redis
+// pub/sub in production
+type Bus interface {
+	Publish(ctx context.Context, channel string, message interface{}) error
+	Subscribe(ctx context.Context, channel string, handle func(message string)) error
+}
+
+// L1 is an in-process cache in front of the shared one, keeping the most
+// recently used entries for a short time. Invalidations are posted to the
+// other instances once connected to a bus; the ones they miss, while the bus
+// reconnects, are made up for by the TTL.
+type L1 struct {
+	size int
+	ttl  time.Duration
+
+	mu  sync.Mutex
+	bus Bus
+	// stop ends the subscription to the bus
+	stop  context.CancelFunc
+	items map[string]*list.Element
+	// lru orders the items from the most to the least recently used
+	lru *list.List
+}
+
+type l1Item struct {
+	key    string
+	entry  *entry
+	expiry time.Time
+}
+
+// NewL1 creates an L1 keeping at most size entries for ttl
+func NewL1(size int, ttl time.Duration) *L1 {
+	return &L1{
+		size:  size,
+		ttl:   ttl,
+		items: make(map[string]*list.Element),
+		lru:   list.New(),
+	}
+}
+
+// Connect posts the invalidations of the L1 to bus and drops the entries
+// the other instances invalidate, until it's closed
+func (c *L1) Connect(bus Bus) error {
+	ctx, stop := context.WithCancel(context.Background())
+	err := bus.Subscribe(ctx, InvalidationChannel, func(message string) {
+		var keys []string
+		if err := json.Unmarshal([]byte(message), &keys); err != nil {
+			log.Printf("Error decoding cache invalidation %q: %v", message, err)
+			return
+		}
+		c.remove(keys...)
+	})
+	if err != nil {
+		stop()
+		return err
+	}
+	c.mu.Lock()
+	c.bus, c.stop = bus, stop
+	c.mu.Unlock()
+	return nil
+}
+
+// Close stops listening to the invalidations of the other instances
+func (c *L1) Close() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stop != nil {
+		c.stop()
+	}
+	c.bus, c.stop = nil, nil
+}
+
+// Invalidate drops keys from the L1 of every instance
+func (c *L1) Invalidate(ctx context.Context, keys ...string) error {
+	if c == nil {
+		return nil
+	}
+	c.remove(keys...)
+
+	c.mu.Lock()
+	bus := c.bus
+	c.mu.Unlock()
+	if bus == nil {
+		return nil
+	}
+	message, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	return bus.Publish(ctx, InvalidationChannel, message)
+}
+
+// get returns the entry kept at key, nil when there's none or it's too old
+func (c *L1) get(key string) *entry {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	item := element.Value.(*l1Item)
+	if !time.Now().Before(item.expiry) {
+		c.lru.Remove(element)
+		delete(c.items, key)
+		return nil
+	}
+	c.lru.MoveToFront(element)
+	return item.entry
+}
+
+// set keeps an entry at key, dropping the least recently used ones beyond
+// the size. Tombstones aren't kept past their own expiry.
+func (c *L1) set(key string, e *entry) {
+	if c == nil || c.size <= 0 {
+		return
+	}
+	expiry := time.Now().Add(c.ttl)
+	if e.tombstone && e.expiry.Before(expiry) {
+		expiry = e.expiry
+	}
+	item := &l1Item{key: key, entry: e, expiry: expiry}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		element.Value = item
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.items[key] = c.lru.PushFront(item)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*l1Item).key)
+	}
+}
+
+func (c *L1) remove(keys ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := c.items[key]; ok {
+			c.lru.Remove(element)
+			delete(c.items, key)
+		}
+	}
+}
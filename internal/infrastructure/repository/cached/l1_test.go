@@ -0,0 +1,173 @@
+package cached
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBus delivers the messages to the subscribers synchronously
+type fakeBus struct {
+	mu       sync.Mutex
+	handlers []func(message string)
+}
+
+func (b *fakeBus) Publish(ctx context.Context, channel string, message interface{}) error {
+	b.mu.Lock()
+	handlers := append([]func(string){}, b.handlers...)
+	b.mu.Unlock()
+	for _, handle := range handlers {
+		handle(string(message.([]byte)))
+	}
+	return nil
+}
+
+func (b *fakeBus) Subscribe(ctx context.Context, channel string, handle func(message string)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handle)
+	return nil
+}
+
+func positive(value string) *entry {
+	return &entry{expiry: time.Now().Add(time.Hour), value: []byte(value)}
+}
+
+func TestL1EvictsTheLeastRecentlyUsed(t *testing.T) {
+	l1 := NewL1(2, time.Minute)
+	l1.set("a", positive("1"))
+	l1.set("b", positive("2"))
+	l1.get("a")
+	l1.set("c", positive("3"))
+
+	if l1.get("b") != nil {
+		t.Error("b was kept though least recently used")
+	}
+	for _, key := range []string{"a", "c"} {
+		if l1.get(key) == nil {
+			t.Errorf("%s was dropped", key)
+		}
+	}
+	if len(l1.items) != 2 || l1.lru.Len() != 2 {
+		t.Errorf("%d items and %d in the list, want 2", len(l1.items), l1.lru.Len())
+	}
+}
+
+func TestL1ReplacesEntries(t *testing.T) {
+	l1 := NewL1(2, time.Minute)
+	l1.set("a", positive("1"))
+	l1.set("a", positive("2"))
+	if e := l1.get("a"); e == nil || string(e.value) != "2" || l1.lru.Len() != 1 {
+		t.Errorf("get = %+v with %d items, want the replaced entry alone", e, l1.lru.Len())
+	}
+}
+
+func TestL1Expiry(t *testing.T) {
+	l1 := NewL1(10, 20*time.Millisecond)
+	l1.set("a", positive("1"))
+	l1.set("missing", &entry{expiry: time.Now().Add(5 * time.Millisecond), tombstone: true})
+
+	time.Sleep(10 * time.Millisecond)
+	if l1.get("missing") != nil {
+		t.Error("tombstone kept past its own expiry")
+	}
+	if l1.get("a") == nil {
+		t.Error("entry dropped before the TTL")
+	}
+	time.Sleep(15 * time.Millisecond)
+	if l1.get("a") != nil {
+		t.Error("entry kept past the TTL")
+	}
+	if len(l1.items) != 0 {
+		t.Errorf("%d expired items left", len(l1.items))
+	}
+}
+
+func TestL1Disabled(t *testing.T) {
+	var l1 *L1
+	l1.set("a", positive("1"))
+	if l1.get("a") != nil || l1.Invalidate(context.Background(), "a") != nil {
+		t.Error("a nil L1 isn't a no-op")
+	}
+	l1.Close()
+
+	empty := NewL1(0, time.Minute)
+	empty.set("a", positive("1"))
+	if empty.get("a") != nil {
+		t.Error("an L1 of size 0 kept an entry")
+	}
+}
+
+func TestL1InvalidatesOtherInstances(t *testing.T) {
+	bus := &fakeBus{}
+	instances := make([]*L1, 3)
+	for i := range instances {
+		instances[i] = NewL1(10, time.Minute)
+		if err := instances[i].Connect(bus); err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"a", "b", "c"} {
+			instances[i].set(key, positive(key))
+		}
+	}
+
+	if err := instances[0].Invalidate(context.Background(), "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	for i, l1 := range instances {
+		if l1.get("a") != nil || l1.get("b") != nil {
+			t.Errorf("instance %d kept invalidated entries", i)
+		}
+		if l1.get("c") == nil {
+			t.Errorf("instance %d dropped an entry that wasn't invalidated", i)
+		}
+	}
+
+	// Closed instances stop posting invalidations
+	instances[1].Close()
+	instances[1].set("c", positive("c"))
+	if err := instances[1].Invalidate(context.Background(), "c"); err != nil {
+		t.Fatal(err)
+	}
+	if instances[2].get("c") == nil {
+		t.Error("a closed instance still posts invalidations")
+	}
+}
+
+func TestL1IgnoresInvalidMessages(t *testing.T) {
+	bus := &fakeBus{}
+	l1 := NewL1(10, time.Minute)
+	if err := l1.Connect(bus); err != nil {
+		t.Fatal(err)
+	}
+	l1.set("a", positive("1"))
+	bus.Publish(context.Background(), InvalidationChannel, []byte(`not json`))
+	if l1.get("a") == nil {
+		t.Error("an invalid message dropped an entry")
+	}
+}
+
+func TestL1Concurrency(t *testing.T) {
+	l1 := NewL1(50, time.Minute)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := fmt.Sprint((i + j) % 80)
+				l1.set(key, positive(key))
+				l1.get(key)
+				if j%10 == 0 {
+					l1.Invalidate(context.Background(), key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if len(l1.items) > 50 || len(l1.items) != l1.lru.Len() {
+		t.Errorf("%d items and %d in the list, want at most 50 and equal", len(l1.items), l1.lru.Len())
+	}
+}
@@ -0,0 +1,132 @@
+package cached
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+)
+
+// cachedTranslationRepository caches the stored translations found by ID and
+// by source text
+type cachedTranslationRepository struct {
+	*CachedRepository[entities.Translation]
+	repo repository.TranslationRepository
+}
+
+// NewCachedTranslationRepository creates a new cached translation
+// repository, keeping translations at translation:<id> unless options say
+// otherwise
+func NewCachedTranslationRepository(
+	repo repository.TranslationRepository,
+	client Client,
+	options Options[entities.Translation],
+) repository.TranslationRepository {
+	if options.KeyFunc == nil {
+		options.KeyFunc = KeyPrefix("translation")
+	}
+	if options.IDFunc == nil {
+		options.IDFunc = func(t *entities.Translation) string { return strconv.FormatUint(uint64(t.ID), 10) }
+	}
+
+	return &cachedTranslationRepository{
+		CachedRepository: NewCachedRepository[entities.Translation](repo, client, options),
+		repo:             repo,
+	}
+}
+
+// textKey returns the cache key of the translation of a text, which is
+// hashed as texts can be long
+func (r *cachedTranslationRepository) textKey(text, targetLang string) string {
+	sum := sha256.Sum256([]byte(text))
+	return r.Key("text:" + targetLang + ":" + hex.EncodeToString(sum[:]))
+}
+
+// Create stores a translation and invalidates the cache of its text
+func (r *cachedTranslationRepository) Create(ctx context.Context, t *entities.Translation) error {
+	if err := r.CachedRepository.Create(ctx, t); err != nil {
+		return err
+	}
+	return r.Evict(ctx, r.textKey(t.SourceText, t.TargetLang))
+}
+
+// Update saves a translation and invalidates the cache of its text
+func (r *cachedTranslationRepository) Update(ctx context.Context, t *entities.Translation) error {
+	if err := r.CachedRepository.Update(ctx, t); err != nil {
+		return err
+	}
+	return r.Evict(ctx, r.textKey(t.SourceText, t.TargetLang))
+}
+
+// Delete deletes a translation and invalidates the cache of its text
+func (r *cachedTranslationRepository) Delete(ctx context.Context, id string) error {
+	t, err := r.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := r.CachedRepository.Delete(ctx, id); err != nil || t == nil {
+		return err
+	}
+	return r.Evict(ctx, r.textKey(t.SourceText, t.TargetLang))
+}
+
+// FindTranslation returns the cached translation of a text, or finds it and
+// caches it
+func (r *cachedTranslationRepository) FindTranslation(ctx context.Context, text string, targetLang string) (*entities.Translation, error) {
+	return r.Fetch(ctx, r.textKey(text, targetLang), func(ctx context.Context) (*entities.Translation, error) {
+		return r.repo.FindTranslation(ctx, text, targetLang)
+	})
+}
+
+// SaveTranslation stores the translation of a text and invalidates its cache
+func (r *cachedTranslationRepository) SaveTranslation(ctx context.Context, text string, targetLang string, translation string) error {
+	if err := r.repo.SaveTranslation(ctx, text, targetLang, translation); err != nil {
+		return err
+	}
+	return r.Evict(ctx, r.textKey(text, targetLang))
+}
+
+// Translate translates a text with the provider, bypassing the cache
+func (r *cachedTranslationRepository) Translate(ctx context.Context, text string, sourceLang string, targetLang string) (string, error) {
+	return r.repo.Translate(ctx, text, sourceLang, targetLang)
+}
+
+// GetTranslation returns the stored translation of a text, bypassing the cache
+func (r *cachedTranslationRepository) GetTranslation(ctx context.Context, text string, targetLang string) (string, error) {
+	return r.repo.GetTranslation(ctx, text, targetLang)
+}
+
+// ConfiguredLanguages lists the configured translation languages
+func (r *cachedTranslationRepository) ConfiguredLanguages(ctx context.Context) ([]string, error) {
+	return r.repo.ConfiguredLanguages(ctx)
+}
+
+// ListTranslations lists the stored translations, bypassing the cache
+func (r *cachedTranslationRepository) ListTranslations(ctx context.Context, targetLang string) ([]*entities.Translation, error) {
+	return r.repo.ListTranslations(ctx, targetLang)
+}
+
+// SaveTranslations saves translations and invalidates their cache
+func (r *cachedTranslationRepository) SaveTranslations(ctx context.Context, translations []*entities.Translation) error {
+	if err := r.repo.SaveTranslations(ctx, translations); err != nil {
+		return err
+	}
+	keys := make([]string, 0, 2*len(translations))
+	for _, t := range translations {
+		keys = append(keys, r.Key(r.options.IDFunc(t)), r.textKey(t.SourceText, t.TargetLang))
+	}
+	return r.Evict(ctx, keys...)
+}
+
+// ListLocaleMessages lists the UI message overrides, bypassing the cache
+func (r *cachedTranslationRepository) ListLocaleMessages(ctx context.Context) ([]*entities.LocaleMessage, error) {
+	return r.repo.ListLocaleMessages(ctx)
+}
+
+// SaveLocaleMessages saves UI message overrides
+func (r *cachedTranslationRepository) SaveLocaleMessages(ctx context.Context, messages []*entities.LocaleMessage) error {
+	return r.repo.SaveLocaleMessages(ctx, messages)
+}
@@ -0,0 +1,105 @@
+package cached
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+)
+
+// fakeTranslationRepository keeps translations in memory, counting the
+// lookups by text
+type fakeTranslationRepository struct {
+	repository.TranslationRepository
+	translations map[uint]entities.Translation
+	textFinds    int
+}
+
+func newFakeTranslationRepository() *fakeTranslationRepository {
+	return &fakeTranslationRepository{translations: make(map[uint]entities.Translation)}
+}
+
+func (r *fakeTranslationRepository) FindByID(ctx context.Context, id string) (*entities.Translation, error) {
+	n, _ := strconv.ParseUint(id, 10, 64)
+	t, ok := r.translations[uint(n)]
+	if !ok {
+		return nil, nil
+	}
+	return &t, nil
+}
+
+func (r *fakeTranslationRepository) Delete(ctx context.Context, id string) error {
+	n, _ := strconv.ParseUint(id, 10, 64)
+	delete(r.translations, uint(n))
+	return nil
+}
+
+func (r *fakeTranslationRepository) FindTranslation(ctx context.Context, text string, targetLang string) (*entities.Translation, error) {
+	r.textFinds++
+	for _, t := range r.translations {
+		if t.SourceText == text && t.TargetLang == targetLang {
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeTranslationRepository) SaveTranslation(ctx context.Context, text string, targetLang string, translation string) error {
+	for id, t := range r.translations {
+		if t.SourceText == text && t.TargetLang == targetLang {
+			t.TranslatedText = translation
+			r.translations[id] = t
+			return nil
+		}
+	}
+	t := entities.Translation{SourceText: text, TargetLang: targetLang, TranslatedText: translation}
+	t.ID = uint(len(r.translations) + 1)
+	r.translations[t.ID] = t
+	return nil
+}
+
+func TestCachedTranslationRepositoryKeepsTranslationsByText(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeTranslationRepository()
+	translations := NewCachedTranslationRepository(repo, newFakeClient(), Options[entities.Translation]{})
+
+	if err := translations.SaveTranslation(ctx, "Hello", "fr", "Bonjour"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		found, err := translations.FindTranslation(ctx, "Hello", "fr")
+		if err != nil || found == nil || found.TranslatedText != "Bonjour" {
+			t.Fatalf("FindTranslation = %+v, %v", found, err)
+		}
+	}
+	if repo.textFinds != 1 {
+		t.Errorf("%d repository lookups, want 1", repo.textFinds)
+	}
+	if found, _ := translations.FindTranslation(ctx, "Hello", "de"); found != nil {
+		t.Errorf("FindTranslation into de = %+v, want the languages cached apart", found)
+	}
+}
+
+func TestCachedTranslationRepositoryEvictsTheTextOfChangedTranslations(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeTranslationRepository()
+	translations := NewCachedTranslationRepository(repo, newFakeClient(), Options[entities.Translation]{})
+
+	translations.SaveTranslation(ctx, "Hello", "fr", "Bonjour")
+	translations.FindTranslation(ctx, "Hello", "fr")
+	if err := translations.SaveTranslation(ctx, "Hello", "fr", "Salut"); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := translations.FindTranslation(ctx, "Hello", "fr"); found == nil || found.TranslatedText != "Salut" {
+		t.Fatalf("FindTranslation = %+v after the save, want Salut", found)
+	}
+
+	if err := translations.Delete(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := translations.FindTranslation(ctx, "Hello", "fr"); found != nil {
+		t.Errorf("FindTranslation = %+v after the delete", found)
+	}
+}
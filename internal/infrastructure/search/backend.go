@@ -0,0 +1,378 @@
+package search
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+)
+
+const (
+	// saveDelay groups the index changes of a burst of book updates into one save
+	saveDelay = 5 * time.Second
+	// rebuildBatchSize is the number of books loaded at once when rebuilding
+	rebuildBatchSize = 500
+	// searchAttempts bounds the searches run again after dropping books
+	// deleted behind the index's back
+	searchAttempts = 3
+)
+
+// EventChannel is the channel the instances post their book changes on, for
+// the others to update their index
+const EventChannel = "search:books"
+
+// Bus carries the book changes between the instances, Redis pub/sub in
+// production
+type Bus interface {
+	Publish(ctx context.Context, channel string, message interface{}) error
+	Subscribe(ctx context.Context, channel string, handle func(message string)) error
+}
+
+// bookEvent is a book change posted to the other instances
+type bookEvent struct {
+	// Origin is the instance which made the change
+	Origin string `json:"origin"`
+	ID     string `json:"id"`
+	// Document is the indexed book, nil when it was deleted
+	Document *Document `json:"document,omitempty"`
+}
+
+// IndexBackend searches books with the embedded index, which it keeps in
+// sync with book events and saves to a file. Once connected to a bus, the
+// events are exchanged with the other instances; the ones missed while the
+// bus reconnects are caught up on by the rebuild at the next start.
+type IndexBackend struct {
+	bookRepo  repository.BookRepository
+	shelfRepo repository.ShelfRepository
+	path      string
+	// origin identifies the events of this instance on the bus
+	origin string
+
+	mu        sync.RWMutex
+	index     *Index
+	bus       Bus
+	stop      context.CancelFunc
+	saveMu    sync.Mutex
+	saveTimer *time.Timer
+}
+
+// NewIndexBackend creates an index backend saving the index to path, which
+// looks the books of shelves up with shelfRepo
+func NewIndexBackend(bookRepo repository.BookRepository, shelfRepo repository.ShelfRepository, path string) *IndexBackend {
+	return &IndexBackend{
+		bookRepo:  bookRepo,
+		shelfRepo: shelfRepo,
+		path:      path,
+		origin:    newOrigin(),
+		index:     NewIndex(),
+	}
+}
+
+// Open loads the saved index, or builds it from the database when there's
+// none or books were changed since it was saved
+func (b *IndexBackend) Open(ctx context.Context) error {
+	file, err := os.Open(b.path)
+	if errors.Is(err, os.ErrNotExist) {
+		count, err := b.Rebuild(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Search index built with %d books", count)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open search index: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to open search index: %w", err)
+	}
+	changedAt, err := b.bookRepo.LastChangedAt(ctx)
+	if err != nil {
+		return err
+	}
+	if changedAt.After(info.ModTime()) {
+		count, err := b.Rebuild(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Search index rebuilt with %d books, books were changed since it was saved", count)
+		return nil
+	}
+
+	index, err := Load(file)
+	if err != nil {
+		return err
+	}
+	b.setIndex(index)
+	log.Printf("Search index loaded with %d books", index.Len())
+	return nil
+}
+
+// Connect posts the book events of the index to bus and applies the ones of
+// the other instances, until it's closed
+func (b *IndexBackend) Connect(bus Bus) error {
+	ctx, stop := context.WithCancel(context.Background())
+	if err := bus.Subscribe(ctx, EventChannel, b.receive); err != nil {
+		stop()
+		return err
+	}
+	b.mu.Lock()
+	b.bus, b.stop = bus, stop
+	b.mu.Unlock()
+	return nil
+}
+
+// Rebuild indexes all books from the database and saves the index
+func (b *IndexBackend) Rebuild(ctx context.Context) (int, error) {
+	index := NewIndex()
+	afterID := ""
+	for {
+		books, err := b.bookRepo.ListAfter(ctx, afterID, rebuildBatchSize)
+		if err != nil {
+			return 0, err
+		}
+		for _, bk := range books {
+			index.Put(NewDocument(bk))
+		}
+		if len(books) < rebuildBatchSize {
+			break
+		}
+		afterID = books[len(books)-1].ID
+	}
+
+	b.setIndex(index)
+	if err := b.Save(); err != nil {
+		return 0, err
+	}
+	return index.Len(), nil
+}
+
+// Save writes the index to its file, replacing it atomically
+func (b *IndexBackend) Save() error {
+	b.saveMu.Lock()
+	defer b.saveMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(b.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create search index directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), filepath.Base(b.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save search index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := b.currentIndex().Save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save search index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), b.path); err != nil {
+		return fmt.Errorf("failed to save search index: %w", err)
+	}
+	return nil
+}
+
+// Close stops listening to the other instances and saves the pending index
+// changes
+func (b *IndexBackend) Close() error {
+	b.mu.Lock()
+	if b.stop != nil {
+		b.stop()
+	}
+	b.bus, b.stop = nil, nil
+	pending := b.saveTimer != nil && b.saveTimer.Stop()
+	b.saveTimer = nil
+	b.mu.Unlock()
+
+	if !pending {
+		return nil
+	}
+	return b.Save()
+}
+
+// Search finds the matching books in the index and loads them from the database
+func (b *IndexBackend) Search(ctx context.Context, filter *book.Filter, withFacets bool) (*repository.BookSearchResult, error) {
+	// Shelves aren't indexed, their books are looked up instead
+	if filter.ShelfID != 0 {
+		ids, err := b.shelfRepo.ListBookIDs(ctx, filter.ShelfID)
+		if err != nil {
+			return nil, err
+		}
+		shelved := *filter
+		shelved.BookIDs = ids
+		filter = &shelved
+	}
+
+	// Nor are grants, the books shared with the viewer are looked up too
+	if filter.ViewerID != "" && filter.Access != book.ScopeOwned && filter.Access != book.ScopePublic {
+		ids, err := b.bookRepo.ListSharedBookIDs(ctx, filter.ViewerID)
+		if err != nil {
+			return nil, err
+		}
+		viewed := *filter
+		viewed.SharedBookIDs = ids
+		filter = &viewed
+	}
+
+	// Books deleted on an instance whose event was missed are still
+	// indexed: they're dropped and the search run again, for the total and
+	// the facets to leave them out too
+	index := b.currentIndex()
+	for attempt := 1; ; attempt++ {
+		found := index.Search(filter, withFacets)
+		result, missing, err := b.loadHits(ctx, found)
+		if err != nil {
+			return nil, err
+		}
+		if len(missing) == 0 || attempt == searchAttempts {
+			result.Total -= int64(len(missing))
+			return result, nil
+		}
+		for _, id := range missing {
+			index.Delete(id)
+		}
+		b.scheduleSave()
+	}
+}
+
+// loadHits loads the books of the hits from the database, returning the IDs
+// of the ones which no longer exist
+func (b *IndexBackend) loadHits(ctx context.Context, found *Result) (*repository.BookSearchResult, []string, error) {
+	ids := make([]string, len(found.Hits))
+	for i, hit := range found.Hits {
+		ids[i] = hit.ID
+	}
+	books, err := b.bookRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[string]*entities.Book, len(books))
+	for _, bk := range books {
+		byID[bk.ID] = bk
+	}
+
+	result := &repository.BookSearchResult{
+		Books:      make([]*entities.Book, 0, len(found.Hits)),
+		Total:      found.Total,
+		Facets:     found.Facets,
+		Highlights: make(map[string]map[string][]string),
+	}
+	var missing []string
+	for _, hit := range found.Hits {
+		bk, ok := byID[hit.ID]
+		if !ok {
+			missing = append(missing, hit.ID)
+			continue
+		}
+		result.Books = append(result.Books, bk)
+		if len(hit.Highlights) > 0 {
+			result.Highlights[hit.ID] = hit.Highlights
+		}
+	}
+	return result, missing, nil
+}
+
+// BookSaved indexes a created or updated book
+func (b *IndexBackend) BookSaved(ctx context.Context, bk *entities.Book) {
+	doc := NewDocument(bk)
+	b.currentIndex().Put(doc)
+	b.scheduleSave()
+	b.publish(ctx, bookEvent{Origin: b.origin, ID: bk.ID, Document: doc})
+}
+
+// BookDeleted removes a book from the index
+func (b *IndexBackend) BookDeleted(ctx context.Context, id string) {
+	b.currentIndex().Delete(id)
+	b.scheduleSave()
+	b.publish(ctx, bookEvent{Origin: b.origin, ID: id})
+}
+
+// publish posts a book event to the other instances
+func (b *IndexBackend) publish(ctx context.Context, event bookEvent) {
+	b.mu.RLock()
+	bus := b.bus
+	b.mu.RUnlock()
+	if bus == nil {
+		return
+	}
+	message, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error encoding book event: %v", err)
+		return
+	}
+	if err := bus.Publish(ctx, EventChannel, message); err != nil {
+		log.Printf("Error posting book event: %v", err)
+	}
+}
+
+// receive applies the book event of another instance. Events can arrive
+// out of order, a book is only replaced by the same or a later version.
+func (b *IndexBackend) receive(message string) {
+	var event bookEvent
+	if err := json.Unmarshal([]byte(message), &event); err != nil {
+		log.Printf("Error decoding book event %q: %v", message, err)
+		return
+	}
+	if event.Origin == b.origin {
+		return
+	}
+	if event.Document == nil {
+		b.currentIndex().Delete(event.ID)
+	} else if !b.currentIndex().PutLatest(event.Document) {
+		return
+	}
+	b.scheduleSave()
+}
+
+func (b *IndexBackend) currentIndex() *Index {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.index
+}
+
+func (b *IndexBackend) setIndex(index *Index) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.index = index
+}
+
+func (b *IndexBackend) scheduleSave() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.saveTimer != nil {
+		return
+	}
+	b.saveTimer = time.AfterFunc(saveDelay, func() {
+		b.mu.Lock()
+		b.saveTimer = nil
+		b.mu.Unlock()
+
+		if err := b.Save(); err != nil {
+			log.Printf("Failed to save search index: %v", err)
+		}
+	})
+}
+
+// newOrigin returns a random instance identifier
+func newOrigin() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,234 @@
+package search
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+)
+
+// fakeBookRepository keeps the books in memory
+type fakeBookRepository struct {
+	repository.BookRepository
+	mu        sync.Mutex
+	books     map[string]*entities.Book
+	changedAt time.Time
+}
+
+func newFakeBookRepository(books ...*entities.Book) *fakeBookRepository {
+	r := &fakeBookRepository{books: make(map[string]*entities.Book)}
+	for _, b := range books {
+		r.save(b)
+	}
+	return r
+}
+
+func (r *fakeBookRepository) save(b *entities.Book) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b.Version++
+	b.UpdatedAt = time.Now()
+	r.books[b.ID] = b
+	r.changedAt = b.UpdatedAt
+}
+
+func (r *fakeBookRepository) delete(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.books, id)
+	r.changedAt = time.Now()
+}
+
+func (r *fakeBookRepository) FindByIDs(ctx context.Context, ids []string) ([]*entities.Book, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var books []*entities.Book
+	for _, id := range ids {
+		if b, ok := r.books[id]; ok {
+			books = append(books, b)
+		}
+	}
+	return books, nil
+}
+
+func (r *fakeBookRepository) ListAfter(ctx context.Context, afterID string, limit int) ([]*entities.Book, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var books []*entities.Book
+	for id, b := range r.books {
+		if id > afterID {
+			books = append(books, b)
+		}
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].ID < books[j].ID })
+	return books[:min(limit, len(books))], nil
+}
+
+func (r *fakeBookRepository) LastChangedAt(ctx context.Context) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.changedAt, nil
+}
+
+// fakeBus delivers the messages to the subscribers synchronously
+type fakeBus struct {
+	mu       sync.Mutex
+	handlers []func(message string)
+}
+
+func (b *fakeBus) Publish(ctx context.Context, channel string, message interface{}) error {
+	b.mu.Lock()
+	handlers := append([]func(string){}, b.handlers...)
+	b.mu.Unlock()
+	for _, handle := range handlers {
+		handle(string(message.([]byte)))
+	}
+	return nil
+}
+
+func (b *fakeBus) Subscribe(ctx context.Context, channel string, handle func(message string)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handle)
+	return nil
+}
+
+func ownedBy(userID, query string) *book.Filter {
+	return &book.Filter{ViewerID: userID, Access: book.ScopeOwned, Query: query, Page: 1, Limit: 10}
+}
+
+func searchIDs(t *testing.T, b *IndexBackend, filter *book.Filter) ([]string, int64) {
+	t.Helper()
+	result, err := b.Search(context.Background(), filter, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]string, len(result.Books))
+	for i, bk := range result.Books {
+		ids[i] = bk.ID
+	}
+	sort.Strings(ids)
+	return ids, result.Total
+}
+
+func newTestBooks() []*entities.Book {
+	return []*entities.Book{
+		{ID: "1", UserID: "user-1", Title: "The Go Programming Language", Author: "Donovan"},
+		{ID: "2", UserID: "user-1", Title: "Go in Action", Author: "Kennedy"},
+		{ID: "3", UserID: "user-1", Title: "Learning Go", Author: "Bodner"},
+	}
+}
+
+func TestSearchLeavesDeletedBooksOut(t *testing.T) {
+	repo := newFakeBookRepository(newTestBooks()...)
+	b := NewIndexBackend(repo, nil, filepath.Join(t.TempDir(), "books.idx"))
+	if err := b.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	// Deleted without the index being told, as on an instance whose event
+	// was missed
+	repo.delete("2")
+	filter := ownedBy("user-1", "go")
+	filter.Limit = 1
+
+	total := int64(0)
+	var ids []string
+	for filter.Page = 1; filter.Page <= 2; filter.Page++ {
+		page, pageTotal := searchIDs(t, b, filter)
+		ids = append(ids, page...)
+		total = pageTotal
+	}
+	sort.Strings(ids)
+	if total != 2 || len(ids) != 2 || ids[0] != "1" || ids[1] != "3" {
+		t.Errorf("search = %v of %d, want [1 3] of 2", ids, total)
+	}
+	if b.currentIndex().Len() != 2 {
+		t.Errorf("%d indexed books, want the deleted one dropped", b.currentIndex().Len())
+	}
+}
+
+func TestBookEventsReachTheOtherInstances(t *testing.T) {
+	books := newTestBooks()
+	repo := newFakeBookRepository(books...)
+	bus := &fakeBus{}
+	instances := make([]*IndexBackend, 2)
+	for i := range instances {
+		instances[i] = NewIndexBackend(repo, nil, filepath.Join(t.TempDir(), "books.idx"))
+		if err := instances[i].Open(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := instances[i].Connect(bus); err != nil {
+			t.Fatal(err)
+		}
+		defer instances[i].Close()
+	}
+
+	renamed := *books[0]
+	renamed.Title = "Concurrency in Practice"
+	repo.save(&renamed)
+	instances[0].BookSaved(context.Background(), &renamed)
+	repo.delete("2")
+	instances[0].BookDeleted(context.Background(), "2")
+
+	for i, instance := range instances {
+		if ids, _ := searchIDs(t, instance, ownedBy("user-1", "concurrency")); len(ids) != 1 || ids[0] != "1" {
+			t.Errorf("instance %d found %v for the new title, want [1]", i, ids)
+		}
+		if instance.currentIndex().Len() != 2 {
+			t.Errorf("instance %d has %d books, want 2", i, instance.currentIndex().Len())
+		}
+	}
+
+	// An event arriving late doesn't bring back an older version
+	stale := *books[0]
+	stale.Version = renamed.Version - 1
+	stale.Title = "Stale title"
+	instances[1].Close()
+	instances[1].BookSaved(context.Background(), &stale)
+	if ids, _ := searchIDs(t, instances[0], ownedBy("user-1", "concurrency")); len(ids) != 1 {
+		t.Error("an older version replaced the indexed book")
+	}
+}
+
+func TestOpenRebuildsAStaleIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "books.idx")
+	repo := newFakeBookRepository(newTestBooks()...)
+	first := NewIndexBackend(repo, nil, path)
+	if err := first.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+
+	// Saved after the last change, the index is loaded as is
+	loaded := NewIndexBackend(newFakeBookRepository(), nil, path)
+	loaded.bookRepo.(*fakeBookRepository).changedAt = repo.changedAt
+	if err := loaded.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.currentIndex().Len() != 3 {
+		t.Errorf("%d books loaded, want the 3 saved", loaded.currentIndex().Len())
+	}
+
+	// A book changed while no instance was running is caught up on
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	repo.save(&entities.Book{ID: "4", UserID: "user-1", Title: "Go Programming Blueprints"})
+	rebuilt := NewIndexBackend(repo, nil, path)
+	if err := rebuilt.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := searchIDs(t, rebuilt, ownedBy("user-1", "blueprints")); len(ids) != 1 || ids[0] != "4" {
+		t.Errorf("search = %v, want the book changed since the save", ids)
+	}
+}
@@ -0,0 +1,119 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	highlightPre  = "<mark>"
+	highlightPost = "</mark>"
+	// fragmentContext is the number of bytes kept around a match in a fragment
+	fragmentContext = 60
+	maxFragments    = 3
+)
+
+// highlights returns, for each field containing matched words, the HTML
+// fragments of the field with the matches marked. Short fields are returned
+// whole, the description as fragments around its matches.
+func highlights(doc *Document, words map[string]bool) map[string][]string {
+	if len(words) == 0 {
+		return nil
+	}
+
+	result := make(map[string][]string)
+	for f := field(0); f < numFields; f++ {
+		text := doc.text(f)
+		var spans []token
+		for _, t := range tokenize(text) {
+			if words[t.term] {
+				spans = append(spans, t)
+			}
+		}
+		if len(spans) == 0 {
+			continue
+		}
+
+		if f != fieldDescription {
+			result[fieldNames[f]] = []string{mark(text, 0, len(text), spans)}
+			continue
+		}
+		result[fieldNames[f]] = fragments(text, spans)
+	}
+	return result
+}
+
+// fragments cuts text around the matched spans, merging overlapping windows
+func fragments(text string, spans []token) []string {
+	var result []string
+	for n := 0; n < len(spans) && len(result) < maxFragments; {
+		start := wordStart(text, max(0, spans[n].start-fragmentContext))
+		end := spans[n].end + fragmentContext
+
+		// Extend the fragment over the following matches it overlaps
+		last := n
+		for last+1 < len(spans) && spans[last+1].start < end {
+			last++
+			end = spans[last].end + fragmentContext
+		}
+		end = wordEnd(text, min(len(text), end))
+
+		fragment := mark(text, start, end, spans[n:last+1])
+		if start > 0 {
+			fragment = "…" + fragment
+		}
+		if end < len(text) {
+			fragment += "…"
+		}
+		result = append(result, fragment)
+		n = last + 1
+	}
+	return result
+}
+
+// mark escapes text[start:end] and wraps the spans inside it in highlight tags
+func mark(text string, start, end int, spans []token) string {
+	var b strings.Builder
+	pos := start
+	for _, span := range spans {
+		if span.start < start || span.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:span.start]))
+		b.WriteString(highlightPre)
+		b.WriteString(html.EscapeString(text[span.start:span.end]))
+		b.WriteString(highlightPost)
+		pos = span.end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	return b.String()
+}
+
+// wordStart moves pos forward to the start of a word, unless it's already at one
+func wordStart(text string, pos int) int {
+	if pos == 0 {
+		return 0
+	}
+	if i := strings.IndexByte(text[pos:], ' '); i >= 0 && i < fragmentContext/2 {
+		return pos + i + 1
+	}
+	for pos < len(text) && !utf8.RuneStart(text[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// wordEnd moves pos back to the end of a word, unless it's already at one
+func wordEnd(text string, pos int) int {
+	if pos == len(text) {
+		return pos
+	}
+	if i := strings.LastIndexByte(text[:pos], ' '); i >= 0 && pos-i < fragmentContext/2 {
+		return i
+	}
+	for pos > 0 && !utf8.RuneStart(text[pos]) {
+		pos--
+	}
+	return pos
+}
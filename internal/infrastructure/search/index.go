@@ -0,0 +1,553 @@
+// Package search implements an embedded inverted index of books with
+// typo-tolerant, BM25-ranked full-text search.
+package search
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+)
+
+// field is a full-text field of a document
+type field int
+
+const (
+	fieldTitle field = iota
+	fieldAuthor
+	fieldDescription
+	numFields
+)
+
+var (
+	fieldNames  = [numFields]string{"title", "author", "description"}
+	fieldBoosts = [numFields]float64{3, 2, 1}
+)
+
+const (
+	// BM25 parameters
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// Score multipliers of query words matching an indexed word by prefix or with typos
+	prefixWeight = 0.8
+	typoWeight   = 0.5
+
+	facetLimit = 20
+)
+
+// Document is the indexed copy of a book
+type Document struct {
+	ID          string
+	Title       string
+	Author      string
+	Description string
+	Year        int
+	ISBN        string
+	Publisher   string
+	Lang        string
+	OwnerID     string
+	Visibility  string
+	Tags        []string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Version     int
+}
+
+// NewDocument returns the document indexing book
+func NewDocument(b *entities.Book) *Document {
+	tags := make([]string, len(b.Tags))
+	for i, tag := range b.Tags {
+		tags[i] = tag.Name
+	}
+	isbn := ""
+	if b.ISBN != nil {
+		isbn = *b.ISBN
+	}
+	return &Document{
+		ID:          b.ID,
+		Title:       b.Title,
+		Author:      b.Author,
+		Description: b.Description,
+		Year:        b.PublishedYear,
+		ISBN:        isbn,
+		Publisher:   b.Publisher,
+		Lang:        b.Lang,
+		OwnerID:     b.UserID,
+		Visibility:  b.Visibility,
+		Tags:        tags,
+		CreatedAt:   b.CreatedAt,
+		UpdatedAt:   b.UpdatedAt,
+		Version:     b.Version,
+	}
+}
+
+func (d *Document) text(f field) string {
+	switch f {
+	case fieldTitle:
+		return d.Title
+	case fieldAuthor:
+		return d.Author
+	default:
+		return d.Description
+	}
+}
+
+// entry is an indexed document with the length of its fields
+type entry struct {
+	doc     *Document
+	lengths [numFields]int
+}
+
+// Hit is a document matching a search
+type Hit struct {
+	ID    string
+	Score float64
+	// Highlights holds the fragments of each field with the matches marked
+	Highlights map[string][]string
+}
+
+// Result is a page of hits
+type Result struct {
+	Hits   []Hit
+	Total  int64
+	Facets map[string][]book.FacetCount
+}
+
+// Index is an in-memory inverted index of books, safe for concurrent use
+type Index struct {
+	mu      sync.RWMutex
+	entries map[string]*entry
+	// postings maps each word to the documents containing it, with the
+	// number of occurrences per field
+	postings     map[string]map[string]*[numFields]int
+	totalLengths [numFields]int
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{
+		entries:  make(map[string]*entry),
+		postings: make(map[string]map[string]*[numFields]int),
+	}
+}
+
+// Len returns the number of indexed documents
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.entries)
+}
+
+// Put adds doc to the index, replacing the document with the same ID
+func (i *Index) Put(doc *Document) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.put(doc)
+}
+
+// PutLatest adds doc to the index unless it holds a later version of the
+// book, and reports whether it did
+func (i *Index) PutLatest(doc *Document) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if e, ok := i.entries[doc.ID]; ok && e.doc.Version > doc.Version {
+		return false
+	}
+	i.put(doc)
+	return true
+}
+
+func (i *Index) put(doc *Document) {
+	i.remove(doc.ID)
+
+	e := &entry{doc: doc}
+	for f := field(0); f < numFields; f++ {
+		words := terms(doc.text(f))
+		e.lengths[f] = len(words)
+		i.totalLengths[f] += len(words)
+		for _, word := range words {
+			docs, ok := i.postings[word]
+			if !ok {
+				docs = make(map[string]*[numFields]int)
+				i.postings[word] = docs
+			}
+			counts, ok := docs[doc.ID]
+			if !ok {
+				counts = new([numFields]int)
+				docs[doc.ID] = counts
+			}
+			counts[f]++
+		}
+	}
+	i.entries[doc.ID] = e
+}
+
+// Delete removes a document from the index
+func (i *Index) Delete(id string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(id)
+}
+
+func (i *Index) remove(id string) {
+	e, ok := i.entries[id]
+	if !ok {
+		return
+	}
+	for f := field(0); f < numFields; f++ {
+		i.totalLengths[f] -= e.lengths[f]
+		for _, word := range terms(e.doc.text(f)) {
+			if docs, ok := i.postings[word]; ok {
+				delete(docs, id)
+				if len(docs) == 0 {
+					delete(i.postings, word)
+				}
+			}
+		}
+	}
+	delete(i.entries, id)
+}
+
+// match is a document matching the query with the indexed words it matched
+type match struct {
+	entry *entry
+	score float64
+	words map[string]bool
+}
+
+// Search returns the page of documents matching filter. Every query word
+// must match an indexed word exactly, with a few typos, or as a prefix for
+// the last one.
+func (i *Index) Search(filter *book.Filter, withFacets bool) *Result {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var matches []*match
+	if queryWords := terms(filter.Query); len(queryWords) > 0 {
+		matches = i.matchQuery(queryWords)
+	} else {
+		matches = make([]*match, 0, len(i.entries))
+		for _, e := range i.entries {
+			matches = append(matches, &match{entry: e})
+		}
+	}
+
+	var allowed map[string]bool
+	if filter.BookIDs != nil {
+		allowed = make(map[string]bool, len(filter.BookIDs))
+		for _, id := range filter.BookIDs {
+			allowed[id] = true
+		}
+	}
+	shared := make(map[string]bool, len(filter.SharedBookIDs))
+	for _, id := range filter.SharedBookIDs {
+		shared[id] = true
+	}
+
+	filtered := matches[:0]
+	for _, m := range matches {
+		if allowed != nil && !allowed[m.entry.doc.ID] {
+			continue
+		}
+		if filter.ViewerID != "" && !visibleTo(m.entry.doc, filter, shared[m.entry.doc.ID]) {
+			continue
+		}
+		if matchesFilter(m.entry.doc, filter) {
+			filtered = append(filtered, m)
+		}
+	}
+	matches = filtered
+	sortMatches(matches, filter.Sort)
+
+	result := &Result{Total: int64(len(matches))}
+	if withFacets {
+		result.Facets = facets(matches)
+	}
+
+	start := (filter.Page - 1) * filter.Limit
+	end := min(start+filter.Limit, len(matches))
+	for _, m := range matches[min(start, end):end] {
+		result.Hits = append(result.Hits, Hit{
+			ID:         m.entry.doc.ID,
+			Score:      m.score,
+			Highlights: highlights(m.entry.doc, m.words),
+		})
+	}
+	return result
+}
+
+// matchQuery returns the documents matching all query words, scored with
+// BM25 and the field boosts
+func (i *Index) matchQuery(queryWords []string) []*match {
+	var matches map[string]*match
+	for n, queryWord := range queryWords {
+		expansions := i.expand(queryWord, n == len(queryWords)-1)
+
+		wordMatches := make(map[string]*match)
+		for word, weight := range expansions {
+			docs := i.postings[word]
+			idf := math.Log(1 + (float64(len(i.entries))-float64(len(docs))+0.5)/(float64(len(docs))+0.5))
+			for id, counts := range docs {
+				score := weight * idf * i.fieldScore(i.entries[id], counts)
+				m, ok := wordMatches[id]
+				if !ok {
+					m = &match{entry: i.entries[id], words: make(map[string]bool)}
+					wordMatches[id] = m
+				}
+				m.score = max(m.score, score)
+				m.words[word] = true
+			}
+		}
+
+		if matches == nil {
+			matches = wordMatches
+			continue
+		}
+		for id, m := range matches {
+			wordMatch, ok := wordMatches[id]
+			if !ok {
+				delete(matches, id)
+				continue
+			}
+			m.score += wordMatch.score
+			for word := range wordMatch.words {
+				m.words[word] = true
+			}
+		}
+	}
+
+	result := make([]*match, 0, len(matches))
+	for _, m := range matches {
+		result = append(result, m)
+	}
+	return result
+}
+
+// expand returns the indexed words matching a query word with their weight
+func (i *Index) expand(queryWord string, prefix bool) map[string]float64 {
+	expansions := make(map[string]float64)
+	if _, ok := i.postings[queryWord]; ok {
+		expansions[queryWord] = 1
+	}
+
+	edits := maxEdits(queryWord)
+	queryRunes := []rune(queryWord)
+	for word := range i.postings {
+		if word == queryWord {
+			continue
+		}
+		weight := 0.0
+		if prefix && len(queryRunes) >= 2 && strings.HasPrefix(word, queryWord) {
+			weight = prefixWeight
+		}
+		if edits > 0 {
+			if d := editDistance(queryRunes, []rune(word), edits); d <= edits {
+				weight = max(weight, typoWeight/float64(d))
+			}
+		}
+		if weight > 0 {
+			expansions[word] = weight
+		}
+	}
+	return expansions
+}
+
+// fieldScore is the BM25 term frequency part of the score, summed over fields
+func (i *Index) fieldScore(e *entry, counts *[numFields]int) float64 {
+	score := 0.0
+	for f := field(0); f < numFields; f++ {
+		if counts[f] == 0 {
+			continue
+		}
+		avgLength := float64(i.totalLengths[f]) / float64(len(i.entries))
+		tf := float64(counts[f])
+		norm := 1 - bm25B + bm25B*float64(e.lengths[f])/avgLength
+		score += fieldBoosts[f] * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+	}
+	return score
+}
+
+// visibleTo tells whether the viewer of filter may see a document, within
+// its access scope. shared is set when the book is shared with the viewer.
+func visibleTo(doc *Document, filter *book.Filter, shared bool) bool {
+	owned := doc.OwnerID == filter.ViewerID
+	switch filter.Access {
+	case book.ScopeOwned:
+		return owned
+	case book.ScopeShared:
+		return !owned && shared
+	case book.ScopePublic:
+		return !owned && doc.Visibility == book.VisibilityPublic
+	}
+	return owned || shared || doc.Visibility == book.VisibilityPublic
+}
+
+// matchesFilter applies the non full-text criteria of a search
+func matchesFilter(doc *Document, filter *book.Filter) bool {
+	switch {
+	case filter.OwnerID != "" && doc.OwnerID != filter.OwnerID:
+		return false
+	case filter.Title != "" && !strings.Contains(fold(doc.Title), fold(filter.Title)):
+		return false
+	case filter.Author != "" && !strings.Contains(fold(doc.Author), fold(filter.Author)):
+		return false
+	case filter.ISBN != "" && doc.ISBN != filter.ISBN:
+		return false
+	case filter.Publisher != "" && !strings.Contains(fold(doc.Publisher), fold(filter.Publisher)):
+		return false
+	case filter.Lang != "" && doc.Lang != filter.Lang:
+		return false
+	case filter.YearFrom > 0 && doc.Year < filter.YearFrom:
+		return false
+	case filter.YearTo > 0 && doc.Year > filter.YearTo:
+		return false
+	}
+
+	for _, wanted := range filter.Tags {
+		found := false
+		for _, tag := range doc.Tags {
+			if strings.EqualFold(tag, wanted) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// sortMatches sorts by the given fields, then by ID so that pages are stable
+func sortMatches(matches []*match, fields []book.SortField) {
+	sort.Slice(matches, func(a, b int) bool {
+		x, y := matches[a], matches[b]
+		for _, f := range fields {
+			c := compare(x, y, f.Field)
+			if f.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return x.entry.doc.ID < y.entry.doc.ID
+	})
+}
+
+func compare(x, y *match, sortField string) int {
+	a, b := x.entry.doc, y.entry.doc
+	switch sortField {
+	case book.SortRelevance:
+		return cmpFloat(x.score, y.score)
+	case book.SortTitle:
+		return strings.Compare(fold(a.Title), fold(b.Title))
+	case book.SortAuthor:
+		return strings.Compare(fold(a.Author), fold(b.Author))
+	case book.SortYear:
+		return a.Year - b.Year
+	case book.SortCreatedAt:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case book.SortUpdatedAt:
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	}
+	return 0
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// facets counts the matches by author, publication year and tag
+func facets(matches []*match) map[string][]book.FacetCount {
+	authors := make(map[string]int64)
+	years := make(map[string]int64)
+	tags := make(map[string]int64)
+	for _, m := range matches {
+		doc := m.entry.doc
+		authors[doc.Author]++
+		if doc.Year > 0 {
+			years[strconv.Itoa(doc.Year)]++
+		}
+		for _, tag := range doc.Tags {
+			tags[tag]++
+		}
+	}
+
+	byCount := func(counts map[string]int64) []book.FacetCount {
+		result := facetCounts(counts)
+		sort.Slice(result, func(i, j int) bool {
+			if result[i].Count != result[j].Count {
+				return result[i].Count > result[j].Count
+			}
+			return result[i].Value < result[j].Value
+		})
+		return result[:min(len(result), facetLimit)]
+	}
+
+	yearFacets := facetCounts(years)
+	sort.Slice(yearFacets, func(i, j int) bool {
+		a, _ := strconv.Atoi(yearFacets[i].Value)
+		b, _ := strconv.Atoi(yearFacets[j].Value)
+		return a > b
+	})
+
+	return map[string][]book.FacetCount{
+		repository.FacetAuthor: byCount(authors),
+		repository.FacetYear:   yearFacets[:min(len(yearFacets), facetLimit)],
+		repository.FacetTag:    byCount(tags),
+	}
+}
+
+func facetCounts(counts map[string]int64) []book.FacetCount {
+	result := make([]book.FacetCount, 0, len(counts))
+	for value, count := range counts {
+		result = append(result, book.FacetCount{Value: value, Count: count})
+	}
+	return result
+}
+
+// Save writes the indexed documents to w
+func (i *Index) Save(w io.Writer) error {
+	i.mu.RLock()
+	docs := make([]*Document, 0, len(i.entries))
+	for _, e := range i.entries {
+		docs = append(docs, e.doc)
+	}
+	i.mu.RUnlock()
+
+	if err := gob.NewEncoder(w).Encode(docs); err != nil {
+		return fmt.Errorf("failed to save search index: %w", err)
+	}
+	return nil
+}
+
+// Load reads an index saved with Save
+func Load(r io.Reader) (*Index, error) {
+	var docs []*Document
+	if err := gob.NewDecoder(r).Decode(&docs); err != nil {
+		return nil, fmt.Errorf("failed to load search index: %w", err)
+	}
+
+	index := NewIndex()
+	for _, doc := range docs {
+		index.Put(doc)
+	}
+	return index, nil
+}
@@ -0,0 +1,104 @@
+// Package bookio streams books to and from CSV and JSON files, reading the
+// CSV exports of Goodreads and similar apps as well as its own.
+package bookio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// File formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// ContentTypes maps the formats to their content type
+var ContentTypes = map[string]string{
+	FormatCSV:  "text/csv",
+	FormatJSON: "application/json",
+}
+
+// ErrUnknownFormat is returned for formats other than CSV and JSON
+var ErrUnknownFormat = errors.New("unknown book file format")
+
+// Fields of a record, as named in row errors and CSV headers
+const (
+	FieldTitle         = "title"
+	FieldAuthor        = "author"
+	FieldDescription   = "description"
+	FieldISBN          = "isbn"
+	FieldPublishedYear = "published_year"
+	FieldPublisher     = "publisher"
+	FieldPageCount     = "page_count"
+	FieldEdition       = "edition"
+	FieldLang          = "lang"
+	FieldTags          = "tags"
+)
+
+// Record is a book as written in a file
+type Record struct {
+	Title         string   `json:"title"`
+	Author        string   `json:"author"`
+	Description   string   `json:"description,omitempty"`
+	ISBN          string   `json:"isbn,omitempty"`
+	PublishedYear int      `json:"published_year,omitempty"`
+	Publisher     string   `json:"publisher,omitempty"`
+	PageCount     int      `json:"page_count,omitempty"`
+	Edition       string   `json:"edition,omitempty"`
+	Lang          string   `json:"lang,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+// RowError is an invalid row, which readers skip to carry on with the next one
+type RowError struct {
+	// Row is the number of the row, from 1, headers excluded
+	Row     int
+	Field   string
+	Message string
+}
+
+func (e *RowError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+	}
+	return fmt.Sprintf("row %d: %s: %s", e.Row, e.Field, e.Message)
+}
+
+// Reader reads the records of a file one at a time
+type Reader interface {
+	// Read returns the next record and its row number. It returns a
+	// *RowError for an invalid row, after which reading can go on, and
+	// io.EOF after the last row.
+	Read() (*Record, int, error)
+}
+
+// Writer writes records to a file
+type Writer interface {
+	Write(record *Record) error
+	// Close completes the file, leaving the underlying writer open
+	Close() error
+}
+
+// NewReader returns a reader of a file in format
+func NewReader(format string, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVReader(r)
+	case FormatJSON:
+		return NewJSONReader(r)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// NewWriter returns a writer of a file in format
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w)
+	case FormatJSON:
+		return NewJSONWriter(w), nil
+	}
+	return nil, ErrUnknownFormat
+}
@@ -0,0 +1,194 @@
+package bookio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvColumn is the field a CSV column holds. When several columns hold the
+// same field, the non-empty one with the lowest rank wins.
+type csvColumn struct {
+	field string
+	rank  int
+}
+
+// csvColumns maps the lowercased CSV headers to fields, with the headers of
+// the Goodreads export next to the ones of this package
+var csvColumns = map[string]csvColumn{
+	"title":                     {FieldTitle, 0},
+	"author":                    {FieldAuthor, 0},
+	"authors":                   {FieldAuthor, 1},
+	"description":               {FieldDescription, 0},
+	"isbn13":                    {FieldISBN, 0},
+	"isbn":                      {FieldISBN, 1},
+	"published_year":            {FieldPublishedYear, 0},
+	"year published":            {FieldPublishedYear, 1},
+	"year":                      {FieldPublishedYear, 2},
+	"original publication year": {FieldPublishedYear, 3},
+	"publisher":                 {FieldPublisher, 0},
+	"page_count":                {FieldPageCount, 0},
+	"number of pages":           {FieldPageCount, 1},
+	"pages":                     {FieldPageCount, 2},
+	"edition":                   {FieldEdition, 0},
+	"lang":                      {FieldLang, 0},
+	"language":                  {FieldLang, 1},
+	"tags":                      {FieldTags, 0},
+	"bookshelves":               {FieldTags, 1},
+}
+
+// csvHeader is the header of the written CSV files
+var csvHeader = []string{
+	FieldTitle, FieldAuthor, FieldDescription, FieldISBN, FieldPublishedYear,
+	FieldPublisher, FieldPageCount, FieldEdition, FieldLang, FieldTags,
+}
+
+// CSVReader reads books from a CSV file with a header row
+type CSVReader struct {
+	r       *csv.Reader
+	columns []*csvColumn
+	row     int
+}
+
+// NewCSVReader reads the header of a CSV file, which must name a title column
+func NewCSVReader(r io.Reader) (*CSVReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty CSV file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	reader := &CSVReader{r: cr, columns: make([]*csvColumn, len(header))}
+	hasTitle := false
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if column, ok := csvColumns[name]; ok {
+			reader.columns[i] = &column
+			hasTitle = hasTitle || column.field == FieldTitle
+		}
+	}
+	if !hasTitle {
+		return nil, errors.New("CSV header has no title column")
+	}
+	return reader, nil
+}
+
+func (r *CSVReader) Read() (*Record, int, error) {
+	values, err := r.r.Read()
+	if err != nil {
+		return nil, r.row, err
+	}
+	r.row++
+
+	// Pick the value of each field from its best ranked non-empty column
+	picked := make(map[string]string)
+	ranks := make(map[string]int)
+	for i, value := range values {
+		if i >= len(r.columns) || r.columns[i] == nil {
+			continue
+		}
+		value = cleanCSVValue(value)
+		column := r.columns[i]
+		if rank, ok := ranks[column.field]; value == "" || (ok && rank <= column.rank) {
+			continue
+		}
+		picked[column.field] = value
+		ranks[column.field] = column.rank
+	}
+
+	record := &Record{
+		Title:       picked[FieldTitle],
+		Author:      picked[FieldAuthor],
+		Description: picked[FieldDescription],
+		ISBN:        picked[FieldISBN],
+		Publisher:   picked[FieldPublisher],
+		Edition:     picked[FieldEdition],
+		Lang:        picked[FieldLang],
+	}
+	if record.PublishedYear, err = r.number(picked, FieldPublishedYear); err != nil {
+		return nil, r.row, err
+	}
+	if record.PageCount, err = r.number(picked, FieldPageCount); err != nil {
+		return nil, r.row, err
+	}
+	for _, tag := range strings.Split(picked[FieldTags], ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			record.Tags = append(record.Tags, tag)
+		}
+	}
+	return record, r.row, nil
+}
+
+func (r *CSVReader) number(picked map[string]string, field string) (int, error) {
+	value, ok := picked[field]
+	if !ok {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, &RowError{Row: r.row, Field: field, Message: "must be a whole number"}
+	}
+	return n, nil
+}
+
+// cleanCSVValue trims a value, unwrapping the ="..." formulas Goodreads
+// writes ISBNs as
+func cleanCSVValue(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "=\"") && strings.HasSuffix(value, "\"") && len(value) >= 3 {
+		value = strings.TrimSpace(value[2 : len(value)-1])
+	}
+	return value
+}
+
+// CSVWriter writes books to a CSV file with a header row
+type CSVWriter struct {
+	w *csv.Writer
+}
+
+// NewCSVWriter writes the header of a CSV file
+func NewCSVWriter(w io.Writer) (*CSVWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	return &CSVWriter{w: cw}, nil
+}
+
+func (w *CSVWriter) Write(record *Record) error {
+	return w.w.Write([]string{
+		record.Title,
+		record.Author,
+		record.Description,
+		record.ISBN,
+		formatNumber(record.PublishedYear),
+		record.Publisher,
+		formatNumber(record.PageCount),
+		record.Edition,
+		record.Lang,
+		strings.Join(record.Tags, ", "),
+	})
+}
+
+func (w *CSVWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// formatNumber writes the unknown zero values as empty cells
+func formatNumber(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,105 @@
+package bookio
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// JSONReader reads books from a JSON array of objects, one at a time
+type JSONReader struct {
+	d   *json.Decoder
+	row int
+}
+
+// NewJSONReader reads the opening bracket of a JSON array
+func NewJSONReader(r io.Reader) (*JSONReader, error) {
+	d := json.NewDecoder(r)
+	token, err := d.Token()
+	if err == io.EOF {
+		return nil, errors.New("empty JSON file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON file: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("JSON file must hold an array of books")
+	}
+	return &JSONReader{d: d}, nil
+}
+
+func (r *JSONReader) Read() (*Record, int, error) {
+	if !r.d.More() {
+		// Consume the closing bracket
+		if _, err := r.d.Token(); err != nil {
+			return nil, r.row, fmt.Errorf("invalid JSON file: %w", err)
+		}
+		return nil, r.row, io.EOF
+	}
+	r.row++
+
+	var record Record
+	if err := r.d.Decode(&record); err != nil {
+		// A value of the wrong type is skipped whole, the decoder can go on
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, r.row, &RowError{Row: r.row, Field: typeErr.Field, Message: "must be " + jsonKind(typeErr.Type)}
+		}
+		return nil, r.row, fmt.Errorf("invalid JSON file at book %d: %w", r.row, err)
+	}
+	return &record, r.row, nil
+}
+
+// jsonKind describes the JSON values a record or its fields take
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int:
+		return "a whole number"
+	case reflect.Slice:
+		return "an array of strings"
+	case reflect.Struct:
+		return "an object"
+	}
+	return "a string"
+}
+
+// JSONWriter writes books as a JSON array, one object per line
+type JSONWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	return &JSONWriter{w: bufio.NewWriter(w)}
+}
+
+func (w *JSONWriter) Write(record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	separator := ",\n"
+	if w.count == 0 {
+		separator = "[\n"
+	}
+	w.count++
+	if _, err := w.w.WriteString(separator); err != nil {
+		return err
+	}
+	_, err = w.w.Write(data)
+	return err
+}
+
+func (w *JSONWriter) Close() error {
+	end := "\n]\n"
+	if w.count == 0 {
+		end = "[]\n"
+	}
+	if _, err := w.w.WriteString(end); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
@@ -0,0 +1,77 @@
+package bookmeta
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/pkg/redis"
+)
+
+// Ensure CachedProvider implements book.MetadataProvider
+var _ book.MetadataProvider = (*CachedProvider)(nil)
+
+// notFound is cached in place of the record of an ISBN the provider doesn't
+// know
+const notFound = "not_found"
+
+// Cache stores the records of CachedProvider
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+}
+
+// CachedProvider keeps the records found by another provider in Redis
+type CachedProvider struct {
+	provider    book.MetadataProvider
+	cache       Cache
+	ttl         time.Duration
+	notFoundTTL time.Duration
+}
+
+// NewCachedProvider caches the records found by provider for ttl, and the
+// ISBNs it doesn't know for notFoundTTL (not at all when 0)
+func NewCachedProvider(provider book.MetadataProvider, cache Cache, ttl, notFoundTTL time.Duration) *CachedProvider {
+	return &CachedProvider{
+		provider:    provider,
+		cache:       cache,
+		ttl:         ttl,
+		notFoundTTL: notFoundTTL,
+	}
+}
+
+// LookupISBN returns the cached record of an ISBN-13, or looks it up and caches it
+func (p *CachedProvider) LookupISBN(ctx context.Context, isbn string) (*book.Metadata, error) {
+	key := "book_metadata:" + isbn
+
+	data, err := p.cache.Get(ctx, key)
+	if err == nil {
+		if data == notFound {
+			return nil, book.ErrMetadataNotFound
+		}
+		var metadata book.Metadata
+		if err := json.Unmarshal([]byte(data), &metadata); err == nil {
+			return &metadata, nil
+		}
+	} else if err != redis.Nil {
+		log.Printf("Error getting book metadata from cache: %v", err)
+	}
+
+	metadata, err := p.provider.LookupISBN(ctx, isbn)
+	if err == book.ErrMetadataNotFound && p.notFoundTTL > 0 {
+		if err := p.cache.Set(ctx, key, notFound, p.notFoundTTL); err != nil {
+			log.Printf("Error caching book metadata: %v", err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(metadata); err == nil {
+		if err := p.cache.Set(ctx, key, string(data), p.ttl); err != nil {
+			log.Printf("Error caching book metadata: %v", err)
+		}
+	}
+	return metadata, nil
+}
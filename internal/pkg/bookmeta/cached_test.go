@@ -0,0 +1,145 @@
+package bookmeta
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/pkg/redis"
+)
+
+// fakeCache keeps the values in memory with their TTL
+type fakeCache struct {
+	values map[string]string
+	ttls   map[string]time.Duration
+	err    error
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{values: make(map[string]string), ttls: make(map[string]time.Duration)}
+}
+
+func (c *fakeCache) Get(ctx context.Context, key string) (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	value, ok := c.values[key]
+	if !ok {
+		return "", redis.Nil
+	}
+	return value, nil
+}
+
+func (c *fakeCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.values[key] = value.(string)
+	c.ttls[key] = expiration
+	return nil
+}
+
+// countingProvider counts the lookups made through it
+type countingProvider struct {
+	book.MetadataProvider
+	lookups int
+}
+
+func (p *countingProvider) LookupISBN(ctx context.Context, isbn string) (*book.Metadata, error) {
+	p.lookups++
+	return p.MetadataProvider.LookupISBN(ctx, isbn)
+}
+
+func newCountingProvider(t *testing.T) *countingProvider {
+	t.Helper()
+	fixtures, err := LoadFixtureProvider("testdata/books.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &countingProvider{MetadataProvider: fixtures}
+}
+
+func TestCachedProviderCachesRecords(t *testing.T) {
+	provider := newCountingProvider(t)
+	cache := newFakeCache()
+	p := NewCachedProvider(provider, cache, time.Hour, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		metadata, err := p.LookupISBN(context.Background(), "9780134190440")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if metadata.Title != "The Go Programming Language" || len(metadata.Authors) != 2 || metadata.PageCount != 380 {
+			t.Errorf("lookup %d = %+v", i, metadata)
+		}
+	}
+	if provider.lookups != 1 {
+		t.Errorf("%d lookups, want 1", provider.lookups)
+	}
+	if ttl := cache.ttls["book_metadata:9780134190440"]; ttl != time.Hour {
+		t.Errorf("TTL = %s, want 1h", ttl)
+	}
+}
+
+func TestCachedProviderCachesUnknownISBNs(t *testing.T) {
+	provider := newCountingProvider(t)
+	cache := newFakeCache()
+	p := NewCachedProvider(provider, cache, time.Hour, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.LookupISBN(context.Background(), "9780306406157"); err != book.ErrMetadataNotFound {
+			t.Fatalf("lookup %d = %v, want ErrMetadataNotFound", i, err)
+		}
+	}
+	if provider.lookups != 1 {
+		t.Errorf("%d lookups, want 1", provider.lookups)
+	}
+	if ttl := cache.ttls["book_metadata:9780306406157"]; ttl != time.Minute {
+		t.Errorf("TTL = %s, want the not found TTL", ttl)
+	}
+
+	// Without a not found TTL, each lookup reaches the provider
+	provider.lookups = 0
+	p = NewCachedProvider(provider, newFakeCache(), time.Hour, 0)
+	p.LookupISBN(context.Background(), "9780306406157")
+	p.LookupISBN(context.Background(), "9780306406157")
+	if provider.lookups != 2 {
+		t.Errorf("%d lookups without a not found TTL, want 2", provider.lookups)
+	}
+}
+
+func TestCachedProviderDoesntCacheFailures(t *testing.T) {
+	unavailable := &countingProvider{MetadataProvider: failingProvider{}}
+	cache := newFakeCache()
+	p := NewCachedProvider(unavailable, cache, time.Hour, time.Minute)
+
+	p.LookupISBN(context.Background(), "9780134190440")
+	if _, err := p.LookupISBN(context.Background(), "9780134190440"); err != book.ErrMetadataUnavailable {
+		t.Errorf("lookup = %v, want ErrMetadataUnavailable", err)
+	}
+	if unavailable.lookups != 2 || len(cache.values) != 0 {
+		t.Errorf("%d lookups and %d cached values, want 2 and none", unavailable.lookups, len(cache.values))
+	}
+}
+
+func TestCachedProviderWithoutRedis(t *testing.T) {
+	provider := newCountingProvider(t)
+	cache := newFakeCache()
+	cache.err = errors.New("connection refused")
+	p := NewCachedProvider(provider, cache, time.Hour, time.Minute)
+
+	if metadata, err := p.LookupISBN(context.Background(), "9780134190440"); err != nil || metadata.Title != "The Go Programming Language" {
+		t.Errorf("lookup = %+v, %v", metadata, err)
+	}
+	if _, err := p.LookupISBN(context.Background(), "9780306406157"); err != book.ErrMetadataNotFound {
+		t.Errorf("lookup = %v, want ErrMetadataNotFound", err)
+	}
+}
+
+type failingProvider struct{}
+
+func (failingProvider) LookupISBN(ctx context.Context, isbn string) (*book.Metadata, error) {
+	return nil, book.ErrMetadataUnavailable
+}
@@ -0,0 +1,59 @@
+package bookmeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"clean-arch-go/internal/domain/book"
+)
+
+// Ensure FixtureProvider implements book.MetadataProvider
+var _ book.MetadataProvider = (*FixtureProvider)(nil)
+
+// FixtureProvider serves metadata from a fixed set of records, for tests and
+// offline development
+type FixtureProvider struct {
+	records map[string]*book.Metadata
+}
+
+// NewFixtureProvider creates a provider knowing the given records, keyed by
+// ISBN-10 or ISBN-13
+func NewFixtureProvider(records map[string]*book.Metadata) (*FixtureProvider, error) {
+	p := &FixtureProvider{records: make(map[string]*book.Metadata, len(records))}
+	for key, record := range records {
+		isbn, err := book.NormalizeISBN(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixture ISBN %q: %w", key, err)
+		}
+		record.ISBN = isbn
+		p.records[isbn] = record
+	}
+	return p, nil
+}
+
+// LoadFixtureProvider creates a provider knowing the records of a JSON file
+// mapping ISBNs to metadata
+func LoadFixtureProvider(path string) (*FixtureProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read book metadata fixtures: %w", err)
+	}
+	var records map[string]*book.Metadata
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse book metadata fixtures: %w", err)
+	}
+	return NewFixtureProvider(records)
+}
+
+// LookupISBN returns a copy of the record of an ISBN-13
+func (p *FixtureProvider) LookupISBN(ctx context.Context, isbn string) (*book.Metadata, error) {
+	record, ok := p.records[isbn]
+	if !ok {
+		return nil, book.ErrMetadataNotFound
+	}
+	metadata := *record
+	metadata.Authors = append([]string(nil), record.Authors...)
+	return &metadata, nil
+}
@@ -0,0 +1,121 @@
+// Package bookmeta provides book metadata providers looking books up by ISBN.
+package bookmeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"clean-arch-go/internal/domain/book"
+)
+
+// DefaultOpenLibraryURL is the base URL of the public Open Library API
+const DefaultOpenLibraryURL = "https://openlibrary.org"
+
+// Ensure OpenLibraryProvider implements book.MetadataProvider
+var _ book.MetadataProvider = (*OpenLibraryProvider)(nil)
+
+// yearPattern finds the year in the free-form publish dates of Open Library
+var yearPattern = regexp.MustCompile(`\b\d{4}\b`)
+
+// OpenLibraryProvider looks books up with the Open Library books API, or any
+// service exposing the same /api/books endpoint
+type OpenLibraryProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewOpenLibraryProvider creates a provider calling the API at baseURL
+func NewOpenLibraryProvider(baseURL string) *OpenLibraryProvider {
+	if baseURL == "" {
+		baseURL = DefaultOpenLibraryURL
+	}
+	return &OpenLibraryProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// openLibraryBook is the part of an Open Library "data" record we use
+type openLibraryBook struct {
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle"`
+	Authors  []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+	Publishers []struct {
+		Name string `json:"name"`
+	} `json:"publishers"`
+	PublishDate   string `json:"publish_date"`
+	NumberOfPages int    `json:"number_of_pages"`
+	Cover         struct {
+		Medium string `json:"medium"`
+		Large  string `json:"large"`
+	} `json:"cover"`
+}
+
+// LookupISBN fetches the record of an ISBN-13
+func (p *OpenLibraryProvider) LookupISBN(ctx context.Context, isbn string) (*book.Metadata, error) {
+	key := "ISBN:" + isbn
+	query := url.Values{
+		"bibkeys": {key},
+		"format":  {"json"},
+		"jscmd":   {"data"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/books?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Open Library request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.Printf("Open Library lookup of %s failed: %v", isbn, err)
+		return nil, book.ErrMetadataUnavailable
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Open Library lookup of %s failed with status %d", isbn, resp.StatusCode)
+		return nil, book.ErrMetadataUnavailable
+	}
+
+	var records map[string]openLibraryBook
+	if err := json.NewDecoder(resp.Body).Decode(&records); err != nil {
+		log.Printf("Open Library lookup of %s returned an invalid response: %v", isbn, err)
+		return nil, book.ErrMetadataUnavailable
+	}
+	record, ok := records[key]
+	if !ok {
+		return nil, book.ErrMetadataNotFound
+	}
+
+	metadata := &book.Metadata{
+		ISBN:      isbn,
+		Title:     record.Title,
+		PageCount: record.NumberOfPages,
+		CoverURL:  record.Cover.Large,
+	}
+	if record.Subtitle != "" {
+		metadata.Title += ": " + record.Subtitle
+	}
+	if metadata.CoverURL == "" {
+		metadata.CoverURL = record.Cover.Medium
+	}
+	for _, author := range record.Authors {
+		metadata.Authors = append(metadata.Authors, author.Name)
+	}
+	if len(record.Publishers) > 0 {
+		metadata.Publisher = record.Publishers[0].Name
+	}
+	if year := yearPattern.FindString(record.PublishDate); year != "" {
+		metadata.PublishedYear, _ = strconv.Atoi(year)
+	}
+	return metadata, nil
+}
@@ -2,16 +2,25 @@ package config
 
 import (
 	"log"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	App       AppConfig       `mapstructure:",squash"`
-	Database  DatabaseConfig  `mapstructure:",squash"`
-	Redis     RedisConfig     `mapstructure:",squash"`
-	JWT       JWTConfig       `mapstructure:",squash"`
-	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	App         AppConfig         `mapstructure:",squash"`
+	Database    DatabaseConfig    `mapstructure:",squash"`
+	Redis       RedisConfig       `mapstructure:",squash"`
+	Cache       CacheConfig       `mapstructure:",squash"`
+	JWT         JWTConfig         `mapstructure:",squash"`
+	RateLimit   RateLimitConfig   `mapstructure:"rate_limit"`
+	Translation TranslationConfig `mapstructure:",squash"`
+	Search      SearchConfig      `mapstructure:",squash"`
+	BookMetadata BookMetadataConfig `mapstructure:",squash"`
+	Storage     StorageConfig     `mapstructure:",squash"`
+	Lending     LendingConfig     `mapstructure:",squash"`
+	Transfer    TransferConfig    `mapstructure:",squash"`
+	Trash       TrashConfig       `mapstructure:",squash"`
 }
 
 type RateLimitConfig struct {
@@ -25,6 +34,8 @@ type AppConfig struct {
 	Port        string
 	GRPCPort    string
 	Secret      string
+	// AdminEmails lists the users allowed to call the admin endpoints
+	AdminEmails []string
 }
 
 type DatabaseConfig struct {
@@ -33,6 +44,9 @@ type DatabaseConfig struct {
 	User     string
 	Password string
 	Name     string
+	// AutoMigrate creates and updates the tables from the models at startup,
+	// disable it when the schema is managed with the SQL migrations
+	AutoMigrate bool
 }
 
 type RedisConfig struct {
@@ -41,11 +55,107 @@ type RedisConfig struct {
 	DB       int
 }
 
+type CacheConfig struct {
+	// BookMinute, UserMinute and TranslationMinute are how long the
+	// repositories cache their entities
+	BookMinute        int
+	UserMinute        int
+	TranslationMinute int
+	// StaleSecond is how long expired entities are still served while
+	// they're refreshed
+	StaleSecond int
+	// NegativeSecond is how long entities that weren't found are remembered
+	// as missing
+	NegativeSecond int
+	// L1Size is how many entries are kept in process memory in front of
+	// Redis, 0 to disable it, and L1Second for how long
+	L1Size   int
+	L1Second int
+	// EarlyExpiration weighs the load time of entities in the odds of
+	// refreshing them before they expire, 0 to wait for their expiry
+	EarlyExpiration float64
+	// FailOnError makes cache errors fail requests instead of being logged
+	FailOnError bool
+}
+
 type JWTConfig struct {
 	Secret           string
 	ExpirationMinute int
 }
 
+type TranslationConfig struct {
+	// Languages supported by the translation provider
+	Languages            []string
+	LanguagesCacheMinute int
+}
+
+type SearchConfig struct {
+	// Backend is "sql" to search the database or "index" for the embedded index
+	Backend string
+	// IndexPath is the file the embedded index is saved to
+	IndexPath string
+}
+
+type BookMetadataConfig struct {
+	// Provider is openlibrary, fixture or none
+	Provider       string
+	OpenLibraryURL string
+	// FixturesPath is the JSON file of the fixture provider
+	FixturesPath string
+	CacheMinute  int
+	// NotFoundMinute is how long ISBNs without metadata are remembered as
+	// unknown
+	NotFoundMinute int
+}
+
+type StorageConfig struct {
+	// Backend is local or s3
+	Backend string
+	// LocalPath is the directory of the local backend
+	LocalPath string
+	// PublicURL is the base URL of the stored files: the path the server
+	// exposes them at for the local backend, the bucket URL by default for s3
+	PublicURL   string
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool
+	// CoverMaxBytes is the maximum size of uploaded cover images
+	CoverMaxBytes int64
+}
+
+type LendingConfig struct {
+	LoanDays    int
+	MaxRenewals int
+	// HoldPickupDays is how long a returned copy is kept for the next hold
+	HoldPickupDays int
+	// SweepMinute is how often holds are expired and overdue loans reported
+	SweepMinute int
+}
+
+type TransferConfig struct {
+	// Path is the directory of the files of the import and export jobs with
+	// the local storage backend
+	Path string
+	// S3Bucket is the private bucket of the files of the jobs with the s3
+	// storage backend
+	S3Bucket string
+	// ImportMaxBytes is the maximum size of imported files
+	ImportMaxBytes int64
+	// Workers is how many import and export jobs run at once
+	Workers int
+}
+
+type TrashConfig struct {
+	// RetentionDays is how long deleted books can be restored before they
+	// are purged, for good when not positive
+	RetentionDays int
+	// PurgeMinute is how often the expired books are purged
+	PurgeMinute int
+}
+
 func LoadConfig() *Config {
 	viper.SetConfigFile(".env")
 	viper.AutomaticEnv()
@@ -58,11 +168,44 @@ func LoadConfig() *Config {
 	viper.SetDefault("APP_PORT", "8080")
 	viper.SetDefault("DB_HOST", "localhost")
 	viper.SetDefault("DB_PORT", "3306")
+	viper.SetDefault("DB_AUTO_MIGRATE", true)
 	viper.SetDefault("REDIS_ADDR", "localhost:6379")
 	viper.SetDefault("REDIS_DB", 0)
+	viper.SetDefault("CACHE_BOOK_MINUTE", 5)
+	viper.SetDefault("CACHE_USER_MINUTE", 5)
+	viper.SetDefault("CACHE_TRANSLATION_MINUTE", 60)
+	viper.SetDefault("CACHE_STALE_SECOND", 60)
+	viper.SetDefault("CACHE_NEGATIVE_SECOND", 30)
+	viper.SetDefault("CACHE_L1_SIZE", 0)
+	viper.SetDefault("CACHE_L1_SECOND", 10)
+	viper.SetDefault("CACHE_EARLY_EXPIRATION", 1)
 	viper.SetDefault("JWT_EXPIRATION_MINUTE", 1440)
 	viper.SetDefault("RATE_LIMIT", 100)
 	viper.SetDefault("RATE_BURST", 30)
+	viper.SetDefault("TRANSLATION_LANGUAGES", "en,vi,fr,es")
+	viper.SetDefault("TRANSLATION_LANGUAGES_CACHE_MINUTE", 60)
+	viper.SetDefault("SEARCH_BACKEND", "sql")
+	viper.SetDefault("SEARCH_INDEX_PATH", "data/books.idx")
+	viper.SetDefault("BOOK_METADATA_PROVIDER", "openlibrary")
+	viper.SetDefault("OPENLIBRARY_URL", "https://openlibrary.org")
+	viper.SetDefault("BOOK_METADATA_FIXTURES", "internal/pkg/bookmeta/testdata/books.json")
+	viper.SetDefault("BOOK_METADATA_CACHE_MINUTE", 1440)
+	viper.SetDefault("BOOK_METADATA_NOT_FOUND_MINUTE", 10)
+	viper.SetDefault("STORAGE_BACKEND", "local")
+	viper.SetDefault("STORAGE_LOCAL_PATH", "data/media")
+	viper.SetDefault("STORAGE_PUBLIC_URL", "/media")
+	viper.SetDefault("S3_REGION", "us-east-1")
+	viper.SetDefault("S3_PATH_STYLE", true)
+	viper.SetDefault("COVER_MAX_BYTES", 5<<20)
+	viper.SetDefault("LOAN_DAYS", 14)
+	viper.SetDefault("LOAN_MAX_RENEWALS", 2)
+	viper.SetDefault("HOLD_PICKUP_DAYS", 3)
+	viper.SetDefault("LENDING_SWEEP_MINUTE", 15)
+	viper.SetDefault("BOOK_TRANSFER_PATH", "data/transfers")
+	viper.SetDefault("BOOK_IMPORT_MAX_BYTES", 20<<20)
+	viper.SetDefault("BOOK_TRANSFER_WORKERS", 2)
+	viper.SetDefault("TRASH_RETENTION_DAYS", 30)
+	viper.SetDefault("TRASH_PURGE_MINUTE", 60)
 
 	// Set default values for app config
 	viper.SetDefault("APP_NAME", "Clean Arch Go")
@@ -71,10 +214,11 @@ func LoadConfig() *Config {
 
 	config := &Config{
 		App: AppConfig{
-			Name:   viper.GetString("APP_NAME"),
-			Env:    viper.GetString("APP_ENV"),
-			Port:   viper.GetString("APP_PORT"),
-			Secret: viper.GetString("APP_SECRET"),
+			Name:        viper.GetString("APP_NAME"),
+			Env:         viper.GetString("APP_ENV"),
+			Port:        viper.GetString("APP_PORT"),
+			Secret:      viper.GetString("APP_SECRET"),
+			AdminEmails: splitList(viper.GetString("ADMIN_EMAILS")),
 		},
 		Database: DatabaseConfig{
 			Host:     viper.GetString("DB_HOST"),
@@ -82,12 +226,24 @@ func LoadConfig() *Config {
 			User:     viper.GetString("DB_USER"),
 			Password: viper.GetString("DB_PASSWORD"),
 			Name:     viper.GetString("DB_NAME"),
+			AutoMigrate: viper.GetBool("DB_AUTO_MIGRATE"),
 		},
 		Redis: RedisConfig{
 			Addr:     viper.GetString("REDIS_ADDR"),
 			Password: viper.GetString("REDIS_PASSWORD"),
 			DB:       viper.GetInt("REDIS_DB"),
 		},
+		Cache: CacheConfig{
+			BookMinute:        viper.GetInt("CACHE_BOOK_MINUTE"),
+			UserMinute:        viper.GetInt("CACHE_USER_MINUTE"),
+			TranslationMinute: viper.GetInt("CACHE_TRANSLATION_MINUTE"),
+			StaleSecond:       viper.GetInt("CACHE_STALE_SECOND"),
+			NegativeSecond:    viper.GetInt("CACHE_NEGATIVE_SECOND"),
+			L1Size:            viper.GetInt("CACHE_L1_SIZE"),
+			L1Second:          viper.GetInt("CACHE_L1_SECOND"),
+			EarlyExpiration:   viper.GetFloat64("CACHE_EARLY_EXPIRATION"),
+			FailOnError:       viper.GetBool("CACHE_FAIL_ON_ERROR"),
+		},
 		JWT: JWTConfig{
 			Secret:           viper.GetString("JWT_SECRET"),
 			ExpirationMinute: viper.GetInt("JWT_EXPIRATION_MINUTE"),
@@ -96,7 +252,61 @@ func LoadConfig() *Config {
 			Limit: viper.GetInt("RATE_LIMIT"),
 			Burst: viper.GetInt("RATE_BURST"),
 		},
+		Translation: TranslationConfig{
+			Languages:            splitList(viper.GetString("TRANSLATION_LANGUAGES")),
+			LanguagesCacheMinute: viper.GetInt("TRANSLATION_LANGUAGES_CACHE_MINUTE"),
+		},
+		Search: SearchConfig{
+			Backend:   viper.GetString("SEARCH_BACKEND"),
+			IndexPath: viper.GetString("SEARCH_INDEX_PATH"),
+		},
+		BookMetadata: BookMetadataConfig{
+			Provider:       viper.GetString("BOOK_METADATA_PROVIDER"),
+			OpenLibraryURL: viper.GetString("OPENLIBRARY_URL"),
+			FixturesPath:   viper.GetString("BOOK_METADATA_FIXTURES"),
+			CacheMinute:    viper.GetInt("BOOK_METADATA_CACHE_MINUTE"),
+			NotFoundMinute: viper.GetInt("BOOK_METADATA_NOT_FOUND_MINUTE"),
+		},
+		Storage: StorageConfig{
+			Backend:       viper.GetString("STORAGE_BACKEND"),
+			LocalPath:     viper.GetString("STORAGE_LOCAL_PATH"),
+			PublicURL:     viper.GetString("STORAGE_PUBLIC_URL"),
+			S3Endpoint:    viper.GetString("S3_ENDPOINT"),
+			S3Region:      viper.GetString("S3_REGION"),
+			S3Bucket:      viper.GetString("S3_BUCKET"),
+			S3AccessKey:   viper.GetString("S3_ACCESS_KEY"),
+			S3SecretKey:   viper.GetString("S3_SECRET_KEY"),
+			S3PathStyle:   viper.GetBool("S3_PATH_STYLE"),
+			CoverMaxBytes: viper.GetInt64("COVER_MAX_BYTES"),
+		},
+		Lending: LendingConfig{
+			LoanDays:       viper.GetInt("LOAN_DAYS"),
+			MaxRenewals:    viper.GetInt("LOAN_MAX_RENEWALS"),
+			HoldPickupDays: viper.GetInt("HOLD_PICKUP_DAYS"),
+			SweepMinute:    viper.GetInt("LENDING_SWEEP_MINUTE"),
+		},
+		Transfer: TransferConfig{
+			Path:           viper.GetString("BOOK_TRANSFER_PATH"),
+			S3Bucket:       viper.GetString("BOOK_TRANSFER_S3_BUCKET"),
+			ImportMaxBytes: viper.GetInt64("BOOK_IMPORT_MAX_BYTES"),
+			Workers:        viper.GetInt("BOOK_TRANSFER_WORKERS"),
+		},
+		Trash: TrashConfig{
+			RetentionDays: viper.GetInt("TRASH_RETENTION_DAYS"),
+			PurgeMinute:   viper.GetInt("TRASH_PURGE_MINUTE"),
+		},
 	}
 
 	return config
 }
+
+// splitList splits a comma-separated value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
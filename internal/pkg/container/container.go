@@ -1,14 +1,26 @@
 package container
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"time"
 
+	apptranslation "clean-arch-go/internal/application/translation"
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
 	"clean-arch-go/internal/domain/repository"
 	"clean-arch-go/internal/domain/service"
+	"clean-arch-go/internal/domain/user"
 	"clean-arch-go/internal/infrastructure/repository/cached"
+	"clean-arch-go/internal/infrastructure/search"
+	"clean-arch-go/internal/pkg/bookmeta"
 	"clean-arch-go/internal/pkg/config"
 	"clean-arch-go/internal/pkg/database"
+	"clean-arch-go/internal/pkg/i18n"
+	"clean-arch-go/internal/pkg/langdetect"
 	"clean-arch-go/internal/pkg/redis"
+	"clean-arch-go/internal/pkg/storage"
 
 	"gorm.io/gorm"
 )
@@ -21,9 +33,26 @@ type Container struct {
 	AuthSvc        service.AuthService
 	BookSvc        service.BookService
 	TranslationSvc service.TranslationService
+	GlossarySvc    service.GlossaryService
+	TranslationMemorySvc service.TranslationMemoryService
+	BookCoverSvc   service.BookCoverService
+	ShelfSvc       service.ShelfService
+	ReadingSvc     service.ReadingService
+	LendingSvc     service.LendingService
+	NotificationSvc service.NotificationService
+	BookTransferSvc service.BookTransferService
+	BookTrashSvc    service.BookTrashService
 	UserRepo       repository.UserRepository
 	BookRepo       repository.BookRepository
 	TranslationRepo repository.TranslationRepository
+	GlossaryRepo   repository.GlossaryRepository
+	ShelfRepo      repository.ShelfRepository
+	// SearchIndex is the embedded book index, nil unless it's the search backend
+	SearchIndex *search.IndexBackend
+	// Caches are the repository caches by name, for their statistics
+	Caches map[string]cached.StatsReporter
+	// l1 is the in-process cache of the repositories, nil when disabled
+	l1 *cached.L1
 }
 
 // NewContainer creates a new application container with all dependencies
@@ -34,9 +63,11 @@ func NewContainer(cfg *config.Config) (*Container, error) {
 		return nil, err
 	}
 
-	// Run database migrations
-	if err := runMigrations(db); err != nil {
-		return nil, err
+	// Run database migrations, unless the schema is migrated separately
+	if cfg.Database.AutoMigrate {
+		if err := runMigrations(db); err != nil {
+			return nil, err
+		}
 	}
 
 	// Initialize Redis
@@ -48,11 +79,56 @@ func NewContainer(cfg *config.Config) (*Container, error) {
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	bookRepo := repository.NewBookRepository(db)
-	translationRepo := repository.NewTranslationRepository(db)
+	translationRepo := repository.NewTranslationRepository(db, cfg.Translation.Languages)
+	glossaryRepo := repository.NewGlossaryRepository(db)
+	shelfRepo := repository.NewShelfRepository(db)
+	readingRepo := repository.NewReadingRepository(db)
+	reviewRepo := repository.NewReviewRepository(db)
+	lendingRepo := repository.NewLendingRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	bookJobRepo := repository.NewBookJobRepository(db)
+	bookRevisionRepo := repository.NewBookRevisionRepository(db)
 
 	// Initialize cached repositories
-	cachedUserRepo := cached.NewCachedUserRepository(userRepo, redisClient)
-	cachedBookRepo := cached.NewCachedBookRepository(bookRepo, redisClient)
+	cachePolicy := cached.IgnoreErrors
+	if cfg.Cache.FailOnError {
+		cachePolicy = cached.FailOnErrors
+	}
+	staleTTL := time.Duration(cfg.Cache.StaleSecond) * time.Second
+	negativeTTL := time.Duration(cfg.Cache.NegativeSecond) * time.Second
+	// The repositories share the in-process cache, connected to the other
+	// instances through Redis
+	var l1 *cached.L1
+	if cfg.Cache.L1Size > 0 {
+		l1 = cached.NewL1(cfg.Cache.L1Size, time.Duration(cfg.Cache.L1Second)*time.Second)
+		if err := l1.Connect(redisClient); err != nil {
+			return nil, fmt.Errorf("subscribe to cache invalidations: %w", err)
+		}
+	}
+	cachedUserRepo := cached.NewCachedUserRepository(userRepo, redisClient, cached.Options[user.User]{
+		TTL:             time.Duration(cfg.Cache.UserMinute) * time.Minute,
+		StaleTTL:        staleTTL,
+		NegativeTTL:     negativeTTL,
+		L1:              l1,
+		EarlyExpiration: cfg.Cache.EarlyExpiration,
+		ErrorPolicy:     cachePolicy,
+	})
+	cachedBookRepo := cached.NewCachedBookRepository(bookRepo, redisClient, cached.Options[entities.Book]{
+		TTL:             time.Duration(cfg.Cache.BookMinute) * time.Minute,
+		StaleTTL:        staleTTL,
+		NegativeTTL:     negativeTTL,
+		L1:              l1,
+		EarlyExpiration: cfg.Cache.EarlyExpiration,
+		ErrorPolicy:     cachePolicy,
+	})
+	cachedTranslationRepo := cached.NewCachedTranslationRepository(translationRepo, redisClient, cached.Options[entities.Translation]{
+		TTL:             time.Duration(cfg.Cache.TranslationMinute) * time.Minute,
+		StaleTTL:        staleTTL,
+		NegativeTTL:     negativeTTL,
+		L1:              l1,
+		EarlyExpiration: cfg.Cache.EarlyExpiration,
+		ErrorPolicy:     cachePolicy,
+	})
 
 	// Initialize services
 	authSvc := service.NewAuthService(
@@ -61,8 +137,101 @@ func NewContainer(cfg *config.Config) (*Container, error) {
 		redisClient,
 	)
 
-	bookSvc := service.NewBookService(cachedBookRepo)
-	translationSvc := service.NewTranslationService(translationRepo)
+	translationUsecase := apptranslation.NewTranslationUsecase(cachedTranslationRepo)
+	translationSvc := service.NewTranslationService(
+		translationUsecase,
+		langdetect.NewNgramDetector(),
+		glossaryRepo,
+		time.Duration(cfg.Translation.LanguagesCacheMinute)*time.Minute,
+	)
+	// Initialize the book search backend
+	var searchBackend service.BookSearchBackend
+	var bookListeners []service.BookEventListener
+	var searchIndex *search.IndexBackend
+	switch cfg.Search.Backend {
+	case "", "sql":
+	case "index":
+		searchIndex = search.NewIndexBackend(cachedBookRepo, shelfRepo, cfg.Search.IndexPath)
+		if err := searchIndex.Open(context.Background()); err != nil {
+			return nil, err
+		}
+		// The book events are exchanged with the other instances
+		if err := searchIndex.Connect(redisClient); err != nil {
+			return nil, err
+		}
+		searchBackend = searchIndex
+		bookListeners = append(bookListeners, searchIndex)
+	default:
+		return nil, fmt.Errorf("unknown search backend %q", cfg.Search.Backend)
+	}
+
+	// Initialize the book metadata provider
+	var metadataProvider book.MetadataProvider
+	switch cfg.BookMetadata.Provider {
+	case "", "none":
+	case "openlibrary":
+		metadataProvider = bookmeta.NewOpenLibraryProvider(cfg.BookMetadata.OpenLibraryURL)
+	case "fixture":
+		if metadataProvider, err = bookmeta.LoadFixtureProvider(cfg.BookMetadata.FixturesPath); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown book metadata provider %q", cfg.BookMetadata.Provider)
+	}
+	if metadataProvider != nil {
+		metadataProvider = bookmeta.NewCachedProvider(
+			metadataProvider,
+			redisClient,
+			time.Duration(cfg.BookMetadata.CacheMinute)*time.Minute,
+			time.Duration(cfg.BookMetadata.NotFoundMinute)*time.Minute,
+		)
+	}
+
+	bookAccessPolicy := service.NewBookAccessPolicy(cachedBookRepo)
+	bookSvc := service.NewBookService(cachedBookRepo, reviewRepo, cachedUserRepo, bookRevisionRepo, shelfRepo, bookAccessPolicy, translationSvc, metadataProvider, searchBackend, bookListeners...)
+	glossarySvc := service.NewGlossaryService(glossaryRepo)
+	shelfSvc := service.NewShelfService(shelfRepo, bookAccessPolicy)
+	readingSvc := service.NewReadingService(readingRepo, bookAccessPolicy)
+	notificationSvc := service.NewNotificationService(notificationRepo)
+	lendingSvc := service.NewLendingService(lendingRepo, cachedBookRepo, bookAccessPolicy, notificationSvc, service.LendingPolicy{
+		LoanPeriod:       time.Duration(cfg.Lending.LoanDays) * 24 * time.Hour,
+		MaxRenewals:      cfg.Lending.MaxRenewals,
+		HoldPickupPeriod: time.Duration(cfg.Lending.HoldPickupDays) * 24 * time.Hour,
+	})
+	// Initialize the file storage of the import and export jobs, kept
+	// private unlike the covers
+	if cfg.Storage.Backend == "s3" && cfg.Transfer.S3Bucket == "" {
+		return nil, fmt.Errorf("BOOK_TRANSFER_S3_BUCKET is required with the s3 storage backend")
+	}
+	transferStorage, err := newFileStorage(&cfg.Storage, cfg.Transfer.Path, cfg.Transfer.S3Bucket, "")
+	if err != nil {
+		return nil, err
+	}
+	bookTransferSvc := service.NewBookTransferService(bookJobRepo, cachedBookRepo, bookSvc, service.BookTransferOptions{
+		Storage:  transferStorage,
+		MaxBytes: cfg.Transfer.ImportMaxBytes,
+		Workers:  cfg.Transfer.Workers,
+	})
+	translationMemorySvc := service.NewTranslationMemoryService(cachedTranslationRepo, i18n.GetLocalizer())
+
+	// Initialize the file storage of book covers
+	fileStorage, err := newFileStorage(&cfg.Storage, cfg.Storage.LocalPath, cfg.Storage.S3Bucket, cfg.Storage.PublicURL)
+	if err != nil {
+		return nil, err
+	}
+	bookCoverSvc := service.NewBookCoverService(cachedBookRepo, fileStorage, cfg.Storage.CoverMaxBytes)
+	bookTrashSvc := service.NewBookTrashService(
+		cachedBookRepo,
+		bookCoverSvc,
+		notificationSvc,
+		time.Duration(cfg.Trash.RetentionDays)*24*time.Hour,
+		bookListeners...,
+	)
+
+	// Apply the UI message translations imported from XLIFF files
+	if err := translationMemorySvc.LoadLocaleMessages(context.Background()); err != nil {
+		log.Printf("Failed to load locale messages: %v", err)
+	}
 
 	return &Container{
 		DB:              db,
@@ -71,12 +240,50 @@ func NewContainer(cfg *config.Config) (*Container, error) {
 		AuthSvc:         authSvc,
 		BookSvc:         bookSvc,
 		TranslationSvc:  translationSvc,
+		GlossarySvc:     glossarySvc,
+		TranslationMemorySvc: translationMemorySvc,
+		BookCoverSvc:    bookCoverSvc,
+		ShelfSvc:        shelfSvc,
+		ReadingSvc:      readingSvc,
+		LendingSvc:      lendingSvc,
+		NotificationSvc: notificationSvc,
+		BookTransferSvc: bookTransferSvc,
+		BookTrashSvc:    bookTrashSvc,
 		UserRepo:        cachedUserRepo,
 		BookRepo:        cachedBookRepo,
-		TranslationRepo: translationRepo,
+		TranslationRepo: cachedTranslationRepo,
+		GlossaryRepo:    glossaryRepo,
+		ShelfRepo:       shelfRepo,
+		SearchIndex:     searchIndex,
+		Caches: map[string]cached.StatsReporter{
+			"books":        cachedBookRepo.(cached.StatsReporter),
+			"users":        cachedUserRepo.(cached.StatsReporter),
+			"translations": cachedTranslationRepo.(cached.StatsReporter),
+		},
+		l1: l1,
 	}, nil
 }
 
+// newFileStorage creates a storage of the configured backend, writing under
+// localPath or to bucket and served at publicURL
+func newFileStorage(cfg *config.StorageConfig, localPath, bucket, publicURL string) (storage.Storage, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return storage.NewLocalStorage(localPath, publicURL), nil
+	case "s3":
+		return storage.NewS3Storage(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+			PublicURL: publicURL,
+		})
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+}
+
 // runMigrations runs database migrations for all domain models
 func runMigrations(db *database.Database) error {
 	// Get the underlying GORM DB instance
@@ -91,16 +298,29 @@ func runMigrations(db *database.Database) error {
 	}
 
 	// Run migrations for all domain models
-	// Note: You'll need to import your domain models here
-	// Example:
-	// if err := db.AutoMigrate(
-	//     &user.User{},
-	//     &book.Book{},
-	//     // Add other models here
-	// ); err != nil {
-	//     return err
-	// }
-
+	if err := db.Migrate(
+		&entities.Tag{},
+		&entities.Book{},
+		&entities.BookTranslation{},
+		&entities.BookRevision{},
+		&entities.BookGrant{},
+		&entities.BookJob{},
+		&entities.Translation{},
+		&entities.Glossary{},
+		&entities.GlossaryTerm{},
+		&entities.Shelf{},
+		&entities.ShelfBook{},
+		&entities.ReadingProgress{},
+		&entities.ReadingSession{},
+		&entities.Review{},
+		&entities.BookCopy{},
+		&entities.Loan{},
+		&entities.Hold{},
+		&entities.Notification{},
+		&entities.LocaleMessage{},
+	); err != nil {
+		return err
+	}
 
 	log.Println("Database migrations completed successfully")
 	return nil
@@ -108,7 +328,16 @@ func runMigrations(db *database.Database) error {
 
 // Close gracefully shuts down all connections and cleans up resources
 func (c *Container) Close() error {
-	// Close Redis connection
+	// Stop listening to the other instances and save the pending search index
+	// changes
+	if c.SearchIndex != nil {
+		if err := c.SearchIndex.Close(); err != nil {
+			log.Printf("Error saving search index: %v", err)
+		}
+	}
+
+	// Close Redis connection, after its subscription
+	c.l1.Close()
 	if c.RedisClient != nil {
 		if err := c.RedisClient.Close(); err != nil {
 			log.Printf("Error closing Redis connection: %v", err)
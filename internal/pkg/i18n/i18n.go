@@ -5,12 +5,114 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"sync"
 
 	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/pelletier/go-toml/v2"
+	"golang.org/x/text/feature/plural"
 	"golang.org/x/text/language"
 )
 
+// Plural forms of a message, as CLDR names them
+const (
+	FormZero  = "zero"
+	FormOne   = "one"
+	FormTwo   = "two"
+	FormFew   = "few"
+	FormMany  = "many"
+	FormOther = "other"
+)
+
+// PluralForms lists the plural forms in the CLDR order
+var PluralForms = []string{FormZero, FormOne, FormTwo, FormFew, FormMany, FormOther}
+
+// Message holds the texts of a message by plural form, messages without
+// plural forms only having FormOther
+type Message map[string]string
+
+// IsPlural tells whether the message has other forms than FormOther
+func (m Message) IsPlural() bool {
+	for form := range m {
+		if form != FormOther {
+			return true
+		}
+	}
+	return false
+}
+
+// Text returns the text of a plural form, the one of FormOther when the
+// message doesn't have it
+func (m Message) Text(form string) string {
+	if text, ok := m[form]; ok {
+		return text
+	}
+	return m[FormOther]
+}
+
+// newMessage returns the texts of a bundle message
+func newMessage(message *i18n.Message) Message {
+	m := make(Message)
+	for form, text := range map[string]string{
+		FormZero:  message.Zero,
+		FormOne:   message.One,
+		FormTwo:   message.Two,
+		FormFew:   message.Few,
+		FormMany:  message.Many,
+		FormOther: message.Other,
+	} {
+		if text != "" {
+			m[form] = text
+		}
+	}
+	return m
+}
+
+// bundleMessage returns the bundle message of the texts of a message
+func bundleMessage(id string, m Message) *i18n.Message {
+	return &i18n.Message{
+		ID:    id,
+		Zero:  m[FormZero],
+		One:   m[FormOne],
+		Two:   m[FormTwo],
+		Few:   m[FormFew],
+		Many:  m[FormMany],
+		Other: m[FormOther],
+	}
+}
+
+// LanguageForms returns the plural forms a language distinguishes, in the
+// CLDR order
+func LanguageForms(lang language.Tag) []string {
+	names := map[plural.Form]string{
+		plural.Zero:  FormZero,
+		plural.One:   FormOne,
+		plural.Two:   FormTwo,
+		plural.Few:   FormFew,
+		plural.Many:  FormMany,
+		plural.Other: FormOther,
+	}
+	// The rules aren't exposed, so the forms are found from the ones of
+	// whole and decimal numbers
+	used := map[string]bool{FormOther: true}
+	for i := 0; i <= 1000; i++ {
+		used[names[plural.Cardinal.MatchPlural(lang, i, 0, 0, 0, 0)]] = true
+	}
+	for i := 0; i <= 100; i++ {
+		for f := 1; f <= 9; f++ {
+			used[names[plural.Cardinal.MatchPlural(lang, i, 1, 1, f, f)]] = true
+		}
+	}
+
+	forms := make([]string, 0, len(used))
+	for _, form := range PluralForms {
+		if used[form] {
+			forms = append(forms, form)
+		}
+	}
+	return forms
+}
+
 //go:embed locales/*
 var localesFS embed.FS
 
@@ -23,7 +125,9 @@ var (
 type Localizer struct {
 	bundle    *i18n.Bundle
 	localizer *i18n.Localizer
-	mu        sync.RWMutex
+	// catalog keeps the raw message texts per language, which the bundle doesn't expose
+	catalog map[language.Tag]map[string]Message
+	mu      sync.RWMutex
 }
 
 // GetLocalizer returns the singleton instance of Localizer
@@ -32,25 +136,34 @@ func GetLocalizer() *Localizer {
 		defaultLang := language.English
 		bundle := i18n.NewBundle(defaultLang)
 		bundle.RegisterUnmarshalFunc("json", json.Unmarshal)
+		bundle.RegisterUnmarshalFunc("toml", toml.Unmarshal)
 
-		// Load all translation files
-		entries, err := localesFS.ReadDir("locales")
+		// Load all translation files, one directory per locale
+		catalog := make(map[language.Tag]map[string]Message)
+		err := fs.WalkDir(localesFS, "locales", func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			file, err := bundle.LoadMessageFileFS(localesFS, path)
+			if err != nil {
+				return fmt.Errorf("failed to load message file %s: %w", path, err)
+			}
+			if catalog[file.Tag] == nil {
+				catalog[file.Tag] = make(map[string]Message)
+			}
+			for _, message := range file.Messages {
+				catalog[file.Tag][message.ID] = newMessage(message)
+			}
+			return nil
+		})
 		if err != nil {
 			panic(fmt.Sprintf("failed to read locales directory: %v", err))
 		}
 
-		for _, entry := range entries {
-			if !entry.IsDir() {
-				_, err := bundle.LoadMessageFileFS(localesFS, "locales/"+entry.Name())
-				if err != nil {
-					panic(fmt.Sprintf("failed to load message file %s: %v", entry.Name(), err))
-				}
-			}
-		}
-
 		instance = &Localizer{
 			bundle:    bundle,
 			localizer: i18n.NewLocalizer(bundle, defaultLang.String()),
+			catalog:   catalog,
 		}
 	})
 	return instance
@@ -63,6 +176,55 @@ func (l *Localizer) SetLanguage(lang language.Tag) {
 	l.localizer = i18n.NewLocalizer(l.bundle, lang.String())
 }
 
+// LanguageTags returns the languages that have messages loaded in the bundle
+func (l *Localizer) LanguageTags() []language.Tag {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]language.Tag(nil), l.bundle.LanguageTags()...)
+}
+
+// DefaultLanguage returns the language messages fall back to
+func (l *Localizer) DefaultLanguage() language.Tag {
+	return language.English
+}
+
+// Messages returns a copy of the messages loaded for a language, keyed by message ID
+func (l *Localizer) Messages(lang language.Tag) map[string]Message {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	messages := make(map[string]Message, len(l.catalog[lang]))
+	for id, message := range l.catalog[lang] {
+		messages[id] = make(Message, len(message))
+		for form, text := range message {
+			messages[id][form] = text
+		}
+	}
+	return messages
+}
+
+// SetMessages adds or replaces messages for a language, keyed by message ID
+func (l *Localizer) SetMessages(lang language.Tag, messages map[string]Message) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	batch := make([]*i18n.Message, 0, len(messages))
+	for id, message := range messages {
+		batch = append(batch, bundleMessage(id, message))
+	}
+	if err := l.bundle.AddMessages(lang, batch...); err != nil {
+		return err
+	}
+
+	if l.catalog[lang] == nil {
+		l.catalog[lang] = make(map[string]Message)
+	}
+	for id, message := range messages {
+		l.catalog[lang][id] = message
+	}
+	return nil
+}
+
 // Translate translates a message with the given ID and template data
 func (l *Localizer) Translate(lang language.Tag, messageID string, templateData map[string]interface{}) (string, error) {
 	l.mu.RLock()
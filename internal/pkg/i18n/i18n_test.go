@@ -0,0 +1,39 @@
+package i18n
+
+import (
+	"slices"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestLanguageForms(t *testing.T) {
+	tests := []struct {
+		lang language.Tag
+		want []string
+	}{
+		{language.English, []string{FormOne, FormOther}},
+		{language.Vietnamese, []string{FormOther}},
+		{language.Russian, []string{FormOne, FormFew, FormMany, FormOther}},
+		{language.Arabic, []string{FormZero, FormOne, FormTwo, FormFew, FormMany, FormOther}},
+	}
+	for _, tt := range tests {
+		if got := LanguageForms(tt.lang); !slices.Equal(got, tt.want) {
+			t.Errorf("LanguageForms(%s) = %v, want %v", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestMessagesKeepThePluralForms(t *testing.T) {
+	l := GetLocalizer()
+	lang := language.MustParse("ru")
+	if err := l.SetMessages(lang, map[string]Message{
+		"test.books": {FormOne: "{{.Count}} книга", FormFew: "{{.Count}} книги", FormMany: "{{.Count}} книг", FormOther: "{{.Count}} книги"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := l.Messages(lang)["test.books"]; len(got) != 4 || !got.IsPlural() || got[FormFew] != "{{.Count}} книги" {
+		t.Errorf("Messages = %v, want the four forms", got)
+	}
+}
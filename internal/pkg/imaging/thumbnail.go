@@ -0,0 +1,111 @@
+// Package imaging decodes uploaded images and makes thumbnails of them.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+
+	// Register the decoders of the accepted upload formats
+	_ "image/gif"
+	_ "image/png"
+)
+
+// thumbnailQuality is the JPEG quality of the thumbnails
+const thumbnailQuality = 85
+
+// ErrTooLarge is returned for images with more pixels than allowed
+var ErrTooLarge = errors.New("image dimensions are too large")
+
+// Decode decodes a JPEG, PNG or GIF image, checking its dimensions before
+// decoding it so that a small file can't claim a huge image
+func Decode(r io.ReadSeeker, maxPixels int) (image.Image, string, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, "", ErrTooLarge
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+	return image.Decode(r)
+}
+
+// Thumbnail scales img down to fit in maxWidth x maxHeight, keeping its
+// aspect ratio, and encodes it as a JPEG. Smaller images are not enlarged.
+// Transparent areas become white.
+func Thumbnail(img image.Image, maxWidth, maxHeight int) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := fit(bounds.Dx(), bounds.Dy(), maxWidth, maxHeight)
+
+	// Flatten the image on white, as JPEG has no transparency
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scale(src, width, height), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fit returns the dimensions of a width x height image scaled down to fit
+// in maxWidth x maxHeight
+func fit(width, height, maxWidth, maxHeight int) (int, int) {
+	if width <= maxWidth && height <= maxHeight {
+		return width, height
+	}
+	if width*maxHeight > height*maxWidth {
+		return maxWidth, max(1, height*maxWidth/width)
+	}
+	return max(1, width*maxHeight/height), maxHeight
+}
+
+// scale resizes src to width x height with a box filter: each pixel of the
+// result is the average of the source pixels it covers, weighted by the
+// covered area. It gives smooth results when scaling down.
+func scale(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	if srcWidth == width && srcHeight == height {
+		copy(dst.Pix, src.Pix)
+		return dst
+	}
+
+	xRatio := float64(srcWidth) / float64(width)
+	yRatio := float64(srcHeight) / float64(height)
+	for y := 0; y < height; y++ {
+		y0, y1 := float64(y)*yRatio, float64(y+1)*yRatio
+		for x := 0; x < width; x++ {
+			x0, x1 := float64(x)*xRatio, float64(x+1)*xRatio
+
+			var r, g, b, a, total float64
+			for sy := int(y0); float64(sy) < y1 && sy < srcHeight; sy++ {
+				wy := min(y1, float64(sy+1)) - max(y0, float64(sy))
+				for sx := int(x0); float64(sx) < x1 && sx < srcWidth; sx++ {
+					w := wy * (min(x1, float64(sx+1)) - max(x0, float64(sx)))
+					i := src.PixOffset(sx, sy)
+					r += float64(src.Pix[i]) * w
+					g += float64(src.Pix[i+1]) * w
+					b += float64(src.Pix[i+2]) * w
+					a += float64(src.Pix[i+3]) * w
+					total += w
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r/total + 0.5)
+			dst.Pix[i+1] = uint8(g/total + 0.5)
+			dst.Pix[i+2] = uint8(b/total + 0.5)
+			dst.Pix[i+3] = uint8(a/total + 0.5)
+		}
+	}
+	return dst
+}
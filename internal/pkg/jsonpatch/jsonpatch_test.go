@@ -0,0 +1,140 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// assertJSON fails unless got and want are the same JSON value
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid result %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("invalid expectation %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"replaces members", `{"a":"b","c":"d"}`, `{"a":"z"}`, `{"a":"z","c":"d"}`},
+		{"null removes members", `{"a":"b","c":"d"}`, `{"a":null}`, `{"c":"d"}`},
+		{"null of a missing member", `{"a":"b"}`, `{"x":null}`, `{"a":"b"}`},
+		{"merges objects", `{"a":{"b":"c","d":"e"}}`, `{"a":{"b":null,"f":"g"}}`, `{"a":{"d":"e","f":"g"}}`},
+		{"replaces arrays", `{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+		{"object over a scalar", `{"a":"b"}`, `{"a":{"c":null,"d":1}}`, `{"a":{"d":1}}`},
+		{"non-object patch replaces", `{"a":"b"}`, `["c"]`, `["c"]`},
+		{"keeps numbers", `{"a":12345678901234567890}`, `{}`, `{"a":12345678901234567890}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestMergePatchInvalid(t *testing.T) {
+	for _, patch := range []string{``, `{`, `{"a":1} {}`} {
+		if _, err := MergePatch([]byte(`{}`), []byte(patch)); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("MergePatch(%q) = %v, want ErrInvalidPatch", patch, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"add member", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`},
+		{"add replaces member", `{"a":1}`, `[{"op":"add","path":"/a","value":2}]`, `{"a":2}`},
+		{"add appends with -", `{"a":[1,2]}`, `[{"op":"add","path":"/a/-","value":3}]`, `{"a":[1,2,3]}`},
+		{"add inserts at index", `{"a":[1,2]}`, `[{"op":"add","path":"/a/1","value":9}]`, `{"a":[1,9,2]}`},
+		{"add inserts at end index", `{"a":[1,2]}`, `[{"op":"add","path":"/a/2","value":9}]`, `{"a":[1,2,9]}`},
+		{"add into nested array", `{"a":[{"b":[]}]}`, `[{"op":"add","path":"/a/0/b/-","value":"c"}]`, `{"a":[{"b":["c"]}]}`},
+		{"remove member", `{"a":1,"b":2}`, `[{"op":"remove","path":"/a"}]`, `{"b":2}`},
+		{"remove array item", `{"a":[1,2,3]}`, `[{"op":"remove","path":"/a/1"}]`, `{"a":[1,3]}`},
+		{"replace", `{"a":{"b":1}}`, `[{"op":"replace","path":"/a/b","value":null}]`, `{"a":{"b":null}}`},
+		{"replace document", `{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+		{"move member", `{"a":{"b":1},"c":{}}`, `[{"op":"move","from":"/a/b","path":"/c/d"}]`, `{"a":{},"c":{"d":1}}`},
+		{"move into a sibling's child", `{"a":1,"b":{}}`, `[{"op":"move","from":"/a","path":"/b/a"}]`, `{"b":{"a":1}}`},
+		{"move array item", `{"a":[1,2,3]}`, `[{"op":"move","from":"/a/0","path":"/a/-"}]`, `{"a":[2,3,1]}`},
+		{"copy is deep", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`},
+		{"test passes", `{"a":[1,{"b":"c"}]}`, `[{"op":"test","path":"/a","value":[1,{"b":"c"}]}]`, `{"a":[1,{"b":"c"}]}`},
+		{"test numbers by value", `{"a":1.0}`, `[{"op":"test","path":"/a","value":1}]`, `{"a":1.0}`},
+		{"test exponent", `{"a":100}`, `[{"op":"test","path":"/a","value":1e2}]`, `{"a":100}`},
+		{"unescapes ~1", `{"a/b":1}`, `[{"op":"replace","path":"/a~1b","value":2}]`, `{"a/b":2}`},
+		{"unescapes ~0", `{"a~b":1}`, `[{"op":"remove","path":"/a~0b"}]`, `{}`},
+		{"unescapes ~01 as ~1", `{"~1":1}`, `[{"op":"remove","path":"/~01"}]`, `{}`},
+		{"empty member name", `{"":1}`, `[{"op":"replace","path":"/","value":2}]`, `{"":2}`},
+		{"operations in order", `{}`, `[{"op":"add","path":"/a","value":[]},{"op":"add","path":"/a/0","value":1},{"op":"test","path":"/a/0","value":1}]`, `{"a":[1]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  error
+	}{
+		{"not an array", `{}`, `{"op":"add"}`, ErrInvalidPatch},
+		{"unknown op", `{}`, `[{"op":"merge","path":"/a"}]`, ErrInvalidPatch},
+		{"missing value", `{}`, `[{"op":"add","path":"/a"}]`, ErrInvalidPatch},
+		{"relative path", `{}`, `[{"op":"add","path":"a","value":1}]`, ErrInvalidPatch},
+		{"missing parent", `{}`, `[{"op":"add","path":"/a/b","value":1}]`, ErrInvalidPatch},
+		{"remove missing", `{}`, `[{"op":"remove","path":"/a"}]`, ErrInvalidPatch},
+		{"replace missing", `{}`, `[{"op":"replace","path":"/a","value":1}]`, ErrInvalidPatch},
+		{"index out of range", `{"a":[1]}`, `[{"op":"add","path":"/a/2","value":1}]`, ErrInvalidPatch},
+		{"leading zero index", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/01"}]`, ErrInvalidPatch},
+		{"- outside add", `{"a":[1]}`, `[{"op":"remove","path":"/a/-"}]`, ErrInvalidPatch},
+		{"move into its child", `{"a":{"b":{}}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`, ErrInvalidPatch},
+		{"test different value", `{"a":"b"}`, `[{"op":"test","path":"/a","value":"c"}]`, ErrTestFailed},
+		{"test number and string", `{"a":1}`, `[{"op":"test","path":"/a","value":"1"}]`, ErrTestFailed},
+		{"test extra member", `{"a":{"b":1}}`, `[{"op":"test","path":"/a","value":{"b":1,"c":2}}]`, ErrTestFailed},
+		{"test missing", `{}`, `[{"op":"test","path":"/a","value":1}]`, ErrInvalidPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Apply([]byte(tt.doc), []byte(tt.patch)); !errors.Is(err, tt.want) {
+				t.Errorf("Apply = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyIsAtomic(t *testing.T) {
+	doc := []byte(`{"a":1}`)
+	patch := []byte(`[{"op":"replace","path":"/a","value":2},{"op":"test","path":"/a","value":3}]`)
+	if _, err := Apply(doc, patch); !errors.Is(err, ErrTestFailed) {
+		t.Fatalf("Apply = %v, want ErrTestFailed", err)
+	}
+	assertJSON(t, doc, `{"a":1}`)
+}
@@ -0,0 +1,156 @@
+// Package langdetect provides a local character n-gram language detector.
+package langdetect
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"clean-arch-go/internal/domain/translation"
+)
+
+const (
+	// maxNgram is the longest character n-gram used for scoring
+	maxNgram = 3
+	// evidenceCap bounds how many n-grams contribute to the confidence, so
+	// long texts don't always end up with a probability of exactly 1
+	evidenceCap = 40
+	// smoothing flattens the posterior; the samples are small and a raw naive
+	// Bayes model reports near-certain probabilities even for a single word
+	smoothing = 0.25
+)
+
+// Ensure NgramDetector implements translation.LanguageDetector
+var _ translation.LanguageDetector = (*NgramDetector)(nil)
+
+// profile holds the n-gram frequencies of a single language
+type profile struct {
+	counts map[string]int
+	total  int
+}
+
+// NgramDetector detects languages with a naive Bayes model over character n-grams
+type NgramDetector struct {
+	profiles map[string]*profile
+	vocab    int
+	mu       sync.RWMutex
+}
+
+// NewNgramDetector creates a detector trained on the built-in language samples
+func NewNgramDetector() *NgramDetector {
+	d := &NgramDetector{
+		profiles: make(map[string]*profile),
+	}
+	for lang, sample := range defaultSamples {
+		d.AddProfile(lang, sample)
+	}
+	return d
+}
+
+// AddProfile trains the detector with a sample text for the given language.
+// Calling it again for the same language extends the existing profile.
+func (d *NgramDetector) AddProfile(lang string, sample string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p, ok := d.profiles[lang]
+	if !ok {
+		p = &profile{counts: make(map[string]int)}
+		d.profiles[lang] = p
+	}
+	for _, gram := range ngrams(sample) {
+		p.counts[gram]++
+		p.total++
+	}
+
+	vocab := make(map[string]struct{})
+	for _, p := range d.profiles {
+		for gram := range p.counts {
+			vocab[gram] = struct{}{}
+		}
+	}
+	d.vocab = len(vocab)
+}
+
+// Languages returns the language codes the detector knows about
+func (d *NgramDetector) Languages() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	langs := make([]string, 0, len(d.profiles))
+	for lang := range d.profiles {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Detect returns the most probable language of the text
+func (d *NgramDetector) Detect(ctx context.Context, text string) (*translation.Detection, error) {
+	grams := ngrams(text)
+	if len(grams) == 0 {
+		return nil, translation.ErrLanguageUndetermined
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if len(d.profiles) == 0 {
+		return nil, translation.ErrLanguageUndetermined
+	}
+
+	// Average log-likelihood per n-gram, scaled by the amount of evidence
+	weight := math.Min(float64(len(grams)), evidenceCap) / float64(len(grams))
+	scores := make(map[string]float64, len(d.profiles))
+	for lang, p := range d.profiles {
+		var score float64
+		for _, gram := range grams {
+			score += math.Log(float64(p.counts[gram]+1) / float64(p.total+d.vocab))
+		}
+		scores[lang] = score * weight * smoothing
+	}
+
+	best, bestScore := "", math.Inf(-1)
+	for lang, score := range scores {
+		if score > bestScore || (score == bestScore && lang < best) {
+			best, bestScore = lang, score
+		}
+	}
+
+	// Posterior of the best language via a numerically stable softmax
+	var sum float64
+	for _, score := range scores {
+		sum += math.Exp(score - bestScore)
+	}
+
+	return &translation.Detection{
+		Lang:       best,
+		Confidence: 1 / sum,
+	}, nil
+}
+
+// ngrams splits the text into lower-cased words and returns all character
+// n-grams up to maxNgram, with word boundaries marked by spaces
+func ngrams(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	var grams []string
+	for _, word := range words {
+		runes := []rune(" " + word + " ")
+		for n := 1; n <= maxNgram; n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				gram := string(runes[i : i+n])
+				if gram == " " {
+					continue
+				}
+				grams = append(grams, gram)
+			}
+		}
+	}
+	return grams
+}
@@ -0,0 +1,111 @@
+package langdetect
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"clean-arch-go/internal/domain/translation"
+)
+
+func TestDetect(t *testing.T) {
+	d := NewNgramDetector()
+	tests := []struct {
+		text          string
+		want          string
+		minConfidence float64
+	}{
+		{"The children were reading books in the library after school.", "en", 0.9},
+		{"Tôi thích đọc sách vào buổi tối.", "vi", 0.9},
+		{"Le chat dort sur le canapé pendant que nous lisons.", "fr", 0.9},
+		{"El niño está leyendo un libro en la biblioteca.", "es", 0.9},
+		{"the", "en", 0.8},
+	}
+	for _, tt := range tests {
+		got, err := d.Detect(context.Background(), tt.text)
+		if err != nil {
+			t.Fatalf("Detect(%q) error = %v", tt.text, err)
+		}
+		if got.Lang != tt.want || got.Confidence < tt.minConfidence || got.Confidence > 1 {
+			t.Errorf("Detect(%q) = %s (%.3f), want %s with a confidence of at least %.2f", tt.text, got.Lang, got.Confidence, tt.want, tt.minConfidence)
+		}
+	}
+}
+
+func TestDetectShortWordsAreUncertain(t *testing.T) {
+	d := NewNgramDetector()
+	for _, text := range []string{"a", "no", "taxi", "hotel"} {
+		got, err := d.Detect(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Detect(%q) error = %v", text, err)
+		}
+		if got.Confidence >= 0.8 {
+			t.Errorf("Detect(%q) confidence = %.3f, want below 0.8 for a single short word", text, got.Confidence)
+		}
+	}
+}
+
+func TestDetectCapsTheEvidence(t *testing.T) {
+	d := NewNgramDetector()
+	text := strings.Repeat("The children were reading books in the library after school. ", 50)
+
+	got, err := d.Detect(context.Background(), text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Lang != "en" || got.Confidence >= 1 {
+		t.Errorf("Detect = %s (%v), want en below a confidence of 1", got.Lang, got.Confidence)
+	}
+}
+
+func TestDetectWithoutLetters(t *testing.T) {
+	d := NewNgramDetector()
+	for _, text := range []string{"", "   ", "1234 !!", "42.5%"} {
+		if _, err := d.Detect(context.Background(), text); err != translation.ErrLanguageUndetermined {
+			t.Errorf("Detect(%q) error = %v, want ErrLanguageUndetermined", text, err)
+		}
+	}
+}
+
+func TestDetectWithoutProfiles(t *testing.T) {
+	d := &NgramDetector{profiles: make(map[string]*profile)}
+	if _, err := d.Detect(context.Background(), "hello world"); err != translation.ErrLanguageUndetermined {
+		t.Errorf("Detect error = %v, want ErrLanguageUndetermined", err)
+	}
+}
+
+func TestDetectAmbiguous(t *testing.T) {
+	d := &NgramDetector{profiles: make(map[string]*profile)}
+	d.AddProfile("pt", "livro de historia")
+	d.AddProfile("gl", "livro de historia")
+
+	// Identical profiles score the same: the confidence is split evenly and
+	// the tie goes to the smallest code, so the result is stable
+	for i := 0; i < 5; i++ {
+		got, err := d.Detect(context.Background(), "historia")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Lang != "gl" || got.Confidence != 0.5 {
+			t.Fatalf("Detect = %s (%v), want gl with a confidence of 0.5", got.Lang, got.Confidence)
+		}
+	}
+}
+
+func TestAddProfileExtendsTheLanguage(t *testing.T) {
+	d := &NgramDetector{profiles: make(map[string]*profile)}
+	d.AddProfile("en", "the book")
+	d.AddProfile("vi", "cuốn sách")
+	d.AddProfile("en", "the library")
+
+	if got := d.Languages(); strings.Join(got, ",") != "en,vi" {
+		t.Errorf("Languages = %v, want [en vi]", got)
+	}
+	got, err := d.Detect(context.Background(), "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Lang != "en" {
+		t.Errorf("Detect = %s, want en from the extended profile", got.Lang)
+	}
+}
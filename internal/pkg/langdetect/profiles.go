@@ -0,0 +1,49 @@
+package langdetect
+
+// defaultSamples holds the training text used to build the built-in language
+// profiles. The samples are short on purpose: character trigrams converge
+// quickly and the detector only has to separate the languages we translate.
+var defaultSamples = map[string]string{
+	"en": `The quick brown fox jumps over the lazy dog. This is a book about the history
+of the world and the people who have lived in it. She said that they would come back
+tomorrow, but nobody knew when the train was going to arrive. We should read more books
+and spend less time looking at our phones. There are many reasons why the weather has
+been changing over the last few years. Would you like a cup of tea with your breakfast?
+The children were playing in the garden while their parents talked about work. It was
+the best of times, it was the worst of times, it was the age of wisdom. Please enter
+your email address and password to sign in to your account. Thank you for your order,
+we will send you a confirmation when it has shipped. Where is the nearest station?
+I think that this is one of the most important things which we have to understand.`,
+
+	"vi": `Tôi là một sinh viên đang học tại trường đại học ở Hà Nội. Hôm nay trời rất đẹp
+và chúng tôi đi dạo trong công viên. Cuốn sách này nói về lịch sử của Việt Nam và những
+con người đã sống trên mảnh đất này. Bạn có khỏe không? Cảm ơn bạn rất nhiều vì đã giúp
+đỡ tôi. Chúng ta nên đọc nhiều sách hơn và dành ít thời gian cho điện thoại. Xin vui lòng
+nhập địa chỉ email và mật khẩu để đăng nhập vào tài khoản của bạn. Những đứa trẻ đang chơi
+ở ngoài vườn trong khi bố mẹ chúng nói chuyện về công việc. Người dân ở thành phố này rất
+thân thiện và mến khách. Tôi nghĩ rằng đây là một trong những điều quan trọng nhất mà
+chúng ta cần phải hiểu. Ga tàu gần nhất ở đâu? Cảm ơn bạn đã đặt hàng, chúng tôi sẽ gửi
+xác nhận khi hàng được giao. Thời tiết đã thay đổi rất nhiều trong những năm gần đây.`,
+
+	"fr": `Je suis un étudiant qui habite à Paris depuis trois ans. Aujourd'hui il fait très
+beau et nous allons nous promener dans le parc. Ce livre parle de l'histoire du monde et
+des gens qui y ont vécu. Comment allez-vous? Merci beaucoup pour votre aide, c'est très
+gentil de votre part. Nous devrions lire plus de livres et passer moins de temps sur nos
+téléphones. Veuillez saisir votre adresse électronique et votre mot de passe pour vous
+connecter à votre compte. Les enfants jouaient dans le jardin pendant que leurs parents
+parlaient de leur travail. C'était le meilleur des temps, c'était le pire des temps.
+Je pense que c'est une des choses les plus importantes que nous devons comprendre. Où se
+trouve la gare la plus proche? Merci pour votre commande, nous vous enverrons une
+confirmation dès qu'elle sera expédiée. Le temps a beaucoup changé ces dernières années.`,
+
+	"es": `Soy un estudiante que vive en Madrid desde hace tres años. Hoy hace muy buen tiempo
+y vamos a pasear por el parque. Este libro habla de la historia del mundo y de las
+personas que han vivido en él. ¿Cómo estás? Muchas gracias por tu ayuda, eres muy amable.
+Deberíamos leer más libros y pasar menos tiempo con nuestros teléfonos. Por favor,
+introduzca su dirección de correo electrónico y su contraseña para iniciar sesión en su
+cuenta. Los niños estaban jugando en el jardín mientras sus padres hablaban del trabajo.
+Era el mejor de los tiempos, era el peor de los tiempos. Creo que esta es una de las cosas
+más importantes que tenemos que entender. ¿Dónde está la estación más cercana? Gracias por
+su pedido, le enviaremos una confirmación cuando haya sido enviado. El clima ha cambiado
+mucho en los últimos años y la gente de esta ciudad es muy simpática.`,
+}
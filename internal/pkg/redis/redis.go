@@ -10,6 +10,9 @@ import (
 	"github.com/go-redis/redis/v8"
 )
 
+// Nil is returned by Get when the key doesn't exist
+var Nil = redis.Nil
+
 type RedisClient struct {
 	client *redis.Client
 }
@@ -82,3 +85,36 @@ func (r *RedisClient) Incr(ctx context.Context, key string) (int64, error) {
 func (r *RedisClient) Expire(ctx context.Context, key string, expiration time.Duration) error {
 	return r.client.Expire(ctx, key, expiration).Err()
 }
+
+// Publish posts a message to the subscribers of channel
+func (r *RedisClient) Publish(ctx context.Context, channel string, message interface{}) error {
+	return r.client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe calls handle with the messages posted to channel until ctx is
+// done. The subscription is restored when the connection drops, missing the
+// messages posted in the meantime.
+func (r *RedisClient) Subscribe(ctx context.Context, channel string, handle func(message string)) error {
+	pubsub := r.client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return err
+	}
+
+	go func() {
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				handle(message.Payload)
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is the room left for the multipart headers and
+// boundaries around an uploaded file
+const multipartOverhead = 64 << 10
+
+// RegisterBookCoverRoutes registers the book cover routes
+// @Summary Register book cover routes
+// @Description Register the routes managing the cover image of a book
+// @Tags books
+// @Security BearerAuth
+// @Router /api/books/{id}/cover [put]
+// @Router /api/books/{id}/cover [delete]
+func (h *Handler) RegisterBookCoverRoutes(router *gin.RouterGroup) {
+	books := router.Group("/books")
+	{
+		books.PUT("/:id/cover", h.UploadBookCover)
+		books.DELETE("/:id/cover", h.DeleteBookCover)
+	}
+}
+
+// UploadBookCover replaces the cover image of a book
+// @Summary Upload a book cover
+// @Description Upload a JPEG, PNG or GIF cover image, as the "file" field of a multipart form or as the request body. Thumbnails are generated in several sizes.
+// @Tags books
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Accept image/jpeg
+// @Accept image/png
+// @Accept image/gif
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param file formData file false "Cover image"
+// @Success 200 {object} BookResponse "Book with its new cover URLs"
+// @Failure 400 {object} ErrorResponse "Invalid image, type or size"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not allowed to edit the book"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Failure 413 {object} ErrorResponse "Request too large"
+// @Router /api/books/{id}/cover [put]
+func (h *Handler) UploadBookCover(c *gin.Context) {
+	book, ok := h.editableBook(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.HTTPConfig.MaxUploadBytes+multipartOverhead)
+	body, err := uploadedFile(c)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if stderrors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: "Request too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Cover image is required"})
+		return
+	}
+	defer body.Close()
+
+	updated, err := h.bookCoverSvc.UploadCover(c.Request.Context(), book.ID, body)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	updated.ContentLang = updated.Lang
+	c.JSON(http.StatusOK, h.newBookResponse(updated))
+}
+
+// DeleteBookCover removes the cover image of a book
+// @Summary Delete a book cover
+// @Description Remove the cover image of a book and its thumbnails
+// @Tags books
+// @Security BearerAuth
+// @Param id path string true "Book ID"
+// @Success 204 "Cover deleted"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not allowed to edit the book"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/cover [delete]
+func (h *Handler) DeleteBookCover(c *gin.Context) {
+	book, ok := h.editableBook(c)
+	if !ok {
+		return
+	}
+
+	if err := h.bookCoverSvc.DeleteCover(c.Request.Context(), book.ID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
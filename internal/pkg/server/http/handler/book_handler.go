@@ -0,0 +1,382 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BookSearchResponse represents a page of search results
+// swagger:response bookSearchResponse
+type BookSearchResponse struct {
+	// Matching books
+	Data []BookResponse `json:"data"`
+
+	// Total number of matching books
+	// example: 42
+	Total int64 `json:"total"`
+
+	// Current page
+	// example: 1
+	Page int `json:"page"`
+
+	// Items per page
+	// example: 10
+	Limit int `json:"limit"`
+
+	// Number of matching books by author, year and tag
+	Facets map[string][]book.FacetCount `json:"facets,omitempty"`
+}
+
+// BookMetadataResponse represents the metadata found for an ISBN
+// swagger:response bookMetadataResponse
+type BookMetadataResponse struct {
+	// ISBN-13 of the book
+	// example: 9780134190440
+	ISBN string `json:"isbn"`
+
+	// ISBN-10 of the book, for ISBN-13s starting with 978
+	// example: 0134190440
+	ISBN10 string `json:"isbn10,omitempty"`
+
+	// Title of the book
+	// example: The Go Programming Language
+	Title string `json:"title"`
+
+	// Authors of the book
+	// example: ["Alan A. A. Donovan","Brian W. Kernighan"]
+	Authors []string `json:"authors"`
+
+	// Publisher of the book
+	// example: Addison-Wesley
+	Publisher string `json:"publisher,omitempty"`
+
+	// Published year of the book
+	// example: 2015
+	PublishedYear int `json:"published_year,omitempty"`
+
+	// Number of pages
+	// example: 380
+	PageCount int `json:"page_count,omitempty"`
+
+	// URL of the cover image
+	// example: https://covers.openlibrary.org/b/isbn/9780134190440-L.jpg
+	CoverURL string `json:"cover_url,omitempty"`
+}
+
+func (i BookInput) toEntity() *entities.Book {
+	tags := make([]entities.Tag, len(i.Tags))
+	for j, name := range i.Tags {
+		tags[j] = entities.Tag{Name: name}
+	}
+	var isbn *string
+	if i.ISBN != "" {
+		isbn = &i.ISBN
+	}
+	return &entities.Book{
+		Title:         i.Title,
+		Author:        i.Author,
+		Description:   i.Description,
+		ISBN:          isbn,
+		PublishedYear: i.PublishedYear,
+		Publisher:     i.Publisher,
+		PageCount:     i.PageCount,
+		Edition:       i.Edition,
+		Lang:          i.Lang,
+		Tags:          tags,
+	}
+}
+
+// newBookInput returns the details of a book as they're sent to change it
+func newBookInput(b *entities.Book) BookInput {
+	tags := make([]string, len(b.Tags))
+	for i, tag := range b.Tags {
+		tags[i] = tag.Name
+	}
+	var isbn string
+	if b.ISBN != nil {
+		isbn = *b.ISBN
+	}
+	return BookInput{
+		Title:         b.Title,
+		Author:        b.Author,
+		Description:   b.Description,
+		PublishedYear: b.PublishedYear,
+		ISBN:          isbn,
+		Publisher:     b.Publisher,
+		PageCount:     b.PageCount,
+		Edition:       b.Edition,
+		Lang:          b.Lang,
+		Tags:          tags,
+	}
+}
+
+func (h *Handler) newBookResponse(b *entities.Book) BookResponse {
+	tags := make([]string, len(b.Tags))
+	for i, tag := range b.Tags {
+		tags[i] = tag.Name
+	}
+	var isbn string
+	if b.ISBN != nil {
+		isbn = *b.ISBN
+	}
+	response := BookResponse{
+		ID:            b.ID,
+		Title:         b.Title,
+		Author:        b.Author,
+		Description:   b.Description,
+		ISBN:          isbn,
+		ISBN10:        book.ISBN10(isbn),
+		PublishedYear: b.PublishedYear,
+		Publisher:     b.Publisher,
+		PageCount:     b.PageCount,
+		Edition:       b.Edition,
+		Lang:          b.ContentLang,
+		OriginalLang:  b.Lang,
+		Tags:          tags,
+		Cover:         h.bookCoverSvc.CoverURLs(b),
+		RatingAverage: b.RatingAverage,
+		RatingCount:   b.RatingCount,
+		OwnerID:       b.UserID,
+		Visibility:    b.Visibility,
+		Version:       b.Version,
+		CreatedAt:     b.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     b.UpdatedAt.Format(time.RFC3339),
+	}
+	if b.DeletedAt.Valid {
+		response.DeletedAt = b.DeletedAt.Time.Format(time.RFC3339)
+	}
+	return response
+}
+
+// viewableBook loads the book of the id path parameter the authenticated
+// user can see, writing an error response otherwise
+func (h *Handler) viewableBook(c *gin.Context) (*entities.Book, bool) {
+	return h.authorizedBook(c, book.AccessView)
+}
+
+// editableBook loads the book of the id path parameter the authenticated
+// user can edit, writing an error response otherwise
+func (h *Handler) editableBook(c *gin.Context) (*entities.Book, bool) {
+	return h.authorizedBook(c, book.AccessEdit)
+}
+
+// ownedBook loads the book of the id path parameter, writing an error
+// response when it doesn't exist or isn't owned by the authenticated user
+func (h *Handler) ownedBook(c *gin.Context) (*entities.Book, bool) {
+	return h.authorizedBook(c, book.AccessOwner)
+}
+
+func (h *Handler) authorizedBook(c *gin.Context, need book.Access) (*entities.Book, bool) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	b, err := h.bookSvc.AuthorizeBook(c.Request.Context(), c.Param("id"), user.ID, need)
+	if err != nil {
+		handleError(c, err)
+		return nil, false
+	}
+	b.ContentLang = b.Lang
+	return b, true
+}
+
+// SearchBooks searches the books the authenticated user can see
+// @Summary Search books
+// @Description Full-text search over title, description and author with filters, multi-field sorting and facet counts
+// @Tags books
+// @Security BearerAuth
+// @Produce json
+// @Param q query string false "Full-text query"
+// @Param title query string false "Title contains"
+// @Param author query string false "Author contains"
+// @Param year query int false "Publication year"
+// @Param year_from query int false "Earliest publication year"
+// @Param year_to query int false "Latest publication year"
+// @Param isbn query string false "ISBN-10 or ISBN-13"
+// @Param publisher query string false "Publisher contains"
+// @Param original_lang query string false "Language the books are written in"
+// @Param tag query []string false "Tags the books must all have" collectionFormat(multi)
+// @Param shelf query int false "Shelf ID the books are on"
+// @Param owner query string false "Owner ID"
+// @Param access query string false "Only the owned, shared or public books" Enums(owned, shared, public)
+// @Param sort query string false "Comma-separated sort fields (relevance, title, author, year, created_at, updated_at), prefixed with - for descending order" example(-year,title)
+// @Param facets query bool false "Include facet counts" default(true)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param Accept-Language header string false "Preferred languages"
+// @Success 200 {object} BookSearchResponse "Matching books"
+// @Failure 400 {object} ErrorResponse "Invalid parameters"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Shelf not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/books/search [get]
+func (h *Handler) SearchBooks(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	filter, ok := bookFilter(c)
+	if !ok {
+		return
+	}
+	if filter.Page, filter.Limit, ok = pagination(c); !ok {
+		return
+	}
+	sort, err := book.ParseSort(c.Query("sort"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	filter.Query = c.Query("q")
+	filter.OwnerID = c.Query("owner")
+	filter.Sort = sort
+	filter.ViewerID = user.ID
+
+	withFacets := true
+	if value := c.Query("facets"); value != "" {
+		if withFacets, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid facets"})
+			return
+		}
+	}
+
+	result, err := h.bookSvc.SearchBooks(c.Request.Context(), filter, withFacets)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	if err := h.bookSvc.LocalizeBooks(c.Request.Context(), preferredLanguages(c), result.Books...); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	data := make([]BookResponse, len(result.Books))
+	for i, b := range result.Books {
+		data[i] = h.newBookResponse(b)
+		data[i].Highlights = result.Highlights[b.ID]
+	}
+
+	c.Header("Vary", "Accept-Language")
+	c.JSON(http.StatusOK, BookSearchResponse{
+		Data:   data,
+		Total:  result.Total,
+		Page:   filter.Page,
+		Limit:  filter.Limit,
+		Facets: result.Facets,
+	})
+}
+
+// bookFilter parses the query parameters filtering books by their details,
+// writing a 400 response when they're invalid
+func bookFilter(c *gin.Context) (*book.Filter, bool) {
+	filter := &book.Filter{
+		Title:     c.Query("title"),
+		Author:    c.Query("author"),
+		ISBN:      c.Query("isbn"),
+		Publisher: c.Query("publisher"),
+		Lang:      c.Query("original_lang"),
+		Access:    c.Query("access"),
+	}
+
+	years := []struct {
+		param string
+		dest  *int
+	}{
+		{"year", &filter.YearFrom},
+		{"year_from", &filter.YearFrom},
+		{"year_to", &filter.YearTo},
+	}
+	for _, year := range years {
+		value := c.Query(year.param)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid " + year.param})
+			return nil, false
+		}
+		*year.dest = parsed
+		if year.param == "year" {
+			filter.YearTo = parsed
+		}
+	}
+
+	if value := c.Query("shelf"); value != "" {
+		shelfID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || shelfID == 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid shelf"})
+			return nil, false
+		}
+		filter.ShelfID = uint(shelfID)
+	}
+
+	for _, value := range c.QueryArray("tag") {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+
+	return filter, true
+}
+
+// LookupBook looks up the metadata of an ISBN, optionally creating the book
+// @Summary Look up a book by ISBN
+// @Description Fetch the title, authors, publisher, year and cover of an ISBN-10 or ISBN-13 from the metadata provider. With create=true, the book is added to the authenticated user's books.
+// @Tags books
+// @Security BearerAuth
+// @Produce json
+// @Param isbn query string true "ISBN-10 or ISBN-13"
+// @Param create query bool false "Create the book from the metadata" default(false)
+// @Success 200 {object} BookMetadataResponse "Metadata of the ISBN"
+// @Success 201 {object} BookResponse "Book created from the metadata"
+// @Failure 400 {object} ErrorResponse "Invalid ISBN"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Unknown ISBN"
+// @Failure 409 {object} ErrorResponse "The user already has a book with this ISBN"
+// @Failure 502 {object} ErrorResponse "Metadata provider unavailable"
+// @Router /api/books/lookup [post]
+func (h *Handler) LookupBook(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	create := false
+	if value := c.Query("create"); value != "" {
+		var err error
+		if create, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid create"})
+			return
+		}
+	}
+
+	if create {
+		b, err := h.bookSvc.CreateBookFromISBN(c.Request.Context(), user.ID, c.Query("isbn"))
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, h.newBookResponse(b))
+		return
+	}
+
+	metadata, err := h.bookSvc.LookupISBN(c.Request.Context(), c.Query("isbn"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, BookMetadataResponse{
+		ISBN:          metadata.ISBN,
+		ISBN10:        book.ISBN10(metadata.ISBN),
+		Title:         metadata.Title,
+		Authors:       metadata.Authors,
+		Publisher:     metadata.Publisher,
+		PublishedYear: metadata.PublishedYear,
+		PageCount:     metadata.PageCount,
+		CoverURL:      metadata.CoverURL,
+	})
+}
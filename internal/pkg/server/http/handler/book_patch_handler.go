@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"strings"
+
+	"clean-arch-go/internal/pkg/jsonpatch"
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// PatchBook changes some details of a book
+// @Summary Patch a book
+// @Description Change some details of a book the authenticated user owns or was made an editor of, as long as it's still at the version of If-Match. The body is either a JSON Merge Patch, where null members clear the fields, or a JSON Patch, applied to the book fields of BookInput.
+// @Tags books
+// @Security BearerAuth
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param If-Match header string true "ETag of the book the patch was made against, * for any version"
+// @Success 200 {object} BookResponse "Patched book"
+// @Failure 400 {object} ErrorResponse "Invalid patch or patched book"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not allowed to edit the book"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Failure 409 {object} ErrorResponse "Test operation failed"
+// @Failure 412 {object} ErrorResponse "Book changed since If-Match"
+// @Failure 415 {object} ErrorResponse "Neither a merge patch nor a JSON patch"
+// @Failure 428 {object} ErrorResponse "If-Match missing"
+// @Router /api/books/{id} [patch]
+func (h *Handler) PatchBook(c *gin.Context) {
+	book, ok := h.editableBook(c)
+	if !ok {
+		return
+	}
+
+	var apply func(doc, patch []byte) ([]byte, error)
+	switch c.ContentType() {
+	case jsonpatch.MediaTypeMergePatch:
+		apply = jsonpatch.MergePatch
+	case jsonpatch.MediaTypeJSONPatch:
+		apply = jsonpatch.Apply
+	default:
+		c.Header("Accept-Patch", jsonpatch.MediaTypeMergePatch+", "+jsonpatch.MediaTypeJSONPatch)
+		c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{Error: "Patches must be " + jsonpatch.MediaTypeMergePatch + " or " + jsonpatch.MediaTypeJSONPatch})
+		return
+	}
+
+	version, ok := ifMatchVersion(c, book)
+	if !ok {
+		return
+	}
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+	doc, err := json.Marshal(newBookInput(book))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	patched, err := apply(doc, patch)
+	if err != nil {
+		status := http.StatusBadRequest
+		if stderrors.Is(err, jsonpatch.ErrTestFailed) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	input, ok := patchedBookInput(c, patched)
+	if !ok {
+		return
+	}
+	h.saveBook(c, book.ID, user.ID, version, input)
+}
+
+// patchedBookInput decodes the details of a patched book, writing a 400
+// response when the patch left them invalid
+func patchedBookInput(c *gin.Context, patched []byte) (BookInput, bool) {
+	var input BookInput
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&input); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if stderrors.As(err, &typeErr) {
+			if typeErr.Field == "" {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: "The patched book must be a JSON object"})
+			} else {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid " + typeErr.Field})
+			}
+			return input, false
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid patched book: " + strings.TrimPrefix(err.Error(), "json: ")})
+		return input, false
+	}
+	if err := binding.Validator.ValidateStruct(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "The title and author of a book can't be cleared"})
+		return input, false
+	}
+	return input, true
+}
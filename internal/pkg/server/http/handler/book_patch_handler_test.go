@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"clean-arch-go/internal/domain/book"
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/service"
+	"clean-arch-go/internal/domain/user"
+	"clean-arch-go/internal/pkg/server/http/httpconfig"
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeBookService serves a single book and records its updates
+type fakeBookService struct {
+	service.BookService
+	book    entities.Book
+	updates []*entities.Book
+}
+
+func (s *fakeBookService) AuthorizeBook(ctx context.Context, id, userID string, need book.Access) (*entities.Book, error) {
+	b := s.book
+	return &b, nil
+}
+
+func (s *fakeBookService) UpdateBook(ctx context.Context, id, userID string, changes *entities.Book) error {
+	s.updates = append(s.updates, changes)
+	s.book.Title = changes.Title
+	s.book.Author = changes.Author
+	s.book.Version++
+	return nil
+}
+
+func (s *fakeBookService) GetBookByID(ctx context.Context, id string) (*entities.Book, error) {
+	b := s.book
+	return &b, nil
+}
+
+type fakeBookCoverService struct {
+	service.BookCoverService
+}
+
+func (fakeBookCoverService) CoverURLs(b *entities.Book) map[string]string { return nil }
+
+func newPatchTestRouter(books *fakeBookService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(nil, books, nil, nil, nil, fakeBookCoverService{}, nil, nil, nil, nil, nil, nil, nil, &httpconfig.HTTPConfig{Secret: "secret"})
+	router := gin.New()
+	router.PATCH("/api/books/:id", func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), middleware.UserKey, &user.User{ID: "user-1"})
+		c.Request = c.Request.WithContext(ctx)
+	}, h.PatchBook)
+	return router
+}
+
+func TestPatchBook(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		ifMatch     string
+		body        string
+		wantStatus  int
+		wantTitle   string
+	}{
+		{
+			name:        "merge patch",
+			contentType: "application/merge-patch+json",
+			ifMatch:     `"2"`,
+			body:        `{"title":"Patched"}`,
+			wantStatus:  http.StatusOK,
+			wantTitle:   "Patched",
+		},
+		{
+			name:        "json patch",
+			contentType: "application/json-patch+json; charset=utf-8",
+			ifMatch:     `"2"`,
+			body:        `[{"op":"test","path":"/title","value":"Original"},{"op":"replace","path":"/title","value":"Patched"}]`,
+			wantStatus:  http.StatusOK,
+			wantTitle:   "Patched",
+		},
+		{
+			name:        "any version",
+			contentType: "application/merge-patch+json",
+			ifMatch:     "*",
+			body:        `{"title":"Patched"}`,
+			wantStatus:  http.StatusOK,
+			wantTitle:   "Patched",
+		},
+		{
+			name:        "plain json",
+			contentType: "application/json",
+			ifMatch:     `"2"`,
+			body:        `{"title":"Patched"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "no if-match",
+			contentType: "application/merge-patch+json",
+			body:        `{"title":"Patched"}`,
+			wantStatus:  http.StatusPreconditionRequired,
+		},
+		{
+			name:        "stale if-match",
+			contentType: "application/merge-patch+json",
+			ifMatch:     `"1"`,
+			body:        `{"title":"Patched"}`,
+			wantStatus:  http.StatusPreconditionFailed,
+		},
+		{
+			name:        "weak if-match",
+			contentType: "application/merge-patch+json",
+			ifMatch:     `W/"2"`,
+			body:        `{"title":"Patched"}`,
+			wantStatus:  http.StatusPreconditionFailed,
+		},
+		{
+			name:        "failed test",
+			contentType: "application/json-patch+json",
+			ifMatch:     `"2"`,
+			body:        `[{"op":"test","path":"/title","value":"Other"},{"op":"replace","path":"/title","value":"Patched"}]`,
+			wantStatus:  http.StatusConflict,
+		},
+		{
+			name:        "invalid json patch",
+			contentType: "application/json-patch+json",
+			ifMatch:     `"2"`,
+			body:        `[{"op":"remove","path":"/missing"}]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "cleared title",
+			contentType: "application/merge-patch+json",
+			ifMatch:     `"2"`,
+			body:        `{"title":null}`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "unknown field",
+			contentType: "application/json-patch+json",
+			ifMatch:     `"2"`,
+			body:        `[{"op":"add","path":"/owner","value":"someone"}]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			books := &fakeBookService{book: entities.Book{ID: "book-1", UserID: "user-1", Title: "Original", Author: "Author", Lang: "en", Version: 2}}
+			router := newPatchTestRouter(books)
+
+			req := httptest.NewRequest(http.MethodPatch, "/api/books/book-1", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if len(books.updates) != 0 {
+					t.Errorf("the book was updated on a %d", rec.Code)
+				}
+				if tt.wantStatus == http.StatusUnsupportedMediaType && rec.Header().Get("Accept-Patch") == "" {
+					t.Error("Accept-Patch missing from the 415")
+				}
+				return
+			}
+
+			if len(books.updates) != 1 {
+				t.Fatalf("%d updates, want 1", len(books.updates))
+			}
+			update := books.updates[0]
+			wantVersion := 2
+			if tt.ifMatch == "*" {
+				wantVersion = 0
+			}
+			if update.Version != wantVersion || update.Title != tt.wantTitle || update.Author != "Author" {
+				t.Errorf("update = version %d, %q by %q, want version %d, %q by %q", update.Version, update.Title, update.Author, wantVersion, tt.wantTitle, "Author")
+			}
+			var response BookResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.Title != tt.wantTitle || rec.Header().Get("ETag") != `"3"` {
+				t.Errorf("response = %q with ETag %s, want %q with \"3\"", response.Title, rec.Header().Get("ETag"), tt.wantTitle)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"net/http"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BookRevisionsListResponse represents a page of the revisions of a book
+// swagger:response bookRevisionsListResponse
+type BookRevisionsListResponse struct {
+	// Revisions, latest first
+	Data []*entities.BookRevision `json:"data"`
+
+	// Total number of revisions
+	// example: 5
+	Total int64 `json:"total"`
+
+	// Current page
+	// example: 1
+	Page int `json:"page"`
+
+	// Items per page
+	// example: 10
+	Limit int `json:"limit"`
+}
+
+// RegisterBookRevisionRoutes registers the book revision routes
+// @Summary Register book revision routes
+// @Description Register the routes reading the history of the changes to a book and reverting them
+// @Tags books
+// @Security BearerAuth
+// @Router /api/books/{id}/revisions [get]
+// @Router /api/books/{id}/revisions/{number} [get]
+// @Router /api/books/{id}/revisions/{number}/revert [post]
+func (h *Handler) RegisterBookRevisionRoutes(router *gin.RouterGroup) {
+	books := router.Group("/books")
+	{
+		books.GET("/:id/revisions", h.ListBookRevisions)
+		books.GET("/:id/revisions/:number", h.GetBookRevision)
+		books.POST("/:id/revisions/:number/revert", h.RevertBook)
+	}
+}
+
+// ListBookRevisions returns the revisions of a book
+// @Summary List the revisions of a book
+// @Description Get a page of the changes to a book, latest first, with who made them, the changed fields and the book as it was afterwards
+// @Tags books
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} BookRevisionsListResponse "Revisions"
+// @Failure 400 {object} ErrorResponse "Invalid page"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not allowed to edit the book"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/revisions [get]
+func (h *Handler) ListBookRevisions(c *gin.Context) {
+	book, ok := h.editableBook(c)
+	if !ok {
+		return
+	}
+
+	page, limit, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	revisions, total, err := h.bookSvc.ListRevisions(c.Request.Context(), book.ID, page, limit)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, BookRevisionsListResponse{
+		Data:  revisions,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	})
+}
+
+// GetBookRevision returns a revision of a book
+// @Summary Get a revision of a book
+// @Description Get a change to a book, with the book as it was afterwards in snapshot
+// @Tags books
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param number path int true "Revision number"
+// @Success 200 {object} entities.BookRevision "Revision"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not allowed to edit the book"
+// @Failure 404 {object} ErrorResponse "Book or revision not found"
+// @Router /api/books/{id}/revisions/{number} [get]
+func (h *Handler) GetBookRevision(c *gin.Context) {
+	book, ok := h.editableBook(c)
+	if !ok {
+		return
+	}
+	number, ok := parseUintParam(c, "number")
+	if !ok {
+		return
+	}
+
+	revision, err := h.bookSvc.GetRevision(c.Request.Context(), book.ID, int(number))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// RevertBook brings a book back to a revision
+// @Summary Revert a book to a revision
+// @Description Bring the details and tags of a book back to the ones of a revision, recorded as a new revision
+// @Tags books
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param number path int true "Revision number"
+// @Success 200 {object} BookResponse "Reverted book"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not allowed to edit the book"
+// @Failure 404 {object} ErrorResponse "Book or revision not found"
+// @Failure 409 {object} ErrorResponse "ISBN of the revision taken by another book"
+// @Router /api/books/{id}/revisions/{number}/revert [post]
+func (h *Handler) RevertBook(c *gin.Context) {
+	book, ok := h.editableBook(c)
+	if !ok {
+		return
+	}
+	number, ok := parseUintParam(c, "number")
+	if !ok {
+		return
+	}
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	reverted, err := h.bookSvc.RevertBook(c.Request.Context(), book.ID, user.ID, int(number))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	reverted.ContentLang = reverted.Lang
+	c.JSON(http.StatusOK, h.newBookResponse(reverted))
+}
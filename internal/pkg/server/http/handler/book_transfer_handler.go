@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"bytes"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/pkg/bookio"
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BookJobsListResponse represents a page of import and export jobs
+// swagger:response bookJobsListResponse
+type BookJobsListResponse struct {
+	// Jobs, newest first
+	Data []*entities.BookJob `json:"data"`
+
+	// Total number of jobs
+	// example: 3
+	Total int64 `json:"total"`
+
+	// Current page
+	// example: 1
+	Page int `json:"page"`
+
+	// Items per page
+	// example: 10
+	Limit int `json:"limit"`
+}
+
+// RegisterBookTransferRoutes registers the book import and export routes
+// @Summary Register book import and export routes
+// @Description Register the routes importing books from files and exporting them, as background jobs
+// @Tags books
+// @Security BearerAuth
+// @Router /api/books/import [post]
+// @Router /api/books/export [post]
+// @Router /api/books/jobs [get]
+// @Router /api/books/jobs/{id} [get]
+// @Router /api/books/jobs/{id} [delete]
+// @Router /api/books/jobs/{id}/download [get]
+func (h *Handler) RegisterBookTransferRoutes(router *gin.RouterGroup) {
+	books := router.Group("/books")
+	{
+		books.POST("/import", h.ImportBooks)
+		books.POST("/export", h.ExportBooks)
+		books.GET("/jobs", h.ListBookJobs)
+		books.GET("/jobs/:id", h.GetBookJob)
+		books.DELETE("/jobs/:id", h.DeleteBookJob)
+		books.GET("/jobs/:id/download", h.DownloadBookExport)
+	}
+}
+
+// ImportBooks queues the import of a file of books
+// @Summary Import books
+// @Description Upload a CSV or JSON file of books, as the "file" field of a multipart form or as the request body, and queue its import. CSV files need a header row; the columns of the Goodreads export are recognized. Rows whose ISBN is already in the library are skipped, invalid rows are reported on the job.
+// @Tags books
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Accept text/csv
+// @Accept json
+// @Produce json
+// @Param format query string false "csv or json, by default from the content type of the request body"
+// @Param dry_run query bool false "Only validate the rows, creating no book"
+// @Param file formData file false "File of books"
+// @Success 202 {object} entities.BookJob "Queued import job"
+// @Failure 400 {object} ErrorResponse "Invalid format or file"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 413 {object} ErrorResponse "Request too large"
+// @Router /api/books/import [post]
+func (h *Handler) ImportBooks(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	dryRun, ok := optionalBoolQuery(c, "dry_run")
+	if !ok {
+		return
+	}
+	format := c.Query("format")
+	if format == "" {
+		for f, contentType := range bookio.ContentTypes {
+			if c.ContentType() == contentType {
+				format = f
+			}
+		}
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.HTTPConfig.MaxImportBytes+multipartOverhead)
+	body, err := uploadedFile(c)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if stderrors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Error: "Request too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "File is required"})
+		return
+	}
+	defer body.Close()
+
+	job, err := h.bookTransferSvc.StartImport(c.Request.Context(), user.ID, format, dryRun != nil && *dryRun, body)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ExportBooks queues the export of the books of the user
+// @Summary Export books
+// @Description Queue the export of all the books of the authenticated user to a CSV or JSON file, downloadable once the job succeeded
+// @Tags books
+// @Security BearerAuth
+// @Produce json
+// @Param format query string false "csv or json" default(csv)
+// @Success 202 {object} entities.BookJob "Queued export job"
+// @Failure 400 {object} ErrorResponse "Invalid format"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Router /api/books/export [post]
+func (h *Handler) ExportBooks(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	job, err := h.bookTransferSvc.StartExport(c.Request.Context(), user.ID, c.DefaultQuery("format", bookio.FormatCSV))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ListBookJobs returns the import and export jobs of the user
+// @Summary List import and export jobs
+// @Description Get a page of the book import and export jobs of the authenticated user, newest first
+// @Tags books
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} BookJobsListResponse "Jobs"
+// @Failure 400 {object} ErrorResponse "Invalid page"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Router /api/books/jobs [get]
+func (h *Handler) ListBookJobs(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	page, limit, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	jobs, total, err := h.bookTransferSvc.ListJobs(c.Request.Context(), user.ID, page, limit)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, BookJobsListResponse{
+		Data:  jobs,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	})
+}
+
+// GetBookJob returns an import or export job of the user
+// @Summary Get an import or export job
+// @Description Get the status, counters and row errors of a job of the authenticated user
+// @Tags books
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {object} entities.BookJob "Job"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Job not found"
+// @Router /api/books/jobs/{id} [get]
+func (h *Handler) GetBookJob(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	job, err := h.bookTransferSvc.GetJob(c.Request.Context(), id, user.ID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// DeleteBookJob deletes an import or export job of the user
+// @Summary Delete an import or export job
+// @Description Delete a job of the authenticated user that isn't running, with its file
+// @Tags books
+// @Security BearerAuth
+// @Param id path int true "Job ID"
+// @Success 204 "Job deleted"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Job not found"
+// @Failure 409 {object} ErrorResponse "Job running"
+// @Router /api/books/jobs/{id} [delete]
+func (h *Handler) DeleteBookJob(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.bookTransferSvc.DeleteJob(c.Request.Context(), id, user.ID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// DownloadBookExport sends the file of a succeeded export
+// @Summary Download an export
+// @Description Download the file written by a succeeded export job of the authenticated user
+// @Tags books
+// @Security BearerAuth
+// @Produce text/csv
+// @Produce json
+// @Param id path int true "Job ID"
+// @Success 200 {file} file "Exported books"
+// @Failure 400 {object} ErrorResponse "Not an export"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Job not found"
+// @Failure 409 {object} ErrorResponse "Export not done"
+// @Router /api/books/jobs/{id}/download [get]
+func (h *Handler) DownloadBookExport(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	job, file, err := h.bookTransferSvc.OpenExport(c.Request.Context(), id, user.ID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	defer file.Close()
+	// Range requests seek through the export, which the stored objects
+	// can't do
+	data, err := io.ReadAll(file)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	name := fmt.Sprintf("books-%d.%s", job.ID, job.Format)
+	modTime := time.Time{}
+	if job.FinishedAt != nil {
+		modTime = *job.FinishedAt
+	}
+	c.Header("Content-Type", bookio.ContentTypes[job.Format])
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	http.ServeContent(c.Writer, c.Request, name, modTime, bytes.NewReader(data))
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"net/http"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BookTranslationInput represents a human-edited book translation
+// swagger:model BookTranslationInput
+type BookTranslationInput struct {
+	// Translated title
+	// required: true
+	// example: Ngôn ngữ lập trình Go
+	Title string `json:"title" binding:"required"`
+
+	// Translated description
+	// example: Tài liệu chính thức cho lập trình viên muốn học Go.
+	Description string `json:"description"`
+}
+
+// MachineTranslateInput represents a machine translation request for a book
+// swagger:model MachineTranslateInput
+type MachineTranslateInput struct {
+	// Language to translate the book into
+	// required: true
+	// example: vi
+	TargetLang string `json:"target_lang" binding:"required"`
+
+	// Replace an existing human translation
+	// example: false
+	Overwrite bool `json:"overwrite"`
+}
+
+// RegisterBookTranslationRoutes registers the book translation routes
+// @Summary Register book translation routes
+// @Description Register the routes managing the translations of a book
+// @Tags books
+// @Security BearerAuth
+// @Router /api/books/{id}/translations [get]
+// @Router /api/books/{id}/translations/{lang} [put]
+// @Router /api/books/{id}/translations/{lang} [delete]
+// @Router /api/books/{id}/translate [post]
+func (h *Handler) RegisterBookTranslationRoutes(router *gin.RouterGroup) {
+	books := router.Group("/books")
+	{
+		books.GET("/:id/translations", h.ListBookTranslations)
+		books.PUT("/:id/translations/:lang", h.SaveBookTranslation)
+		books.DELETE("/:id/translations/:lang", h.DeleteBookTranslation)
+		books.POST("/:id/translate", h.MachineTranslateBook)
+	}
+}
+
+// ListBookTranslations returns the translations of a book
+// @Summary List book translations
+// @Description Get the stored translations of a book owned by the authenticated user
+// @Tags books
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {array} entities.BookTranslation "Successfully retrieved translations"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Book not shared with the user"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/translations [get]
+func (h *Handler) ListBookTranslations(c *gin.Context) {
+	book, ok := h.viewableBook(c)
+	if !ok {
+		return
+	}
+
+	translations, err := h.bookSvc.ListBookTranslations(c.Request.Context(), book.ID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, translations)
+}
+
+// SaveBookTranslation creates or replaces a human-edited translation
+// @Summary Save a book translation
+// @Description Create or replace the translation of a book into a language
+// @Tags books
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param lang path string true "Language code"
+// @Param translation body BookTranslationInput true "Translation data"
+// @Success 200 {object} entities.BookTranslation "Successfully saved translation"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not allowed to edit the book"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/translations/{lang} [put]
+func (h *Handler) SaveBookTranslation(c *gin.Context) {
+	book, ok := h.editableBook(c)
+	if !ok {
+		return
+	}
+
+	var input BookTranslationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	translation := &entities.BookTranslation{
+		BookID:      book.ID,
+		Lang:        c.Param("lang"),
+		Title:       input.Title,
+		Description: input.Description,
+		Source:      entities.BookTranslationHuman,
+	}
+	if err := h.bookSvc.SaveBookTranslation(c.Request.Context(), translation); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, translation)
+}
+
+// DeleteBookTranslation deletes the translation of a book
+// @Summary Delete a book translation
+// @Description Delete the translation of a book into a language
+// @Tags books
+// @Security BearerAuth
+// @Param id path string true "Book ID"
+// @Param lang path string true "Language code"
+// @Success 204 "Successfully deleted translation"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not allowed to edit the book"
+// @Failure 404 {object} ErrorResponse "Book or translation not found"
+// @Router /api/books/{id}/translations/{lang} [delete]
+func (h *Handler) DeleteBookTranslation(c *gin.Context) {
+	book, ok := h.editableBook(c)
+	if !ok {
+		return
+	}
+
+	if err := h.bookSvc.DeleteBookTranslation(c.Request.Context(), book.ID, c.Param("lang")); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MachineTranslateBook machine-translates a book into a language
+// @Summary Machine-translate a book
+// @Description Translate the title and description of a book with the translation service, applying the owner's glossaries. Human translations are kept unless overwrite is set.
+// @Tags books
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param input body MachineTranslateInput true "Translation request"
+// @Success 200 {object} entities.BookTranslation "Successfully translated book"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not allowed to edit the book"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Failure 409 {object} ErrorResponse "Book already has a human translation"
+// @Failure 422 {object} ErrorResponse "Book language could not be detected"
+// @Router /api/books/{id}/translate [post]
+func (h *Handler) MachineTranslateBook(c *gin.Context) {
+	book, ok := h.editableBook(c)
+	if !ok {
+		return
+	}
+
+	var input MachineTranslateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+	translation, err := h.bookSvc.MachineTranslateBook(c.Request.Context(), book.ID, user.ID, input.TargetLang, input.Overwrite)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, translation)
+}
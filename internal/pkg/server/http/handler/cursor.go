@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"clean-arch-go/internal/domain/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	cursorNext = "next"
+	cursorPrev = "prev"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// cursorPayload is the content of a cursor token. It's bound to the list it
+// was issued for, so it can't be replayed against another endpoint.
+type cursorPayload struct {
+	List      string `json:"l"`
+	Direction string `json:"d"`
+	CreatedAt int64  `json:"t"`
+	ID        string `json:"i"`
+}
+
+// cursorCodec turns list positions into opaque tokens signed with HMAC-SHA256,
+// so that clients can't forge positions
+type cursorCodec struct {
+	secret []byte
+}
+
+func newCursorCodec(secret string) *cursorCodec {
+	return &cursorCodec{secret: []byte(secret)}
+}
+
+func (cc *cursorCodec) encode(list, direction string, cursor *repository.Cursor) string {
+	payload, _ := json.Marshal(cursorPayload{
+		List:      list,
+		Direction: direction,
+		CreatedAt: cursor.CreatedAt.UnixNano(),
+		ID:        cursor.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(cc.sign(payload))
+}
+
+func (cc *cursorCodec) decode(list, token string) (string, *repository.Cursor, error) {
+	encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", nil, errInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", nil, errInvalidCursor
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !hmac.Equal(sig, cc.sign(payload)) {
+		return "", nil, errInvalidCursor
+	}
+
+	var p cursorPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", nil, errInvalidCursor
+	}
+	if p.List != list || (p.Direction != cursorNext && p.Direction != cursorPrev) {
+		return "", nil, errInvalidCursor
+	}
+	return p.Direction, &repository.Cursor{CreatedAt: time.Unix(0, p.CreatedAt), ID: p.ID}, nil
+}
+
+func (cc *cursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, cc.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// cursorQuery parses the cursor and limit query parameters of a list,
+// writing a 400 response when they're invalid
+func (h *Handler) cursorQuery(c *gin.Context) (repository.CursorQuery, bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit"})
+		return repository.CursorQuery{}, false
+	}
+
+	query := repository.CursorQuery{Limit: limit}
+	token := c.Query("cursor")
+	if token == "" {
+		return query, true
+	}
+	direction, cursor, err := h.cursors.decode(c.FullPath(), token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid cursor"})
+		return repository.CursorQuery{}, false
+	}
+	if direction == cursorNext {
+		query.After = cursor
+	} else {
+		query.Before = cursor
+	}
+	return query, true
+}
+
+// pageCursors encodes the cursors of the pages around the current one and
+// advertises them in a Link header
+func (h *Handler) pageCursors(c *gin.Context, next, prev *repository.Cursor) (nextToken, prevToken string) {
+	var links []string
+	link := func(token, rel string) {
+		u := *c.Request.URL
+		query := u.Query()
+		query.Del("page")
+		query.Set("cursor", token)
+		u.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf("<%s>; rel=%q", u.RequestURI(), rel))
+	}
+
+	if next != nil {
+		nextToken = h.cursors.encode(c.FullPath(), cursorNext, next)
+		link(nextToken, "next")
+	}
+	if prev != nil {
+		prevToken = h.cursors.encode(c.FullPath(), cursorPrev, prev)
+		link(prevToken, "prev")
+	}
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+	return nextToken, prevToken
+}
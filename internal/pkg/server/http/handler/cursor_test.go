@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/pkg/server/http/httpconfig"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	codec := newCursorCodec("secret")
+	cursor := &repository.Cursor{CreatedAt: time.Unix(1700000000, 123456789), ID: "book-1"}
+
+	for _, direction := range []string{cursorNext, cursorPrev} {
+		token := codec.encode("/api/books", direction, cursor)
+		gotDirection, got, err := codec.decode("/api/books", token)
+		if err != nil {
+			t.Fatalf("decode(%q): %v", token, err)
+		}
+		if gotDirection != direction || !got.CreatedAt.Equal(cursor.CreatedAt) || got.ID != cursor.ID {
+			t.Errorf("decode = %s %+v, want %s %+v", gotDirection, got, direction, cursor)
+		}
+	}
+}
+
+func TestCursorRejectsTampering(t *testing.T) {
+	codec := newCursorCodec("secret")
+	cursor := &repository.Cursor{CreatedAt: time.Unix(1700000000, 0), ID: "book-1"}
+	token := codec.encode("/api/books", cursorNext, cursor)
+	encodedPayload, encodedSig, _ := strings.Cut(token, ".")
+
+	// signed re-encodes a payload with the signature of the codec
+	signed := func(p cursorPayload) string {
+		payload, _ := json.Marshal(p)
+		return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(codec.sign(payload))
+	}
+	forged, _ := json.Marshal(cursorPayload{List: "/api/books", Direction: cursorNext, CreatedAt: 1, ID: "book-2"})
+	flipped := []byte(encodedSig)
+	flipped[0] ^= 1
+
+	tests := []struct {
+		name  string
+		list  string
+		token string
+	}{
+		{"empty", "/api/books", ""},
+		{"no signature", "/api/books", encodedPayload},
+		{"payload not base64", "/api/books", "!!!." + encodedSig},
+		{"signature not base64", "/api/books", encodedPayload + ".!!!"},
+		{"forged payload", "/api/books", base64.RawURLEncoding.EncodeToString(forged) + "." + encodedSig},
+		{"altered signature", "/api/books", encodedPayload + "." + string(flipped)},
+		{"other secret", "/api/books", newCursorCodec("other").encode("/api/books", cursorNext, cursor)},
+		{"foreign list", "/api/shelves/:id/books", token},
+		{"unknown direction", "/api/books", signed(cursorPayload{List: "/api/books", Direction: "sideways", ID: "book-1"})},
+		{"not json", "/api/books", base64.RawURLEncoding.EncodeToString([]byte("x")) + "." + base64.RawURLEncoding.EncodeToString(codec.sign([]byte("x")))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := codec.decode(tt.list, tt.token); err != errInvalidCursor {
+				t.Errorf("decode = %v, want errInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestCursorQueryIsBoundToTheRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &Handler{HTTPConfig: &httpconfig.HTTPConfig{Secret: "secret"}, cursors: newCursorCodec("secret")}
+	next := &repository.Cursor{CreatedAt: time.Unix(1700000000, 0), ID: "book-1"}
+
+	router := gin.New()
+	list := func(c *gin.Context) {
+		query, ok := h.cursorQuery(c)
+		if !ok {
+			return
+		}
+		nextToken, _ := h.pageCursors(c, next, nil)
+		c.JSON(http.StatusOK, gin.H{"after": query.After, "limit": query.Limit, "next": nextToken})
+	}
+	router.GET("/api/books", list)
+	router.GET("/api/shelves/:id/books", list)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	first := get("/api/books?limit=5&page=2")
+	if first.Code != http.StatusOK {
+		t.Fatalf("first page: %d %s", first.Code, first.Body)
+	}
+	var body struct {
+		Next  string
+		Limit int
+	}
+	json.Unmarshal(first.Body.Bytes(), &body)
+	if body.Limit != 5 || body.Next == "" {
+		t.Fatalf("first page = %s", first.Body)
+	}
+	link := first.Header().Get("Link")
+	wantLink := "</api/books?cursor=" + url.QueryEscape(body.Next) + "&limit=5>; rel=\"next\""
+	if link != wantLink {
+		t.Errorf("Link = %s, want %s", link, wantLink)
+	}
+
+	if rec := get("/api/books?cursor=" + url.QueryEscape(body.Next)); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"book-1"`) {
+		t.Errorf("next page: %d %s", rec.Code, rec.Body)
+	}
+	// The cursor of the book list isn't accepted by another list, whatever
+	// its parameters
+	if rec := get("/api/shelves/1/books?cursor=" + url.QueryEscape(body.Next)); rec.Code != http.StatusBadRequest {
+		t.Errorf("foreign cursor: %d %s, want 400", rec.Code, rec.Body)
+	}
+
+	for _, limit := range []string{"0", "-1", "x", "101"} {
+		if rec := get("/api/books?limit=" + limit); rec.Code != http.StatusBadRequest {
+			t.Errorf("limit %s: %d, want 400", limit, rec.Code)
+		}
+	}
+}
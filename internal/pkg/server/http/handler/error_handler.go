@@ -0,0 +1,43 @@
+package handler
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"clean-arch-go/internal/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorStatus maps application error codes to HTTP status codes
+var errorStatus = map[string]int{
+	"BAD_REQUEST":           http.StatusBadRequest,
+	"VALIDATION_ERROR":      http.StatusBadRequest,
+	"LANGUAGE_UNDETERMINED": http.StatusUnprocessableEntity,
+	"UNAUTHORIZED":          http.StatusForbidden,
+	"NOT_FOUND":             http.StatusNotFound,
+	"USER_NOT_FOUND":        http.StatusNotFound,
+	"TRANSLATION_NOT_FOUND": http.StatusNotFound,
+	"CONFLICT":              http.StatusConflict,
+	"PRECONDITION_FAILED":   http.StatusPreconditionFailed,
+	"PRECONDITION_REQUIRED": http.StatusPreconditionRequired,
+	"METADATA_UNAVAILABLE":  http.StatusBadGateway,
+}
+
+// handleError writes an error response with the status matching the error code.
+// Errors that are not application errors are reported as internal server errors.
+func handleError(c *gin.Context, err error) {
+	var appErr *errors.AppError
+	if !stderrors.As(err, &appErr) {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+		return
+	}
+
+	status, ok := errorStatus[appErr.Code]
+	if !ok {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+		return
+	}
+
+	c.JSON(status, ErrorResponse{Error: appErr.Message})
+}
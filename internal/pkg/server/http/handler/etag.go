@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"clean-arch-go/internal/domain/entities"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bookETag returns the entity tag of the representation of a book: its
+// version, followed by the language of its content once localized
+func bookETag(b *entities.Book) string {
+	tag := strconv.Itoa(b.Version)
+	if b.ContentLang != "" && b.ContentLang != b.Lang {
+		tag += "-" + b.ContentLang
+	}
+	return `"` + tag + `"`
+}
+
+// notModified tells whether the If-None-Match header of a request matches
+// etag, comparing entity tags weakly
+func notModified(c *gin.Context, etag string) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion returns the version of a book the If-Match header of a
+// request was made against, 0 for any version. A 428 response is written
+// when the header is missing and a 412 one when it doesn't match the book.
+func ifMatchVersion(c *gin.Context, b *entities.Book) (int, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, ErrorResponse{Error: "If-Match header is required"})
+		return 0, false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return 0, true
+		}
+		// Weak tags never match, the book version is compared strongly
+		if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+			continue
+		}
+		version, _, _ := strings.Cut(strings.Trim(tag, `"`), "-")
+		if v, err := strconv.Atoi(version); err == nil && v == b.Version {
+			return v, true
+		}
+	}
+	c.JSON(http.StatusPreconditionFailed, ErrorResponse{Error: "The book was changed in the meantime"})
+	return 0, false
+}
@@ -0,0 +1,430 @@
+package handler
+
+import (
+	"net/http"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/user"
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GlossaryTermInput represents a glossary term in a request body
+// swagger:model GlossaryTermInput
+type GlossaryTermInput struct {
+	// Term to look for in the source text
+	// required: true
+	// example: The Go Programming Language
+	Term string `json:"term" binding:"required"`
+
+	// Target language code the term applies to, all languages when empty
+	// example: vi
+	TargetLang string `json:"target_lang"`
+
+	// Forced translation of the term, required unless the term is protected
+	// example: Ngôn ngữ lập trình Go
+	Translation string `json:"translation"`
+
+	// Keep the term untranslated
+	// example: false
+	Protected bool `json:"protected"`
+
+	// Match the term case-sensitively
+	// example: false
+	CaseSensitive bool `json:"case_sensitive"`
+}
+
+// GlossaryInput represents the glossary creation/update request body
+// swagger:model GlossaryInput
+type GlossaryInput struct {
+	// Name of the glossary
+	// required: true
+	// example: Brand names
+	Name string `json:"name" binding:"required"`
+
+	// Share the glossary with the tenant of the user
+	// example: false
+	Shared bool `json:"shared"`
+
+	// Initial terms of the glossary
+	Terms []GlossaryTermInput `json:"terms"`
+}
+
+// GlossaryImportResponse represents the result of a CSV import
+// swagger:response glossaryImportResponse
+type GlossaryImportResponse struct {
+	// Number of imported terms
+	// example: 42
+	Imported int `json:"imported"`
+}
+
+// glossaryTenant returns the tenant to share a glossary with, reporting a bad
+// request when the user has none
+func glossaryTenant(c *gin.Context, input GlossaryInput, user *user.User) (string, bool) {
+	if !input.Shared {
+		return "", true
+	}
+	if user.TenantID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "You don't belong to a tenant to share the glossary with"})
+		return "", false
+	}
+	return user.TenantID, true
+}
+
+func (i GlossaryTermInput) toEntity() entities.GlossaryTerm {
+	return entities.GlossaryTerm{
+		Term:          i.Term,
+		TargetLang:    i.TargetLang,
+		Translation:   i.Translation,
+		Protected:     i.Protected,
+		CaseSensitive: i.CaseSensitive,
+	}
+}
+
+// RegisterGlossaryRoutes registers the glossary routes
+// @Summary Register glossary routes
+// @Description Register all glossary related routes
+// @Tags glossaries
+// @Security BearerAuth
+// @Router /api/translations/glossaries [get]
+// @Router /api/translations/glossaries [post]
+// @Router /api/translations/glossaries/{id} [get]
+// @Router /api/translations/glossaries/{id} [put]
+// @Router /api/translations/glossaries/{id} [delete]
+func (h *Handler) RegisterGlossaryRoutes(router *gin.RouterGroup) {
+	glossaries := router.Group("/translations/glossaries")
+	{
+		glossaries.GET("", h.ListGlossaries)
+		glossaries.POST("", h.CreateGlossary)
+		glossaries.GET("/:id", h.GetGlossary)
+		glossaries.PUT("/:id", h.UpdateGlossary)
+		glossaries.DELETE("/:id", h.DeleteGlossary)
+		glossaries.POST("/:id/terms", h.AddGlossaryTerm)
+		glossaries.PUT("/:id/terms/:termId", h.UpdateGlossaryTerm)
+		glossaries.DELETE("/:id/terms/:termId", h.DeleteGlossaryTerm)
+		glossaries.POST("/:id/import", h.ImportGlossaryTerms)
+	}
+}
+
+// ListGlossaries returns the glossaries of the authenticated user
+// @Summary List glossaries
+// @Description Get the glossaries of the authenticated user and the ones shared with their tenant
+// @Tags glossaries
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entities.Glossary "Successfully retrieved glossaries"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/translations/glossaries [get]
+func (h *Handler) ListGlossaries(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	glossaries, err := h.glossarySvc.ListGlossaries(c.Request.Context(), user.ID, user.TenantID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, glossaries)
+}
+
+// CreateGlossary creates a new glossary
+// @Summary Create a glossary
+// @Description Create a glossary with optional initial terms, shared with the tenant of the user when asked
+// @Tags glossaries
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param glossary body GlossaryInput true "Glossary data"
+// @Success 201 {object} entities.Glossary "Successfully created glossary"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/translations/glossaries [post]
+func (h *Handler) CreateGlossary(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	var input GlossaryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	tenantID, ok := glossaryTenant(c, input, user)
+	if !ok {
+		return
+	}
+
+	glossary := &entities.Glossary{
+		UserID:   user.ID,
+		TenantID: tenantID,
+		Name:     input.Name,
+	}
+	for _, term := range input.Terms {
+		glossary.Terms = append(glossary.Terms, term.toEntity())
+	}
+
+	if err := h.glossarySvc.CreateGlossary(c.Request.Context(), glossary); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, glossary)
+}
+
+// GetGlossary gets a glossary with its terms
+// @Summary Get a glossary
+// @Description Get a glossary of the authenticated user, or shared with their tenant, with its terms
+// @Tags glossaries
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Glossary ID"
+// @Success 200 {object} entities.Glossary "Successfully retrieved glossary"
+// @Failure 400 {object} ErrorResponse "Invalid ID"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Glossary not found"
+// @Router /api/translations/glossaries/{id} [get]
+func (h *Handler) GetGlossary(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	glossary, err := h.glossarySvc.GetGlossary(c.Request.Context(), id, user.ID, user.TenantID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, glossary)
+}
+
+// UpdateGlossary renames a glossary and shares it or not
+// @Summary Update a glossary
+// @Description Rename a glossary of the authenticated user and share it with their tenant or stop sharing it
+// @Tags glossaries
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Glossary ID"
+// @Param glossary body GlossaryInput true "Glossary data"
+// @Success 200 {object} entities.Glossary "Successfully updated glossary"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Glossary not found"
+// @Router /api/translations/glossaries/{id} [put]
+func (h *Handler) UpdateGlossary(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input GlossaryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	tenantID, ok := glossaryTenant(c, input, user)
+	if !ok {
+		return
+	}
+
+	glossary, err := h.glossarySvc.UpdateGlossary(c.Request.Context(), id, user.ID, input.Name, tenantID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, glossary)
+}
+
+// DeleteGlossary deletes a glossary and its terms
+// @Summary Delete a glossary
+// @Description Delete a glossary of the authenticated user with all its terms
+// @Tags glossaries
+// @Security BearerAuth
+// @Param id path int true "Glossary ID"
+// @Success 204 "Successfully deleted glossary"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Glossary not found"
+// @Router /api/translations/glossaries/{id} [delete]
+func (h *Handler) DeleteGlossary(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.glossarySvc.DeleteGlossary(c.Request.Context(), id, user.ID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AddGlossaryTerm adds a term to a glossary
+// @Summary Add a glossary term
+// @Description Add a forced translation or a protected term to a glossary
+// @Tags glossaries
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Glossary ID"
+// @Param term body GlossaryTermInput true "Term data"
+// @Success 201 {object} entities.GlossaryTerm "Successfully added term"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Glossary not found"
+// @Router /api/translations/glossaries/{id}/terms [post]
+func (h *Handler) AddGlossaryTerm(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input GlossaryTermInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	term := input.toEntity()
+	if err := h.glossarySvc.AddTerm(c.Request.Context(), id, user.ID, &term); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, term)
+}
+
+// UpdateGlossaryTerm updates a glossary term
+// @Summary Update a glossary term
+// @Description Update a term of a glossary
+// @Tags glossaries
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Glossary ID"
+// @Param termId path int true "Term ID"
+// @Param term body GlossaryTermInput true "Term data"
+// @Success 200 {object} entities.GlossaryTerm "Successfully updated term"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Term not found"
+// @Router /api/translations/glossaries/{id}/terms/{termId} [put]
+func (h *Handler) UpdateGlossaryTerm(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+	termID, ok := parseUintParam(c, "termId")
+	if !ok {
+		return
+	}
+
+	var input GlossaryTermInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	term := input.toEntity()
+	term.ID = termID
+	if err := h.glossarySvc.UpdateTerm(c.Request.Context(), id, user.ID, &term); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, term)
+}
+
+// DeleteGlossaryTerm removes a term from a glossary
+// @Summary Delete a glossary term
+// @Description Remove a term from a glossary
+// @Tags glossaries
+// @Security BearerAuth
+// @Param id path int true "Glossary ID"
+// @Param termId path int true "Term ID"
+// @Success 204 "Successfully deleted term"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Term not found"
+// @Router /api/translations/glossaries/{id}/terms/{termId} [delete]
+func (h *Handler) DeleteGlossaryTerm(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+	termID, ok := parseUintParam(c, "termId")
+	if !ok {
+		return
+	}
+
+	if err := h.glossarySvc.DeleteTerm(c.Request.Context(), id, termID, user.ID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ImportGlossaryTerms imports glossary terms from a CSV file
+// @Summary Import glossary terms
+// @Description Import terms from a CSV file with the columns term, target_lang, translation, protected and case_sensitive. The file is sent as the "file" form field or as a text/csv body.
+// @Tags glossaries
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Accept text/csv
+// @Produce json
+// @Param id path int true "Glossary ID"
+// @Param file formData file false "CSV file"
+// @Success 200 {object} GlossaryImportResponse "Successfully imported terms"
+// @Failure 400 {object} ErrorResponse "Invalid CSV"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Glossary not found"
+// @Router /api/translations/glossaries/{id}/import [post]
+func (h *Handler) ImportGlossaryTerms(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	body := c.Request.Body
+	if c.ContentType() == "multipart/form-data" {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "CSV file is required"})
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid CSV file"})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	imported, err := h.glossarySvc.ImportTermsCSV(c.Request.Context(), id, user.ID, body)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, GlossaryImportResponse{Imported: imported})
+}
@@ -3,9 +3,12 @@ package handler
 import (
 	"net/http"
 
+	"clean-arch-go/internal/domain/entities"
 	"clean-arch-go/internal/domain/service"
+	"clean-arch-go/internal/domain/translation"
 	"clean-arch-go/internal/pkg/redis"
 	"clean-arch-go/internal/pkg/server/http/httpconfig"
+	"clean-arch-go/internal/pkg/server/http/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -30,6 +33,30 @@ type BookInput struct {
 	// Published year of the book
 	// example: 2015
 	PublishedYear int `json:"published_year"`
+
+	// ISBN-10 or ISBN-13 of the book, hyphens allowed
+	// example: 978-0-13-419044-0
+	ISBN string `json:"isbn"`
+
+	// Publisher of the book
+	// example: Addison-Wesley
+	Publisher string `json:"publisher"`
+
+	// Number of pages
+	// example: 380
+	PageCount int `json:"page_count"`
+
+	// Edition of the book
+	// example: 1st
+	Edition string `json:"edition"`
+
+	// Language the title and description are written in
+	// example: en
+	Lang string `json:"lang"`
+
+	// Tags of the book, replacing the current ones on update
+	// example: ["programming","go"]
+	Tags []string `json:"tags"`
 }
 
 // BookResponse represents a book response
@@ -55,6 +82,64 @@ type BookResponse struct {
 	// example: 2015
 	PublishedYear int `json:"published_year,omitempty"`
 
+	// ISBN-13 of the book
+	// example: 9780134190440
+	ISBN string `json:"isbn,omitempty"`
+
+	// ISBN-10 of the book, for ISBN-13s starting with 978
+	// example: 0134190440
+	ISBN10 string `json:"isbn10,omitempty"`
+
+	// Publisher of the book
+	// example: Addison-Wesley
+	Publisher string `json:"publisher,omitempty"`
+
+	// Number of pages
+	// example: 380
+	PageCount int `json:"page_count,omitempty"`
+
+	// Edition of the book
+	// example: 1st
+	Edition string `json:"edition,omitempty"`
+
+	// Language of the returned title and description
+	// example: en
+	Lang string `json:"lang,omitempty"`
+
+	// Language the book was written in
+	// example: en
+	OriginalLang string `json:"original_lang,omitempty"`
+
+	// Tags of the book
+	// example: ["programming","go"]
+	Tags []string `json:"tags"`
+
+	// URLs of the cover image by size: original, small, medium and large
+	Cover map[string]string `json:"cover,omitempty"`
+
+	// Average rating of the visible reviews, 0 without any
+	// example: 4.3
+	RatingAverage float64 `json:"rating_average"`
+
+	// Number of visible reviews
+	// example: 12
+	RatingCount int `json:"rating_count"`
+
+	// ID of the user owning the book
+	// example: 550e8400-e29b-41d4-a716-446655440000
+	OwnerID string `json:"owner_id"`
+
+	// Visibility of the book: private, unlisted or public
+	// example: private
+	Visibility string `json:"visibility"`
+
+	// Version of the book, counting its changes
+	// example: 3
+	Version int `json:"version"`
+
+	// Search matches by field, with the matched words in <mark> tags
+	Highlights map[string][]string `json:"highlights,omitempty"`
+
 	// CreatedAt timestamp
 	// example: 2023-01-01T00:00:00Z
 	CreatedAt string `json:"created_at"`
@@ -62,6 +147,10 @@ type BookResponse struct {
 	// UpdatedAt timestamp
 	// example: 2023-01-01T00:00:00Z
 	UpdatedAt string `json:"updated_at"`
+
+	// DeletedAt timestamp, for the books in the trash
+	// example: 2023-01-01T00:00:00Z
+	DeletedAt string `json:"deleted_at,omitempty"`
 }
 
 // BooksListResponse represents a list of books response
@@ -73,6 +162,12 @@ type BooksListResponse struct {
 	// Total number of books
 	// example: 42
 	Total int `json:"total"`
+
+	// Cursor of the next page, when there's one
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	// Cursor of the previous page, when there's one
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 // TranslateInput represents the translation request body
@@ -82,7 +177,7 @@ type TranslateInput struct {
 	// required: true
 	// example: Hello, world!
 	Text string `json:"text" binding:"required"`
-	// Source language code (ISO 639-1)
+	// Source language code (ISO 639-1), detected automatically when omitted
 	// example: en
 	SourceLang string `json:"source_lang"`
 
@@ -106,38 +201,95 @@ type TranslateResponse struct {
 	// Target language code (ISO 639-1)
 	// example: vi
 	TargetLang string `json:"target_lang"`
+
+	// Whether the source language was detected automatically
+	// example: true
+	Detected bool `json:"detected"`
+
+	// Confidence of the detected source language, between 0 and 1
+	// example: 0.98
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// DetectInput represents the language detection request body
+// swagger:model DetectInput
+type DetectInput struct {
+	// Text to detect the language of
+	// required: true
+	// example: Xin chào thế giới!
+	Text string `json:"text" binding:"required"`
+}
+
+// DetectResponse represents the language detection response
+// swagger:response detectResponse
+type DetectResponse struct {
+	// Detected language code (ISO 639-1)
+	// example: vi
+	Lang string `json:"lang"`
+
+	// Confidence of the detected language, between 0 and 1
+	// example: 0.98
+	Confidence float64 `json:"confidence"`
 }
 
 // LanguagesResponse represents supported languages response
 // swagger:response languagesResponse
 type LanguagesResponse struct {
-	// List of supported language codes (ISO 639-1)
-	// example: ["en", "vi", "fr", "es"]
-	Languages []string `json:"languages"`
+	// List of supported languages with their metadata
+	Languages []*translation.Language `json:"languages"`
 }
 
 type Handler struct {
-	authSvc        service.AuthService
-	bookSvc        service.BookService
-	translationSvc service.TranslationService
-	redisClient    *redis.RedisClient
-	HTTPConfig     *httpconfig.HTTPConfig
-	AuthHandler    *AuthHandler
+	authSvc              service.AuthService
+	bookSvc              service.BookService
+	translationSvc       service.TranslationService
+	glossarySvc          service.GlossaryService
+	translationMemorySvc service.TranslationMemoryService
+	bookCoverSvc         service.BookCoverService
+	shelfSvc             service.ShelfService
+	readingSvc           service.ReadingService
+	lendingSvc           service.LendingService
+	notificationSvc      service.NotificationService
+	bookTransferSvc      service.BookTransferService
+	bookTrashSvc         service.BookTrashService
+	redisClient          *redis.RedisClient
+	HTTPConfig           *httpconfig.HTTPConfig
+	AuthHandler          *AuthHandler
+	cursors              *cursorCodec
 }
 
 func NewHandler(
 	authSvc service.AuthService,
 	bookSvc service.BookService,
 	translationSvc service.TranslationService,
+	glossarySvc service.GlossaryService,
+	translationMemorySvc service.TranslationMemoryService,
+	bookCoverSvc service.BookCoverService,
+	shelfSvc service.ShelfService,
+	readingSvc service.ReadingService,
+	lendingSvc service.LendingService,
+	notificationSvc service.NotificationService,
+	bookTransferSvc service.BookTransferService,
+	bookTrashSvc service.BookTrashService,
 	redisClient *redis.RedisClient,
 	HTTPConfig *httpconfig.HTTPConfig,
 ) *Handler {
 	h := &Handler{
-		authSvc:        authSvc,
-		bookSvc:        bookSvc,
-		translationSvc: translationSvc,
-		redisClient:    redisClient,
-		HTTPConfig:     HTTPConfig,
+		authSvc:              authSvc,
+		bookSvc:              bookSvc,
+		translationSvc:       translationSvc,
+		glossarySvc:          glossarySvc,
+		translationMemorySvc: translationMemorySvc,
+		bookCoverSvc:         bookCoverSvc,
+		shelfSvc:             shelfSvc,
+		readingSvc:           readingSvc,
+		lendingSvc:           lendingSvc,
+		notificationSvc:      notificationSvc,
+		bookTransferSvc:      bookTransferSvc,
+		bookTrashSvc:         bookTrashSvc,
+		redisClient:          redisClient,
+		HTTPConfig:           HTTPConfig,
+		cursors:              newCursorCodec(HTTPConfig.Secret),
 	}
 	h.AuthHandler = NewAuthHandler(authSvc)
 	return h
@@ -150,13 +302,23 @@ func NewHandler(
 // @Security BearerAuth
 // @Router /api/books [get]
 // @Router /api/books [post]
+// @Router /api/books/search [get]
+// @Router /api/books/lookup [post]
 // @Router /api/books/{id} [get]
+// @Router /api/books/{id} [put]
+// @Router /api/books/{id} [patch]
+// @Router /api/books/{id} [delete]
 func (h *Handler) RegisterBookRoutes(router *gin.RouterGroup) {
 	books := router.Group("/books")
 	{
 		books.GET("", h.ListBooks)
+		books.GET("/search", h.SearchBooks)
+		books.POST("/lookup", h.LookupBook)
 		books.POST("", h.CreateBook)
 		books.GET("/:id", h.GetBook)
+		books.PUT("/:id", h.UpdateBook)
+		books.PATCH("/:id", h.PatchBook)
+		books.DELETE("/:id", h.DeleteBook)
 	}
 }
 
@@ -165,11 +327,13 @@ func (h *Handler) RegisterBookRoutes(router *gin.RouterGroup) {
 // @Description Register all translation related routes
 // @Tags translations
 // @Router /api/translations/translate [post]
+// @Router /api/translations/detect [post]
 // @Router /api/translations/languages [get]
 func (h *Handler) RegisterTranslationRoutes(router *gin.RouterGroup) {
 	translations := router.Group("/translations")
 	{
 		translations.POST("/translate", h.Translate)
+		translations.POST("/detect", h.DetectLanguage)
 		translations.GET("/languages", h.GetSupportedLanguages)
 	}
 }
@@ -190,22 +354,89 @@ func (h *Handler) Register(c *gin.Context) {
 
 // ListBooks returns a list of books with pagination
 // @Summary List all books
-// @Description Get a paginated list of books
+// @Description Get the books the authenticated user can see, their own, the ones shared with them and the public ones, newest first, localized for the reader's Accept-Language when translations exist.
+// @Description Pages are walked with the next_cursor and prev_cursor of the response, also advertised in the Link header; page selects an offset-based page instead.
 // @Tags books
 // @Security BearerAuth
 // @Produce json
-// @Param page query int false "Page number" default(1)
+// @Param title query string false "Title contains"
+// @Param author query string false "Author contains"
+// @Param isbn query string false "ISBN-10 or ISBN-13"
+// @Param publisher query string false "Publisher contains"
+// @Param original_lang query string false "Language the books are written in"
+// @Param year query int false "Publication year"
+// @Param year_from query int false "Earliest publication year"
+// @Param year_to query int false "Latest publication year"
+// @Param tag query []string false "Tags the books must all have" collectionFormat(multi)
+// @Param shelf query int false "Shelf ID the books are on"
+// @Param access query string false "Only the owned, shared or public books" Enums(owned, shared, public)
+// @Param cursor query string false "Cursor of the page to return"
+// @Param page query int false "Page number, instead of a cursor"
 // @Param limit query int false "Items per page" default(10)
+// @Param lang query string false "Preferred language, overrides Accept-Language"
+// @Param Accept-Language header string false "Preferred languages"
 // @Success 200 {object} BooksListResponse "Successfully retrieved books"
+// @Failure 400 {object} ErrorResponse "Invalid filter, cursor or pagination"
 // @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Shelf not found"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/books [get]
 func (h *Handler) ListBooks(c *gin.Context) {
-	// TODO: Implement list books with pagination
-	c.JSON(http.StatusOK, BooksListResponse{
-		Data:  []BookResponse{},
-		Total: 0,
-	})
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	filter, ok := bookFilter(c)
+	if !ok {
+		return
+	}
+	filter.ViewerID = user.ID
+
+	var (
+		books    []*entities.Book
+		response BooksListResponse
+	)
+	if c.Query("page") != "" {
+		if filter.Page, filter.Limit, ok = pagination(c); !ok {
+			return
+		}
+		result, err := h.bookSvc.ListBooks(c.Request.Context(), filter)
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+		books = result.Books
+		response.Total = int(result.Total)
+	} else {
+		query, ok := h.cursorQuery(c)
+		if !ok {
+			return
+		}
+		page, err := h.bookSvc.ListBooksPage(c.Request.Context(), filter, query)
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+		total, err := h.bookSvc.CountBooks(c.Request.Context(), filter)
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+		books = page.Items
+		response.Total = int(total)
+		response.NextCursor, response.PrevCursor = h.pageCursors(c, page.Next, page.Prev)
+	}
+
+	if err := h.bookSvc.LocalizeBooks(c.Request.Context(), preferredLanguages(c), books...); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Data = make([]BookResponse, len(books))
+	for i, book := range books {
+		response.Data[i] = h.newBookResponse(book)
+	}
+
+	c.Header("Vary", "Accept-Language")
+	c.JSON(http.StatusOK, response)
 }
 
 // CreateBook creates a new book
@@ -222,65 +453,169 @@ func (h *Handler) ListBooks(c *gin.Context) {
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/books [post]
 func (h *Handler) CreateBook(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
 	var input BookInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
 		return
 	}
 
-	// TODO: Implement create book
-	c.JSON(http.StatusCreated, BookResponse{
-		ID:            "generated-id",
-		Title:         input.Title,
-		Author:        input.Author,
-		Description:   input.Description,
-		PublishedYear: input.PublishedYear,
-		CreatedAt:     "2023-01-01T00:00:00Z",
-		UpdatedAt:     "2023-01-01T00:00:00Z",
-	})
+	book := input.toEntity()
+	book.UserID = user.ID
+	if err := h.bookSvc.CreateBook(c.Request.Context(), book); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Header("ETag", bookETag(book))
+	c.JSON(http.StatusCreated, h.newBookResponse(book))
 }
 
 // GetBook gets a book by ID
 // @Summary Get a book by ID
-// @Description Get detailed information about a specific book
+// @Description Get detailed information about a specific book, localized for the reader's Accept-Language when a translation exists. The ETag of the response is to be sent back in If-Match to change the book.
 // @Tags books
 // @Security BearerAuth
 // @Produce json
 // @Param id path string true "Book ID"
+// @Param lang query string false "Preferred language, overrides Accept-Language"
+// @Param Accept-Language header string false "Preferred languages"
+// @Param If-None-Match header string false "ETag of the cached book"
 // @Success 200 {object} BookResponse "Successfully retrieved book"
+// @Success 304 "Book unchanged since If-None-Match"
 // @Failure 400 {object} ErrorResponse "Invalid ID"
 // @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Book not shared with the user"
 // @Failure 404 {object} ErrorResponse "Book not found"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/books/{id} [get]
 func (h *Handler) GetBook(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Book ID is required"})
+	book, ok := h.viewableBook(c)
+	if !ok {
 		return
 	}
 
-	// TODO: Implement get book by ID
-	c.JSON(http.StatusOK, BookResponse{
-		ID:            id,
-		Title:         "Sample Book",
-		Author:        "Sample Author",
-		Description:   "This is a sample book description",
-		PublishedYear: 2023,
-		CreatedAt:     "2023-01-01T00:00:00Z",
-		UpdatedAt:     "2023-01-01T00:00:00Z",
-	})
+	if err := h.bookSvc.LocalizeBooks(c.Request.Context(), preferredLanguages(c), book); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	etag := bookETag(book)
+	c.Header("Vary", "Accept-Language")
+	c.Header("ETag", etag)
+	if book.ContentLang != "" {
+		c.Header("Content-Language", book.ContentLang)
+	}
+	if notModified(c, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, h.newBookResponse(book))
+}
+
+// UpdateBook updates a book
+// @Summary Update a book
+// @Description Update the information of a book the authenticated user owns or was made an editor of, as long as it's still at the version of If-Match
+// @Tags books
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param If-Match header string true "ETag of the book the update was made against, * for any version"
+// @Param book body BookInput true "Book data"
+// @Success 200 {object} BookResponse "Successfully updated book"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not allowed to edit the book"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Failure 412 {object} ErrorResponse "Book changed since If-Match"
+// @Failure 428 {object} ErrorResponse "If-Match missing"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/books/{id} [put]
+func (h *Handler) UpdateBook(c *gin.Context) {
+	book, ok := h.editableBook(c)
+	if !ok {
+		return
+	}
+	version, ok := ifMatchVersion(c, book)
+	if !ok {
+		return
+	}
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	var input BookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	h.saveBook(c, book.ID, user.ID, version, input)
+}
+
+// saveBook replaces the details of a book at version with input, writing
+// the updated book in the response
+func (h *Handler) saveBook(c *gin.Context, id, userID string, version int, input BookInput) {
+	changes := input.toEntity()
+	changes.Version = version
+	if err := h.bookSvc.UpdateBook(c.Request.Context(), id, userID, changes); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	updated, err := h.bookSvc.GetBookByID(c.Request.Context(), id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Header("ETag", bookETag(updated))
+	c.JSON(http.StatusOK, h.newBookResponse(updated))
+}
+
+// DeleteBook deletes a book
+// @Summary Delete a book
+// @Description Delete a book owned by the authenticated user, as long as it's still at the version of If-Match
+// @Tags books
+// @Security BearerAuth
+// @Param id path string true "Book ID"
+// @Param If-Match header string true "ETag of the book, * for any version"
+// @Success 204 "Successfully deleted book"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not the owner of the book"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Failure 412 {object} ErrorResponse "Book changed since If-Match"
+// @Failure 428 {object} ErrorResponse "If-Match missing"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/books/{id} [delete]
+func (h *Handler) DeleteBook(c *gin.Context) {
+	book, ok := h.ownedBook(c)
+	if !ok {
+		return
+	}
+	version, ok := ifMatchVersion(c, book)
+	if !ok {
+		return
+	}
+
+	if err := h.bookSvc.DeleteBook(c.Request.Context(), book.ID, version); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
 // Translate translates text from one language to another
 // @Summary Translate text
-// @Description Translate text from source language to target language
+// @Description Translate text from source language to target language. The source language is detected when omitted and the glossaries of an authenticated caller are applied.
 // @Tags translations
 // @Accept json
 // @Produce json
 // @Param input body TranslateInput true "Translation input"
 // @Success 200 {object} TranslateResponse "Successfully translated text"
 // @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 422 {object} ErrorResponse "Source language could not be detected"
 // @Failure 500 {object} ErrorResponse "Translation service error"
 // @Router /api/translations/translate [post]
 func (h *Handler) Translate(c *gin.Context) {
@@ -290,25 +625,78 @@ func (h *Handler) Translate(c *gin.Context) {
 		return
 	}
 
-	// TODO: Implement translation
-	c.JSON(http.StatusOK, TranslateResponse{
-		Text:       "Translated: " + input.Text,
+	req := &service.TranslationRequest{
+		Text:       input.Text,
 		SourceLang: input.SourceLang,
 		TargetLang: input.TargetLang,
+	}
+	// Apply the glossaries of the caller when the request is authenticated
+	if user, ok := middleware.GetUserFromContext(c.Request.Context()); ok {
+		req.UserID = user.ID
+		req.TenantID = user.TenantID
+	}
+
+	result, err := h.translationSvc.Translate(c.Request.Context(), req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, TranslateResponse{
+		Text:       result.Text,
+		SourceLang: result.SourceLang,
+		TargetLang: result.TargetLang,
+		Detected:   result.Detected,
+		Confidence: result.Confidence,
+	})
+}
+
+// DetectLanguage detects the language of a text
+// @Summary Detect language
+// @Description Detect the language of a text and return it with a confidence score
+// @Tags translations
+// @Accept json
+// @Produce json
+// @Param input body DetectInput true "Detection input"
+// @Success 200 {object} DetectResponse "Successfully detected language"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 422 {object} ErrorResponse "Language could not be detected"
+// @Router /api/translations/detect [post]
+func (h *Handler) DetectLanguage(c *gin.Context) {
+	var input DetectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	detection, err := h.translationSvc.DetectLanguage(c.Request.Context(), input.Text)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, DetectResponse{
+		Lang:       detection.Lang,
+		Confidence: detection.Confidence,
 	})
 }
 
 // GetSupportedLanguages returns a list of supported languages
 // @Summary Get supported languages
-// @Description Get a list of all supported languages for translation
+// @Description Get the languages texts can be translated into and the UI locales, with their BCP-47 code, English and native names and text direction
 // @Tags translations
 // @Produce json
 // @Success 200 {object} LanguagesResponse "Successfully retrieved supported languages"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/translations/languages [get]
 func (h *Handler) GetSupportedLanguages(c *gin.Context) {
-	// TODO: Get supported languages from service
+	languages, err := h.translationSvc.GetSupportedLanguages(c.Request.Context())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, LanguagesResponse{
-		Languages: []string{"en", "vi", "fr", "es"},
+		Languages: languages,
 	})
 }
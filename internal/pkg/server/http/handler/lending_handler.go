@@ -0,0 +1,523 @@
+package handler
+
+import (
+	"net/http"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CopyInput represents the copy creation request body
+// swagger:model CopyInput
+type CopyInput struct {
+	// Label telling the copy apart, like a barcode or a shelf mark
+	// example: LIB-0042
+	Label string `json:"label"`
+}
+
+// BorrowInput represents the optional borrowing request body
+// swagger:model BorrowInput
+type BorrowInput struct {
+	// Copy to borrow, any available one when omitted
+	// example: 3
+	CopyID uint `json:"copy_id"`
+}
+
+// LibraryResponse represents a page of the books that can be borrowed
+// swagger:response libraryResponse
+type LibraryResponse struct {
+	// Books with copies, by title
+	Data []repository.LibraryEntry `json:"data"`
+
+	// Total number of books with copies
+	// example: 42
+	Total int64 `json:"total"`
+
+	// Current page
+	// example: 1
+	Page int `json:"page"`
+
+	// Items per page
+	// example: 10
+	Limit int `json:"limit"`
+}
+
+// LoansListResponse represents a page of loans
+// swagger:response loansListResponse
+type LoansListResponse struct {
+	// Loans, latest first
+	Data []*entities.Loan `json:"data"`
+
+	// Total number of matching loans
+	// example: 3
+	Total int64 `json:"total"`
+
+	// Current page
+	// example: 1
+	Page int `json:"page"`
+
+	// Items per page
+	// example: 10
+	Limit int `json:"limit"`
+}
+
+// RegisterLendingRoutes registers the lending library routes
+// @Summary Register lending routes
+// @Description Register the routes managing copies, loans and holds
+// @Tags lending
+// @Security BearerAuth
+// @Router /api/library [get]
+// @Router /api/books/{id}/copies [get]
+// @Router /api/books/{id}/copies [post]
+// @Router /api/books/{id}/copies/{copyId} [delete]
+// @Router /api/books/{id}/loans [get]
+// @Router /api/books/{id}/loans [post]
+// @Router /api/books/{id}/holds [get]
+// @Router /api/books/{id}/holds [post]
+// @Router /api/loans [get]
+// @Router /api/loans/{id}/renew [post]
+// @Router /api/loans/{id}/return [post]
+// @Router /api/holds [get]
+// @Router /api/holds/{id} [delete]
+func (h *Handler) RegisterLendingRoutes(router *gin.RouterGroup) {
+	router.GET("/library", h.ListLibrary)
+
+	books := router.Group("/books/:id")
+	{
+		books.GET("/copies", h.ListCopies)
+		books.POST("/copies", h.AddCopy)
+		books.DELETE("/copies/:copyId", h.RemoveCopy)
+		books.GET("/loans", h.ListBookLoans)
+		books.POST("/loans", h.BorrowBook)
+		books.GET("/holds", h.ListBookHolds)
+		books.POST("/holds", h.PlaceHold)
+	}
+
+	loans := router.Group("/loans")
+	{
+		loans.GET("", h.ListMyLoans)
+		loans.POST("/:id/renew", h.RenewLoan)
+		loans.POST("/:id/return", h.ReturnLoan)
+	}
+
+	holds := router.Group("/holds")
+	{
+		holds.GET("", h.ListMyHolds)
+		holds.DELETE("/:id", h.CancelHold)
+	}
+}
+
+// RegisterLoanAdminRoutes registers the admin routes following all loans
+// @Summary Register loan admin routes
+// @Description Register the route listing the loans of all users
+// @Tags admin
+// @Security BearerAuth
+// @Router /api/admin/loans [get]
+func (h *Handler) RegisterLoanAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/loans", h.ListAllLoans)
+}
+
+// ListLibrary returns the books that can be borrowed
+// @Summary List the library
+// @Description Get a page of the books with copies the authenticated user can see, by title, with their number of copies, available copies and waiting holds
+// @Tags lending
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} LibraryResponse "Books with copies"
+// @Failure 400 {object} ErrorResponse "Invalid pagination"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Router /api/library [get]
+func (h *Handler) ListLibrary(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	page, limit, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	entries, total, err := h.lendingSvc.ListLibrary(c.Request.Context(), user.ID, page, limit)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, LibraryResponse{
+		Data:  entries,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	})
+}
+
+// ListCopies returns the copies of a book
+// @Summary List copies
+// @Description Get the copies of a book with their status
+// @Tags lending
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {array} entities.BookCopy "Copies"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/copies [get]
+func (h *Handler) ListCopies(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	copies, err := h.lendingSvc.ListCopies(c.Request.Context(), user.ID, c.Param("id"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, copies)
+}
+
+// AddCopy adds a copy of a book
+// @Summary Add a copy
+// @Description Add a copy of a book of the authenticated user to the library. The copy goes straight to the first waiting hold.
+// @Tags lending
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param copy body CopyInput false "Copy"
+// @Success 201 {object} entities.BookCopy "Added copy"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/copies [post]
+func (h *Handler) AddCopy(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	var input CopyInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+			return
+		}
+	}
+
+	bookCopy := &entities.BookCopy{BookID: c.Param("id"), Label: input.Label}
+	if err := h.lendingSvc.AddCopy(c.Request.Context(), user.ID, bookCopy); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, bookCopy)
+}
+
+// RemoveCopy removes a copy of a book
+// @Summary Remove a copy
+// @Description Remove an available copy of a book of the authenticated user from the library
+// @Tags lending
+// @Security BearerAuth
+// @Param id path string true "Book ID"
+// @Param copyId path int true "Copy ID"
+// @Success 204 "Copy removed"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Copy not found"
+// @Failure 409 {object} ErrorResponse "Copy on loan or kept for a hold"
+// @Router /api/books/{id}/copies/{copyId} [delete]
+func (h *Handler) RemoveCopy(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "copyId")
+	if !ok {
+		return
+	}
+
+	if err := h.lendingSvc.RemoveCopy(c.Request.Context(), user.ID, c.Param("id"), id); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListBookLoans returns the loans of a book
+// @Summary List the loans of a book
+// @Description Get a page of the loans of a book of the authenticated user, latest first
+// @Tags lending
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param status query string false "active, overdue or returned"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} LoansListResponse "Loans"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/loans [get]
+func (h *Handler) ListBookLoans(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	page, limit, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	loans, total, err := h.lendingSvc.ListBookLoans(c.Request.Context(), user.ID, c.Param("id"), c.Query("status"), page, limit)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, LoansListResponse{Data: loans, Total: total, Page: page, Limit: limit})
+}
+
+// BorrowBook borrows a copy of a book
+// @Summary Borrow a book
+// @Description Borrow a copy of a book for the loan period: the copy kept for a ready hold of the authenticated user, the requested copy, or any available one
+// @Tags lending
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param loan body BorrowInput false "Copy to borrow"
+// @Success 201 {object} entities.Loan "Loan"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Failure 409 {object} ErrorResponse "No copy available or already borrowed"
+// @Router /api/books/{id}/loans [post]
+func (h *Handler) BorrowBook(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	var input BorrowInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+			return
+		}
+	}
+
+	loan, err := h.lendingSvc.Borrow(c.Request.Context(), user.ID, c.Param("id"), input.CopyID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, loan)
+}
+
+// ListBookHolds returns the holds queue of a book
+// @Summary List the holds of a book
+// @Description Get the waiting and ready holds of a book of the authenticated user, in queue order
+// @Tags lending
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {array} entities.Hold "Holds"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/holds [get]
+func (h *Handler) ListBookHolds(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	holds, err := h.lendingSvc.ListBookHolds(c.Request.Context(), user.ID, c.Param("id"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, holds)
+}
+
+// PlaceHold queues for a book
+// @Summary Place a hold
+// @Description Queue the authenticated user for a book without available copies. The first user in the queue is notified when a copy comes back and can borrow it until the hold expires.
+// @Tags lending
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 201 {object} entities.Hold "Hold with its position"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Failure 409 {object} ErrorResponse "Copy available, already held or borrowed"
+// @Router /api/books/{id}/holds [post]
+func (h *Handler) PlaceHold(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	hold, err := h.lendingSvc.PlaceHold(c.Request.Context(), user.ID, c.Param("id"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, hold)
+}
+
+// ListMyLoans returns the loans of the authenticated user
+// @Summary List my loans
+// @Description Get a page of the loans of the authenticated user, latest first
+// @Tags lending
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "active, overdue or returned"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} LoansListResponse "Loans"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Router /api/loans [get]
+func (h *Handler) ListMyLoans(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+	h.listLoans(c, repository.LoanFilter{UserID: user.ID, Status: c.Query("status")})
+}
+
+// ListAllLoans returns the loans of all users
+// @Summary List all loans
+// @Description Get a page of the loans of all users, latest first, for instance the overdue ones
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "active, overdue or returned"
+// @Param book query string false "Only the loans of this book"
+// @Param user query string false "Only the loans of this user"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} LoansListResponse "Loans"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Failure 403 {object} ErrorResponse "Admin access required"
+// @Router /api/admin/loans [get]
+func (h *Handler) ListAllLoans(c *gin.Context) {
+	h.listLoans(c, repository.LoanFilter{
+		UserID: c.Query("user"),
+		BookID: c.Query("book"),
+		Status: c.Query("status"),
+	})
+}
+
+func (h *Handler) listLoans(c *gin.Context, filter repository.LoanFilter) {
+	page, limit, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	loans, total, err := h.lendingSvc.ListLoans(c.Request.Context(), filter, page, limit)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, LoansListResponse{Data: loans, Total: total, Page: page, Limit: limit})
+}
+
+// RenewLoan extends a loan
+// @Summary Renew a loan
+// @Description Extend a loan of the authenticated user by the loan period from now, unless it was renewed too many times or other users are waiting for the book
+// @Tags lending
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Loan ID"
+// @Success 200 {object} entities.Loan "Renewed loan"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Loan not found"
+// @Failure 409 {object} ErrorResponse "Loan can't be renewed"
+// @Router /api/loans/{id}/renew [post]
+func (h *Handler) RenewLoan(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	loan, err := h.lendingSvc.RenewLoan(c.Request.Context(), user.ID, id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, loan)
+}
+
+// ReturnLoan ends a loan
+// @Summary Return a loan
+// @Description Return a borrowed copy, as its borrower or the owner of the book. The copy goes to the first waiting hold, whose user is notified.
+// @Tags lending
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Loan ID"
+// @Success 200 {object} entities.Loan "Returned loan"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Loan not found"
+// @Failure 409 {object} ErrorResponse "Loan already returned"
+// @Router /api/loans/{id}/return [post]
+func (h *Handler) ReturnLoan(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	loan, err := h.lendingSvc.ReturnLoan(c.Request.Context(), user.ID, id)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, loan)
+}
+
+// ListMyHolds returns the holds of the authenticated user
+// @Summary List my holds
+// @Description Get the waiting and ready holds of the authenticated user with their position in the queue
+// @Tags lending
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entities.Hold "Holds"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Router /api/holds [get]
+func (h *Handler) ListMyHolds(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	holds, err := h.lendingSvc.ListHolds(c.Request.Context(), user.ID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, holds)
+}
+
+// CancelHold withdraws a hold
+// @Summary Cancel a hold
+// @Description Withdraw a hold of the authenticated user. The copy kept for a ready hold goes to the next user waiting.
+// @Tags lending
+// @Security BearerAuth
+// @Param id path int true "Hold ID"
+// @Success 204 "Hold cancelled"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Hold not found"
+// @Failure 409 {object} ErrorResponse "Hold no longer active"
+// @Router /api/holds/{id} [delete]
+func (h *Handler) CancelHold(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.lendingSvc.CancelHold(c.Request.Context(), user.ID, id); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationsListResponse represents a page of notifications
+// swagger:response notificationsListResponse
+type NotificationsListResponse struct {
+	// Notifications, newest first
+	Data []*entities.Notification `json:"data"`
+
+	// Total number of matching notifications
+	// example: 2
+	Total int64 `json:"total"`
+
+	// Current page
+	// example: 1
+	Page int `json:"page"`
+
+	// Items per page
+	// example: 10
+	Limit int `json:"limit"`
+}
+
+// RegisterNotificationRoutes registers the notification routes
+// @Summary Register notification routes
+// @Description Register the routes reading the notifications of the user
+// @Tags notifications
+// @Security BearerAuth
+// @Router /api/notifications [get]
+// @Router /api/notifications/{id}/read [put]
+func (h *Handler) RegisterNotificationRoutes(router *gin.RouterGroup) {
+	notifications := router.Group("/notifications")
+	{
+		notifications.GET("", h.ListNotifications)
+		notifications.PUT("/:id/read", h.MarkNotificationRead)
+	}
+}
+
+// ListNotifications returns the notifications of the authenticated user
+// @Summary List notifications
+// @Description Get a page of the notifications of the authenticated user, newest first
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Param unread query bool false "Only the unread notifications"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} NotificationsListResponse "Notifications"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Router /api/notifications [get]
+func (h *Handler) ListNotifications(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	page, limit, ok := pagination(c)
+	if !ok {
+		return
+	}
+	unread, ok := optionalBoolQuery(c, "unread")
+	if !ok {
+		return
+	}
+
+	notifications, total, err := h.notificationSvc.ListNotifications(c.Request.Context(), user.ID, unread != nil && *unread, page, limit)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NotificationsListResponse{
+		Data:  notifications,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	})
+}
+
+// MarkNotificationRead marks a notification as read
+// @Summary Mark a notification as read
+// @Description Mark a notification of the authenticated user as read
+// @Tags notifications
+// @Security BearerAuth
+// @Param id path int true "Notification ID"
+// @Success 204 "Notification read"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Notification not found"
+// @Router /api/notifications/{id}/read [put]
+func (h *Handler) MarkNotificationRead(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.notificationSvc.MarkRead(c.Request.Context(), user.ID, id); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadingProgressInput represents the reading progress update request body
+// swagger:model ReadingProgressInput
+type ReadingProgressInput struct {
+	// Reading status: want_to_read, reading, finished or abandoned
+	// required: true
+	// example: reading
+	Status string `json:"status" binding:"required"`
+
+	// Last page read, sets the percentage when the page count is known
+	// example: 120
+	CurrentPage int `json:"current_page"`
+
+	// Share of the book read between 0 and 100, used when current_page is omitted
+	// example: 35.5
+	Percent float64 `json:"percent"`
+
+	// When the user started the book, now when starting to read by default
+	// example: 2024-01-15T20:00:00Z
+	StartedAt *time.Time `json:"started_at"`
+
+	// When the user finished the book, now when finishing it by default
+	// example: 2024-02-01T22:30:00Z
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+// ReadingSessionInput represents a reading session in a request body
+// swagger:model ReadingSessionInput
+type ReadingSessionInput struct {
+	// Page the session started at
+	// example: 100
+	StartPage int `json:"start_page"`
+
+	// Last page read during the session
+	// example: 142
+	EndPage int `json:"end_page"`
+
+	// When the session started
+	// required: true
+	// example: 2024-01-20T20:00:00Z
+	StartedAt time.Time `json:"started_at" binding:"required"`
+
+	// When the session ended
+	// example: 2024-01-20T21:15:00Z
+	EndedAt *time.Time `json:"ended_at"`
+}
+
+// RegisterReadingRoutes registers the reading progress routes
+// @Summary Register reading routes
+// @Description Register the routes tracking the reading progress of books
+// @Tags reading
+// @Security BearerAuth
+// @Router /api/books/{id}/progress [get]
+// @Router /api/books/{id}/progress [put]
+// @Router /api/books/{id}/progress [delete]
+// @Router /api/books/{id}/progress/sessions [get]
+// @Router /api/books/{id}/progress/sessions [post]
+// @Router /api/reading/stats [get]
+func (h *Handler) RegisterReadingRoutes(router *gin.RouterGroup) {
+	progress := router.Group("/books/:id/progress")
+	{
+		progress.GET("", h.GetReadingProgress)
+		progress.PUT("", h.UpdateReadingProgress)
+		progress.DELETE("", h.DeleteReadingProgress)
+		progress.GET("/sessions", h.ListReadingSessions)
+		progress.POST("/sessions", h.LogReadingSession)
+	}
+	router.GET("/reading/stats", h.GetReadingStats)
+}
+
+// GetReadingProgress returns the reading progress of the user in a book
+// @Summary Get reading progress
+// @Description Get the reading status, position and dates of the authenticated user in a book
+// @Tags reading
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {object} entities.ReadingProgress "Reading progress"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Book or progress not found"
+// @Router /api/books/{id}/progress [get]
+func (h *Handler) GetReadingProgress(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	progress, err := h.readingSvc.GetProgress(c.Request.Context(), user.ID, c.Param("id"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// UpdateReadingProgress saves the reading progress of the user in a book
+// @Summary Update reading progress
+// @Description Set the reading status and position of the authenticated user in a book. The page and percentage are derived from each other when the page count of the book is known.
+// @Tags reading
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param progress body ReadingProgressInput true "Reading progress"
+// @Success 200 {object} entities.ReadingProgress "Saved reading progress"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/progress [put]
+func (h *Handler) UpdateReadingProgress(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	var input ReadingProgressInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	progress := &entities.ReadingProgress{
+		UserID:      user.ID,
+		BookID:      c.Param("id"),
+		Status:      input.Status,
+		CurrentPage: input.CurrentPage,
+		Percent:     input.Percent,
+		StartedAt:   input.StartedAt,
+		FinishedAt:  input.FinishedAt,
+	}
+	if err := h.readingSvc.UpdateProgress(c.Request.Context(), progress); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// DeleteReadingProgress forgets the reading progress of the user in a book
+// @Summary Delete reading progress
+// @Description Delete the reading progress of the authenticated user in a book with its sessions
+// @Tags reading
+// @Security BearerAuth
+// @Param id path string true "Book ID"
+// @Success 204 "Reading progress deleted"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/progress [delete]
+func (h *Handler) DeleteReadingProgress(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	if err := h.readingSvc.DeleteProgress(c.Request.Context(), user.ID, c.Param("id")); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListReadingSessions returns the reading sessions of the user in a book
+// @Summary List reading sessions
+// @Description Get the reading sessions of the authenticated user in a book, latest first
+// @Tags reading
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {array} entities.ReadingSession "Reading sessions"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/progress/sessions [get]
+func (h *Handler) ListReadingSessions(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	sessions, err := h.readingSvc.ListSessions(c.Request.Context(), user.ID, c.Param("id"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// LogReadingSession records a reading session
+// @Summary Log a reading session
+// @Description Record a reading session of the authenticated user in a book. The progress moves to the last page of the session; the book is started if needed and finished on its last page.
+// @Tags reading
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param session body ReadingSessionInput true "Reading session"
+// @Success 201 {object} entities.ReadingProgress "Reading progress after the session"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/progress/sessions [post]
+func (h *Handler) LogReadingSession(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	var input ReadingSessionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	session := &entities.ReadingSession{
+		UserID:    user.ID,
+		BookID:    c.Param("id"),
+		StartPage: input.StartPage,
+		EndPage:   input.EndPage,
+		StartedAt: input.StartedAt,
+		EndedAt:   input.EndedAt,
+	}
+	progress, err := h.readingSvc.LogSession(c.Request.Context(), session)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, progress)
+}
+
+// GetReadingStats returns the yearly reading statistics of the user
+// @Summary Get reading statistics
+// @Description Count the books the authenticated user finished in a year and their pages, by month
+// @Tags reading
+// @Security BearerAuth
+// @Produce json
+// @Param year query int false "Year, the current one by default"
+// @Success 200 {object} service.ReadingStats "Reading statistics"
+// @Failure 400 {object} ErrorResponse "Invalid year"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Router /api/reading/stats [get]
+func (h *Handler) GetReadingStats(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	year := time.Now().Year()
+	if value := c.Query("year"); value != "" {
+		var err error
+		if year, err = strconv.Atoi(value); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid year"})
+			return
+		}
+	}
+
+	stats, err := h.readingSvc.YearlyStats(c.Request.Context(), user.ID, year)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
+)
+
+// uploadedFile returns the "file" field of a multipart request, or the request body otherwise
+func uploadedFile(c *gin.Context) (io.ReadCloser, error) {
+	if c.ContentType() != "multipart/form-data" {
+		return c.Request.Body, nil
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	return fileHeader.Open()
+}
+
+// parseUintParam parses a numeric path parameter, writing a 400 response when it's invalid
+func parseUintParam(c *gin.Context, name string) (uint, bool) {
+	value, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid " + name})
+		return 0, false
+	}
+	return uint(value), true
+}
+
+// pagination parses the page and limit query parameters, writing a 400
+// response when they're invalid
+func pagination(c *gin.Context) (page, limit int, ok bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid page"})
+		return 0, 0, false
+	}
+	limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit"})
+		return 0, 0, false
+	}
+	return page, limit, true
+}
+
+// preferredLanguages returns the reader's languages by preference: the lang
+// query parameter when set, then the Accept-Language header
+func preferredLanguages(c *gin.Context) []language.Tag {
+	if lang := c.Query("lang"); lang != "" {
+		if tag, err := language.Parse(lang); err == nil {
+			return []language.Tag{tag}
+		}
+	}
+	tags, _, _ := language.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+	return tags
+}
@@ -0,0 +1,402 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/domain/repository"
+	"clean-arch-go/internal/domain/service"
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReviewInput represents the review creation/update request body
+// swagger:model ReviewInput
+type ReviewInput struct {
+	// Rating from 1 to 5 stars
+	// required: true
+	// example: 4
+	Rating int `json:"rating" binding:"required"`
+
+	// Title of the review
+	// example: A solid introduction
+	Title string `json:"title"`
+
+	// Text of the review
+	// example: Clear explanations, though the last chapters feel rushed.
+	Body string `json:"body"`
+}
+
+// ReviewModerationInput represents the review moderation request body,
+// omitted fields are left unchanged
+// swagger:model ReviewModerationInput
+type ReviewModerationInput struct {
+	// Leave the review out of listings and ratings
+	// example: true
+	Hidden *bool `json:"hidden"`
+
+	// Mark the review for a closer look
+	// example: false
+	Flagged *bool `json:"flagged"`
+
+	// Note of the moderator, only shown to moderators
+	// example: Spoilers in the second paragraph
+	Note *string `json:"note"`
+}
+
+// ReviewsListResponse represents a page of the reviews of a book
+// swagger:response reviewsListResponse
+type ReviewsListResponse struct {
+	// Reviews, newest first
+	Data []*entities.Review `json:"data"`
+
+	// Average rating of the visible reviews of the book
+	// example: 4.3
+	RatingAverage float64 `json:"rating_average"`
+
+	// Number of visible reviews of the book
+	// example: 12
+	RatingCount int `json:"rating_count"`
+
+	// Total number of matching reviews
+	// example: 12
+	Total int64 `json:"total"`
+
+	// Current page
+	// example: 1
+	Page int `json:"page"`
+
+	// Items per page
+	// example: 10
+	Limit int `json:"limit"`
+}
+
+// ModerationReviewsListResponse represents a page of reviews to moderate
+// swagger:response moderationReviewsListResponse
+type ModerationReviewsListResponse struct {
+	// Reviews, newest first
+	Data []*entities.Review `json:"data"`
+
+	// Total number of matching reviews
+	// example: 3
+	Total int64 `json:"total"`
+
+	// Current page
+	// example: 1
+	Page int `json:"page"`
+
+	// Items per page
+	// example: 10
+	Limit int `json:"limit"`
+}
+
+// RegisterReviewRoutes registers the book review routes
+// @Summary Register review routes
+// @Description Register the routes rating and reviewing books
+// @Tags reviews
+// @Security BearerAuth
+// @Router /api/books/{id}/reviews [get]
+// @Router /api/books/{id}/reviews [post]
+// @Router /api/books/{id}/reviews/mine [get]
+// @Router /api/books/{id}/reviews/{reviewId} [put]
+// @Router /api/books/{id}/reviews/{reviewId} [delete]
+func (h *Handler) RegisterReviewRoutes(router *gin.RouterGroup) {
+	reviews := router.Group("/books/:id/reviews")
+	{
+		reviews.GET("", h.ListReviews)
+		reviews.POST("", h.CreateReview)
+		reviews.GET("/mine", h.GetMyReview)
+		reviews.PUT("/:reviewId", h.UpdateReview)
+		reviews.DELETE("/:reviewId", h.DeleteReview)
+	}
+}
+
+// RegisterReviewModerationRoutes registers the admin routes moderating reviews
+// @Summary Register review moderation routes
+// @Description Register the routes listing and moderating the reviews of all books
+// @Tags admin
+// @Security BearerAuth
+// @Router /api/admin/reviews [get]
+// @Router /api/admin/reviews/{id}/moderation [put]
+func (h *Handler) RegisterReviewModerationRoutes(router *gin.RouterGroup) {
+	reviews := router.Group("/reviews")
+	{
+		reviews.GET("", h.ListReviewsForModeration)
+		reviews.PUT("/:id/moderation", h.ModerateReview)
+	}
+}
+
+// ListReviews returns the visible reviews of a book
+// @Summary List reviews
+// @Description Get a page of the visible reviews of a book, newest first, with its average rating
+// @Tags reviews
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} ReviewsListResponse "Reviews of the book"
+// @Failure 400 {object} ErrorResponse "Invalid pagination"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/reviews [get]
+func (h *Handler) ListReviews(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	page, limit, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	reviews, total, err := h.bookSvc.ListReviews(c.Request.Context(), c.Param("id"), user.ID, page, limit)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	book, err := h.bookSvc.GetBookByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ReviewsListResponse{
+		Data:          reviews,
+		RatingAverage: book.RatingAverage,
+		RatingCount:   book.RatingCount,
+		Total:         total,
+		Page:          page,
+		Limit:         limit,
+	})
+}
+
+// GetMyReview returns the review of the authenticated user on a book
+// @Summary Get my review
+// @Description Get the review of the authenticated user on a book, hidden or not
+// @Tags reviews
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {object} entities.Review "Review"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Book or review not found"
+// @Router /api/books/{id}/reviews/mine [get]
+func (h *Handler) GetMyReview(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	review, err := h.bookSvc.GetReviewByUser(c.Request.Context(), c.Param("id"), user.ID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// CreateReview rates and reviews a book
+// @Summary Create a review
+// @Description Rate and review a book, once per user and book
+// @Tags reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param review body ReviewInput true "Review"
+// @Success 201 {object} entities.Review "Created review"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Failure 409 {object} ErrorResponse "Book already reviewed"
+// @Router /api/books/{id}/reviews [post]
+func (h *Handler) CreateReview(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	var input ReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	review := &entities.Review{
+		BookID: c.Param("id"),
+		UserID: user.ID,
+		Rating: input.Rating,
+		Title:  input.Title,
+		Body:   input.Body,
+	}
+	if err := h.bookSvc.CreateReview(c.Request.Context(), review); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
+
+// UpdateReview changes a review of the authenticated user
+// @Summary Update a review
+// @Description Change the rating, title and body of a review of the authenticated user
+// @Tags reviews
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param reviewId path int true "Review ID"
+// @Param review body ReviewInput true "Review"
+// @Success 200 {object} entities.Review "Updated review"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Review not found"
+// @Router /api/books/{id}/reviews/{reviewId} [put]
+func (h *Handler) UpdateReview(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "reviewId")
+	if !ok {
+		return
+	}
+
+	var input ReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	review := &entities.Review{
+		Rating: input.Rating,
+		Title:  input.Title,
+		Body:   input.Body,
+	}
+	if err := h.bookSvc.UpdateReview(c.Request.Context(), c.Param("id"), id, user.ID, review); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// DeleteReview removes a review of the authenticated user
+// @Summary Delete a review
+// @Description Delete a review of the authenticated user
+// @Tags reviews
+// @Security BearerAuth
+// @Param id path string true "Book ID"
+// @Param reviewId path int true "Review ID"
+// @Success 204 "Review deleted"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Review not found"
+// @Router /api/books/{id}/reviews/{reviewId} [delete]
+func (h *Handler) DeleteReview(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "reviewId")
+	if !ok {
+		return
+	}
+
+	if err := h.bookSvc.DeleteReview(c.Request.Context(), c.Param("id"), id, user.ID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListReviewsForModeration returns the reviews of all books
+// @Summary List reviews to moderate
+// @Description Get a page of the reviews of all books, newest first, hidden ones included
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param book query string false "Only the reviews of this book"
+// @Param hidden query bool false "Only hidden or visible reviews"
+// @Param flagged query bool false "Only flagged or unflagged reviews"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} ModerationReviewsListResponse "Reviews"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Failure 403 {object} ErrorResponse "Admin access required"
+// @Router /api/admin/reviews [get]
+func (h *Handler) ListReviewsForModeration(c *gin.Context) {
+	page, limit, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	filter := repository.ReviewFilter{BookID: c.Query("book")}
+	if filter.Hidden, ok = optionalBoolQuery(c, "hidden"); !ok {
+		return
+	}
+	if filter.Flagged, ok = optionalBoolQuery(c, "flagged"); !ok {
+		return
+	}
+
+	reviews, total, err := h.bookSvc.ListReviewsForModeration(c.Request.Context(), filter, page, limit)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ModerationReviewsListResponse{
+		Data:  reviews,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	})
+}
+
+// ModerateReview hides, flags or annotates a review
+// @Summary Moderate a review
+// @Description Hide, flag or annotate a review. Hidden reviews are left out of the listings and ratings of the book.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param moderation body ReviewModerationInput true "Moderation"
+// @Success 200 {object} entities.Review "Moderated review"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 403 {object} ErrorResponse "Admin access required"
+// @Failure 404 {object} ErrorResponse "Review not found"
+// @Router /api/admin/reviews/{id}/moderation [put]
+func (h *Handler) ModerateReview(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input ReviewModerationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	review, err := h.bookSvc.ModerateReview(c.Request.Context(), id, user.ID, service.ReviewModeration{
+		Hidden:  input.Hidden,
+		Flagged: input.Flagged,
+		Note:    input.Note,
+	})
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// optionalBoolQuery parses a boolean query parameter, nil when it's absent,
+// writing a 400 response when it's invalid
+func optionalBoolQuery(c *gin.Context, name string) (*bool, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid " + name})
+		return nil, false
+	}
+	return &b, true
+}
@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"net/http"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sharedBookPath is the path of the share links, followed by their token
+const sharedBookPath = "/api/shared/"
+
+// GrantInput represents the request body granting a role on a book
+// swagger:model GrantInput
+type GrantInput struct {
+	// ID or email address of the user
+	// required: true
+	// example: jane@example.com
+	User string `json:"user" binding:"required"`
+
+	// Role of the user: viewer or editor
+	// required: true
+	// example: editor
+	Role string `json:"role" binding:"required"`
+}
+
+// VisibilityInput represents the request body changing the visibility of a book
+// swagger:model VisibilityInput
+type VisibilityInput struct {
+	// Visibility of the book: private, unlisted or public
+	// required: true
+	// example: unlisted
+	Visibility string `json:"visibility" binding:"required"`
+}
+
+// SharingResponse represents the visibility and share link of a book
+// swagger:response sharingResponse
+type SharingResponse struct {
+	// Visibility of the book: private, unlisted or public
+	// example: unlisted
+	Visibility string `json:"visibility"`
+
+	// Path of the share link, which anyone can open, omitted for private books
+	// example: /api/shared/q2Xv0l9sR8mC3bZ1yT6nWkP4aE7hJ5dF
+	SharePath string `json:"share_path,omitempty"`
+}
+
+// RegisterSharingRoutes registers the routes sharing books with other users
+// @Summary Register sharing routes
+// @Description Register the routes granting roles on books and changing their visibility
+// @Tags sharing
+// @Security BearerAuth
+// @Router /api/books/{id}/grants [get]
+// @Router /api/books/{id}/grants [post]
+// @Router /api/books/{id}/grants/{userId} [delete]
+// @Router /api/books/{id}/visibility [get]
+// @Router /api/books/{id}/visibility [put]
+// @Router /api/books/{id}/share-link [post]
+func (h *Handler) RegisterSharingRoutes(router *gin.RouterGroup) {
+	books := router.Group("/books/:id")
+	{
+		books.GET("/grants", h.ListGrants)
+		books.POST("/grants", h.GrantAccess)
+		books.DELETE("/grants/:userId", h.RevokeAccess)
+		books.GET("/visibility", h.GetVisibility)
+		books.PUT("/visibility", h.SetVisibility)
+		books.POST("/share-link", h.RotateShareLink)
+	}
+}
+
+// RegisterSharedBookRoutes registers the share link routes, open to anyone
+// @Summary Register share link routes
+// @Description Register the routes opening the share links of unlisted and public books
+// @Tags sharing
+// @Router /api/shared/{token} [get]
+func (h *Handler) RegisterSharedBookRoutes(router *gin.RouterGroup) {
+	router.GET("/shared/:token", h.GetSharedBook)
+}
+
+// ListGrants returns the users a book is shared with
+// @Summary List the grants on a book
+// @Description Get the users a book of the authenticated user is shared with and their roles
+// @Tags sharing
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {array} entities.BookGrant "Grants on the book"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not the owner of the book"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/grants [get]
+func (h *Handler) ListGrants(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	grants, err := h.bookSvc.ListGrants(c.Request.Context(), c.Param("id"), user.ID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, grants)
+}
+
+// GrantAccess shares a book with a user
+// @Summary Grant a role on a book
+// @Description Make a user, found by ID or email address, a viewer or an editor of a book of the authenticated user, replacing the role they had
+// @Tags sharing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param grant body GrantInput true "User and role"
+// @Success 200 {object} entities.BookGrant "Grant on the book"
+// @Failure 400 {object} ErrorResponse "Invalid role or user"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not the owner of the book"
+// @Failure 404 {object} ErrorResponse "Book or user not found"
+// @Router /api/books/{id}/grants [post]
+func (h *Handler) GrantAccess(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	var input GrantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	grant, err := h.bookSvc.GrantAccess(c.Request.Context(), c.Param("id"), user.ID, input.User, input.Role)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, grant)
+}
+
+// RevokeAccess stops sharing a book with a user
+// @Summary Revoke a grant on a book
+// @Description Remove the role of a user on a book. Owners revoke any grant on their books, other users can give up their own.
+// @Tags sharing
+// @Security BearerAuth
+// @Param id path string true "Book ID"
+// @Param userId path string true "User ID"
+// @Success 204 "Grant revoked"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not the owner of the book"
+// @Failure 404 {object} ErrorResponse "Book or grant not found"
+// @Router /api/books/{id}/grants/{userId} [delete]
+func (h *Handler) RevokeAccess(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	if err := h.bookSvc.RevokeAccess(c.Request.Context(), c.Param("id"), user.ID, c.Param("userId")); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetVisibility returns the visibility and share link of a book
+// @Summary Get the visibility of a book
+// @Description Get the visibility and share link of a book of the authenticated user
+// @Tags sharing
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {object} SharingResponse "Visibility of the book"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not the owner of the book"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/visibility [get]
+func (h *Handler) GetVisibility(c *gin.Context) {
+	book, ok := h.ownedBook(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, newSharingResponse(book))
+}
+
+// SetVisibility changes the visibility of a book
+// @Summary Change the visibility of a book
+// @Description Make a book of the authenticated user private, unlisted, that is seen by whoever has its share link, or public, listed to every user. Private books lose their share link.
+// @Tags sharing
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param visibility body VisibilityInput true "Visibility"
+// @Success 200 {object} SharingResponse "New visibility of the book"
+// @Failure 400 {object} ErrorResponse "Invalid visibility"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not the owner of the book"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/visibility [put]
+func (h *Handler) SetVisibility(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	var input VisibilityInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	book, err := h.bookSvc.SetVisibility(c.Request.Context(), c.Param("id"), user.ID, input.Visibility)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newSharingResponse(book))
+}
+
+// RotateShareLink replaces the share link of a book
+// @Summary Replace the share link of a book
+// @Description Give an unlisted or public book of the authenticated user a new share link, the previous one no longer opening it
+// @Tags sharing
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {object} SharingResponse "New share link of the book"
+// @Failure 400 {object} ErrorResponse "Private book"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Not the owner of the book"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/books/{id}/share-link [post]
+func (h *Handler) RotateShareLink(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	book, err := h.bookSvc.RotateShareLink(c.Request.Context(), c.Param("id"), user.ID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newSharingResponse(book))
+}
+
+// GetSharedBook opens a share link
+// @Summary Open a share link
+// @Description Get the unlisted or public book of a share link, localized for the reader's Accept-Language when a translation exists. No authentication is needed.
+// @Tags sharing
+// @Produce json
+// @Param token path string true "Share link token"
+// @Param lang query string false "Preferred language, overrides Accept-Language"
+// @Param Accept-Language header string false "Preferred languages"
+// @Success 200 {object} BookResponse "Shared book"
+// @Failure 404 {object} ErrorResponse "Book not found"
+// @Router /api/shared/{token} [get]
+func (h *Handler) GetSharedBook(c *gin.Context) {
+	book, err := h.bookSvc.GetSharedBook(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	book.ContentLang = book.Lang
+	if err := h.bookSvc.LocalizeBooks(c.Request.Context(), preferredLanguages(c), book); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Header("Vary", "Accept-Language")
+	if book.ContentLang != "" {
+		c.Header("Content-Language", book.ContentLang)
+	}
+	c.JSON(http.StatusOK, h.newBookResponse(book))
+}
+
+func newSharingResponse(b *entities.Book) SharingResponse {
+	response := SharingResponse{Visibility: b.Visibility}
+	if b.ShareToken != nil {
+		response.SharePath = sharedBookPath + *b.ShareToken
+	}
+	return response
+}
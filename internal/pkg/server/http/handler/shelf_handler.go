@@ -0,0 +1,397 @@
+package handler
+
+import (
+	"net/http"
+
+	"clean-arch-go/internal/domain/entities"
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShelfInput represents the shelf creation/update request body
+// swagger:model ShelfInput
+type ShelfInput struct {
+	// Name of the shelf, unique among the user's shelves
+	// required: true
+	// example: To read
+	Name string `json:"name" binding:"required"`
+
+	// Description of the shelf
+	// example: Books I want to read this year
+	Description string `json:"description"`
+
+	// Position of the shelf among the user's shelves, lowest first
+	// example: 0
+	Position int `json:"position"`
+}
+
+// ShelfBookInput represents the request body placing a book on a shelf
+// swagger:model ShelfBookInput
+type ShelfBookInput struct {
+	// Position of the book on the shelf counted from 0, last when omitted
+	// example: 0
+	Position *int `json:"position"`
+}
+
+// ShelfOrderInput represents the request body reordering the books of a shelf
+// swagger:model ShelfOrderInput
+type ShelfOrderInput struct {
+	// IDs of all the books of the shelf in their new order
+	// required: true
+	BookIDs []string `json:"book_ids" binding:"required"`
+}
+
+// ShelfBooksResponse represents a page of the books of a shelf
+// swagger:response shelfBooksResponse
+type ShelfBooksResponse struct {
+	// Books of the shelf, in the shelf order
+	Data []BookResponse `json:"data"`
+
+	// Total number of books on the shelf
+	// example: 42
+	Total int64 `json:"total"`
+
+	// Current page
+	// example: 1
+	Page int `json:"page"`
+
+	// Items per page
+	// example: 10
+	Limit int `json:"limit"`
+}
+
+func (i ShelfInput) toEntity() *entities.Shelf {
+	return &entities.Shelf{
+		Name:        i.Name,
+		Description: i.Description,
+		Position:    i.Position,
+	}
+}
+
+// RegisterShelfRoutes registers the shelf routes
+// @Summary Register shelf routes
+// @Description Register all shelf related routes
+// @Tags shelves
+// @Security BearerAuth
+// @Router /api/shelves [get]
+// @Router /api/shelves [post]
+// @Router /api/shelves/{id} [get]
+// @Router /api/shelves/{id} [put]
+// @Router /api/shelves/{id} [delete]
+// @Router /api/shelves/{id}/books [get]
+// @Router /api/shelves/{id}/books [put]
+// @Router /api/shelves/{id}/books/{bookId} [put]
+// @Router /api/shelves/{id}/books/{bookId} [delete]
+func (h *Handler) RegisterShelfRoutes(router *gin.RouterGroup) {
+	shelves := router.Group("/shelves")
+	{
+		shelves.GET("", h.ListShelves)
+		shelves.POST("", h.CreateShelf)
+		shelves.GET("/:id", h.GetShelf)
+		shelves.PUT("/:id", h.UpdateShelf)
+		shelves.DELETE("/:id", h.DeleteShelf)
+		shelves.GET("/:id/books", h.ListShelfBooks)
+		shelves.PUT("/:id/books", h.ReorderShelfBooks)
+		shelves.PUT("/:id/books/:bookId", h.PlaceShelfBook)
+		shelves.DELETE("/:id/books/:bookId", h.RemoveShelfBook)
+	}
+}
+
+// ListShelves returns the shelves of the authenticated user
+// @Summary List shelves
+// @Description Get the shelves of the authenticated user in their order, with the number of books on each
+// @Tags shelves
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entities.Shelf "Successfully retrieved shelves"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/shelves [get]
+func (h *Handler) ListShelves(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	shelves, err := h.shelfSvc.ListShelves(c.Request.Context(), user.ID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shelves)
+}
+
+// CreateShelf creates a new shelf
+// @Summary Create a shelf
+// @Description Create an empty shelf for the authenticated user
+// @Tags shelves
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param shelf body ShelfInput true "Shelf data"
+// @Success 201 {object} entities.Shelf "Successfully created shelf"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 409 {object} ErrorResponse "The user already has a shelf with this name"
+// @Router /api/shelves [post]
+func (h *Handler) CreateShelf(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	var input ShelfInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	shelf := input.toEntity()
+	shelf.UserID = user.ID
+	if err := h.shelfSvc.CreateShelf(c.Request.Context(), shelf); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, shelf)
+}
+
+// GetShelf gets a shelf
+// @Summary Get a shelf
+// @Description Get a shelf of the authenticated user with its number of books
+// @Tags shelves
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Shelf ID"
+// @Success 200 {object} entities.Shelf "Successfully retrieved shelf"
+// @Failure 400 {object} ErrorResponse "Invalid ID"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Shelf not found"
+// @Router /api/shelves/{id} [get]
+func (h *Handler) GetShelf(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	shelf, err := h.shelfSvc.GetShelf(c.Request.Context(), id, user.ID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shelf)
+}
+
+// UpdateShelf updates a shelf
+// @Summary Update a shelf
+// @Description Update the name, description and position of a shelf
+// @Tags shelves
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Shelf ID"
+// @Param shelf body ShelfInput true "Shelf data"
+// @Success 200 {object} entities.Shelf "Successfully updated shelf"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Shelf not found"
+// @Failure 409 {object} ErrorResponse "The user already has a shelf with this name"
+// @Router /api/shelves/{id} [put]
+func (h *Handler) UpdateShelf(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input ShelfInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	shelf := input.toEntity()
+	if err := h.shelfSvc.UpdateShelf(c.Request.Context(), id, user.ID, shelf); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shelf)
+}
+
+// DeleteShelf deletes a shelf
+// @Summary Delete a shelf
+// @Description Delete a shelf of the authenticated user, leaving its books untouched
+// @Tags shelves
+// @Security BearerAuth
+// @Param id path int true "Shelf ID"
+// @Success 204 "Successfully deleted shelf"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Shelf not found"
+// @Router /api/shelves/{id} [delete]
+func (h *Handler) DeleteShelf(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.shelfSvc.DeleteShelf(c.Request.Context(), id, user.ID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListShelfBooks returns the books of a shelf
+// @Summary List the books of a shelf
+// @Description Get a page of the books of a shelf in the shelf order, localized for the reader's Accept-Language
+// @Tags shelves
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Shelf ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Param Accept-Language header string false "Preferred languages"
+// @Success 200 {object} ShelfBooksResponse "Books of the shelf"
+// @Failure 400 {object} ErrorResponse "Invalid pagination"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Shelf not found"
+// @Router /api/shelves/{id}/books [get]
+func (h *Handler) ListShelfBooks(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+	page, limit, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	books, total, err := h.shelfSvc.ListShelfBooks(c.Request.Context(), id, user.ID, page, limit)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	if err := h.bookSvc.LocalizeBooks(c.Request.Context(), preferredLanguages(c), books...); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	data := make([]BookResponse, len(books))
+	for i, b := range books {
+		data[i] = h.newBookResponse(b)
+	}
+
+	c.Header("Vary", "Accept-Language")
+	c.JSON(http.StatusOK, ShelfBooksResponse{
+		Data:  data,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	})
+}
+
+// ReorderShelfBooks sets the order of the books of a shelf
+// @Summary Reorder the books of a shelf
+// @Description Set the order of the books of a shelf, listing each of them exactly once
+// @Tags shelves
+// @Security BearerAuth
+// @Accept json
+// @Param id path int true "Shelf ID"
+// @Param order body ShelfOrderInput true "New order of the books"
+// @Success 204 "Successfully reordered books"
+// @Failure 400 {object} ErrorResponse "Invalid order"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Shelf not found"
+// @Router /api/shelves/{id}/books [put]
+func (h *Handler) ReorderShelfBooks(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input ShelfOrderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	if err := h.shelfSvc.ReorderBooks(c.Request.Context(), id, user.ID, input.BookIDs); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PlaceShelfBook puts a book on a shelf
+// @Summary Add a book to a shelf
+// @Description Put a book on a shelf at a position, or move it there when it's already on the shelf. Without a position, the book goes last.
+// @Tags shelves
+// @Security BearerAuth
+// @Accept json
+// @Param id path int true "Shelf ID"
+// @Param bookId path string true "Book ID"
+// @Param placement body ShelfBookInput false "Position of the book"
+// @Success 204 "Successfully placed book"
+// @Failure 400 {object} ErrorResponse "Invalid position"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Shelf or book not found"
+// @Router /api/shelves/{id}/books/{bookId} [put]
+func (h *Handler) PlaceShelfBook(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input ShelfBookInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+			return
+		}
+	}
+
+	if err := h.shelfSvc.PlaceBook(c.Request.Context(), id, user.ID, c.Param("bookId"), input.Position); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RemoveShelfBook takes a book off a shelf
+// @Summary Remove a book from a shelf
+// @Description Take a book off a shelf, leaving the book itself untouched
+// @Tags shelves
+// @Security BearerAuth
+// @Param id path int true "Shelf ID"
+// @Param bookId path string true "Book ID"
+// @Success 204 "Successfully removed book"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Shelf not found"
+// @Router /api/shelves/{id}/books/{bookId} [delete]
+func (h *Handler) RemoveShelfBook(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.shelfSvc.RemoveBook(c.Request.Context(), id, user.ID, c.Param("bookId")); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
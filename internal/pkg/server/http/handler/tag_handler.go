@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"net/http"
+
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TagInput represents the tag update request body
+// swagger:model TagInput
+type TagInput struct {
+	// Name of the tag
+	// required: true
+	// example: science-fiction
+	Name string `json:"name" binding:"required"`
+}
+
+// RegisterTagRoutes registers the tag routes
+// @Summary Register tag routes
+// @Description Register all tag related routes
+// @Tags tags
+// @Security BearerAuth
+// @Router /api/tags [get]
+// @Router /api/tags/{id} [put]
+// @Router /api/tags/{id} [delete]
+func (h *Handler) RegisterTagRoutes(router *gin.RouterGroup) {
+	tags := router.Group("/tags")
+	{
+		tags.GET("", h.ListTags)
+		tags.PUT("/:id", h.UpdateTag)
+		tags.DELETE("/:id", h.DeleteTag)
+	}
+}
+
+// ListTags returns the tags of the authenticated user
+// @Summary List tags
+// @Description Get the tags of the authenticated user by name, with the number of books having each of them
+// @Tags tags
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} entities.Tag "Successfully retrieved tags"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/tags [get]
+func (h *Handler) ListTags(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	tags, err := h.bookSvc.ListTags(c.Request.Context(), user.ID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// UpdateTag renames a tag
+// @Summary Rename a tag
+// @Description Rename a tag of the authenticated user on all the books having it
+// @Tags tags
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Tag ID"
+// @Param tag body TagInput true "Tag data"
+// @Success 200 {object} entities.Tag "Successfully renamed tag"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 404 {object} ErrorResponse "Tag not found"
+// @Failure 409 {object} ErrorResponse "The user already has a tag with this name"
+// @Router /api/tags/{id} [put]
+func (h *Handler) UpdateTag(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input TagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	tag, err := h.bookSvc.RenameTag(c.Request.Context(), id, user.ID, input.Name)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag deletes a tag
+// @Summary Delete a tag
+// @Description Remove a tag of the authenticated user from all its books and delete it
+// @Tags tags
+// @Security BearerAuth
+// @Param id path int true "Tag ID"
+// @Success 204 "Successfully deleted tag"
+// @Failure 404 {object} ErrorResponse "Tag not found"
+// @Router /api/tags/{id} [delete]
+func (h *Handler) DeleteTag(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.bookSvc.DeleteTag(c.Request.Context(), id, user.ID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
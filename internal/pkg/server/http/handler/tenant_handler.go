@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantInput represents the tenant of a user in a request body
+// swagger:model TenantInput
+type TenantInput struct {
+	// ID of the tenant, empty to take the user out of their tenant
+	// example: acme
+	TenantID string `json:"tenant_id"`
+}
+
+// RegisterTenantAdminRoutes registers the admin routes moving users between
+// tenants
+// @Summary Register tenant admin routes
+// @Description Register the route setting the tenant of a user
+// @Tags admin
+// @Security BearerAuth
+// @Router /api/admin/users/{id}/tenant [put]
+func (h *Handler) RegisterTenantAdminRoutes(router *gin.RouterGroup) {
+	router.PUT("/users/:id/tenant", h.SetUserTenant)
+}
+
+// SetUserTenant sets the tenant of a user
+// @Summary Set the tenant of a user
+// @Description Move a user to a tenant, whose shared glossaries they then use, or out of their tenant with an empty ID
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param tenant body TenantInput true "Tenant"
+// @Success 200 {object} user.User "The user in their tenant"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 403 {object} ErrorResponse "Admin access required"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Router /api/admin/users/{id}/tenant [put]
+func (h *Handler) SetUserTenant(c *gin.Context) {
+	var input TenantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request body"})
+		return
+	}
+
+	user, err := h.authSvc.SetTenant(c.Request.Context(), c.Param("id"), input.TenantID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"clean-arch-go/internal/domain/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterTranslationMemoryRoutes registers the admin routes exchanging
+// translations with CAT tools
+// @Summary Register translation memory routes
+// @Description Register the TMX and XLIFF import/export routes
+// @Tags admin
+// @Security BearerAuth
+// @Router /api/admin/translations/tmx [get]
+// @Router /api/admin/translations/tmx [post]
+// @Router /api/admin/translations/xliff [get]
+// @Router /api/admin/translations/xliff [post]
+func (h *Handler) RegisterTranslationMemoryRoutes(router *gin.RouterGroup) {
+	translations := router.Group("/translations")
+	{
+		translations.GET("/tmx", h.ExportTMX)
+		translations.POST("/tmx", h.ImportTMX)
+		translations.GET("/xliff", h.ExportXLIFF)
+		translations.POST("/xliff", h.ImportXLIFF)
+	}
+}
+
+// ExportTMX exports the translation memory as TMX
+// @Summary Export translation memory
+// @Description Export the stored translations as a TMX 1.4 file
+// @Tags admin
+// @Security BearerAuth
+// @Produce application/x-tmx+xml
+// @Param lang query string false "Only export translations into this language"
+// @Success 200 {file} file "TMX file"
+// @Failure 403 {object} ErrorResponse "Admin access required"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/admin/translations/tmx [get]
+func (h *Handler) ExportTMX(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := h.translationMemorySvc.ExportTMX(c.Request.Context(), &buf, c.Query("lang")); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="translations.tmx"`)
+	c.Data(http.StatusOK, "application/x-tmx+xml", buf.Bytes())
+}
+
+// ImportTMX imports a reviewed TMX file into the translation memory
+// @Summary Import translation memory
+// @Description Import a TMX file. Translations modified after the export are reported as conflicts and left untouched unless overwrite is set.
+// @Tags admin
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Accept application/x-tmx+xml
+// @Produce json
+// @Param file formData file false "TMX file"
+// @Param dry_run query bool false "Only report the changes"
+// @Param overwrite query bool false "Apply conflicting translations"
+// @Success 200 {object} service.ImportReport "Import report"
+// @Failure 400 {object} ErrorResponse "Invalid file"
+// @Failure 403 {object} ErrorResponse "Admin access required"
+// @Router /api/admin/translations/tmx [post]
+func (h *Handler) ImportTMX(c *gin.Context) {
+	opts, ok := importOptions(c)
+	if !ok {
+		return
+	}
+
+	body, err := uploadedFile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "TMX file is required"})
+		return
+	}
+	defer body.Close()
+
+	report, err := h.translationMemorySvc.ImportTMX(c.Request.Context(), body, opts)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportXLIFF exports the UI messages as XLIFF
+// @Summary Export UI messages
+// @Description Export the UI messages with their translation into a language as an XLIFF 2.0 file
+// @Tags admin
+// @Security BearerAuth
+// @Produce application/xliff+xml
+// @Param lang query string true "Target language"
+// @Success 200 {file} file "XLIFF file"
+// @Failure 400 {object} ErrorResponse "Invalid language"
+// @Failure 403 {object} ErrorResponse "Admin access required"
+// @Router /api/admin/translations/xliff [get]
+func (h *Handler) ExportXLIFF(c *gin.Context) {
+	lang := c.Query("lang")
+	if lang == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Target language is required"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.translationMemorySvc.ExportXLIFF(c.Request.Context(), &buf, lang); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="messages.%s.xlf"`, lang))
+	c.Data(http.StatusOK, "application/xliff+xml", buf.Bytes())
+}
+
+// ImportXLIFF imports reviewed UI messages from an XLIFF file
+// @Summary Import UI messages
+// @Description Import an XLIFF 2.0 file. Messages whose source or translation changed after the export are reported as conflicts and left untouched unless overwrite is set.
+// @Tags admin
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Accept application/xliff+xml
+// @Produce json
+// @Param file formData file false "XLIFF file"
+// @Param dry_run query bool false "Only report the changes"
+// @Param overwrite query bool false "Apply conflicting messages"
+// @Success 200 {object} service.ImportReport "Import report"
+// @Failure 400 {object} ErrorResponse "Invalid file"
+// @Failure 403 {object} ErrorResponse "Admin access required"
+// @Router /api/admin/translations/xliff [post]
+func (h *Handler) ImportXLIFF(c *gin.Context) {
+	opts, ok := importOptions(c)
+	if !ok {
+		return
+	}
+
+	body, err := uploadedFile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "XLIFF file is required"})
+		return
+	}
+	defer body.Close()
+
+	report, err := h.translationMemorySvc.ImportXLIFF(c.Request.Context(), body, opts)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// importOptions parses the dry_run and overwrite query parameters
+func importOptions(c *gin.Context) (service.ImportOptions, bool) {
+	var opts service.ImportOptions
+	var err error
+
+	if value := c.Query("dry_run"); value != "" {
+		if opts.DryRun, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid dry_run"})
+			return opts, false
+		}
+	}
+	if value := c.Query("overwrite"); value != "" {
+		if opts.Overwrite, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid overwrite"})
+			return opts, false
+		}
+	}
+	return opts, true
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"net/http"
+
+	"clean-arch-go/internal/pkg/server/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrashListResponse represents a page of deleted books
+// swagger:response trashListResponse
+type TrashListResponse struct {
+	// Deleted books, last deleted first
+	Data []BookResponse `json:"data"`
+
+	// Total number of deleted books
+	// example: 2
+	Total int64 `json:"total"`
+
+	// Current page
+	// example: 1
+	Page int `json:"page"`
+
+	// Items per page
+	// example: 10
+	Limit int `json:"limit"`
+}
+
+// RegisterTrashRoutes registers the routes of the trash of deleted books
+// @Summary Register trash routes
+// @Description Register the routes listing, restoring and purging the deleted books of the user
+// @Tags books
+// @Security BearerAuth
+// @Router /api/books/trash [get]
+// @Router /api/books/trash/{id}/restore [post]
+// @Router /api/books/trash/{id} [delete]
+func (h *Handler) RegisterTrashRoutes(router *gin.RouterGroup) {
+	trash := router.Group("/books/trash")
+	{
+		trash.GET("", h.ListTrash)
+		trash.POST("/:id/restore", h.RestoreBook)
+		trash.DELETE("/:id", h.PurgeBook)
+	}
+}
+
+// ListTrash returns the deleted books of the user
+// @Summary List deleted books
+// @Description Get a page of the deleted books of the authenticated user, last deleted first. They are purged once the trash retention period is over.
+// @Tags books
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} TrashListResponse "Deleted books"
+// @Failure 400 {object} ErrorResponse "Invalid page"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Router /api/books/trash [get]
+func (h *Handler) ListTrash(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	page, limit, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	books, total, err := h.bookTrashSvc.ListTrash(c.Request.Context(), user.ID, page, limit)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	data := make([]BookResponse, len(books))
+	for i, b := range books {
+		b.ContentLang = b.Lang
+		data[i] = h.newBookResponse(b)
+	}
+	c.JSON(http.StatusOK, TrashListResponse{
+		Data:  data,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	})
+}
+
+// RestoreBook takes a deleted book out of the trash
+// @Summary Restore a deleted book
+// @Description Restore a deleted book of the authenticated user with its tags, reviews, shelves and shares
+// @Tags books
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Book ID"
+// @Success 200 {object} BookResponse "Restored book"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Book not found in the trash"
+// @Router /api/books/trash/{id}/restore [post]
+func (h *Handler) RestoreBook(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	book, err := h.bookTrashSvc.RestoreBook(c.Request.Context(), user.ID, c.Param("id"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	book.ContentLang = book.Lang
+	c.JSON(http.StatusOK, h.newBookResponse(book))
+}
+
+// PurgeBook deletes a book in the trash for good
+// @Summary Purge a deleted book
+// @Description Permanently delete a book of the authenticated user from the trash, with its cover, translations, reviews, reading progress and lending history. The users waiting for it are told their hold is cancelled.
+// @Tags books
+// @Security BearerAuth
+// @Param id path string true "Book ID"
+// @Success 204 "Book purged"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Book not found in the trash"
+// @Failure 409 {object} ErrorResponse "A copy of the book is on loan"
+// @Router /api/books/trash/{id} [delete]
+func (h *Handler) PurgeBook(c *gin.Context) {
+	user, _ := middleware.GetUserFromContext(c.Request.Context())
+
+	if err := h.bookTrashSvc.PurgeBook(c.Request.Context(), user.ID, c.Param("id")); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -12,6 +12,12 @@ type HTTPConfig struct {
 	RateLimit   int
 	RateBurst   int
 	ShutdownTimeout time.Duration
+	// Secret signs the pagination cursors
+	Secret      string
+	// MaxUploadBytes is the maximum size of uploaded images
+	MaxUploadBytes int64
+	// MaxImportBytes is the maximum size of imported book files
+	MaxImportBytes int64
 }
 
 func NewHTTPConfig(cfg *config.Config) *HTTPConfig {
@@ -22,5 +28,8 @@ func NewHTTPConfig(cfg *config.Config) *HTTPConfig {
 		RateLimit:   cfg.RateLimit.Limit,
 		RateBurst:   cfg.RateLimit.Burst,
 		ShutdownTimeout: time.Second * 5,
+		Secret:      cfg.App.Secret,
+		MaxUploadBytes: cfg.Storage.CoverMaxBytes,
+		MaxImportBytes: cfg.Transfer.ImportMaxBytes,
 	}
 }
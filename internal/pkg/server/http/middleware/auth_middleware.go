@@ -78,6 +78,26 @@ func (m *AuthMiddleware) AuthOptional() gin.HandlerFunc {
 	}
 }
 
+// AdminRequired is a middleware that only lets the given admin users through.
+// It must run after AuthRequired.
+func (m *AuthMiddleware) AdminRequired(adminEmails []string) gin.HandlerFunc {
+	admins := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		admins[strings.ToLower(email)] = true
+	}
+
+	return func(c *gin.Context) {
+		user, ok := GetUserFromContext(c.Request.Context())
+		if !ok || !admins[strings.ToLower(user.Email)] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // extractToken extracts the JWT token from the Authorization header
 func extractToken(c *gin.Context) (string, error) {
 	authHeader := c.GetHeader("Authorization")
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	root := t.TempDir()
+	s := NewLocalStorage(root, "http://localhost:8080/static/")
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "covers/book-1/x/original.png", []byte("first"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(ctx, "covers/book-1/x/original.png", []byte("second"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	file, err := s.Get(ctx, "covers/book-1/x/original.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "second" {
+		t.Errorf("Get = %q, want the replaced object", data)
+	}
+	info, err := os.Stat(filepath.Join(root, "covers", "book-1", "x", "original.png"))
+	if err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("stored file = %v, %v, want it readable by the server", info, err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(root, "covers", "book-1", "x"))
+	if len(entries) != 1 {
+		t.Errorf("%d files next to the object, want no temporary file left", len(entries))
+	}
+
+	if got := s.URL("covers/book-1/x/original.png"); got != "http://localhost:8080/static/covers/book-1/x/original.png" {
+		t.Errorf("URL = %s", got)
+	}
+
+	if err := s.Put(ctx, "covers/book-2/y/original.png", []byte("other"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "covers/book-1/x/original.png", "covers/book-1/x/missing.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "covers/book-1/x/original.png"); err != ErrNotFound {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	// The directories left empty go, the ones still holding objects stay
+	if _, err := os.Stat(filepath.Join(root, "covers", "book-1")); !os.IsNotExist(err) {
+		t.Errorf("empty directory kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "covers", "book-2", "y", "original.png")); err != nil {
+		t.Errorf("other object: %v", err)
+	}
+	if _, err := os.Stat(root); err != nil {
+		t.Errorf("root: %v", err)
+	}
+}
+
+func TestLocalStorageRejectsKeysOutsideTheRoot(t *testing.T) {
+	s := NewLocalStorage(filepath.Join(t.TempDir(), "root"), "/static")
+	ctx := context.Background()
+	for _, key := range []string{"../escape", "covers/../../escape", "/etc/passwd", ""} {
+		if err := s.Put(ctx, key, []byte("x"), "text/plain"); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+		if _, err := s.Get(ctx, key); err == nil || err == ErrNotFound {
+			t.Errorf("Get(%q) = %v, want an invalid key", key, err)
+		}
+		if err := s.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) succeeded", key)
+		}
+	}
+}
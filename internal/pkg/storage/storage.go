@@ -0,0 +1,24 @@
+// Package storage stores files, such as book covers, in a local directory or
+// an S3-compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when there's no object at a key
+var ErrNotFound = errors.New("object not found")
+
+// Storage stores objects by key and tells where clients can download them
+type Storage interface {
+	// Put creates or replaces the object at key
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get opens the object at key, ErrNotFound when there's none
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the objects at keys, ignoring the missing ones
+	Delete(ctx context.Context, keys ...string) error
+	// URL returns the public URL of the object at key
+	URL(key string) string
+}
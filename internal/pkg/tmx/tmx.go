@@ -0,0 +1,112 @@
+// Package tmx reads and writes Translation Memory eXchange (TMX 1.4) documents.
+package tmx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DateFormat is the TMX date format (ISO 8601 basic, UTC)
+const DateFormat = "20060102T150405Z"
+
+// Document is a TMX document
+type Document struct {
+	XMLName xml.Name `xml:"tmx"`
+	Version string   `xml:"version,attr"`
+	Header  Header   `xml:"header"`
+	Units   []Unit   `xml:"body>tu"`
+}
+
+// Header holds the TMX header attributes
+type Header struct {
+	CreationTool        string `xml:"creationtool,attr"`
+	CreationToolVersion string `xml:"creationtoolversion,attr"`
+	SegType             string `xml:"segtype,attr"`
+	OTMF                string `xml:"o-tmf,attr"`
+	AdminLang           string `xml:"adminlang,attr"`
+	SrcLang             string `xml:"srclang,attr"`
+	DataType            string `xml:"datatype,attr"`
+	CreationDate        string `xml:"creationdate,attr,omitempty"`
+}
+
+// Unit is a translation unit, holding the same text in several languages
+type Unit struct {
+	ID         string    `xml:"tuid,attr,omitempty"`
+	SrcLang    string    `xml:"srclang,attr,omitempty"`
+	ChangeDate string    `xml:"changedate,attr,omitempty"`
+	Variants   []Variant `xml:"tuv"`
+}
+
+// Variant is the text of a translation unit in one language
+type Variant struct {
+	Lang    string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Segment string `xml:"seg"`
+}
+
+// NewDocument creates an empty document whose units use srcLang as source
+func NewDocument(srcLang string) *Document {
+	return &Document{
+		Version: "1.4",
+		Header: Header{
+			CreationTool:        "clean-arch-go",
+			CreationToolVersion: "1.0",
+			SegType:             "sentence",
+			OTMF:                "clean-arch-go",
+			AdminLang:           "en",
+			SrcLang:             srcLang,
+			DataType:            "plaintext",
+			CreationDate:        time.Now().UTC().Format(DateFormat),
+		},
+	}
+}
+
+// Source returns the variant in the source language of the unit. The unit
+// srclang wins over the header one; the first variant is used otherwise.
+func (d *Document) Source(unit *Unit) (*Variant, bool) {
+	srcLang := unit.SrcLang
+	if srcLang == "" {
+		srcLang = d.Header.SrcLang
+	}
+	for i := range unit.Variants {
+		if unit.Variants[i].Lang == srcLang {
+			return &unit.Variants[i], true
+		}
+	}
+	if len(unit.Variants) == 0 {
+		return nil, false
+	}
+	return &unit.Variants[0], true
+}
+
+// ChangedAt returns the change date of a unit, if it has a valid one
+func (u *Unit) ChangedAt() (time.Time, bool) {
+	if u.ChangeDate == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(DateFormat, u.ChangeDate)
+	return t, err == nil
+}
+
+// Encode writes the document as indented XML
+func Encode(w io.Writer, doc *Document) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode TMX: %w", err)
+	}
+	return encoder.Flush()
+}
+
+// Decode reads a TMX document
+func Decode(r io.Reader) (*Document, error) {
+	var doc Document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode TMX: %w", err)
+	}
+	return &doc, nil
+}
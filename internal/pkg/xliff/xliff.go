@@ -0,0 +1,118 @@
+// Package xliff reads and writes XLIFF 2.0 documents.
+package xliff
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Namespace is the XLIFF 2.0 core namespace
+const Namespace = "urn:oasis:names:tc:xliff:document:2.0"
+
+// Segment states
+const (
+	StateInitial    = "initial"
+	StateTranslated = "translated"
+	StateReviewed   = "reviewed"
+	StateFinal      = "final"
+)
+
+// Document is an XLIFF 2.0 document with a single source and target language
+type Document struct {
+	XMLName xml.Name `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+	Version string   `xml:"version,attr"`
+	SrcLang string   `xml:"srcLang,attr"`
+	TrgLang string   `xml:"trgLang,attr,omitempty"`
+	Files   []File   `xml:"file"`
+}
+
+// File groups the units extracted from one resource
+type File struct {
+	ID    string `xml:"id,attr"`
+	Units []Unit `xml:"unit"`
+}
+
+// Unit is a translatable message
+type Unit struct {
+	ID       string    `xml:"id,attr"`
+	Notes    []Note    `xml:"notes>note,omitempty"`
+	Segments []Segment `xml:"segment"`
+}
+
+// Note is an annotation of a unit
+type Note struct {
+	ID       string `xml:"id,attr,omitempty"`
+	Category string `xml:"category,attr,omitempty"`
+	Text     string `xml:",chardata"`
+}
+
+// Segment holds the source text and its translation
+type Segment struct {
+	ID     string `xml:"id,attr,omitempty"`
+	State  string `xml:"state,attr,omitempty"`
+	Source string `xml:"source"`
+	Target string `xml:"target,omitempty"`
+}
+
+// NewDocument creates an empty document
+func NewDocument(srcLang, trgLang string) *Document {
+	return &Document{
+		Version: "2.0",
+		SrcLang: srcLang,
+		TrgLang: trgLang,
+	}
+}
+
+// Note returns the text of the first note with the given category
+func (u *Unit) Note(category string) (string, bool) {
+	for _, note := range u.Notes {
+		if note.Category == category {
+			return note.Text, true
+		}
+	}
+	return "", false
+}
+
+// Source returns the source text of the unit, joining its segments
+func (u *Unit) Source() string {
+	var text string
+	for _, segment := range u.Segments {
+		text += segment.Source
+	}
+	return text
+}
+
+// Target returns the target text of the unit, joining its segments
+func (u *Unit) Target() string {
+	var text string
+	for _, segment := range u.Segments {
+		text += segment.Target
+	}
+	return text
+}
+
+// Encode writes the document as indented XML
+func Encode(w io.Writer, doc *Document) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode XLIFF: %w", err)
+	}
+	return encoder.Flush()
+}
+
+// Decode reads an XLIFF 2.0 document
+func Decode(r io.Reader) (*Document, error) {
+	var doc Document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode XLIFF: %w", err)
+	}
+	if doc.Version != "2.0" {
+		return nil, fmt.Errorf("unsupported XLIFF version %q", doc.Version)
+	}
+	return &doc, nil
+}
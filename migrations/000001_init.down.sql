@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS `locale_messages`;
+DROP TABLE IF EXISTS `glossary_terms`;
+DROP TABLE IF EXISTS `glossaries`;
+DROP TABLE IF EXISTS `translations`;
+DROP TABLE IF EXISTS `book_translations`;
+DROP TABLE IF EXISTS `book_tags`;
+DROP TABLE IF EXISTS `books`;
+DROP TABLE IF EXISTS `tags`;
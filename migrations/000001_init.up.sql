@@ -0,0 +1,104 @@
+CREATE TABLE `tags` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` varchar(36) NOT NULL,
+    `name` varchar(50) NOT NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_tag_user_name` (`user_id`, `name`)
+);
+
+CREATE TABLE `books` (
+    `id` varchar(36),
+    `title` varchar(255) NOT NULL,
+    `description` text,
+    `author` varchar(100) NOT NULL,
+    `published_year` bigint,
+    `lang` varchar(10),
+    `user_id` varchar(36) NOT NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    FULLTEXT INDEX `idx_books_fulltext` (`title`, `description`, `author`),
+    INDEX `idx_books_author` (`author`),
+    INDEX `idx_books_published_year` (`published_year`),
+    INDEX `idx_books_user_id` (`user_id`),
+    INDEX `idx_books_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE `book_tags` (
+    `book_id` varchar(36),
+    `tag_id` bigint unsigned,
+    PRIMARY KEY (`book_id`, `tag_id`),
+    CONSTRAINT `fk_book_tags_book` FOREIGN KEY (`book_id`) REFERENCES `books` (`id`),
+    CONSTRAINT `fk_book_tags_tag` FOREIGN KEY (`tag_id`) REFERENCES `tags` (`id`)
+);
+
+CREATE TABLE `book_translations` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `book_id` varchar(36) NOT NULL,
+    `lang` varchar(10) NOT NULL,
+    `title` varchar(255) NOT NULL,
+    `description` text,
+    `source` varchar(10) NOT NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_book_translation` (`book_id`, `lang`)
+);
+
+CREATE TABLE `translations` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `source_text` longtext NOT NULL,
+    `source_lang` varchar(10),
+    `target_lang` longtext NOT NULL,
+    `translated_text` longtext NOT NULL,
+    `last_accessed` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_translations_deleted_at` (`deleted_at`),
+    INDEX `idx_translations_last_accessed` (`last_accessed`)
+);
+
+CREATE TABLE `glossaries` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` varchar(36) NOT NULL,
+    `name` varchar(100) NOT NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_glossaries_user_id` (`user_id`),
+    INDEX `idx_glossaries_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE `glossary_terms` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `glossary_id` bigint unsigned NOT NULL,
+    `term` varchar(255) NOT NULL,
+    `target_lang` varchar(10),
+    `translation` varchar(255),
+    `protected` boolean,
+    `case_sensitive` boolean,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_glossary_terms_glossary_id` (`glossary_id`),
+    INDEX `idx_glossary_terms_target_lang` (`target_lang`),
+    INDEX `idx_glossary_terms_deleted_at` (`deleted_at`),
+    CONSTRAINT `fk_glossaries_terms` FOREIGN KEY (`glossary_id`) REFERENCES `glossaries` (`id`)
+);
+
+CREATE TABLE `locale_messages` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `lang` varchar(10) NOT NULL,
+    `message_id` varchar(191) NOT NULL,
+    `text` text NOT NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_locale_message` (`lang`, `message_id`)
+);
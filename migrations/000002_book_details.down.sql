@@ -0,0 +1,8 @@
+ALTER TABLE `books`
+    DROP INDEX `idx_books_lang`,
+    DROP INDEX `idx_books_publisher`,
+    DROP INDEX `idx_books_user_isbn`,
+    DROP COLUMN `edition`,
+    DROP COLUMN `page_count`,
+    DROP COLUMN `publisher`,
+    DROP COLUMN `isbn`;
@@ -0,0 +1,8 @@
+ALTER TABLE `books`
+    ADD COLUMN `isbn` varchar(13) NULL AFTER `author`,
+    ADD COLUMN `publisher` varchar(255) AFTER `published_year`,
+    ADD COLUMN `page_count` bigint AFTER `publisher`,
+    ADD COLUMN `edition` varchar(50) AFTER `page_count`,
+    ADD UNIQUE INDEX `idx_books_user_isbn` (`user_id`, `isbn`),
+    ADD INDEX `idx_books_publisher` (`publisher`),
+    ADD INDEX `idx_books_lang` (`lang`);
@@ -0,0 +1,2 @@
+ALTER TABLE `books`
+    ADD COLUMN `cover_key` varchar(255) AFTER `edition`;
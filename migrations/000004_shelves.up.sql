@@ -0,0 +1,20 @@
+CREATE TABLE `shelves` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` varchar(36) NOT NULL,
+    `name` varchar(100) NOT NULL,
+    `description` varchar(500),
+    `position` bigint NOT NULL DEFAULT 0,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_shelf_user_name` (`user_id`, `name`)
+);
+
+CREATE TABLE `shelf_books` (
+    `shelf_id` bigint unsigned,
+    `book_id` varchar(36),
+    `position` bigint NOT NULL,
+    `added_at` datetime(3) NULL,
+    PRIMARY KEY (`shelf_id`, `book_id`),
+    INDEX `idx_shelf_books_book_id` (`book_id`)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `reading_sessions`;
+DROP TABLE IF EXISTS `reading_progress`;
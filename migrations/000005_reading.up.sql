@@ -0,0 +1,29 @@
+CREATE TABLE `reading_progress` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` varchar(36) NOT NULL,
+    `book_id` varchar(36) NOT NULL,
+    `status` varchar(20) NOT NULL,
+    `current_page` bigint,
+    `percent` double,
+    `started_at` datetime(3) NULL,
+    `finished_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_reading_user_book` (`user_id`, `book_id`),
+    INDEX `idx_reading_user_finished` (`user_id`, `finished_at`),
+    INDEX `idx_reading_progress_book_id` (`book_id`)
+);
+
+CREATE TABLE `reading_sessions` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` varchar(36) NOT NULL,
+    `book_id` varchar(36) NOT NULL,
+    `start_page` bigint,
+    `end_page` bigint,
+    `started_at` datetime(3) NOT NULL,
+    `ended_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_reading_session_user_book` (`user_id`, `book_id`)
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `reviews`;
+
+ALTER TABLE `books`
+    DROP COLUMN `rating_count`,
+    DROP COLUMN `rating_average`;
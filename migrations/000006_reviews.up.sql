@@ -0,0 +1,24 @@
+ALTER TABLE `books`
+    ADD COLUMN `rating_average` double NOT NULL DEFAULT 0 AFTER `cover_key`,
+    ADD COLUMN `rating_count` bigint NOT NULL DEFAULT 0 AFTER `rating_average`;
+
+CREATE TABLE `reviews` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `book_id` varchar(36) NOT NULL,
+    `user_id` varchar(36) NOT NULL,
+    `rating` bigint NOT NULL,
+    `title` varchar(200),
+    `body` text,
+    `hidden` boolean NOT NULL DEFAULT false,
+    `flagged` boolean NOT NULL DEFAULT false,
+    `moderation_note` varchar(500),
+    `moderated_by` varchar(36),
+    `moderated_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_review_book_user` (`book_id`, `user_id`),
+    INDEX `idx_review_book_created` (`book_id`, `created_at`),
+    INDEX `idx_reviews_user_id` (`user_id`),
+    INDEX `idx_reviews_flagged` (`flagged`)
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `notifications`;
+DROP TABLE IF EXISTS `holds`;
+DROP TABLE IF EXISTS `loans`;
+DROP TABLE IF EXISTS `book_copies`;
@@ -0,0 +1,56 @@
+CREATE TABLE `book_copies` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `book_id` varchar(36) NOT NULL,
+    `label` varchar(100),
+    `status` varchar(20) NOT NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_copy_book_status` (`book_id`, `status`)
+);
+
+CREATE TABLE `loans` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `copy_id` bigint unsigned NOT NULL,
+    `book_id` varchar(36) NOT NULL,
+    `user_id` varchar(36) NOT NULL,
+    `checked_out_at` datetime(3) NOT NULL,
+    `due_at` datetime(3) NOT NULL,
+    `returned_at` datetime(3) NULL,
+    `renewals` bigint NOT NULL DEFAULT 0,
+    `overdue_notified_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_loans_copy_id` (`copy_id`),
+    INDEX `idx_loans_book_id` (`book_id`),
+    INDEX `idx_loans_user_id` (`user_id`),
+    INDEX `idx_loans_due_at` (`due_at`)
+);
+
+CREATE TABLE `holds` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `book_id` varchar(36) NOT NULL,
+    `user_id` varchar(36) NOT NULL,
+    `status` varchar(20) NOT NULL,
+    `copy_id` bigint unsigned NULL,
+    `ready_at` datetime(3) NULL,
+    `expires_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_hold_book_status` (`book_id`, `status`),
+    INDEX `idx_holds_user_id` (`user_id`)
+);
+
+CREATE TABLE `notifications` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` varchar(36) NOT NULL,
+    `kind` varchar(30) NOT NULL,
+    `book_id` varchar(36),
+    `message` varchar(500) NOT NULL,
+    `read_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_notification_user_read` (`user_id`, `read_at`)
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS `book_grants`;
+
+ALTER TABLE `books`
+    DROP INDEX `idx_books_share_token`,
+    DROP INDEX `idx_books_visibility`,
+    DROP COLUMN `share_token`,
+    DROP COLUMN `visibility`;
@@ -0,0 +1,16 @@
+ALTER TABLE `books`
+    ADD COLUMN `visibility` varchar(10) NOT NULL DEFAULT 'private' AFTER `rating_count`,
+    ADD COLUMN `share_token` varchar(64) NULL AFTER `visibility`,
+    ADD INDEX `idx_books_visibility` (`visibility`),
+    ADD UNIQUE INDEX `idx_books_share_token` (`share_token`);
+
+CREATE TABLE `book_grants` (
+    `book_id` varchar(36) NOT NULL,
+    `user_id` varchar(36) NOT NULL,
+    `role` varchar(10) NOT NULL,
+    `granted_by` varchar(36) NOT NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`book_id`, `user_id`),
+    INDEX `idx_book_grants_user_id` (`user_id`)
+);
@@ -0,0 +1,21 @@
+CREATE TABLE `book_jobs` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` varchar(36) NOT NULL,
+    `kind` varchar(10) NOT NULL,
+    `format` varchar(10) NOT NULL,
+    `status` varchar(20) NOT NULL,
+    `dry_run` boolean NOT NULL DEFAULT false,
+    `processed` bigint NOT NULL DEFAULT 0,
+    `created` bigint NOT NULL DEFAULT 0,
+    `skipped` bigint NOT NULL DEFAULT 0,
+    `failed` bigint NOT NULL DEFAULT 0,
+    `row_errors` text,
+    `error` varchar(500),
+    `started_at` datetime(3) NULL,
+    `finished_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_book_jobs_user_id` (`user_id`),
+    INDEX `idx_book_jobs_status` (`status`)
+);
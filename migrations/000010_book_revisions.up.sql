@@ -0,0 +1,14 @@
+CREATE TABLE `book_revisions` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `book_id` varchar(36) NOT NULL,
+    `number` bigint NOT NULL,
+    `user_id` varchar(36) NOT NULL,
+    `action` varchar(10) NOT NULL,
+    `reverted_to` bigint NOT NULL DEFAULT 0,
+    `changes` text,
+    `snapshot` text,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_book_revision_number` (`book_id`, `number`),
+    INDEX `idx_book_revisions_user_id` (`user_id`)
+);
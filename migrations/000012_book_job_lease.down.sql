@@ -0,0 +1,3 @@
+ALTER TABLE `book_jobs`
+    DROP COLUMN `heartbeat_at`,
+    DROP COLUMN `worker`;
@@ -0,0 +1,3 @@
+ALTER TABLE `book_jobs`
+    ADD COLUMN `worker` varchar(32) AFTER `error`,
+    ADD COLUMN `heartbeat_at` datetime(3) NULL AFTER `worker`;
@@ -0,0 +1,2 @@
+ALTER TABLE `locale_messages`
+    DROP COLUMN `forms`;
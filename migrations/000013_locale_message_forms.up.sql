@@ -0,0 +1,2 @@
+ALTER TABLE `locale_messages`
+    ADD COLUMN `forms` text NULL AFTER `text`;
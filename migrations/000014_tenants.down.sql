@@ -0,0 +1,7 @@
+ALTER TABLE `glossaries`
+    DROP INDEX `idx_glossaries_tenant_id`,
+    DROP COLUMN `tenant_id`;
+
+ALTER TABLE `users`
+    DROP INDEX `idx_users_tenant_id`,
+    DROP COLUMN `tenant_id`;
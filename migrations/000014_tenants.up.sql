@@ -0,0 +1,7 @@
+ALTER TABLE `users`
+    ADD COLUMN `tenant_id` varchar(36) NULL AFTER `name`,
+    ADD INDEX `idx_users_tenant_id` (`tenant_id`);
+
+ALTER TABLE `glossaries`
+    ADD COLUMN `tenant_id` varchar(36) NULL AFTER `user_id`,
+    ADD INDEX `idx_glossaries_tenant_id` (`tenant_id`);